@@ -3,10 +3,12 @@ package dalgo2memory
 import (
 	"context"
 	"fmt"
+	"maps"
 	"reflect"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/dal-go/dalgo/branching"
 	"github.com/dal-go/dalgo/dal"
@@ -23,6 +25,9 @@ var checkpointSequence atomic.Uint64
 
 var (
 	serializedEngineFactoryPC = reflect.ValueOf(serializedEngineFactory).Pointer()
+	// Method values share their code pointer whatever the receiver, so this
+	// identifies the factory of every collection with secondary indexes.
+	indexedSerializedEngineFactoryPC = reflect.ValueOf(serializedConfig{}.newEngine).Pointer()
 )
 
 // NewBranchingProvider returns the optional database branching capability for
@@ -102,7 +107,7 @@ func validateSerializedConfiguration(db *database) error {
 	for _, collection := range sortedFactoryNames(db.schema.engines) {
 		factory := db.schema.engines[collection]
 		factoryPC := reflect.ValueOf(factory).Pointer()
-		if factoryPC == serializedEngineFactoryPC {
+		if factoryPC == serializedEngineFactoryPC || factoryPC == indexedSerializedEngineFactoryPC {
 			continue
 		}
 		// A non-serialized factory is user-supplied configuration. Do not call it
@@ -149,6 +154,9 @@ func unsupportedEngineMode(collection, mode string) error {
 
 type databaseSnapshot struct {
 	collections                     map[string]serializedEngineSnapshot
+	updateTimes                     map[string]map[string]time.Time
+	clock                           func() time.Time
+	lastStamp                       time.Time
 	schema                          *memorySchema
 	noReadsAfterWritesInTransaction bool
 	schemaRefBreaking               bool
@@ -159,6 +167,7 @@ type serializedEngineSnapshot struct {
 	factory    func() any
 	records    map[string][]byte
 	keys       map[string]*record.Key
+	indexes    []*secondaryIndex
 }
 
 func snapshotDatabase(db *database) databaseSnapshot {
@@ -169,6 +178,9 @@ func snapshotDatabase(db *database) databaseSnapshot {
 	}
 	return databaseSnapshot{
 		collections:                     collections,
+		updateTimes:                     cloneUpdateTimes(db.updateTimes),
+		clock:                           db.clock,
+		lastStamp:                       db.lastStamp,
 		schema:                          cloneMemorySchema(db.schema),
 		noReadsAfterWritesInTransaction: db.noReadsAfterWritesInTransaction,
 		schemaRefBreaking:               db.schemaRefBreaking,
@@ -189,9 +201,18 @@ func snapshotSerializedEngine(engine *serializedEngine) serializedEngineSnapshot
 		factory:    engine.factory,
 		records:    records,
 		keys:       keys,
+		indexes:    cloneIndexes(engine.indexes),
 	}
 }
 
+func cloneUpdateTimes(updateTimes map[string]map[string]time.Time) map[string]map[string]time.Time {
+	clone := make(map[string]map[string]time.Time, len(updateTimes))
+	for collection, times := range updateTimes {
+		clone[collection] = maps.Clone(times)
+	}
+	return clone
+}
+
 func cloneMemorySchema(schema *memorySchema) *memorySchema {
 	if schema == nil {
 		return nil
@@ -207,6 +228,8 @@ func cloneMemorySchema(schema *memorySchema) *memorySchema {
 	return &memorySchema{
 		collections:    collections,
 		engines:        engines,
+		uniques:        maps.Clone(schema.uniques),
+		defs:           cloneCollectionDefs(schema.defs),
 		allowUndefined: schema.allowUndefined,
 	}
 }
@@ -275,6 +298,9 @@ func (c *serializedCheckpoint) Release(context.Context) error {
 func (s databaseSnapshot) newDatabase() dal.DB {
 	db := &database{
 		collections:                     make(map[string]storageEngine, len(s.collections)),
+		updateTimes:                     cloneUpdateTimes(s.updateTimes),
+		clock:                           s.clock,
+		lastStamp:                       s.lastStamp,
 		schema:                          cloneMemorySchema(s.schema),
 		noReadsAfterWritesInTransaction: s.noReadsAfterWritesInTransaction,
 		schemaRefBreaking:               s.schemaRefBreaking,
@@ -299,6 +325,7 @@ func (s serializedEngineSnapshot) newEngine() *serializedEngine {
 		factory:    s.factory,
 		records:    records,
 		keys:       keys,
+		indexes:    cloneIndexes(s.indexes),
 	}
 }
 
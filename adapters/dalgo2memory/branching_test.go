@@ -303,6 +303,7 @@ func (e *branchingCustomEngine) update(id string, updates []update.Update) error
 	return e.inner.update(id, updates)
 }
 func (e *branchingCustomEngine) rows() ([]engineRow, error) { return e.inner.rows() }
+func (e *branchingCustomEngine) snapshot() func()           { return e.inner.snapshot() }
 
 func TestTwoMemoryDatabasesConformAsOneGroup(t *testing.T) {
 	t.Run("fresh handles and sibling isolation", func(t *testing.T) {
@@ -1,9 +1,12 @@
 package dalgo2memory
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 
@@ -28,6 +31,7 @@ type column struct {
 	values      reflect.Value // a slice of elemType, indexed by slot
 	elemType    reflect.Type
 	refBearing  bool
+	orderKind   int // kindNumber or kindString when the query path orders every value alike, else -1
 	strategy    ColumnStrategy
 	defaultStgy *typedSliceStrategy // non-nil when strategy is the default
 	engine      *columnarEngine
@@ -202,6 +206,7 @@ func (e *columnarEngine) buildColumns() {
 			values:     reflect.MakeSlice(reflect.SliceOf(elemType), 0, 0),
 			elemType:   elemType,
 			refBearing: isRefBearing(field.Type),
+			orderKind:  orderKindOf(elemType, quotedField(field)),
 			engine:     e,
 		}
 		if stgy, ok := e.stgyByCol[name]; ok && stgy != nil {
@@ -233,6 +238,7 @@ func (e *columnarEngine) buildDeclaredColumns(declared []declaredColumn) {
 			values:     reflect.MakeSlice(reflect.SliceOf(elemType), 0, 0),
 			elemType:   elemType,
 			refBearing: isRefBearing(dc.elemType),
+			orderKind:  orderKindOf(elemType, false),
 			engine:     e,
 		}
 		if stgy, ok := e.stgyByCol[dc.name]; ok && stgy != nil {
@@ -275,6 +281,43 @@ func jsonFieldName(field reflect.StructField) (string, bool) {
 	}
 }
 
+// quotedField reports whether a field's json tag has the "string" option,
+// which encodes a number as a JSON string.
+func quotedField(field reflect.StructField) bool {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return false
+	}
+	_, opts, _ := strings.Cut(tag, ",")
+	return slices.Contains(strings.Split(opts, ","), "string")
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// orderKindOf returns the kind every value of a column of type t decodes to
+// in the rows' field view when that is a number or a string, and -1 for any
+// other type, one with its own JSON or text encoding, or a quoted field. A
+// float32 is left out too: it decodes to the float64 of its shortest
+// representation, not of its value.
+func orderKindOf(t reflect.Type, quoted bool) int {
+	if quoted || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return -1
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float64:
+		return kindNumber
+	case reflect.String:
+		return kindString
+	default:
+		return -1
+	}
+}
+
 // isRefBearing reports whether a field type can share references with the
 // caller's value (slice, map, pointer, interface, or a struct/array containing
 // one). Such columns are deep-copied via a JSON round-trip on write when the
@@ -433,12 +476,12 @@ func (e *columnarEngine) buildRows(ids []string) ([]engineRow, error) {
 	return rows, nil
 }
 
-// candidateRows implements the equality-WHERE acceleration the query path uses:
-// for the single supported field==value predicate it consults the target
-// column's strategy. When the strategy returns a slot set (ok=true), only those
-// live slots are reassembled; "no opinion" returns ok=false and the caller
-// scans all rows. The caller re-applies matchesWhere to the returned rows, so
-// the result is identical to a full scan regardless of the strategy.
+// candidateRows implements the WHERE acceleration the query path uses: the
+// columns' strategies answer the conjuncts of the condition (see
+// candidateSlots). When they return a slot set (ok=true), only those live
+// slots are reassembled; "no opinion" returns ok=false and the caller scans
+// all rows. The caller re-applies matchesWhere to the returned rows, so the
+// result is identical to a full scan regardless of the strategy.
 func (e *columnarEngine) candidateRows(condition dal.Condition) ([]engineRow, bool, error) {
 	if e.initErr != nil {
 		return nil, false, e.initErr
@@ -447,6 +490,15 @@ func (e *columnarEngine) candidateRows(condition dal.Condition) ([]engineRow, bo
 	if !ok {
 		return nil, false, nil
 	}
+	rows, err := e.buildRows(e.liveIDs(slots))
+	if err != nil {
+		return nil, false, err
+	}
+	return rows, true, nil
+}
+
+// liveIDs returns, sorted, the ids of the live slots among slots.
+func (e *columnarEngine) liveIDs(slots SlotSet) []string {
 	ids := make([]string, 0, len(slots))
 	for slot := range slots {
 		if slot >= 0 && slot < len(e.live) && e.live[slot] {
@@ -454,35 +506,220 @@ func (e *columnarEngine) candidateRows(condition dal.Condition) ([]engineRow, bo
 		}
 	}
 	sort.Strings(ids)
-	rows, err := e.buildRows(ids)
-	if err != nil {
-		return nil, false, err
+	return ids
+}
+
+// candidateCount is the number of rows candidateRows returns for the condition
+// and the columns whose strategies select them, with ok=false when the
+// strategies have no opinion.
+func (e *columnarEngine) candidateCount(condition dal.Condition) (string, int, bool, error) {
+	if e.initErr != nil {
+		return "", 0, false, e.initErr
 	}
-	return rows, true, nil
+	slots, columns, ok := e.selectSlots(condition)
+	if !ok {
+		return "", 0, false, nil
+	}
+	return strings.Join(columns, ", "), len(e.liveIDs(slots)), true, nil
+}
+
+// count is the number of stored rows.
+func (e *columnarEngine) count() int {
+	return len(e.idToSlot)
 }
 
-// candidateSlots returns the live slots a single equality predicate selects via
-// the target column's strategy, with ok=true; ok=false means "no opinion"
-// (caller scans). It is only consulted for the single field==value predicate
-// the adapter supports; any other condition shape yields no opinion.
+// candidateSlots returns the slots the conjuncts of a WHERE condition select
+// via their columns' strategies, intersected, with ok=true; ok=false means "no
+// opinion" (caller scans). A field==value conjunct
+// is answered by EqualSlots, and a range (>, >=, <, <=, Between) by the
+// RangeSlots of a column the query path orders (see orderKindOf) whose
+// strategy implements RangeStrategy. Other conjuncts are left to the filter,
+// so ignoring them is safe.
 func (e *columnarEngine) candidateSlots(condition dal.Condition) (SlotSet, bool) {
-	comparison, ok := condition.(dal.Comparison)
-	if !ok || comparison.Operator != dal.Equal {
+	slots, _, ok := e.selectSlots(condition)
+	return slots, ok
+}
+
+// selectSlots is candidateSlots, also returning the columns consulted.
+func (e *columnarEngine) selectSlots(condition dal.Condition) (SlotSet, []string, bool) {
+	var slots SlotSet
+	var columns []string
+	narrow := func(col *column, selected SlotSet, ok bool) {
+		if !ok {
+			return
+		}
+		columns = append(columns, col.name)
+		if slots == nil {
+			slots = selected
+			return
+		}
+		both := make(SlotSet, min(len(slots), len(selected)))
+		for slot := range slots {
+			if _, ok := selected[slot]; ok {
+				both[slot] = struct{}{}
+			}
+		}
+		slots = both
+	}
+	ranges := indexPredicates(condition)
+	consulted := make(map[string]bool)
+	for _, c := range conjuncts(condition) {
+		comparison, ok := c.(dal.Comparison)
+		if !ok || isComputed(comparison.Left) || isComputed(comparison.Right) {
+			continue
+		}
+		field, ok := comparison.Left.(dal.FieldRef)
+		if !ok {
+			continue
+		}
+		col, ok := e.byName[field.Name()]
+		if !ok {
+			continue
+		}
+		if constant, ok := comparison.Right.(dal.Constant); ok && comparison.Operator == dal.Equal {
+			selected, ok := col.strategy.EqualSlots(constant.Value)
+			narrow(col, selected, ok)
+			continue
+		}
+		if consulted[col.name] {
+			continue
+		}
+		consulted[col.name] = true
+		if lower, upper, ok := col.rangeBounds(ranges[col.name]); ok {
+			selected, ok := col.strategy.(RangeStrategy).RangeSlots(lower, upper)
+			narrow(col, selected, ok)
+		}
+	}
+	if slots == nil {
+		return nil, nil, false
+	}
+	return slots, columns, true
+}
+
+// rangeBounds returns the bounds a field predicate sets on the column, when
+// the column's strategy answers ranges and the bounds are of the column's kind.
+func (c *column) rangeBounds(p *fieldPredicate) (lower, upper *Bound, ok bool) {
+	if _, ok := c.strategy.(RangeStrategy); !ok || c.orderKind < 0 || p == nil || p.lower == nil && p.upper == nil {
+		return nil, nil, false
+	}
+	for _, b := range []struct {
+		from *indexBound
+		to   **Bound
+	}{{p.lower, &lower}, {p.upper, &upper}} {
+		if b.from == nil {
+			continue
+		}
+		if kindOf(b.from.value) != c.orderKind {
+			return nil, nil, false
+		}
+		*b.to = &Bound{Value: b.from.value, Inclusive: b.from.inclusive}
+	}
+	return lower, upper, true
+}
+
+// orderedColumn returns the column a query's rows can be read in order from:
+// its first ORDER BY key, when every key is a field and that field's column
+// is one the query path orders (see orderKindOf) with a strategy that
+// implements OrderedStrategy and has an opinion.
+func (e *columnarEngine) orderedColumn(orderBy []dal.OrderExpression) (*column, bool) {
+	if e.initErr != nil {
 		return nil, false
 	}
-	field, ok := comparison.Left.(dal.FieldRef)
-	if !ok {
+	for _, oe := range orderBy {
+		if _, ok := oe.Expression().(dal.FieldRef); !ok {
+			return nil, false
+		}
+	}
+	col, ok := e.byName[orderBy[0].Expression().(dal.FieldRef).Name()]
+	if !ok || col.orderKind < 0 {
 		return nil, false
 	}
-	constant, ok := comparison.Right.(dal.Constant)
-	if !ok {
+	stgy, ok := col.strategy.(OrderedStrategy)
+	if !ok || !stgy.OrderedSlots(false, func([]int) bool { return false }) {
 		return nil, false
 	}
-	col, ok := e.byName[field.Name()]
+	return col, true
+}
+
+// orderedIndex reports the column orderedRows reads in order and the number
+// of rows it considers: those the WHERE's strategies select, else all.
+func (e *columnarEngine) orderedIndex(orderBy []dal.OrderExpression, condition dal.Condition) (string, int, bool) {
+	col, ok := e.orderedColumn(orderBy)
 	if !ok {
-		return nil, false
+		return "", 0, false
+	}
+	if slots, ok := e.candidateSlots(condition); ok {
+		return col.name, len(e.liveIDs(slots)), true
 	}
-	return col.strategy.EqualSlots(constant.Value)
+	return col.name, e.count(), true
+}
+
+// orderedRows reads the rows group by group of equal values of the first
+// ORDER BY column, as its strategy iterates them, skipping the slots the
+// WHERE's strategies rule out. The rows of a group are ordered by the other
+// ORDER BY keys and then by id, like orderBySources.
+func (e *columnarEngine) orderedRows(orderBy []dal.OrderExpression, condition dal.Condition, n int, keep func(engineRow) (bool, error)) ([]engineRow, bool, error) {
+	col, ok := e.orderedColumn(orderBy)
+	if !ok {
+		return nil, false, nil
+	}
+	candidates, filtered := e.candidateSlots(condition)
+	var rows []engineRow
+	var err error
+	visit := func(row engineRow) bool {
+		var kept bool
+		if kept, err = keep(row); err != nil {
+			return false
+		}
+		if kept {
+			rows = append(rows, row)
+		}
+		return len(rows) < n
+	}
+	col.strategy.(OrderedStrategy).OrderedSlots(orderBy[0].Descending(), func(group []int) bool {
+		slots := make(SlotSet, len(group))
+		for _, slot := range group {
+			if _, ok := candidates[slot]; ok || !filtered {
+				slots[slot] = struct{}{}
+			}
+		}
+		ids := e.liveIDs(slots)
+		if len(orderBy) == 1 {
+			for _, id := range ids {
+				var row []engineRow
+				if row, err = e.buildRows([]string{id}); err != nil || !visit(row[0]) {
+					return false
+				}
+			}
+			return true
+		}
+		var groupRows []engineRow
+		if groupRows, err = e.buildRows(ids); err != nil {
+			return false
+		}
+		sort.SliceStable(groupRows, func(i, j int) bool {
+			return compareOrderValues(fieldValues(orderBy, groupRows[i].data), fieldValues(orderBy, groupRows[j].data), orderBy) < 0
+		})
+		for _, row := range groupRows {
+			if !visit(row) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return rows, true, nil
+}
+
+// fieldValues returns a row's values of ORDER BY keys that are all fields.
+func fieldValues(orderBy []dal.OrderExpression, data map[string]any) []any {
+	values := make([]any, len(orderBy))
+	for i, oe := range orderBy {
+		values[i] = data[oe.Expression().(dal.FieldRef).Name()]
+	}
+	return values
 }
 
 // allocSlot reserves a slot for id: it reuses a free (tombstoned) slot when one
@@ -792,6 +1029,55 @@ func (e *columnarEngine) compact() {
 	e.rebuildStrategies()
 }
 
+// snapshot copies the slot model and every column slice. Cells are written in
+// place (writeSlot), so the slices are copied rather than shared; leftover maps
+// and keys are replaced on write and are shared. On restore an explicit
+// external strategy is re-synced: every slot the discarded state may have
+// touched is cleared, then the restored live slots are re-set.
+func (e *columnarEngine) snapshot() func() {
+	if e.initErr != nil {
+		return func() {}
+	}
+	values := make([]reflect.Value, len(e.columns))
+	for i, col := range e.columns {
+		values[i] = reflect.MakeSlice(col.values.Type(), col.values.Len(), col.values.Len())
+		reflect.Copy(values[i], col.values)
+	}
+	idToSlot := maps.Clone(e.idToSlot)
+	slotToID := slices.Clone(e.slotToID)
+	slotToKey := slices.Clone(e.slotToKey)
+	live := slices.Clone(e.live)
+	freeList := slices.Clone(e.freeList)
+	deadCount := e.deadCount
+	leftover := slices.Clone(e.leftover)
+	return func() {
+		touched := max(len(e.live), len(live))
+		for i, col := range e.columns {
+			col.values = values[i]
+		}
+		e.idToSlot = idToSlot
+		e.slotToID = slotToID
+		e.slotToKey = slotToKey
+		e.live = live
+		e.freeList = freeList
+		e.deadCount = deadCount
+		e.leftover = leftover
+		for _, col := range e.columns {
+			if col.defaultStgy != nil {
+				continue
+			}
+			for slot := 0; slot < touched; slot++ {
+				col.strategy.ClearValue(slot)
+			}
+			for slot, isLive := range e.live {
+				if isLive {
+					col.strategy.SetValue(slot, col.values.Index(slot).Interface())
+				}
+			}
+		}
+	}
+}
+
 // rebuildStrategies re-syncs each column's strategy with the compacted slices:
 // the default strategy reads the live slices directly (nothing to do), while an
 // explicit external strategy is notified via its write side per live slot.
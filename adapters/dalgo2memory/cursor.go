@@ -0,0 +1,139 @@
+package dalgo2memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+)
+
+// pageCursor is the decoded form of the opaque cursor a dalgo2memory reader
+// reports after each Next. It is a keyset position: the ORDER BY values of the
+// last returned row plus the row's identity (the final ORDER BY tiebreak), so a
+// query resumed via StartFrom continues after that row even when rows before
+// it were inserted or deleted in between. Grouped results also carry the
+// group's ordinal, used when the group no longer exists on resume.
+type pageCursor struct {
+	Values  []any  `json:"v,omitempty"`
+	ID      string `json:"id"`
+	Ordinal int    `json:"n,omitempty"`
+}
+
+// encode renders the cursor as an opaque URL-safe string.
+func (c pageCursor) encode() string {
+	// Values are drawn from JSON-normalized row data and aggregate results, so
+	// marshaling cannot fail.
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor passed to StartFrom. An empty cursor yields nil
+// (start from the beginning); a cursor not produced by this adapter is an
+// error.
+func decodeCursor(cursor dal.Cursor) (*pageCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(cursor))
+	if err != nil {
+		return nil, fmt.Errorf("dalgo2memory: invalid cursor %q: %w", cursor, err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("dalgo2memory: invalid cursor %q: %w", cursor, err)
+	}
+	return &c, nil
+}
+
+// follows reports whether a row with the given ORDER BY values and identity
+// sorts strictly after the cursor position under the shared comparator.
+func (c *pageCursor) follows(values []any, id string, orderBy []dal.OrderExpression) bool {
+	if cmp := compareOrderValues(values, c.Values, orderBy); cmp != 0 {
+		return cmp > 0
+	}
+	return id > c.ID
+}
+
+// paginate applies StartFrom, then OFFSET, then LIMIT to rows already in their
+// final order, returning the page and one cursor per returned row. keyOf
+// reports a row's ORDER BY values and its identity (the sort tiebreak).
+func paginate[T any](rows []T, q dal.StructuredQuery, keyOf func(T) ([]any, string)) ([]T, []string, error) {
+	start, err := decodeCursor(q.StartFrom())
+	if err != nil {
+		return nil, nil, err
+	}
+	if start != nil {
+		orderBy := q.OrderBy()
+		i := 0
+		for i < len(rows) {
+			values, id := keyOf(rows[i])
+			if start.follows(values, id, orderBy) {
+				break
+			}
+			i++
+		}
+		rows = rows[i:]
+	}
+	rows = applyOffsetLimit(rows, q.Offset(), q.Limit())
+	cursors := make([]string, len(rows))
+	for i, row := range rows {
+		values, id := keyOf(row)
+		cursors[i] = pageCursor{Values: values, ID: id}.encode()
+	}
+	return rows, cursors, nil
+}
+
+// applyOffsetLimit skips offset rows and then keeps at most limit rows. A
+// non-positive offset or limit is ignored.
+func applyOffsetLimit[T any](rows []T, offset, limit int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return rows[:0]
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// recordsReader is the dal.RecordsReader the query paths return: it walks a
+// fully built result and reports, via Cursor, the position after the most
+// recently returned record.
+type recordsReader struct {
+	records []record.Record
+	cursors []string
+	current int
+}
+
+var _ dal.RecordsReader = (*recordsReader)(nil)
+
+// newRecordsReader builds a reader over records with one cursor per record.
+func newRecordsReader(records []record.Record, cursors []string) *recordsReader {
+	return &recordsReader{records: records, cursors: cursors, current: -1}
+}
+
+func (r *recordsReader) Next() (record.Record, error) {
+	if r.current+1 >= len(r.records) {
+		return nil, dal.ErrNoMoreRecords
+	}
+	r.current++
+	return r.records[r.current], nil
+}
+
+// Cursor returns the position after the last record returned by Next, or an
+// empty string before the first Next. Passing it to StartFrom resumes the same
+// query after that record.
+func (r *recordsReader) Cursor() (string, error) {
+	if r.current < 0 {
+		return "", nil
+	}
+	return r.cursors[r.current], nil
+}
+
+func (r *recordsReader) Close() error {
+	return nil
+}
@@ -0,0 +1,245 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/require"
+)
+
+// seedScores loads five players p1..p5 with distinct scores, inserted out of
+// score order.
+func seedScores(t *testing.T) (*database, context.Context) {
+	t.Helper()
+	db := NewDB().(*database)
+	ctx := context.Background()
+	for id, score := range map[string]int{"p1": 30, "p2": 10, "p3": 50, "p4": 20, "p5": 40} {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("players", id), &map[string]any{"score": score})))
+	}
+	return db, ctx
+}
+
+// readPage drains a reader, returning the ids read and the final cursor.
+func readPage(t *testing.T, reader dal.RecordsReader) ([]string, dal.Cursor) {
+	t.Helper()
+	var ids []string
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, dal.ErrNoMoreRecords) {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, fmt.Sprint(rec.Key().ID))
+	}
+	cursor, err := reader.Cursor()
+	require.NoError(t, err)
+	return ids, dal.Cursor(cursor)
+}
+
+func playersByScore(cursor dal.Cursor, offset, limit int) dal.StructuredQuery {
+	return dal.From(dal.NewRootCollectionRef("players", "")).NewQuery().
+		OrderBy(dal.AscendingField("score")).
+		StartFrom(cursor).
+		Offset(offset).
+		Limit(limit).
+		SelectKeysOnly(reflect.String)
+}
+
+func TestPaging_OffsetAndLimit(t *testing.T) {
+	db, ctx := seedScores(t)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore("", 1, 2))
+	require.NoError(t, err)
+	ids, _ := readPage(t, reader)
+	require.Equal(t, []string{"p4", "p1"}, ids)
+
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, playersByScore("", 9, 0))
+	require.NoError(t, err)
+	ids, _ = readPage(t, reader)
+	require.Empty(t, ids)
+}
+
+func TestPaging_StartFromResumesAfterCursor(t *testing.T) {
+	db, ctx := seedScores(t)
+	var all []string
+	var cursor dal.Cursor
+	for page := 0; page < 3; page++ {
+		reader, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore(cursor, 0, 2))
+		require.NoError(t, err)
+		ids, next := readPage(t, reader)
+		all = append(all, ids...)
+		cursor = next
+	}
+	require.Equal(t, []string{"p2", "p4", "p1", "p5", "p3"}, all)
+}
+
+func TestPaging_CursorIsStableAcrossWrites(t *testing.T) {
+	db, ctx := seedScores(t)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore("", 0, 2))
+	require.NoError(t, err)
+	ids, cursor := readPage(t, reader)
+	require.Equal(t, []string{"p2", "p4"}, ids)
+
+	// A row sorting before the cursor, and the deletion of the cursor row
+	// itself, must not shift the next page.
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("players", "p0"), &map[string]any{"score": 1})))
+	require.NoError(t, db.Delete(ctx, record.NewKeyWithID("players", "p4")))
+
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, playersByScore(cursor, 0, 2))
+	require.NoError(t, err)
+	ids, _ = readPage(t, reader)
+	require.Equal(t, []string{"p1", "p5"}, ids)
+}
+
+func TestPaging_StartFromThenOffset(t *testing.T) {
+	db, ctx := seedScores(t)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore("", 0, 1))
+	require.NoError(t, err)
+	_, cursor := readPage(t, reader)
+
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, playersByScore(cursor, 1, 2))
+	require.NoError(t, err)
+	ids, _ := readPage(t, reader)
+	require.Equal(t, []string{"p1", "p5"}, ids)
+}
+
+func TestPaging_DescendingOrder(t *testing.T) {
+	db, ctx := seedScores(t)
+	build := func(cursor dal.Cursor) dal.Query {
+		return dal.From(dal.NewRootCollectionRef("players", "")).NewQuery().
+			OrderBy(dal.DescendingField("score")).
+			StartFrom(cursor).
+			Limit(3).
+			SelectKeysOnly(reflect.String)
+	}
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, build(""))
+	require.NoError(t, err)
+	ids, cursor := readPage(t, reader)
+	require.Equal(t, []string{"p3", "p5", "p1"}, ids)
+
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, build(cursor))
+	require.NoError(t, err)
+	ids, _ = readPage(t, reader)
+	require.Equal(t, []string{"p4", "p2"}, ids)
+}
+
+func TestPaging_JoinedQuery(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	build := func(cursor dal.Cursor, offset int) dal.Query {
+		join := dal.NewJoinedSource(ordersAlias(), dal.JoinLeft, onUserEqOrder())
+		return dal.From(usersAlias()).Join(join).NewQuery().
+			StartFrom(cursor).
+			Offset(offset).
+			Limit(1).
+			SelectColumns(
+				dal.Column{Alias: "user", Expression: dal.NewFieldRef("u", "id")},
+				dal.Column{Alias: "order", Expression: dal.NewFieldRef("o", "userId")},
+			)
+	}
+	var rows []map[string]any
+	var cursor dal.Cursor
+	for page := 0; page < 4; page++ {
+		reader, err := db.ExecuteQueryToRecordsReader(ctx, build(cursor, 0))
+		require.NoError(t, err)
+		for {
+			rec, err := reader.Next()
+			if errors.Is(err, dal.ErrNoMoreRecords) {
+				break
+			}
+			require.NoError(t, err)
+			rows = append(rows, rec.Data().(map[string]any))
+		}
+		c, err := reader.Cursor()
+		require.NoError(t, err)
+		cursor = dal.Cursor(c)
+	}
+	require.Len(t, rows, 3, "two matched pairs for user 1, one unmatched LEFT row for user 2")
+	require.EqualValues(t, 1, rows[0]["user"])
+	require.EqualValues(t, 1, rows[1]["user"])
+	require.EqualValues(t, 2, rows[2]["user"])
+	require.Nil(t, rows[2]["order"])
+
+	got := runJoinQuery(t, db, ctx, build("", 2))
+	require.Len(t, got, 1)
+	require.EqualValues(t, 2, got[0]["user"])
+}
+
+func TestPaging_GroupedQuery(t *testing.T) {
+	db, ctx := seedSales(t)
+	build := func(cursor dal.Cursor) dal.Query {
+		return salesQuery().
+			GroupBy(dal.Field("category")).
+			OrderBy(dal.AscendingField("category")).
+			StartFrom(cursor).
+			Limit(1).
+			SelectColumns(dal.Column{Expression: dal.Field("category")})
+	}
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, build(""))
+	require.NoError(t, err)
+	rec, err := reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "A", rec.Data().(map[string]any)["category"])
+	cursor, err := reader.Cursor()
+	require.NoError(t, err)
+
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, build(dal.Cursor(cursor)))
+	require.NoError(t, err)
+	rec, err = reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "B", rec.Data().(map[string]any)["category"])
+	_, err = reader.Next()
+	require.ErrorIs(t, err, dal.ErrNoMoreRecords)
+
+	// When the cursor's group is gone, the cursor's ordinal is used.
+	gone := pageCursor{ID: "missing", Ordinal: 0}.encode()
+	reader, err = db.ExecuteQueryToRecordsReader(ctx, build(dal.Cursor(gone)))
+	require.NoError(t, err)
+	rec, err = reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "B", rec.Data().(map[string]any)["category"])
+}
+
+func TestPaging_InvalidCursor(t *testing.T) {
+	db, ctx := seedScores(t)
+	_, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore("not base64!", 0, 0))
+	require.ErrorContains(t, err, "invalid cursor")
+
+	_, err = db.ExecuteQueryToRecordsReader(ctx, playersByScore(dal.Cursor("bm90IGpzb24"), 0, 0))
+	require.ErrorContains(t, err, "invalid cursor")
+
+	empty := dal.From(dal.NewRootCollectionRef("nobody", "")).NewQuery().StartFrom("!").SelectKeysOnly(reflect.String)
+	_, err = db.ExecuteQueryToRecordsReader(ctx, empty)
+	require.ErrorContains(t, err, "invalid cursor")
+
+	grouped := salesQuery().GroupBy(dal.Field("category")).StartFrom("!").
+		SelectColumns(dal.Column{Expression: dal.Field("category")})
+	db2, ctx2 := seedSales(t)
+	_, err = db2.ExecuteQueryToRecordsReader(ctx2, grouped)
+	require.ErrorContains(t, err, "invalid cursor")
+}
+
+func TestPaging_RecordsetReaderCursor(t *testing.T) {
+	db, ctx := seedScores(t)
+	reader, err := db.ExecuteQueryToRecordsetReader(ctx, playersByScore("", 0, 2))
+	require.NoError(t, err)
+	cursor, err := reader.Cursor()
+	require.NoError(t, err)
+	require.Empty(t, cursor)
+	for {
+		if _, _, err := reader.Next(); errors.Is(err, dal.ErrNoMoreRecords) {
+			break
+		}
+	}
+	cursor, err = reader.Cursor()
+	require.NoError(t, err)
+	require.NotEmpty(t, cursor)
+
+	records, err := db.ExecuteQueryToRecordsReader(ctx, playersByScore(dal.Cursor(cursor), 0, 0))
+	require.NoError(t, err)
+	ids, _ := readPage(t, records)
+	require.Equal(t, []string{"p1", "p5", "p3"}, ids)
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/dalgo/recordset"
@@ -48,6 +50,16 @@ type database struct {
 	// schemaRefBreaking is the schema-wide columnar fidelity default (faithful
 	// unless WithoutSchemaRefBreaking was used). NewDB initializes it to true.
 	schemaRefBreaking bool
+	// updateTimes holds each stored record's last write time, per collection
+	// and storage id, for WithLastUpdateTimePrecondition (see touch).
+	updateTimes map[string]map[string]time.Time
+	// clock stamps writes (time.Now when nil, see WithClock); lastStamp is the
+	// latest stamp issued, keeping stamps strictly increasing.
+	clock     func() time.Time
+	lastStamp time.Time
+	// uniques holds, per collection with unique indexes, the indexes built
+	// from its rows on first use (see uniqueIndexes).
+	uniques map[string][]*uniqueIndex
 }
 
 func (db *database) ID() string {
@@ -65,15 +77,56 @@ func (db *database) Schema() dal.Schema {
 func (db *database) RunReadonlyTransaction(ctx context.Context, f dal.ROTxWorker, _ ...dal.TransactionOption) error {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
-	return f(ctx, session{db: db})
+	return f(db.lockedContext(ctx, nil), session{db: db})
 }
 
-func (db *database) RunReadwriteTransaction(ctx context.Context, f dal.RWTxWorker, _ ...dal.TransactionOption) error {
+// lockedDB is the context key under which a transaction records the database
+// whose lock its worker runs under, so that DDL does not wait for it (see
+// runDDL).
+type lockedDB struct{}
+
+// lockedTransaction is the value stored under lockedDB: the database and, for
+// a read-write transaction, its state.
+type lockedTransaction struct {
+	db *database
+	tx *transactionState
+}
+
+func (db *database) lockedContext(ctx context.Context, tx *transactionState) context.Context {
+	return context.WithValue(ctx, lockedDB{}, lockedTransaction{db: db, tx: tx})
+}
+
+// transactionOf reports whether ctx is the context of a transaction worker of
+// db (or derived from it), which holds the database lock, and returns the
+// transaction's state, nil for a read-only transaction.
+func (db *database) transactionOf(ctx context.Context) (*transactionState, bool) {
+	locked, ok := ctx.Value(lockedDB{}).(lockedTransaction)
+	if !ok || locked.db != db {
+		return nil, false
+	}
+	return locked.tx, true
+}
+
+// RunReadwriteTransaction runs f under the database write lock. Every
+// collection the worker writes to is snapshotted before its first write; when
+// f returns an error or panics, all of the transaction's mutations are rolled
+// back, matching the all-or-nothing behavior of real transactional backends.
+func (db *database) RunReadwriteTransaction(ctx context.Context, f dal.RWTxWorker, _ ...dal.TransactionOption) (err error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	return f(ctx, session{db: db, txState: &transactionState{
+	tx := &transactionState{
 		noReadsAfterWrites: db.noReadsAfterWritesInTransaction,
-	}})
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+	if err = f(db.lockedContext(ctx, tx), session{db: db, txState: tx}); err != nil {
+		tx.rollback()
+	}
+	return err
 }
 
 func (db *database) Exists(ctx context.Context, key *record.Key) (bool, error) {
@@ -170,8 +223,33 @@ type session struct {
 type transactionState struct {
 	noReadsAfterWrites bool
 	hasWritten         bool
+	// restores holds, per collection written in the transaction, the func that
+	// restores the collection's engine to its state before the first write.
+	restores map[string]func()
+	// ddlRestores holds, in order, the funcs that undo the schema changes of
+	// the DDL run in the transaction (see runDDL).
+	ddlRestores []func()
+}
+
+// rollback restores every collection written in the transaction to its state
+// before the transaction's first write to it, then undoes the transaction's
+// DDL, latest first.
+func (tx *transactionState) rollback() {
+	for _, restore := range tx.restores {
+		restore()
+	}
+	for i := len(tx.ddlRestores) - 1; i >= 0; i-- {
+		tx.ddlRestores[i]()
+	}
+	tx.restores, tx.ddlRestores = nil, nil
 }
 
+// ErrDDLInTransaction is returned by CreateCollection, DropCollection and
+// AlterCollection called with the context of a read-only transaction of the
+// same database: the transaction holds the read lock, which the DDL cannot
+// upgrade to the write lock it needs.
+var ErrDDLInTransaction = errors.New("dalgo2memory: DDL cannot run inside a read-only transaction of the same database")
+
 // ErrReadAfterWriteInTransaction matches the ordering error returned by
 // Firestore transactions when a read follows a queued write.
 var ErrReadAfterWriteInTransaction = errors.New("firestore: read after write in transaction")
@@ -183,6 +261,33 @@ func (s session) allowRead() error {
 	return nil
 }
 
+// writeEngine resolves the engine for a collection about to be written. Inside
+// a read-write transaction the engine is snapshotted before the transaction's
+// first write to the collection so a failed transaction can roll it back.
+func (s session) writeEngine(collection string) storageEngine {
+	eng := s.db.engine(collection)
+	if s.txState == nil {
+		return eng
+	}
+	if _, ok := s.txState.restores[collection]; !ok {
+		if s.txState.restores == nil {
+			s.txState.restores = make(map[string]func())
+		}
+		restoreEngine := eng.snapshot()
+		times := maps.Clone(s.db.updateTimes[collection])
+		s.txState.restores[collection] = func() {
+			restoreEngine()
+			delete(s.db.uniques, collection)
+			if times == nil {
+				delete(s.db.updateTimes, collection)
+				return
+			}
+			s.db.updateTimes[collection] = times
+		}
+	}
+	return eng
+}
+
 func (s session) markWrite() {
 	if s.txState != nil {
 		s.txState.hasWritten = true
@@ -299,7 +404,11 @@ func (s session) InsertMulti(ctx context.Context, records []record.Record, opts
 }
 
 func (s session) Delete(_ context.Context, key *record.Key) error {
-	s.db.engine(key.Collection()).delete(keyID(key))
+	s.writeEngine(key.Collection()).delete(keyID(key))
+	for _, ix := range s.db.uniques[key.Collection()] {
+		ix.remove(keyID(key))
+	}
+	s.db.forget(key.Collection(), keyID(key))
 	s.markWrite()
 	return nil
 }
@@ -316,14 +425,37 @@ func (s session) Update(ctx context.Context, key *record.Key, updates []update.U
 	return s.UpdateRecord(ctx, record, updates, preconditions...)
 }
 
-func (s session) UpdateRecord(_ context.Context, record record.Record, updates []update.Update, _ ...dal.Precondition) error {
+// UpdateRecord applies updates to the stored record after evaluating the
+// preconditions (see checkPreconditions); a failed precondition leaves the
+// record unchanged.
+func (s session) UpdateRecord(_ context.Context, record record.Record, updates []update.Update, preconditions ...dal.Precondition) error {
 	collectionName := record.Key().Collection()
 	if err := s.db.guardCollection(collectionName); err != nil {
 		return err
 	}
-	if err := s.db.engine(collectionName).update(keyID(record.Key()), updates); err != nil {
+	if err := s.checkPreconditions(record.Key(), preconditions); err != nil {
 		return err
 	}
+	id := keyID(record.Key())
+	eng := s.writeEngine(collectionName)
+	uniques, err := s.db.uniqueIndexes(collectionName)
+	if err != nil {
+		return err
+	}
+	var data map[string]any
+	if len(uniques) > 0 {
+		var ok bool
+		if data, ok = updatedView(eng, record.Key(), updates); ok {
+			if err := checkUnique(uniques, collectionName, id, data); err != nil {
+				return err
+			}
+		}
+	}
+	if err := eng.update(id, updates); err != nil {
+		return err
+	}
+	s.db.putUnique(collectionName, uniques, id, record.Key(), data)
+	s.db.touch(collectionName, id)
 	s.markWrite()
 	return nil
 }
@@ -337,7 +469,7 @@ func (s session) UpdateMulti(ctx context.Context, keys []*record.Key, updates []
 	return nil
 }
 
-func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query) (dal.RecordsReader, error) {
+func (s session) ExecuteQueryToRecordsReader(ctx context.Context, query dal.Query) (dal.RecordsReader, error) {
 	if err := s.allowRead(); err != nil {
 		return nil, err
 	}
@@ -346,7 +478,7 @@ func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query)
 		return nil, dal.ErrNotSupported
 	}
 	if len(q.From().Joins()) > 0 {
-		return s.executeJoinQuery(q)
+		return s.executeJoinQuery(ctx, q)
 	}
 	base := q.From().Base()
 	collectionName := base.Name()
@@ -354,21 +486,30 @@ func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query)
 	if err != nil {
 		return nil, err
 	}
-	allRows, err := s.loadCandidateRows(collectionName, q.Where())
-	if err != nil {
-		return nil, err
-	}
-	if len(allRows) == 0 {
-		return dal.NewRecordsReader([]record.Record{}), nil
+	grouped := isGroupedQuery(q)
+	ordered, isOrdered := s.orderedPageEngine(collectionName, q)
+	var allRows []memoryRow
+	if !isOrdered {
+		if allRows, err = s.loadCandidateRows(collectionName, q.Where()); err != nil {
+			return nil, err
+		}
+		if len(allRows) == 0 && !grouped {
+			if _, err := decodeCursor(q.StartFrom()); err != nil {
+				return nil, err
+			}
+			return newRecordsReader(nil, nil), nil
+		}
 	}
 	known := map[string]bool{"": true, base.Name(): true}
 	if a := base.Alias(); a != "" {
 		known[a] = true
 	}
+	if err := validateConditionExpressions(q.Where(), known); err != nil {
+		return nil, err
+	}
 	if err := validateOrderSources(q.OrderBy(), known); err != nil {
 		return nil, err
 	}
-	grouped := len(q.GroupBy()) > 0
 	if !grouped {
 		if err := validateColumns(q.Columns(), known); err != nil {
 			return nil, err
@@ -378,20 +519,51 @@ func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query)
 	if cr, ok := base.(dal.CollectionRef); ok {
 		parent = cr.Parent()
 	}
-	rows := make([]memoryRow, 0, len(allRows))
-	for _, row := range allRows {
-		if !matchesWhere(row.data, q.Where()) {
-			continue
-		}
+	var subqueries *subqueryBinder
+	if dal.HasSubquery(q.Where()) {
+		subqueries = newSubqueryBinder(ctx, s, known)
+	}
+	keep := func(row memoryRow) (bool, error) {
 		// Parent-scoped query: keep only rows whose stored key is a direct child
 		// of the requested parent. A nil parent (root collection ref) is the
 		// collection-group case and keeps every row across all parents.
 		if parent != nil && !isChildOf(row.key, parent) {
-			continue
+			return false, nil
+		}
+		where := q.Where()
+		if subqueries != nil {
+			var err error
+			if where, err = subqueries.bind(where, baseSources(base, row.data)); err != nil {
+				return false, err
+			}
+		}
+		return matchesWhere(row.data, where), nil
+	}
+	var rows []memoryRow
+	if isOrdered {
+		page, _, err := ordered.orderedRows(q.OrderBy(), q.Where(), max(q.Offset(), 0)+q.Limit(), func(row engineRow) (bool, error) {
+			return keep(memoryRow(row))
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows = toMemoryRows(page)
+	} else {
+		rows = make([]memoryRow, 0, len(allRows))
+		for _, row := range allRows {
+			kept, err := keep(row)
+			if err != nil {
+				return nil, err
+			}
+			if kept {
+				rows = append(rows, row)
+			}
 		}
-		rows = append(rows, row)
 	}
 	if grouped {
+		// Group in id order so first-seen group order (and so group cursors)
+		// does not depend on the engine's enumeration order.
+		sortByID(rows)
 		srcs := make([]rowSources, len(rows))
 		for i, row := range rows {
 			srcs[i] = baseSources(base, row.data)
@@ -403,8 +575,19 @@ func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query)
 			return baseSources(base, r.data)
 		},
 		func(r memoryRow) string { return r.id })
-	if limit := q.Limit(); limit > 0 && limit < len(rows) {
-		rows = rows[:limit]
+	if dal.IsDistinct(q) {
+		rows = distinctRows(rows, func(r memoryRow) any {
+			if columns := q.Columns(); len(columns) > 0 {
+				return projectRow(columns, baseSources(base, r.data))
+			}
+			return r.data
+		})
+	}
+	rows, cursors, err := paginate(rows, q, func(r memoryRow) ([]any, string) {
+		return orderValues(q.OrderBy(), baseSources(base, r.data)), r.id
+	})
+	if err != nil {
+		return nil, err
 	}
 	columns := q.Columns()
 	records := make([]record.Record, len(rows))
@@ -433,7 +616,26 @@ func (s session) ExecuteQueryToRecordsReader(_ context.Context, query dal.Query)
 		}
 		records[i] = record.NewRecordWithData(key, data).SetError(nil)
 	}
-	return dal.NewRecordsReader(records), nil
+	return newRecordsReader(records, cursors), nil
+}
+
+// orderedPageEngine returns the collection's engine when it reads the rows of
+// a single-source query in ORDER BY order (see orderedRowsEngine), so that
+// only the rows up to the end of its page are read. That is only done for a
+// query with an ORDER BY and a LIMIT, and without grouping, DISTINCT or a
+// StartFrom cursor, which all need the rows before the page too.
+func (s session) orderedPageEngine(collectionName string, q dal.StructuredQuery) (orderedRowsEngine, bool) {
+	if len(q.OrderBy()) == 0 || q.Limit() <= 0 || dal.IsDistinct(q) || q.StartFrom() != "" || isGroupedQuery(q) {
+		return nil, false
+	}
+	eng, ok := s.db.engine(collectionName).(orderedRowsEngine)
+	if !ok {
+		return nil, false
+	}
+	if _, _, ok = eng.orderedIndex(q.OrderBy(), q.Where()); !ok {
+		return nil, false
+	}
+	return eng, true
 }
 
 var _ dal.ReadwriteTransaction = (*session)(nil)
@@ -452,10 +654,29 @@ func (s session) save(record record.Record, overwrite bool) error {
 		return err
 	}
 	record.SetError(nil)
-	if err := s.db.engine(collectionName).store(keyID(record.Key()), record, overwrite); err != nil {
+	id := keyID(record.Key())
+	eng := s.writeEngine(collectionName)
+	uniques, err := s.db.uniqueIndexes(collectionName)
+	if err != nil {
+		record.SetError(err)
+		return err
+	}
+	var data map[string]any
+	if len(uniques) > 0 {
+		var ok bool
+		if data, ok = fieldView(record.Data()); ok && (overwrite || !eng.exists(id)) {
+			if err := checkUnique(uniques, collectionName, id, data); err != nil {
+				record.SetError(err)
+				return err
+			}
+		}
+	}
+	if err := eng.store(id, record, overwrite); err != nil {
 		record.SetError(err)
 		return err
 	}
+	s.db.putUnique(collectionName, uniques, id, record.Key(), data)
+	s.db.touch(collectionName, id)
 	record.SetError(nil)
 	return nil
 }
@@ -511,56 +732,97 @@ func isChildOf(key, parent *record.Key) bool {
 
 // matchesWhere evaluates the WHERE condition shapes that dalgo2firestore
 // translates to native Firestore filters, so memory-backed tests behave like
-// the Firestore adapter:
+// the Firestore adapter, plus the extended operators (see applyOperator):
 //
-//   - FieldRef op Constant for ==, >, >=, <, <=
+//   - FieldRef op Constant for ==, !=, >, >=, <, <=, StartsWith, Like and
+//     ArrayContains
+//   - FieldRef IsNull / IsNotNull (no right operand)
+//   - FieldRef NotIn / Between dal.Array
 //   - Constant In FieldRef    → Firestore's "array-contains"
-//   - FieldRef op dal.Array   → Firestore's "array-contains-any"
+//   - FieldRef op dal.Array   → Firestore's "array-contains-any" for any other
+//     operator
+//   - any operator with a computed operand (dal.ScalarFunction or
+//     dal.Arithmetic) on either side, both sides evaluated per row
 //   - GroupCondition with AND → all sub-conditions must match
+//   - NotCondition            → the wrapped condition must be false; a
+//     comparison with a NULL left operand is unknown, so its negation does
+//     not match either (see truth)
+//   - InSubquery and Exists, once bound to their result for the row by a
+//     subqueryBinder
 //
 // Any other shape (including OR groups, which dalgo2firestore rejects) does
 // not match.
 func matchesWhere(data map[string]any, condition dal.Condition) bool {
-	if condition == nil {
-		return true
-	}
+	return whereTruth(data, condition) == isTrue
+}
+
+// whereTruth evaluates a WHERE condition in three-valued logic (see truth).
+func whereTruth(data map[string]any, condition dal.Condition) truth {
 	switch cond := condition.(type) {
+	case nil:
+		return isTrue
 	case dal.GroupCondition:
 		if cond.Operator() != dal.And {
-			return false
+			return isFalse
 		}
+		t := isTrue
 		for _, c := range cond.Conditions() {
-			if !matchesWhere(data, c) {
-				return false
+			if t = t.and(whereTruth(data, c)); t == isFalse {
+				break
 			}
 		}
-		return true
+		return t
+	case dal.NotCondition:
+		return whereTruth(data, cond.Condition()).not()
 	case dal.Comparison:
-		return matchesComparison(data, cond)
+		return comparisonTruth(cond.Operator, comparedValue(data, cond), matchesComparison(data, cond))
+	case subqueryResult:
+		return truthOf(bool(cond))
+	case unknownCondition:
+		return isUnknown
 	default:
-		return false
+		return isFalse
+	}
+}
+
+// comparedValue resolves the operand of a comparison that is tested against
+// the other: the left one, or the array field of Constant In FieldRef.
+func comparedValue(data map[string]any, comparison dal.Comparison) any {
+	if _, ok := comparison.Left.(dal.Constant); ok {
+		if right, ok := comparison.Right.(dal.FieldRef); ok {
+			return data[right.Name()]
+		}
 	}
+	l, _ := rowValue(data, comparison.Left)
+	return l
 }
 
 func matchesComparison(data map[string]any, comparison dal.Comparison) bool {
+	if isComputed(comparison.Left) || isComputed(comparison.Right) {
+		return matchesComputedComparison(data, comparison)
+	}
 	switch left := comparison.Left.(type) {
 	case dal.FieldRef:
+		if dal.IsUnaryOperator(comparison.Operator) {
+			return applyOperator(comparison.Operator, data[left.Name()], nil)
+		}
 		switch right := comparison.Right.(type) {
 		case dal.Constant:
 			norm := normalizeConstant(right.Value)
 			switch comparison.Operator {
 			case dal.Equal:
 				return data[left.Name()] == norm
-			case dal.GreaterThen, dal.GreaterOrEqual, dal.LessThen, dal.LessOrEqual:
-				value, ok := data[left.Name()]
-				if !ok {
-					return false
-				}
-				return compareOp(comparison.Operator, value, norm)
+			case dal.GreaterThen, dal.GreaterOrEqual, dal.LessThen, dal.LessOrEqual,
+				dal.NotEqual, dal.StartsWith, dal.Like, dal.ArrayContains:
+				return applyOperator(comparison.Operator, data[left.Name()], norm)
 			default:
 				return false
 			}
 		case dal.Array:
+			switch comparison.Operator {
+			case dal.NotIn, dal.Between:
+				return applyOperator(comparison.Operator, data[left.Name()], normalizeConstant(right.Value))
+			}
 			// dalgo2firestore maps FieldRef vs dal.Array to "array-contains-any"
 			// regardless of the operator; mirror that.
 			return fieldContainsAny(data[left.Name()], right.Value)
@@ -579,6 +841,26 @@ func matchesComparison(data map[string]any, comparison dal.Comparison) bool {
 	}
 }
 
+// matchesComputedComparison evaluates both operands of a comparison with a
+// computed side and applies the operator; a NULL left operand only satisfies
+// IsNull.
+func matchesComputedComparison(data map[string]any, comparison dal.Comparison) bool {
+	l, err := rowValue(data, comparison.Left)
+	if err != nil {
+		return false
+	}
+	var r any
+	if !dal.IsUnaryOperator(comparison.Operator) {
+		if r, err = rowValue(data, comparison.Right); err != nil {
+			return false
+		}
+		if l == nil {
+			return false
+		}
+	}
+	return applyOperator(comparison.Operator, l, r)
+}
+
 // fieldContains reports whether the record field's value is a slice or array
 // containing v. Serialized rows decode JSON arrays as []any, so elements are
 // matched per elementEquals.
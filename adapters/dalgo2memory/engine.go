@@ -3,6 +3,7 @@ package dalgo2memory
 import (
 	"fmt"
 
+	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/record"
 	"github.com/dal-go/record/update"
 )
@@ -43,6 +44,13 @@ type storageEngine interface {
 	// BY/ORDER BY/projection) and a materialize callback that decodes the row
 	// into a caller-provided typed target.
 	rows() ([]engineRow, error)
+
+	// snapshot captures the engine's stored state and returns a func that
+	// restores the engine, in place, to exactly that state. The read-write
+	// transaction takes a snapshot before the first write to a collection and
+	// calls restore when the worker fails, so a failed transaction leaves no
+	// trace.
+	snapshot() (restore func())
 }
 
 // engineRow is one enumerated row: its id, a decoded field view, a materialize
@@ -56,6 +64,21 @@ type engineRow struct {
 	key         *record.Key
 }
 
+// orderedRowsEngine is the optional capability a storage engine implements to
+// read a query's rows in ORDER BY order, so that a query with a LIMIT reads
+// only as many rows as its page needs. orderedRows returns, in the order
+// orderBySources gives (ties by id), the first n rows that keep accepts among
+// those of the WHERE condition, or signals "no opinion" (ok=false) so the
+// caller loads and sorts every candidate row.
+//
+// orderedIndex reports, without decoding them, the index orderedRows would
+// read and how many rows it would consider, so ExplainQuery reflects the same
+// decision.
+type orderedRowsEngine interface {
+	orderedRows(orderBy []dal.OrderExpression, condition dal.Condition, n int, keep func(engineRow) (bool, error)) (rows []engineRow, ok bool, err error)
+	orderedIndex(orderBy []dal.OrderExpression, condition dal.Condition) (index string, n int, ok bool)
+}
+
 // applyUpdatesToMap applies a slice of Update values to a flat-or-nested
 // map[string]any. Each Update has either a FieldName (a single top-level key)
 // or a FieldPath (a sequence of nested keys). A value of update.DeleteField
@@ -36,6 +36,8 @@ func (e *countingEngine) update(id string, updates []update.Update) error {
 
 func (e *countingEngine) rows() ([]engineRow, error) { return e.inner.rows() }
 
+func (e *countingEngine) snapshot() func() { return e.inner.snapshot() }
+
 // withCountingStorage is a test-only CollectionOption selecting countingEngine.
 func withCountingStorage() CollectionOption {
 	return func(def *collectionDef) {
@@ -0,0 +1,199 @@
+package dalgo2memory
+
+import (
+	"context"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+var _ dal.Explainer = (*database)(nil)
+var _ dal.Explainer = (*session)(nil)
+
+func (db *database) ExplainQuery(ctx context.Context, query dal.Query) (dal.QueryPlan, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return session{db: db}.ExplainQuery(ctx, query)
+}
+
+// rowCounter is the optional capability a storage engine implements to count
+// its rows without enumerating them.
+type rowCounter interface {
+	count() int
+}
+
+// ExplainQuery describes how ExecuteQueryToRecordsReader would execute a
+// structured query, validating it the same way but reading no rows. A read is
+// an INDEX_LOOKUP when the engine answers its WHERE from a column strategy or
+// an index (see loadCandidateRows and candidateCondition), or reads the rows
+// in ORDER BY order from an index, which then needs no SORT (see
+// orderedPageEngine), and a SCAN otherwise; a join is a HASH_JOIN when its ON
+// has equalities to key on (see joinRowsOf). An ordered read stops at the end
+// of the page, so it evaluates the WHERE itself and reports the LIMIT and
+// OFFSET instead of a FILTER and a LIMIT step. Reads report exact row counts
+// (a paged read its page size at most); steps that only drop rows report
+// their input's count as an upper bound, LIMIT its limit; other joins than
+// CROSS and aggregates with GROUP BY report dal.UnknownRows.
+func (s session) ExplainQuery(_ context.Context, query dal.Query) (dal.QueryPlan, error) {
+	if err := s.allowRead(); err != nil {
+		return dal.QueryPlan{}, err
+	}
+	q, ok := query.(dal.StructuredQuery)
+	if !ok {
+		return dal.QueryPlan{}, dal.ErrNotSupported
+	}
+	var node *dal.PlanNode
+	var paged bool
+	var err error
+	if len(q.From().Joins()) > 0 {
+		node, err = s.explainJoin(q)
+	} else {
+		node, paged, err = s.explainSingleSource(q)
+	}
+	if err != nil {
+		return dal.QueryPlan{}, err
+	}
+	if isGroupedQuery(q) {
+		rows := dal.UnknownRows
+		if len(q.GroupBy()) == 0 {
+			rows = 1
+		}
+		node = &dal.PlanNode{Operation: dal.PlanAggregate, GroupBy: q.GroupBy(), Condition: q.Having(), EstimatedRows: rows, Inputs: []*dal.PlanNode{node}}
+	}
+	if len(q.OrderBy()) > 0 && !paged {
+		node = &dal.PlanNode{Operation: dal.PlanSort, OrderBy: q.OrderBy(), EstimatedRows: node.EstimatedRows, Inputs: []*dal.PlanNode{node}}
+	}
+	if dal.IsDistinct(q) {
+		node = &dal.PlanNode{Operation: dal.PlanDistinct, EstimatedRows: node.EstimatedRows, Inputs: []*dal.PlanNode{node}}
+	}
+	if (q.Limit() > 0 || q.Offset() > 0) && !paged {
+		node = &dal.PlanNode{Operation: dal.PlanLimit, Limit: max(q.Limit(), 0), Offset: max(q.Offset(), 0),
+			EstimatedRows: limitedRows(node.EstimatedRows, q.Offset(), q.Limit()), Inputs: []*dal.PlanNode{node}}
+	}
+	return dal.QueryPlan{Root: node}, nil
+}
+
+// explainSingleSource describes the read and filtering of a single-source
+// query's rows, and whether the read pages them: it then returns them in
+// ORDER BY order with OFFSET and LIMIT applied.
+func (s session) explainSingleSource(q dal.StructuredQuery) (*dal.PlanNode, bool, error) {
+	base := q.From().Base()
+	if _, err := s.db.recordFactory(base.Name()); err != nil {
+		return nil, false, err
+	}
+	known := map[string]bool{"": true, base.Name(): true}
+	if a := base.Alias(); a != "" {
+		known[a] = true
+	}
+	if err := validateConditionExpressions(q.Where(), known); err != nil {
+		return nil, false, err
+	}
+	if err := validateOrderSources(q.OrderBy(), known); err != nil {
+		return nil, false, err
+	}
+	if !isGroupedQuery(q) {
+		if err := validateColumns(q.Columns(), known); err != nil {
+			return nil, false, err
+		}
+	}
+	if eng, ok := s.orderedPageEngine(base.Name(), q); ok {
+		index, n, _ := eng.orderedIndex(q.OrderBy(), q.Where())
+		read := &dal.PlanNode{Operation: dal.PlanIndexLookup, Collection: base.Name(), Alias: base.Alias(), Index: index,
+			Condition: q.Where(), OrderBy: q.OrderBy(), Limit: q.Limit(), Offset: max(q.Offset(), 0),
+			EstimatedRows: limitedRows(n, q.Offset(), q.Limit())}
+		return read, true, nil
+	}
+	read, err := s.explainRead(base.Name(), base.Alias(), q.Where())
+	if err != nil {
+		return nil, false, err
+	}
+	return filterNode(read, q.Where()), false, nil
+}
+
+func (s session) explainJoin(q dal.StructuredQuery) (*dal.PlanNode, error) {
+	sources, _, err := joinSourcesOf(q)
+	if err != nil {
+		return nil, err
+	}
+	joins := q.From().Joins()
+	plan := planJoin(q.Where(), joins, sources)
+	readSource := func(i int) (*dal.PlanNode, error) {
+		src := sources[i]
+		read, err := s.explainRead(src.collection, src.alias, candidateCondition(plan.filters[i], src.key, i == 0))
+		if err != nil {
+			return nil, err
+		}
+		return filterNode(read, allOf(plan.filters[i])), nil
+	}
+	node, err := readSource(0)
+	if err != nil {
+		return nil, err
+	}
+	earlier := map[string]bool{sources[0].key: true}
+	for i, join := range joins {
+		right, err := readSource(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		key := sources[i+1].key
+		joined := &dal.PlanNode{Operation: dal.PlanNestedLoopJoin, JoinType: join.JoinType(), Condition: allOf(join.On()),
+			EstimatedRows: dal.UnknownRows, Inputs: []*dal.PlanNode{node, right}}
+		if len(hashJoinKeys(join.On(), key, sources[0].key, earlier)) > 0 {
+			joined.Operation = dal.PlanHashJoin
+		}
+		if join.JoinType() == dal.JoinCross && node.EstimatedRows != dal.UnknownRows {
+			joined.EstimatedRows = node.EstimatedRows * right.EstimatedRows
+		}
+		earlier[key] = true
+		node = joined
+	}
+	return filterNode(node, plan.residual), nil
+}
+
+// explainRead describes how the rows of a collection are read for a
+// condition, mirroring loadCandidateRows.
+func (s session) explainRead(collection, alias string, condition dal.Condition) (*dal.PlanNode, error) {
+	eng := s.db.engine(collection)
+	if ce, ok := eng.(candidateRowsEngine); ok {
+		index, n, hasOpinion, err := ce.candidateCount(condition)
+		if err != nil {
+			return nil, err
+		}
+		if hasOpinion {
+			return &dal.PlanNode{Operation: dal.PlanIndexLookup, Collection: collection, Alias: alias, Index: index, Condition: condition, EstimatedRows: n}, nil
+		}
+	}
+	n := 0
+	if counter, ok := eng.(rowCounter); ok {
+		n = counter.count()
+	} else {
+		rows, err := eng.rows()
+		if err != nil {
+			return nil, err
+		}
+		n = len(rows)
+	}
+	return &dal.PlanNode{Operation: dal.PlanScan, Collection: collection, Alias: alias, EstimatedRows: n}, nil
+}
+
+// filterNode puts a FILTER on the condition over node, if there is a condition.
+func filterNode(node *dal.PlanNode, condition dal.Condition) *dal.PlanNode {
+	if condition == nil {
+		return node
+	}
+	return &dal.PlanNode{Operation: dal.PlanFilter, Condition: condition, EstimatedRows: node.EstimatedRows, Inputs: []*dal.PlanNode{node}}
+}
+
+// limitedRows estimates the rows left of rows after OFFSET and LIMIT.
+func limitedRows(rows, offset, limit int) int {
+	if rows == dal.UnknownRows {
+		if limit > 0 {
+			return limit
+		}
+		return dal.UnknownRows
+	}
+	rows = max(rows-max(offset, 0), 0)
+	if limit > 0 {
+		rows = min(rows, limit)
+	}
+	return rows
+}
@@ -0,0 +1,141 @@
+package dalgo2memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainQuery_SingleSource(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	q := dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().
+		WhereField("status", dal.Equal, "shipped").
+		OrderBy(dal.DescendingField("userId")).
+		Limit(2).
+		SelectKeysOnly(reflect.String)
+
+	plan, err := dal.ExplainQuery(ctx, db, q)
+	require.NoError(t, err)
+	assert.Equal(t, `LIMIT 2 (rows: 2)
+  SORT BY userId DESC (rows: 3)
+    FILTER WHERE status = 'shipped' (rows: 3)
+      SCAN orders (rows: 3)
+`, plan.String())
+	assert.Empty(t, plan.Find(dal.PlanIndexLookup), "the collection declares no index")
+
+	countAll := dal.Count()
+	countAll.Alias = "n"
+	grouped := dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().
+		Distinct().Offset(1).SelectColumns(countAll)
+	plan, err = dal.ExplainQuery(ctx, db, grouped)
+	require.NoError(t, err)
+	assert.Equal(t, `LIMIT ALL OFFSET 1 (rows: 0)
+  DISTINCT (rows: 1)
+    AGGREGATE (rows: 1)
+      SCAN orders (rows: 3)
+`, plan.String())
+}
+
+func TestExplainQuery_IndexLookup(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(true, WithCollection[queryRow]("q", nil, WithColumnarStorage()))).(*database)
+	seedQueryRows(t, db, "q")
+	q := dal.From(dal.NewRootCollectionRef("q", "")).NewQuery().
+		WhereField("Group", dal.Equal, "a").
+		SelectKeysOnly(reflect.String)
+
+	plan, err := dal.ExplainQuery(ctx, db, q)
+	require.NoError(t, err)
+	lookups := plan.Find(dal.PlanIndexLookup)
+	require.Len(t, lookups, 1)
+	assert.Equal(t, "Group", lookups[0].Index)
+	assert.Equal(t, 3, lookups[0].EstimatedRows)
+	records := readAll(t, mustQuery(t, db, q))
+	assert.Len(t, records, lookups[0].EstimatedRows)
+
+	ranks := dal.WhereField("Rank", dal.GreaterThen, 2)
+	plan, err = dal.ExplainQuery(ctx, db, dal.From(dal.NewRootCollectionRef("q", "")).NewQuery().Where(ranks).SelectKeysOnly(reflect.String))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Find(dal.PlanIndexLookup), "no strategy answers a range")
+	assert.Equal(t, 4, plan.Find(dal.PlanScan)[0].EstimatedRows)
+}
+
+func mustQuery(t *testing.T, db dal.DB, q dal.Query) dal.RecordsReader {
+	t.Helper()
+	reader, err := db.ExecuteQueryToRecordsReader(context.Background(), q)
+	require.NoError(t, err)
+	return reader
+}
+
+func TestExplainQuery_Joins(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	shipped := dal.NewComparison(dal.NewFieldRef("o", "status"), dal.Equal, dal.Constant{Value: "shipped"})
+	activeUser := dal.NewComparison(dal.NewFieldRef("u", "status"), dal.Equal, dal.Constant{Value: "active"})
+
+	t.Run("hash join with pushed filters", func(t *testing.T) {
+		q := dal.From(usersAlias()).Join(dal.NewJoinedSource(ordersAlias(), dal.JoinLeft, onUserEqOrder())).
+			NewQuery().Where(dal.NewGroupCondition(dal.And, activeUser, shipped)).SelectIntoRecord(intoMapRecord())
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Equal(t, `FILTER WHERE o.status = 'shipped'
+  HASH_JOIN LEFT ON u.id = o.userId
+    FILTER WHERE u.status = 'active' (rows: 2)
+      SCAN users AS u (rows: 2)
+    SCAN orders AS o (rows: 3)
+`, plan.String(), "the WHERE on the null-filled side stays above the join")
+	})
+
+	t.Run("nested loop and cross join", func(t *testing.T) {
+		before := dal.NewComparison(dal.NewFieldRef("u", "id"), dal.LessThen, dal.NewFieldRef("o", "userId"))
+		q := dal.From(usersAlias()).
+			Join(dal.NewJoinedSource(ordersAlias(), dal.JoinInner, before)).
+			Join(dal.NewJoinedSource(dal.NewRootCollectionRef("users", "v"), dal.JoinCross)).
+			NewQuery().SelectIntoRecord(intoMapRecord())
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Equal(t, `NESTED_LOOP_JOIN CROSS
+  NESTED_LOOP_JOIN INNER ON u.id < o.userId
+    SCAN users AS u (rows: 2)
+    SCAN orders AS o (rows: 3)
+  SCAN users AS v (rows: 2)
+`, plan.String())
+	})
+
+	t.Run("invalid join", func(t *testing.T) {
+		q := dal.From(usersAlias()).Join(dal.NewJoinedSource(ordersAlias(), dal.JoinCross, onUserEqOrder())).
+			NewQuery().SelectIntoRecord(intoMapRecord())
+		_, err := dal.ExplainQuery(ctx, db, q)
+		assert.ErrorContains(t, err, "takes no ON conditions")
+	})
+}
+
+func TestExplainQuery_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewDB().(dal.Explainer).ExplainQuery(ctx, dal.NewTextQuery("SELECT 1", nil))
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+
+	strict := NewDB(WithSchema(false, WithCollection[queryRow]("q", nil)))
+	_, err = dal.ExplainQuery(ctx, strict, dal.From(dal.NewRootCollectionRef("other", "")).NewQuery().SelectKeysOnly(reflect.String))
+	assert.ErrorContains(t, err, "not defined in the schema")
+
+	unknown := dal.NewComparison(dal.Lower(dal.NewFieldRef("x", "y")), dal.Equal, dal.Constant{Value: "a"})
+	_, err = dal.ExplainQuery(ctx, NewDB(), dal.From(dal.NewRootCollectionRef("q", "")).NewQuery().Where(unknown).SelectKeysOnly(reflect.String))
+	assert.ErrorContains(t, err, "unknown source")
+}
+
+func TestExplainQuery_InTransaction(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	q := dal.From(dal.NewRootCollectionRef("users", "")).NewQuery().SelectKeysOnly(reflect.String)
+	err := db.RunReadonlyTransaction(ctx, func(ctx context.Context, tx dal.ReadTransaction) error {
+		plan, err := dal.ExplainQuery(ctx, tx, q)
+		require.NoError(t, err)
+		assert.Equal(t, "SCAN users (rows: 2)\n", plan.String())
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package dalgo2memory
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
@@ -19,6 +20,7 @@ type rowSources = map[string]map[string]any
 // evaluation) and the projected output row (set during projection, then read
 // back by HAVING/ORDER BY when an operand references a SELECT alias).
 type aggGroup struct {
+	key  string
 	rows []rowSources
 	out  map[string]any
 }
@@ -31,29 +33,32 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 	groupBy := q.GroupBy()
 	columns := q.Columns()
 
-	// Validate GROUP BY field sources up front.
+	// Validate GROUP BY field sources and computed keys up front.
 	for _, ge := range groupBy {
 		if f, ok := ge.(dal.FieldRef); ok {
 			if src := f.Source(); src != "" && !known[src] {
 				return nil, fmt.Errorf("dalgo2memory: GROUP BY field %q references unknown source %q", f.Name(), src)
 			}
 		}
+		if err := validateExpression(ge, known); err != nil {
+			return nil, err
+		}
 	}
 
 	// Validate selected columns: each must be an aggregate or a GROUP BY
-	// expression (the SQL grouping rule), rejected before any group is emitted.
+	// expression, or computed from those (the SQL grouping rule), rejected
+	// before any group is emitted.
 	groupKeys := make(map[string]bool, len(groupBy))
 	for _, ge := range groupBy {
 		groupKeys[ge.String()] = true
 	}
 	for _, col := range columns {
-		if _, ok := col.Expression.(dal.AggregateFunc); ok {
-			continue
+		if err := validateExpression(col.Expression, known); err != nil {
+			return nil, err
 		}
-		if groupKeys[col.Expression.String()] {
-			continue
+		if !isGroupedExpression(col.Expression, groupKeys) {
+			return nil, fmt.Errorf("dalgo2memory: selected column %q is neither an aggregate nor in GROUP BY", col.String())
 		}
-		return nil, fmt.Errorf("dalgo2memory: selected column %q is neither an aggregate nor in GROUP BY", col.String())
 	}
 
 	// Partition into groups, preserving first-seen order.
@@ -66,7 +71,7 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 		}
 		g := byKey[key]
 		if g == nil {
-			g = &aggGroup{}
+			g = &aggGroup{key: key}
 			byKey[key] = g
 			groupOrder = append(groupOrder, key)
 		}
@@ -76,6 +81,10 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 	for i, k := range groupOrder {
 		groups[i] = byKey[k]
 	}
+	if len(groupBy) == 0 && len(groups) == 0 {
+		// Aggregates without GROUP BY yield one row even over no rows.
+		groups = append(groups, &aggGroup{})
+	}
 
 	// Project each group's selected columns into its output row.
 	for _, g := range groups {
@@ -105,6 +114,10 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 		groups = kept
 	}
 
+	if dal.IsDistinct(q) {
+		groups = distinctRows(groups, func(g *aggGroup) any { return g.out })
+	}
+
 	// ORDER BY over the grouped rows. Sort keys are resolved once per group
 	// up front so any resolution error surfaces before sorting (the comparator
 	// itself cannot return an error).
@@ -145,16 +158,9 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 		groups = sorted
 	}
 
-	// OFFSET then LIMIT on the grouped rows.
-	if offset := q.Offset(); offset > 0 {
-		if offset >= len(groups) {
-			groups = nil
-		} else {
-			groups = groups[offset:]
-		}
-	}
-	if limit := q.Limit(); limit > 0 && limit < len(groups) {
-		groups = groups[:limit]
+	groups, cursors, err := paginateGroups(groups, q)
+	if err != nil {
+		return nil, err
 	}
 
 	records := make([]record.Record, len(groups))
@@ -162,7 +168,95 @@ func executeGroupedReader(q dal.StructuredQuery, rows []rowSources, collection s
 		key := record.NewKeyWithID(collection, fmt.Sprint(i))
 		records[i] = record.NewRecordWithData(key, g.out).SetError(nil)
 	}
-	return dal.NewRecordsReader(records), nil
+	return newRecordsReader(records, cursors), nil
+}
+
+// isGroupedQuery reports whether q aggregates its rows: it has GROUP BY
+// expressions, or selects an aggregate, which without GROUP BY aggregates all
+// rows as a single group.
+func isGroupedQuery(q dal.StructuredQuery) bool {
+	if len(q.GroupBy()) > 0 {
+		return true
+	}
+	for _, col := range q.Columns() {
+		if containsAggregate(col.Expression) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAggregate(e dal.Expression) bool {
+	switch ex := e.(type) {
+	case dal.AggregateFunc:
+		return true
+	case dal.Arithmetic:
+		return containsAggregate(ex.Left) || containsAggregate(ex.Right)
+	case dal.ScalarFunction:
+		for _, arg := range ex.Args {
+			if containsAggregate(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paginateGroups applies StartFrom, then OFFSET, then LIMIT to the ordered
+// groups. A group cursor identifies its group by partition key and resumes
+// right after it; when that group no longer exists the cursor's ordinal is
+// used instead.
+func paginateGroups(groups []*aggGroup, q dal.StructuredQuery) ([]*aggGroup, []string, error) {
+	start, err := decodeCursor(q.StartFrom())
+	if err != nil {
+		return nil, nil, err
+	}
+	first := 0
+	if start != nil {
+		first = min(start.Ordinal+1, len(groups))
+		for i, g := range groups {
+			if g.key == start.ID {
+				first = i + 1
+				break
+			}
+		}
+	}
+	ordinals := make([]int, len(groups)-first)
+	for i := range ordinals {
+		ordinals[i] = first + i
+	}
+	ordinals = applyOffsetLimit(ordinals, q.Offset(), q.Limit())
+	page := make([]*aggGroup, len(ordinals))
+	cursors := make([]string, len(ordinals))
+	for i, n := range ordinals {
+		page[i] = groups[n]
+		cursors[i] = pageCursor{ID: groups[n].key, Ordinal: n}.encode()
+	}
+	return page, cursors, nil
+}
+
+// isGroupedExpression reports whether e has a single value per group: it is an
+// aggregate, a GROUP BY expression or a constant, or is computed only from
+// those.
+func isGroupedExpression(e dal.Expression, groupKeys map[string]bool) bool {
+	if groupKeys[e.String()] {
+		return true
+	}
+	switch ex := e.(type) {
+	case dal.AggregateFunc, dal.Constant:
+		return true
+	case dal.Arithmetic:
+		return isGroupedExpression(ex.Left, groupKeys) && isGroupedExpression(ex.Right, groupKeys)
+	case dal.ScalarFunction:
+		for _, arg := range ex.Args {
+			if !isGroupedExpression(arg, groupKeys) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
 }
 
 // groupKey builds a stable partition key from the resolved GROUP BY expression
@@ -184,7 +278,8 @@ func groupKey(groupBy []dal.Expression, r rowSources, known map[string]bool) (st
 // aggregate is evaluated over the group's rows; a FieldRef resolves first
 // against the already-projected output row (so HAVING/ORDER BY can reference a
 // SELECT alias), then as a GROUP BY field from a member row; a Constant yields
-// its value.
+// its value; a computed expression is evaluated over its operands resolved
+// the same way.
 func resolveGroupValue(e dal.Expression, g *aggGroup, known map[string]bool) (any, error) {
 	switch ex := e.(type) {
 	case dal.AggregateFunc:
@@ -195,134 +290,249 @@ func resolveGroupValue(e dal.Expression, g *aggGroup, known map[string]bool) (an
 				return v, nil
 			}
 		}
-		// A group always has at least one member row.
+		if len(g.rows) == 0 { // the single group of an aggregate over no rows
+			return nil, nil
+		}
 		v, _, err := resolveJoinExpr(ex, g.rows[0], known)
 		return v, err
 	case dal.Constant:
 		return normalizeConstant(ex.Value), nil
+	case dal.Array:
+		return normalizeConstant(ex.Value), nil
+	case dal.ScalarFunction, dal.Arithmetic:
+		return evalComputed(e, func(arg dal.Expression) (any, error) {
+			return resolveGroupValue(arg, g, known)
+		})
 	default:
 		return nil, fmt.Errorf("dalgo2memory: unsupported grouped expression %T", e)
 	}
 }
 
 // evalAggregate computes one aggregate over a group's rows with standard SQL
-// null handling (NULLs skipped). COUNT(*) (a non-field argument) counts all
-// rows; COUNT(field) counts non-null values; SUM/AVG operate on numeric values
-// (all-null -> nil); MIN/MAX reduce by the shared comparator (all-null -> nil).
+// null handling (NULLs skipped, except by ARRAY_AGG). COUNT(*) (a non-field,
+// non-computed argument) counts all rows; COUNT(field) counts non-null values;
+// SUM/AVG/MEDIAN/PERCENTILE/STDDEV operate on numeric values (none -> nil);
+// MIN/MAX reduce by the shared comparator (all-null -> nil). A DISTINCT
+// aggregate sees each value once.
 func evalAggregate(af dal.AggregateFunc, rows []rowSources, known map[string]bool) (any, error) {
-	args := af.FuncArgs()
-	switch af.FuncName() {
-	case dal.COUNT:
-		if len(args) == 1 {
-			if _, isField := args[0].(dal.FieldRef); !isField {
-				return len(rows), nil
-			}
-		}
-		n := 0
-		for _, r := range rows {
-			v, present, err := resolveJoinExpr(args[0], r, known)
-			if err != nil {
-				return nil, err
-			}
-			if present && v != nil {
-				n++
-			}
+	name, args := af.FuncName(), af.FuncArgs()
+	if len(args) == 0 {
+		return nil, fmt.Errorf("dalgo2memory: aggregate %s has no arguments", name)
+	}
+	if name == dal.COUNT && !dal.IsDistinctAggregate(af) {
+		if _, isField := args[0].(dal.FieldRef); !isField && !isComputed(args[0]) {
+			return len(rows), nil
 		}
-		return n, nil
+	}
+	values, err := aggregateValues(args[0], rows, known, name == dal.ARRAY_AGG, dal.IsDistinctAggregate(af))
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case dal.COUNT:
+		return len(values), nil
 	case dal.SUM, dal.AVERAGE:
-		sum, cnt := 0.0, 0
-		for _, r := range rows {
-			v, present, err := resolveJoinExpr(args[0], r, known)
-			if err != nil {
-				return nil, err
-			}
-			if !present || v == nil {
-				continue
-			}
-			f, ok := number(v)
-			if !ok {
-				continue
-			}
-			sum += f
-			cnt++
-		}
-		if cnt == 0 {
+		nums := numbers(values)
+		if len(nums) == 0 {
 			return nil, nil
 		}
-		if af.FuncName() == dal.AVERAGE {
-			return sum / float64(cnt), nil
+		sum := 0.0
+		for _, f := range nums {
+			sum += f
+		}
+		if name == dal.AVERAGE {
+			return sum / float64(len(nums)), nil
 		}
 		return sum, nil
 	case dal.MIN, dal.MAX:
 		var best any
-		found := false
-		for _, r := range rows {
-			v, present, err := resolveJoinExpr(args[0], r, known)
-			if err != nil {
-				return nil, err
-			}
-			if !present || v == nil {
-				continue
-			}
-			if !found {
-				best, found = v, true
+		for i, v := range values {
+			if i == 0 {
+				best = v
 				continue
 			}
 			c := compare(v, best)
-			if (af.FuncName() == dal.MIN && c < 0) || (af.FuncName() == dal.MAX && c > 0) {
+			if (name == dal.MIN && c < 0) || (name == dal.MAX && c > 0) {
 				best = v
 			}
 		}
-		if !found {
+		return best, nil
+	case dal.MEDIAN:
+		return percentile(numbers(values), 0.5), nil
+	case dal.PERCENTILE:
+		p, ok := aggregateConstant(args, 1)
+		f, isNumber := number(p)
+		if !ok || !isNumber || f < 0 || f > 1 {
+			return nil, fmt.Errorf("dalgo2memory: %s expects a percentile between 0 and 1 as its second argument", name)
+		}
+		return percentile(numbers(values), f), nil
+	case dal.STRING_AGG:
+		sep, ok := aggregateConstant(args, 1)
+		separator, isString := sep.(string)
+		if !ok || !isString {
+			return nil, fmt.Errorf("dalgo2memory: %s expects a string separator as its second argument", name)
+		}
+		if len(values) == 0 {
 			return nil, nil
 		}
-		return best, nil
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, separator), nil
+	case dal.ARRAY_AGG:
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values, nil
+	case dal.STDDEV, dal.STDDEV_POP:
+		return stdDev(numbers(values), name == dal.STDDEV), nil
 	default:
-		return nil, fmt.Errorf("dalgo2memory: unsupported aggregate %q", af.FuncName())
+		return nil, fmt.Errorf("dalgo2memory: unsupported aggregate %q", name)
 	}
 }
 
+// aggregateValues resolves an aggregated expression for each row of a group,
+// in row order, skipping NULLs unless keepNulls is set and keeping only the
+// first occurrence of each value when distinct is set.
+func aggregateValues(e dal.Expression, rows []rowSources, known map[string]bool, keepNulls, distinct bool) ([]any, error) {
+	values := make([]any, 0, len(rows))
+	var seen map[string]bool
+	if distinct {
+		seen = make(map[string]bool, len(rows))
+	}
+	for _, r := range rows {
+		v, present, err := resolveJoinExpr(e, r, known)
+		if err != nil {
+			return nil, err
+		}
+		if !present {
+			v = nil
+		}
+		if v == nil && !keepNulls {
+			continue
+		}
+		if distinct {
+			k := valueKey(v)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// valueKey identifies a value, or a whole row map, for DISTINCT. Go-syntax
+// formatting keeps a string apart from a number with the same text and prints
+// maps with sorted keys.
+func valueKey(v any) string {
+	return fmt.Sprintf("%#v", v)
+}
+
+// aggregateConstant returns the value of the i-th aggregate argument when it
+// is a Constant.
+func aggregateConstant(args []dal.Expression, i int) (any, bool) {
+	if i >= len(args) {
+		return nil, false
+	}
+	c, ok := args[i].(dal.Constant)
+	return c.Value, ok
+}
+
+// numbers keeps the numeric values as float64, skipping the rest.
+func numbers(values []any) []float64 {
+	nums := make([]float64, 0, len(values))
+	for _, v := range values {
+		if f, ok := number(v); ok {
+			nums = append(nums, f)
+		}
+	}
+	return nums
+}
+
+// percentile returns the continuous percentile p (0..1) of nums, linearly
+// interpolated between the closest ranks as SQL PERCENTILE_CONT does; nil for
+// no values.
+func percentile(nums []float64, p float64) any {
+	if len(nums) == 0 {
+		return nil
+	}
+	sort.Float64s(nums)
+	pos := p * float64(len(nums)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return nums[lo] + (nums[hi]-nums[lo])*(pos-float64(lo))
+}
+
+// stdDev returns the sample (n-1) or population (n) standard deviation of
+// nums; nil when there are too few values.
+func stdDev(nums []float64, sample bool) any {
+	n := float64(len(nums))
+	if n == 0 || (sample && n < 2) {
+		return nil
+	}
+	mean := 0.0
+	for _, f := range nums {
+		mean += f
+	}
+	mean /= n
+	sq := 0.0
+	for _, f := range nums {
+		sq += (f - mean) * (f - mean)
+	}
+	if sample {
+		n--
+	}
+	return math.Sqrt(sq / n)
+}
+
 // matchesHaving evaluates a HAVING condition over a projected group. A
 // Comparison resolves both operands to per-group values (alias or aggregate
-// expression) and applies the operator; a GroupCondition composes AND/OR.
+// expression) and applies the operator; a GroupCondition composes AND/OR and a
+// NotCondition negates, in three-valued logic (see truth).
 func matchesHaving(cond dal.Condition, g *aggGroup, known map[string]bool) (bool, error) {
+	t, err := havingTruth(cond, g, known)
+	return t == isTrue, err
+}
+
+func havingTruth(cond dal.Condition, g *aggGroup, known map[string]bool) (truth, error) {
 	switch c := cond.(type) {
 	case dal.Comparison:
 		l, err := resolveGroupValue(c.Left, g, known)
 		if err != nil {
-			return false, err
+			return isFalse, err
 		}
-		r, err := resolveGroupValue(c.Right, g, known)
-		if err != nil {
-			return false, err
+		var r any
+		if !dal.IsUnaryOperator(c.Operator) {
+			if r, err = resolveGroupValue(c.Right, g, known); err != nil {
+				return isFalse, err
+			}
 		}
-		return compareOp(c.Operator, l, r), nil
+		return comparisonTruth(c.Operator, l, applyOperator(c.Operator, l, r)), nil
+	case dal.NotCondition:
+		t, err := havingTruth(c.Condition(), g, known)
+		return t.not(), err
 	case dal.GroupCondition:
-		conds := c.Conditions()
+		t := isTrue
 		if c.Operator() == dal.Or {
-			for _, sub := range conds {
-				ok, err := matchesHaving(sub, g, known)
-				if err != nil {
-					return false, err
-				}
-				if ok {
-					return true, nil
-				}
-			}
-			return false, nil
+			t = isFalse
 		}
-		for _, sub := range conds {
-			ok, err := matchesHaving(sub, g, known)
+		for _, sub := range c.Conditions() {
+			st, err := havingTruth(sub, g, known)
 			if err != nil {
-				return false, err
+				return isFalse, err
 			}
-			if !ok {
-				return false, nil
+			if c.Operator() == dal.Or {
+				t = t.or(st)
+			} else {
+				t = t.and(st)
 			}
 		}
-		return true, nil
+		return t, nil
 	default:
-		return false, fmt.Errorf("dalgo2memory: unsupported HAVING condition %T", cond)
+		return isFalse, fmt.Errorf("dalgo2memory: unsupported HAVING condition %T", cond)
 	}
 }
 
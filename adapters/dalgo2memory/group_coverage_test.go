@@ -21,8 +21,8 @@ func (fakeExpr) String() string { return "FAKE_EXPR" }
 
 type fakeAgg struct{}
 
-func (fakeAgg) String() string             { return "MEDIAN(amount)" }
-func (fakeAgg) FuncName() string           { return "MEDIAN" }
+func (fakeAgg) String() string             { return "MODE(amount)" }
+func (fakeAgg) FuncName() string           { return "MODE" }
 func (fakeAgg) FuncArgs() []dal.Expression { return []dal.Expression{dal.Field("amount")} }
 
 // MIN/MAX over a group, with the null amount skipped.
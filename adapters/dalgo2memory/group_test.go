@@ -2,6 +2,7 @@ package dalgo2memory
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/dal-go/dalgo/dal"
@@ -90,6 +91,42 @@ func TestGroupBy_AllNullGroup(t *testing.T) {
 	require.EqualValues(t, 2, rows[0]["n"])
 }
 
+// A group whose MAX is null is unknown to HAVING comparisons and to their
+// negation alike.
+func TestGroupBy_HavingNullAggregate(t *testing.T) {
+	db, ctx := seedSales(t)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("sales", "5"), &map[string]any{"category": "C", "amount": nil})))
+	hi := dal.Field("hi")
+	for _, tt := range []struct {
+		name   string
+		having dal.Condition
+		want   []string
+	}{
+		{"greater", dal.NewComparison(hi, dal.GreaterThen, dal.NewConstant(5)), []string{"A"}},
+		{"not", dal.Not(dal.NewComparison(hi, dal.GreaterThen, dal.NewConstant(5))), []string{"B"}},
+		{"is_null", dal.Comparison{Left: hi, Operator: dal.IsNull}, []string{"C"}},
+		{"or", dal.NewGroupCondition(dal.Or,
+			dal.NewComparison(hi, dal.Equal, dal.NewConstant(5)),
+			dal.Comparison{Left: hi, Operator: dal.IsNull}), []string{"B", "C"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q := salesQuery().
+				GroupBy(dal.Field("category")).
+				Having(tt.having).
+				OrderBy(dal.AscendingField("category")).
+				SelectColumns(
+					dal.Column{Expression: dal.Field("category")},
+					dal.MaxAs(dal.Field("amount"), "hi"),
+				)
+			categories := make([]string, 0)
+			for _, row := range runProjection(t, db, ctx, q) {
+				categories = append(categories, row["category"].(string))
+			}
+			require.Equal(t, tt.want, categories)
+		})
+	}
+}
+
 // AC non-grouped-select-column-errors: selecting a non-aggregate column absent
 // from GROUP BY errors before producing rows.
 func TestGroupBy_NonGroupedColumnErrors(t *testing.T) {
@@ -160,6 +197,41 @@ func TestGroupBy_HavingOnUnselectedAggregate(t *testing.T) {
 }
 
 // AC grouped-order-and-limit: order by COUNT(*) descending with LIMIT 2 returns
+// HAVING evaluates the extended operators and NOT over per-group values.
+func TestGroupBy_HavingExtendedOperators(t *testing.T) {
+	db, ctx := seedSales(t)
+	total := dal.Field("total")
+	for _, tt := range []struct {
+		name   string
+		having dal.Condition
+		want   []string
+	}{
+		{"not_equal", dal.NewComparison(total, dal.NotEqual, dal.NewConstant(5)), []string{"A"}},
+		{"in", dal.NewComparison(total, dal.In, dal.NewArray([]int{5, 7})), []string{"B"}},
+		{"not_in", dal.NewComparison(total, dal.NotIn, dal.NewArray([]int{5, 7})), []string{"A"}},
+		{"between", dal.NewComparison(total, dal.Between, dal.NewArray([]int{1, 30})), []string{"A", "B"}},
+		{"is_not_null", dal.Comparison{Left: total, Operator: dal.IsNotNull}, []string{"A", "B"}},
+		{"starts_with", dal.NewComparison(dal.Field("category"), dal.StartsWith, dal.String("B")), []string{"B"}},
+		{"not", dal.Not(dal.NewComparison(total, dal.GreaterThen, dal.NewConstant(10))), []string{"B"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q := salesQuery().
+				GroupBy(dal.Field("category")).
+				Having(tt.having).
+				OrderBy(dal.AscendingField("category")).
+				SelectColumns(
+					dal.Column{Expression: dal.Field("category")},
+					dal.SumAs(dal.Field("amount"), "total"),
+				)
+			categories := make([]string, 0)
+			for _, row := range runProjection(t, db, ctx, q) {
+				categories = append(categories, row["category"].(string))
+			}
+			require.Equal(t, tt.want, categories)
+		})
+	}
+}
+
 // the two highest-count groups in order.
 func TestGroupBy_OrderAndLimit(t *testing.T) {
 	db := NewDB().(*database)
@@ -237,3 +309,132 @@ func TestGroupBy_JoinQualified(t *testing.T) {
 	require.EqualValues(t, 3, groups["US"], "US: orders a,b,d joined")
 	require.EqualValues(t, 1, groups["DE"], "DE: order c joined")
 }
+
+// seedTeamScores loads per-team scores with a repeated player and a null score.
+func seedTeamScores(t *testing.T) (*database, context.Context) {
+	t.Helper()
+	db := NewDB().(*database)
+	ctx := context.Background()
+	rows := []map[string]any{
+		{"team": "red", "player": "ann", "score": 2},
+		{"team": "red", "player": "ben", "score": 4},
+		{"team": "red", "player": "ann", "score": 4},
+		{"team": "red", "player": "cid", "score": 9},
+		{"team": "blue", "player": "dan", "score": 7},
+		{"team": "blue", "player": "eve", "score": nil},
+	}
+	for i, row := range rows {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("scores", fmt.Sprint(i)), &row)))
+	}
+	return db, ctx
+}
+
+func teamScoresQuery() *dal.QueryBuilder {
+	return dal.From(dal.NewRootCollectionRef("scores", "")).NewQuery()
+}
+
+func TestGroupBy_ExtendedAggregates(t *testing.T) {
+	db, ctx := seedTeamScores(t)
+	q := teamScoresQuery().
+		GroupBy(dal.Field("team")).
+		OrderBy(dal.AscendingField("team")).
+		SelectColumns(
+			dal.Column{Expression: dal.Field("team")},
+			dal.CountDistinctAs(dal.Field("player"), "players"),
+			dal.CountDistinctAs(dal.Field("score"), "scores"),
+			dal.MedianAs(dal.Field("score"), "median"),
+			dal.PercentileAs(dal.Field("score"), 0.25, "p25"),
+			dal.StringAggAs(dal.Field("player"), ",", "names"),
+			dal.ArrayAggAs(dal.Field("score"), "all"),
+			dal.StdDevAs(dal.Field("score"), "sd"),
+			dal.StdDevPopAs(dal.Field("score"), "sdPop"),
+		)
+	rows := runProjection(t, db, ctx, q)
+	require.Len(t, rows, 2)
+	blue, red := rows[0], rows[1]
+
+	require.EqualValues(t, 3, red["players"])
+	require.EqualValues(t, 3, red["scores"])
+	require.EqualValues(t, 4, red["median"])
+	require.EqualValues(t, 3.5, red["p25"])
+	require.Equal(t, "ann,ben,ann,cid", red["names"])
+	require.Equal(t, []any{2.0, 4.0, 4.0, 9.0}, red["all"])
+	require.InDelta(t, 2.9861, red["sd"], 1e-4)
+	require.InDelta(t, 2.5860, red["sdPop"], 1e-4)
+
+	require.EqualValues(t, 2, blue["players"])
+	require.EqualValues(t, 1, blue["scores"])
+	require.EqualValues(t, 7, blue["median"])
+	require.Equal(t, []any{7.0, nil}, blue["all"])
+	require.Nil(t, blue["sd"], "a sample standard deviation needs two values")
+	require.EqualValues(t, 0, blue["sdPop"])
+}
+
+func TestGroupBy_AggregateWithoutGroupBy(t *testing.T) {
+	db, ctx := seedTeamScores(t)
+	rows := runProjection(t, db, ctx, teamScoresQuery().SelectColumns(
+		dal.CountDistinctAs(dal.Field("player"), "players"),
+		dal.SumAs(dal.Field("score"), "total"),
+	))
+	require.Equal(t, []map[string]any{{"players": 5, "total": 26.0}}, rows)
+
+	empty := dal.From(dal.NewRootCollectionRef("nothing", "")).NewQuery().SelectColumns(
+		dal.CountAs(dal.Field("x"), "n"),
+		dal.SumAs(dal.Field("x"), "total"),
+	)
+	require.Equal(t, []map[string]any{{"n": 0, "total": nil}}, runProjection(t, db, ctx, empty))
+
+	_, err := db.ExecuteQueryToRecordsReader(ctx, teamScoresQuery().SelectColumns(
+		dal.Column{Expression: dal.Field("team")},
+		dal.Count(),
+	))
+	require.ErrorContains(t, err, "neither an aggregate nor in GROUP BY")
+}
+
+func TestGroupBy_AggregateArgumentErrors(t *testing.T) {
+	for name, column := range map[string]dal.Column{
+		"percentile": {Alias: "p", Expression: fakeFunc{name: dal.PERCENTILE, args: []dal.Expression{dal.Field("amount"), dal.String("x")}}},
+		"separator":  {Alias: "s", Expression: fakeFunc{name: dal.STRING_AGG, args: []dal.Expression{dal.Field("amount")}}},
+		"no_args":    {Alias: "n", Expression: fakeFunc{name: dal.SUM}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q := salesQuery().GroupBy(dal.Field("category")).SelectColumns(column)
+			db, ctx := seedSales(t)
+			_, err := db.ExecuteQueryToRecordsReader(ctx, q)
+			require.Error(t, err)
+		})
+	}
+}
+
+// fakeFunc is an aggregate with arbitrary arguments, for the malformed calls
+// the dal constructors do not build.
+type fakeFunc struct {
+	name string
+	args []dal.Expression
+}
+
+func (f fakeFunc) String() string             { return f.name }
+func (f fakeFunc) FuncName() string           { return f.name }
+func (f fakeFunc) FuncArgs() []dal.Expression { return f.args }
+
+func TestQuery_Distinct(t *testing.T) {
+	db, ctx := seedTeamScores(t)
+	q := teamScoresQuery().
+		Distinct().
+		OrderBy(dal.AscendingField("player")).
+		Limit(3).
+		SelectColumns(dal.Column{Expression: dal.Field("player")})
+	require.Equal(t, []map[string]any{{"player": "ann"}, {"player": "ben"}, {"player": "cid"}}, runProjection(t, db, ctx, q))
+
+	grouped := teamScoresQuery().
+		Distinct().
+		GroupBy(dal.Field("team")).
+		SelectColumns(dal.CountDistinctAs(dal.Field("player"), "players"))
+	require.Len(t, runProjection(t, db, ctx, grouped), 2)
+
+	sameScore := teamScoresQuery().
+		Distinct().
+		GroupBy(dal.Field("player")).
+		SelectColumns(dal.MaxAs(dal.Field("score"), "best"))
+	require.Len(t, runProjection(t, db, ctx, sameScore), 4, "ann and ben share a best score of 4")
+}
@@ -1,90 +1,102 @@
 package dalgo2memory
 
 import (
+	"context"
 	"fmt"
 	"maps"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/record"
 )
 
-// joinedRow is one row of a join result: the base record id, the per-source
-// data used to resolve qualified fields, and a flat merge of both sources
-// used as the output record data. For an unmatched LEFT row the joined
-// source's data is nil.
+// joinedRow is one row of a join result: the id of each source's record (in
+// From order: the base, then each join; empty for a source null-filled by an
+// outer join), the per-source data used to resolve qualified fields, and a
+// flat merge of all sources used as the output record data.
 type joinedRow struct {
-	baseID  string
+	ids     []string
 	sources map[string]map[string]any
 	merged  map[string]any
 }
 
-// executeJoinQuery executes a StructuredQuery whose From carries a single
-// INNER or LEFT equi-join, via a nested loop over the two in-memory
-// collections with source-qualified field resolution for ON and WHERE.
-func (s session) executeJoinQuery(q dal.StructuredQuery) (dal.RecordsReader, error) {
-	from := q.From()
-	joins := from.Joins()
-	if len(joins) != 1 {
-		return nil, fmt.Errorf("dalgo2memory: only a single join is supported per query, got %d", len(joins))
-	}
-	join := joins[0]
-	switch join.JoinType() {
-	case dal.JoinInner, dal.JoinLeft:
-		// supported
-	default:
-		return nil, fmt.Errorf("dalgo2memory: unsupported join type %q (only INNER and LEFT are supported)", join.JoinType())
-	}
+// rowID is the join row's identity: the source ids joined by NUL. The NUL
+// separator sorts below every id byte, so ordering by rowID matches ordering
+// by the ids in From order, with null-filled sources first.
+func (r joinedRow) rowID() string {
+	return strings.Join(r.ids, "\x00")
+}
 
-	base := from.Base()
-	baseKey := sourceKey(base)
-	joinKey := sourceKey(join)
-	known := map[string]bool{"": true, baseKey: true, joinKey: true}
+// joinSource is one recordset of a join query with its qualifier.
+type joinSource struct {
+	collection string
+	alias      string
+	key        string
+}
 
-	if err := validateOrderSources(q.OrderBy(), known); err != nil {
+// executeJoinQuery executes a StructuredQuery whose From carries one or more
+// joins of any kind — INNER, LEFT, RIGHT and FULL joins and CROSS joins —
+// applied left to right over the rows joined so far and the next in-memory
+// collection, with source-qualified field resolution for ON and WHERE. WHERE
+// conjuncts on a single source are applied to its rows before joining (see
+// planJoin), and each join is a hash join on its ON equalities where it has
+// any (see joinRowsOf). An outer join null-fills the unmatched side: a nil
+// data map for every source of that side. The ordered result is paged by
+// StartFrom, OFFSET and LIMIT.
+func (s session) executeJoinQuery(ctx context.Context, q dal.StructuredQuery) (dal.RecordsReader, error) {
+	from := q.From()
+	base := from.Base()
+	joins := from.Joins()
+	sources, known, err := joinSourcesOf(q)
+	if err != nil {
 		return nil, err
 	}
-	grouped := len(q.GroupBy()) > 0
-	if !grouped {
-		if err := validateColumns(q.Columns(), known); err != nil {
-			return nil, err
-		}
-	}
+	grouped := isGroupedQuery(q)
 
-	baseRows, err := s.loadRows(base.Name())
+	plan := planJoin(q.Where(), joins, sources)
+	baseRows, err := s.loadJoinSource(sources[0], true, plan.filters[0], known)
 	if err != nil {
 		return nil, err
 	}
-	joinRows, err := s.loadRows(join.Name())
-	if err != nil {
-		return nil, err
+	combined := make([]joinedRow, len(baseRows))
+	for i, br := range baseRows {
+		combined[i] = joinedRow{
+			ids:     []string{br.id},
+			sources: map[string]map[string]any{"": br.data, sources[0].key: br.data},
+		}
 	}
-	sortByID(baseRows)
-	sortByID(joinRows)
-
-	combined := make([]joinedRow, 0, len(baseRows))
-	for _, br := range baseRows {
-		matched := false
-		for _, jr := range joinRows {
-			sources := map[string]map[string]any{"": br.data, baseKey: br.data, joinKey: jr.data}
-			ok, err := allConditionsMatch(join.On(), sources, known)
-			if err != nil {
-				return nil, err
-			}
-			if ok {
-				combined = append(combined, joinedRow{baseID: br.id, sources: sources, merged: mergeData(br.data, jr.data)})
-				matched = true
-			}
+	for i, join := range joins {
+		joinRows, err := s.loadJoinSource(sources[i+1], false, plan.filters[i+1], known)
+		if err != nil {
+			return nil, err
 		}
-		if !matched && join.JoinType() == dal.JoinLeft {
-			sources := map[string]map[string]any{"": br.data, baseKey: br.data, joinKey: nil}
-			combined = append(combined, joinedRow{baseID: br.id, sources: sources, merged: mergeData(br.data, nil)})
+		if combined, err = joinRowsOf(combined, joinRows, join, sources[:i+1], sources[i+1].key, known); err != nil {
+			return nil, err
 		}
 	}
+	for i := range combined {
+		merged := make(map[string]any)
+		for _, src := range sources {
+			maps.Copy(merged, combined[i].sources[src.key])
+		}
+		combined[i].merged = merged
+	}
 
+	var subqueries *subqueryBinder
+	if dal.HasSubquery(plan.residual) {
+		subqueries = newSubqueryBinder(ctx, s, known)
+	}
 	filtered := make([]joinedRow, 0, len(combined))
 	for _, row := range combined {
-		ok, err := matchesJoinCondition(q.Where(), row.sources, known)
+		where := plan.residual
+		if subqueries != nil {
+			if where, err = subqueries.bind(where, row.sources); err != nil {
+				return nil, err
+			}
+		}
+		ok, err := matchesJoinCondition(where, row.sources, known)
 		if err != nil {
 			return nil, err
 		}
@@ -102,15 +114,26 @@ func (s session) executeJoinQuery(q dal.StructuredQuery) (dal.RecordsReader, err
 	}
 
 	orderJoinedRows(filtered, q.OrderBy())
+	if dal.IsDistinct(q) {
+		filtered = distinctRows(filtered, func(r joinedRow) any {
+			if columns := q.Columns(); len(columns) > 0 {
+				return projectRow(columns, r.sources)
+			}
+			return r.merged
+		})
+	}
 
-	if limit := q.Limit(); limit > 0 && limit < len(filtered) {
-		filtered = filtered[:limit]
+	filtered, cursors, err := paginate(filtered, q, func(r joinedRow) ([]any, string) {
+		return orderValues(q.OrderBy(), r.sources), r.rowID()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	columns := q.Columns()
 	records := make([]record.Record, 0, len(filtered))
 	for _, row := range filtered {
-		key := record.NewKeyWithID(base.Name(), row.baseID)
+		key := joinedRowKey(row, sources)
 		if len(columns) > 0 {
 			records = append(records, record.NewRecordWithData(key, projectRow(columns, row.sources)).SetError(nil))
 			continue
@@ -121,7 +144,150 @@ func (s session) executeJoinQuery(q dal.StructuredQuery) (dal.RecordsReader, err
 		}
 		records = append(records, record.NewRecordWithData(key, row.merged).SetError(nil))
 	}
-	return dal.NewRecordsReader(records), nil
+	return newRecordsReader(records, cursors), nil
+}
+
+// joinSourcesOf returns the recordsets of a join query, base first, and the
+// set of qualifiers its fields may use. It rejects an unknown join type, a
+// CROSS join with ON conditions, a recordset joined twice under the same
+// qualifier, and ORDER BY keys and columns referencing unknown sources.
+func joinSourcesOf(q dal.StructuredQuery) ([]joinSource, map[string]bool, error) {
+	base := q.From().Base()
+	sources := []joinSource{{collection: base.Name(), alias: base.Alias(), key: sourceKey(base)}}
+	known := map[string]bool{"": true, sources[0].key: true}
+	for _, join := range q.From().Joins() {
+		switch join.JoinType() {
+		case dal.JoinInner, dal.JoinLeft, dal.JoinRight, dal.JoinFull:
+		case dal.JoinCross:
+			if len(join.On()) > 0 {
+				return nil, nil, fmt.Errorf("dalgo2memory: CROSS join with %q takes no ON conditions", join.Name())
+			}
+		default:
+			return nil, nil, fmt.Errorf("dalgo2memory: unsupported join type %q", join.JoinType())
+		}
+		key := sourceKey(join)
+		if known[key] {
+			return nil, nil, fmt.Errorf("dalgo2memory: duplicate source %q in join; alias one of them", key)
+		}
+		known[key] = true
+		sources = append(sources, joinSource{collection: join.Name(), alias: join.Alias(), key: key})
+	}
+	if err := validateOrderSources(q.OrderBy(), known); err != nil {
+		return nil, nil, err
+	}
+	if !isGroupedQuery(q) {
+		if err := validateColumns(q.Columns(), known); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sources, known, nil
+}
+
+// joinRowsOf joins the rows joined so far (over the left sources) with the
+// rows of the next joined collection, whose qualifier is key. A LEFT or FULL
+// join keeps an unmatched left row with the new source null-filled; a RIGHT or
+// FULL join adds each unmatched right row with every left source null-filled.
+// The result order is settled later by orderJoinedRows.
+//
+// When ON has equalities between the new source and the earlier ones (see
+// hashJoinKeys), the right rows are bucketed by their key values and each left
+// row is paired only with its bucket; otherwise every pair is tried. ON is
+// evaluated in full for each pair tried, so non-equality conditions work
+// either way.
+func joinRowsOf(left []joinedRow, right []memoryRow, join dal.JoinedSource, leftSources []joinSource, key string, known map[string]bool) ([]joinedRow, error) {
+	joinType := join.JoinType()
+	earlier := make(map[string]bool, len(leftSources))
+	for _, src := range leftSources {
+		earlier[src.key] = true
+	}
+	var probe []dal.Expression
+	var buckets map[string][]int
+	if keys := hashJoinKeys(join.On(), key, leftSources[0].key, earlier); len(keys) > 0 {
+		build := make([]dal.Expression, len(keys))
+		for i, k := range keys {
+			probe = append(probe, k.probe)
+			build[i] = k.build
+		}
+		buckets = make(map[string][]int)
+		for j, rr := range right {
+			hk, ok, err := hashKeyOf(build, map[string]map[string]any{key: rr.data}, known)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				buckets[hk] = append(buckets[hk], j)
+			}
+		}
+	}
+	all := make([]int, len(right))
+	for j := range all {
+		all[j] = j
+	}
+
+	out := make([]joinedRow, 0, len(left))
+	rightMatched := make([]bool, len(right))
+	for _, lr := range left {
+		candidates := all
+		if buckets != nil {
+			hk, ok, err := hashKeyOf(probe, lr.sources, known)
+			if err != nil {
+				return nil, err
+			}
+			candidates = nil
+			if ok {
+				candidates = buckets[hk]
+			}
+		}
+		matched := false
+		for _, j := range candidates {
+			rr := right[j]
+			sources := maps.Clone(lr.sources)
+			sources[key] = rr.data
+			if joinType != dal.JoinCross {
+				ok, err := allConditionsMatch(join.On(), sources, known)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+			}
+			out = append(out, joinedRow{ids: append(slices.Clone(lr.ids), rr.id), sources: sources})
+			matched = true
+			rightMatched[j] = true
+		}
+		if !matched && (joinType == dal.JoinLeft || joinType == dal.JoinFull) {
+			sources := maps.Clone(lr.sources)
+			sources[key] = nil
+			out = append(out, joinedRow{ids: append(slices.Clone(lr.ids), ""), sources: sources})
+		}
+	}
+	if joinType == dal.JoinRight || joinType == dal.JoinFull {
+		for j, rr := range right {
+			if rightMatched[j] {
+				continue
+			}
+			sources := map[string]map[string]any{"": nil, key: rr.data}
+			ids := make([]string, len(leftSources), len(leftSources)+1)
+			for _, src := range leftSources {
+				sources[src.key] = nil
+			}
+			out = append(out, joinedRow{ids: append(ids, rr.id), sources: sources})
+		}
+	}
+	return out, nil
+}
+
+// joinedRowKey is the result key of a join row: the key of its base record,
+// or, for a row whose base was null-filled by a RIGHT or FULL join, of the
+// first source that is present.
+func joinedRowKey(row joinedRow, sources []joinSource) *record.Key {
+	for i, id := range row.ids {
+		if id != "" {
+			return record.NewKeyWithID(sources[i].collection, id)
+		}
+	}
+	return record.NewKeyWithID(sources[0].collection, "")
 }
 
 // sourceKey is the key a qualified FieldRef.Source() must match to resolve
@@ -134,11 +300,17 @@ func sourceKey(rs dal.RecordsetSource) string {
 }
 
 // validateOrderSources rejects an ORDER BY FieldRef whose non-empty Source()
-// names no recordset in the query, before sorting (the sort callback cannot
-// return an error). A non-FieldRef key is not an error here — it is skipped
-// during the sort.
+// names no recordset in the query, and an invalid computed key (see
+// validateExpression), before sorting (the sort callback cannot return an
+// error). Any other key is not an error here — it is skipped during the sort.
 func validateOrderSources(orderBy []dal.OrderExpression, known map[string]bool) error {
 	for _, oe := range orderBy {
+		if isComputed(oe.Expression()) {
+			if err := validateExpression(oe.Expression(), known); err != nil {
+				return err
+			}
+			continue
+		}
 		f, ok := oe.Expression().(dal.FieldRef)
 		if !ok {
 			continue
@@ -153,54 +325,84 @@ func validateOrderSources(orderBy []dal.OrderExpression, known map[string]bool)
 // orderBySources is the shared ORDER BY comparator for both the single-source
 // and join paths. It stably sorts rows by the ORDER BY expressions in declared
 // order, resolving each FieldRef key against sourcesOf(row) (empty Source() ->
-// the "" entry; non-empty -> the matching source), honoring Descending() per
-// key, skipping non-FieldRef keys, with idOf(row) as the final tiebreak.
+// the "" entry; non-empty -> the matching source) and evaluating computed keys,
+// honoring Descending() per key, skipping other keys, with idOf(row) as the
+// final tiebreak.
 func orderBySources[T any](rows []T, orderBy []dal.OrderExpression, sourcesOf func(T) map[string]map[string]any, idOf func(T) string) {
 	sort.SliceStable(rows, func(i, j int) bool {
-		si, sj := sourcesOf(rows[i]), sourcesOf(rows[j])
-		for _, oe := range orderBy {
-			f, ok := oe.Expression().(dal.FieldRef)
-			if !ok {
-				continue
-			}
-			c := compare(si[f.Source()][f.Name()], sj[f.Source()][f.Name()])
-			if oe.Descending() {
-				c = -c
-			}
-			if c != 0 {
-				return c < 0
-			}
+		c := compareOrderValues(orderValues(orderBy, sourcesOf(rows[i])), orderValues(orderBy, sourcesOf(rows[j])), orderBy)
+		if c != 0 {
+			return c < 0
 		}
 		return idOf(rows[i]) < idOf(rows[j])
 	})
 }
 
+// orderValues resolves a row's ORDER BY key values, one per expression; a key
+// that is neither a FieldRef nor computed resolves to nil and is skipped by
+// compareOrderValues.
+func orderValues(orderBy []dal.OrderExpression, sources map[string]map[string]any) []any {
+	if len(orderBy) == 0 {
+		return nil
+	}
+	values := make([]any, len(orderBy))
+	for k, oe := range orderBy {
+		values[k] = sourcesValue(sources, oe.Expression())
+	}
+	return values
+}
+
+// compareOrderValues compares two rows' ORDER BY key values in declared order,
+// honoring Descending() per key and skipping keys that are neither a FieldRef
+// nor computed. A missing value (e.g. from a cursor with fewer keys) compares
+// as nil.
+func compareOrderValues(a, b []any, orderBy []dal.OrderExpression) int {
+	for k, oe := range orderBy {
+		if _, ok := oe.Expression().(dal.FieldRef); !ok && !isComputed(oe.Expression()) {
+			continue
+		}
+		c := compare(valueAt(a, k), valueAt(b, k))
+		if oe.Descending() {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func valueAt(values []any, i int) any {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
 // orderJoinedRows orders join result rows via the shared comparator.
 func orderJoinedRows(rows []joinedRow, orderBy []dal.OrderExpression) {
 	orderBySources(rows, orderBy,
 		func(r joinedRow) map[string]map[string]any { return r.sources },
-		func(r joinedRow) string { return r.baseID })
-}
-
-func (s session) loadRows(collectionName string) ([]memoryRow, error) {
-	engineRows, err := s.db.engine(collectionName).rows()
-	if err != nil {
-		return nil, err
-	}
-	return toMemoryRows(engineRows), nil
+		joinedRow.rowID)
 }
 
 // candidateRowsEngine is the optional capability a storage engine implements to
-// accelerate the single supported equality WHERE predicate: it returns the rows
-// its column strategy selects (ok=true) or signals "no opinion" (ok=false) so
-// the caller scans all rows. The returned rows are still re-filtered with
-// matchesWhere, so the result is identical to a full scan.
+// accelerate a WHERE predicate: it returns the rows its column strategy or
+// index selects (ok=true) or signals "no opinion" (ok=false) so the caller
+// scans all rows. The returned rows are a superset of the matching ones and
+// are still re-filtered with matchesWhere, so the result is identical to a
+// full scan.
+//
+// candidateCount reports, without decoding them, how many rows candidateRows
+// would return and the index (or column) it reads them through, so
+// ExplainQuery reflects the same decision.
 type candidateRowsEngine interface {
 	candidateRows(condition dal.Condition) ([]engineRow, bool, error)
+	candidateCount(condition dal.Condition) (index string, n int, ok bool, err error)
 }
 
-// loadCandidateRows returns the rows a single-source query must consider for a
-// WHERE condition. When the collection's engine implements candidateRowsEngine
+// loadCandidateRows returns the rows a query must consider for a WHERE
+// condition (or, in a join, for a condition pushed to the source). When the collection's engine implements candidateRowsEngine
 // and has an opinion about the predicate, only the candidate rows are loaded;
 // otherwise every row is loaded for a full scan.
 func (s session) loadCandidateRows(collectionName string, condition dal.Condition) ([]memoryRow, error) {
@@ -221,6 +423,36 @@ func (s session) loadCandidateRows(collectionName string, condition dal.Conditio
 	return toMemoryRows(allRows), nil
 }
 
+// loadJoinSource returns the rows of one source of a join query, sorted by id,
+// that satisfy the conditions planJoin pushed to it. When one of them is an
+// equality the engine can answer (see candidateCondition), only its candidate
+// rows are loaded.
+func (s session) loadJoinSource(src joinSource, isBase bool, filters []dal.Condition, known map[string]bool) ([]memoryRow, error) {
+	rows, err := s.loadCandidateRows(src.collection, candidateCondition(filters, src.key, isBase))
+	if err != nil {
+		return nil, err
+	}
+	sortByID(rows)
+	if len(filters) == 0 {
+		return rows, nil
+	}
+	kept := rows[:0]
+	for _, row := range rows {
+		sources := map[string]map[string]any{src.key: row.data}
+		if isBase {
+			sources[""] = row.data
+		}
+		ok, err := allConditionsMatch(filters, sources, known)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, row)
+		}
+	}
+	return kept, nil
+}
+
 func toMemoryRows(engineRows []engineRow) []memoryRow {
 	rows := make([]memoryRow, len(engineRows))
 	for i, r := range engineRows {
@@ -233,16 +465,11 @@ func sortByID(rows []memoryRow) {
 	sort.Slice(rows, func(i, j int) bool { return rows[i].id < rows[j].id })
 }
 
-func mergeData(base, join map[string]any) map[string]any {
-	merged := make(map[string]any, len(base)+len(join))
-	maps.Copy(merged, base)
-	maps.Copy(merged, join)
-	return merged
-}
-
-// resolveJoinExpr resolves a FieldRef against the per-source data, or returns
-// a Constant's value. An empty source denotes the From base. A non-empty
-// source that names no recordset in the query is a descriptive error.
+// resolveJoinExpr resolves a FieldRef against the per-source data, returns a
+// Constant's value normalized as in rowValue, or evaluates a computed
+// expression over resolved operands. An empty source denotes the From base. A
+// non-empty source that names no recordset in the query is a descriptive
+// error.
 func resolveJoinExpr(e dal.Expression, sources map[string]map[string]any, known map[string]bool) (any, bool, error) {
 	switch v := e.(type) {
 	case dal.FieldRef:
@@ -253,7 +480,20 @@ func resolveJoinExpr(e dal.Expression, sources map[string]map[string]any, known
 		val, present := sources[src][v.Name()]
 		return val, present, nil
 	case dal.Constant:
-		return v.Value, true, nil
+		return normalizeConstant(v.Value), true, nil
+	case dal.Array:
+		return normalizeConstant(v.Value), true, nil
+	case dal.ScalarFunction, dal.Arithmetic:
+		if f, ok := e.(dal.ScalarFunction); ok {
+			if err := validateScalarArity(f); err != nil {
+				return nil, false, err
+			}
+		}
+		val, err := evalComputed(e, func(arg dal.Expression) (any, error) {
+			val, _, err := resolveJoinExpr(arg, sources, known)
+			return val, err
+		})
+		return val, err == nil, err
 	default:
 		return nil, false, fmt.Errorf("dalgo2memory: unsupported expression %T in join query", e)
 	}
@@ -273,33 +513,69 @@ func allConditionsMatch(conds []dal.Condition, sources map[string]map[string]any
 	return true, nil
 }
 
-// matchesJoinCondition evaluates a single equality Comparison over the
-// per-source data. A nil condition matches; any non-equality shape does not
-// (mirroring the in-memory adapter's single-source WHERE support).
+// matchesJoinCondition evaluates an ON or WHERE condition over the per-source
+// data. A nil condition matches. As in the single-source WHERE (see
+// matchesWhere), AND groups and negation are evaluated in three-valued logic
+// and an OR group does not match. An equality needs both operands present;
+// the other operators follow applyOperator.
 func matchesJoinCondition(cond dal.Condition, sources map[string]map[string]any, known map[string]bool) (bool, error) {
-	if cond == nil {
-		return true, nil
-	}
-	cmp, ok := cond.(dal.Comparison)
-	if !ok || cmp.Operator != dal.Equal {
-		return false, nil
+	t, err := joinTruth(cond, sources, known)
+	return t == isTrue, err
+}
+
+func joinTruth(cond dal.Condition, sources map[string]map[string]any, known map[string]bool) (truth, error) {
+	switch c := cond.(type) {
+	case nil:
+		return isTrue, nil
+	case subqueryResult:
+		return truthOf(bool(c)), nil
+	case unknownCondition:
+		return isUnknown, nil
+	case dal.GroupCondition:
+		if c.Operator() != dal.And {
+			return isFalse, nil
+		}
+		t := isTrue
+		for _, sub := range c.Conditions() {
+			st, err := joinTruth(sub, sources, known)
+			if err != nil {
+				return isFalse, err
+			}
+			if t = t.and(st); t == isFalse {
+				break
+			}
+		}
+		return t, nil
+	case dal.NotCondition:
+		t, err := joinTruth(c.Condition(), sources, known)
+		if err != nil {
+			return isFalse, err
+		}
+		return t.not(), nil
+	case dal.Comparison:
+		return joinComparisonTruth(c, sources, known)
+	default:
+		return isFalse, nil
 	}
+}
+
+func joinComparisonTruth(cmp dal.Comparison, sources map[string]map[string]any, known map[string]bool) (truth, error) {
 	l, lok, err := resolveJoinExpr(cmp.Left, sources, known)
 	if err != nil {
-		return false, err
+		return isFalse, err
+	}
+	if dal.IsUnaryOperator(cmp.Operator) {
+		return truthOf(applyOperator(cmp.Operator, l, nil)), nil
 	}
 	r, rok, err := resolveJoinExpr(cmp.Right, sources, known)
 	if err != nil {
-		return false, err
+		return isFalse, err
 	}
-	return lok && rok && valuesEqual(l, r), nil
-}
-
-func valuesEqual(a, b any) bool {
-	if af, aok := number(a); aok {
-		if bf, bok := number(b); bok {
-			return af == bf
-		}
+	if !lok || !rok {
+		return isUnknown, nil
+	}
+	if cmp.Operator == dal.Equal {
+		return comparisonTruth(cmp.Operator, l, elementEquals(l, r)), nil
 	}
-	return a == b
+	return comparisonTruth(cmp.Operator, l, applyOperator(cmp.Operator, l, r)), nil
 }
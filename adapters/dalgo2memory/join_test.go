@@ -5,6 +5,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/record"
@@ -108,25 +109,150 @@ func TestExecuteJoin_QualifiedResolutionInWhere(t *testing.T) {
 	}
 }
 
-// Task 6: unsupported join type and chained joins error, no rows.
+// Constants are normalized like the stored data, so a time.Time compares
+// equal to the stored timestamp in a join just as in a single-source WHERE.
+func TestExecuteJoin_NormalizedConstants(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	shipped := time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("orders", "a"), &map[string]any{"userId": 1, "shippedAt": shipped})))
+	at := dal.Constant{Value: shipped}
+
+	single := dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().
+		WhereField("shippedAt", dal.Equal, shipped).SelectKeysOnly(reflect.String)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, single)
+	require.NoError(t, err)
+	require.Len(t, readAll(t, reader), 1)
+
+	join := dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())
+	q := dal.From(usersAlias()).Join(join).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("o", "shippedAt"), dal.Equal, at)).
+		SelectIntoRecord(intoMapRecord())
+	got := runJoinQuery(t, db, ctx, q)
+	require.Len(t, got, 1)
+	require.EqualValues(t, 1, got[0]["id"])
+}
+
+// Task 6: an unknown join type, a source joined twice under the same name and
+// a CROSS join with ON conditions error, no rows.
 func TestExecuteJoin_UnsupportedJoinErrors(t *testing.T) {
 	db, ctx := seedUsersOrders(t)
 
-	t.Run("reserved RIGHT type", func(t *testing.T) {
-		right := dal.NewJoinedSource(ordersAlias(), dal.JoinRight, onUserEqOrder())
-		q := dal.From(usersAlias()).Join(right).NewQuery().SelectIntoRecord(intoMapRecord())
+	t.Run("unknown type", func(t *testing.T) {
+		join := dal.NewJoinedSource(ordersAlias(), dal.JoinType("SIDEWAYS"), onUserEqOrder())
+		q := dal.From(usersAlias()).Join(join).NewQuery().SelectIntoRecord(intoMapRecord())
 		reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
 		require.Nil(t, reader)
 		require.ErrorContains(t, err, "unsupported join type")
 	})
 
-	t.Run("chained second join", func(t *testing.T) {
+	t.Run("same alias joined twice", func(t *testing.T) {
 		j1 := dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())
 		j2 := dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())
 		q := dal.From(usersAlias()).Join(j1).Join(j2).NewQuery().SelectIntoRecord(intoMapRecord())
 		reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
 		require.Nil(t, reader)
-		require.ErrorContains(t, err, "single join")
+		require.ErrorContains(t, err, "duplicate source")
+	})
+
+	t.Run("CROSS with ON", func(t *testing.T) {
+		join := dal.NewJoinedSource(ordersAlias(), dal.JoinCross, onUserEqOrder())
+		q := dal.From(usersAlias()).Join(join).NewQuery().SelectIntoRecord(intoMapRecord())
+		reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
+		require.Nil(t, reader)
+		require.ErrorContains(t, err, "takes no ON conditions")
+	})
+}
+
+// joinedPairs runs q projecting u.id and o.userId and returns the pairs in
+// result order, with nil for a null-filled side.
+func joinedPairs(t *testing.T, db *database, ctx context.Context, joinType dal.JoinType) [][2]any {
+	t.Helper()
+	var on []dal.Condition
+	if joinType != dal.JoinCross {
+		on = append(on, onUserEqOrder())
+	}
+	q := dal.From(usersAlias()).Join(dal.NewJoinedSource(ordersAlias(), joinType, on...)).NewQuery().
+		SelectColumns(dal.Column{Expression: dal.NewFieldRef("u", "id"), Alias: "uid"}, dal.Column{Expression: dal.NewFieldRef("o", "userId"), Alias: "oid"})
+	rows := runJoinQuery(t, db, ctx, q)
+	pairs := make([][2]any, len(rows))
+	for i, r := range rows {
+		pairs[i] = [2]any{r["uid"], r["oid"]}
+	}
+	return pairs
+}
+
+// RIGHT, FULL and CROSS joins null-fill the unmatched side of each row.
+func TestExecuteJoin_OuterAndCrossJoins(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+
+	t.Run("RIGHT keeps the unmatched order", func(t *testing.T) {
+		got := joinedPairs(t, db, ctx, dal.JoinRight)
+		require.Equal(t, [][2]any{{nil, 9.0}, {1.0, 1.0}, {1.0, 1.0}}, got)
+	})
+
+	t.Run("FULL keeps both unmatched sides", func(t *testing.T) {
+		got := joinedPairs(t, db, ctx, dal.JoinFull)
+		require.Equal(t, [][2]any{{nil, 9.0}, {1.0, 1.0}, {1.0, 1.0}, {2.0, nil}}, got)
+	})
+
+	t.Run("CROSS pairs every row", func(t *testing.T) {
+		got := joinedPairs(t, db, ctx, dal.JoinCross)
+		require.Len(t, got, 6)
+		require.Equal(t, [2]any{1.0, 1.0}, got[0])
+		require.Equal(t, [2]any{2.0, 9.0}, got[5])
+	})
+
+	t.Run("RIGHT row without a base is keyed by the joined record", func(t *testing.T) {
+		join := dal.NewJoinedSource(ordersAlias(), dal.JoinRight, onUserEqOrder())
+		q := dal.From(usersAlias()).Join(join).NewQuery().SelectIntoRecord(intoMapRecord())
+		reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
+		require.NoError(t, err)
+		rec, err := reader.Next()
+		require.NoError(t, err)
+		require.Equal(t, "orders", rec.Key().Collection())
+		require.Equal(t, "c", rec.Key().ID)
+		require.Equal(t, map[string]any{"userId": 9.0, "status": "shipped"}, rec.Data())
+	})
+}
+
+// A three-way join chains joins left to right; an outer join null-fills every
+// source of the unmatched side, including those joined before it.
+func TestExecuteJoin_MultiWay(t *testing.T) {
+	db, ctx := seedUsersOrders(t)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("shipments", "s1"), &map[string]any{"order": "a", "carrier": "ups"})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("shipments", "s2"), &map[string]any{"order": "z", "carrier": "dhl"})))
+	shipments := dal.NewRootCollectionRef("shipments", "s")
+	shippedOrder := dal.NewComparison(dal.NewFieldRef("o", "status"), dal.Equal, dal.Constant{Value: "shipped"})
+	columns := []dal.Column{
+		{Expression: dal.NewFieldRef("u", "id"), Alias: "uid"},
+		{Expression: dal.NewFieldRef("o", "userId"), Alias: "oid"},
+		{Expression: dal.NewFieldRef("s", "carrier"), Alias: "carrier"},
+	}
+
+	t.Run("INNER then INNER", func(t *testing.T) {
+		q := dal.From(usersAlias()).
+			Join(dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())).
+			Join(dal.NewJoinedSource(shipments, dal.JoinInner, shippedOrder, dal.NewComparison(dal.NewFieldRef("s", "carrier"), dal.Equal, dal.Constant{Value: "ups"}))).
+			NewQuery().SelectColumns(columns...)
+		got := runJoinQuery(t, db, ctx, q)
+		require.Equal(t, []map[string]any{
+			{"uid": 1.0, "oid": 1.0, "carrier": "ups"},
+			{"uid": 1.0, "oid": 1.0, "carrier": "ups"},
+		}, got)
+	})
+
+	t.Run("LEFT then RIGHT", func(t *testing.T) {
+		onShipment := dal.NewComparison(dal.NewFieldRef("s", "carrier"), dal.Equal, dal.Constant{Value: "dhl"})
+		q := dal.From(usersAlias()).
+			Join(dal.NewJoinedSource(ordersAlias(), dal.JoinLeft, onUserEqOrder())).
+			Join(dal.NewJoinedSource(shipments, dal.JoinRight, onUserEqOrder(), onShipment)).
+			NewQuery().SelectColumns(columns...)
+		got := runJoinQuery(t, db, ctx, q)
+		require.Equal(t, []map[string]any{
+			{"uid": nil, "oid": nil, "carrier": "ups"},
+			{"uid": 1.0, "oid": 1.0, "carrier": "dhl"},
+			{"uid": 1.0, "oid": 1.0, "carrier": "dhl"},
+		}, got)
 	})
 }
 
@@ -169,12 +295,12 @@ func (weirdExpr) String() string { return "weird" }
 
 // Edge cases for full branch coverage of the join executor.
 func TestExecuteJoin_EdgeCases(t *testing.T) {
-	t.Run("non-equality WHERE matches nothing", func(t *testing.T) {
+	t.Run("non-equality WHERE compares", func(t *testing.T) {
 		db, ctx := seedUsersOrders(t)
-		join := dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())
+		join := dal.NewJoinedSource(ordersAlias(), dal.JoinLeft, onUserEqOrder())
 		where := dal.NewComparison(dal.NewFieldRef("o", "userId"), dal.GreaterThen, dal.Constant{Value: 0})
 		q := dal.From(usersAlias()).Join(join).NewQuery().Where(where).SelectIntoRecord(intoMapRecord())
-		require.Empty(t, runJoinQuery(t, db, ctx, q))
+		require.Len(t, runJoinQuery(t, db, ctx, q), 2, "the null-filled order of user 2 is not > 0")
 	})
 
 	t.Run("unsupported expression in WHERE errors", func(t *testing.T) {
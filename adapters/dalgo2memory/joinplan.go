@@ -0,0 +1,271 @@
+package dalgo2memory
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// joinPlan is how executeJoinQuery evaluates a join query: the conditions that
+// filter each source's rows before any join (by source index, base first), and
+// the WHERE remainder evaluated over the joined rows.
+type joinPlan struct {
+	filters  [][]dal.Condition
+	residual dal.Condition
+}
+
+// planJoin pushes below the joins every WHERE conjunct that references a
+// single source which no outer join null-fills (filtering that source's rows
+// first gives the same result), and, for an INNER or LEFT join, the ON
+// conjuncts that reference only the joined source (a row failing them can
+// never match). The ON conditions are still evaluated in full while joining.
+func planJoin(where dal.Condition, joins []dal.JoinedSource, sources []joinSource) joinPlan {
+	plan := joinPlan{filters: make([][]dal.Condition, len(sources)), residual: where}
+	index := make(map[string]int, len(sources))
+	for i, src := range sources {
+		index[src.key] = i
+	}
+	var residual []dal.Condition
+	for _, c := range conjuncts(where) {
+		if i, ok := singleSource(c, sources[0].key, index); ok && !nullFilled(i, joins) {
+			plan.filters[i] = append(plan.filters[i], c)
+			continue
+		}
+		residual = append(residual, c)
+	}
+	if len(residual) < len(conjuncts(where)) {
+		plan.residual = allOf(residual)
+	}
+	for k, join := range joins {
+		if t := join.JoinType(); t != dal.JoinInner && t != dal.JoinLeft {
+			continue
+		}
+		for _, c := range join.On() {
+			for _, cc := range conjuncts(c) {
+				if i, ok := singleSource(cc, sources[0].key, index); ok && i == k+1 {
+					plan.filters[i] = append(plan.filters[i], cc)
+				}
+			}
+		}
+	}
+	return plan
+}
+
+// allOf returns the conjunction of conditions: nil for none, the condition
+// itself for one.
+func allOf(conds []dal.Condition) dal.Condition {
+	switch len(conds) {
+	case 0:
+		return nil
+	case 1:
+		return conds[0]
+	default:
+		return dal.NewGroupCondition(dal.And, conds...)
+	}
+}
+
+// nullFilled reports whether an outer join can null-fill the i-th source: a
+// LEFT or FULL join null-fills the source it joins, a RIGHT or FULL join every
+// source joined before it.
+func nullFilled(i int, joins []dal.JoinedSource) bool {
+	for k, join := range joins {
+		switch join.JoinType() {
+		case dal.JoinLeft:
+			if i == k+1 {
+				return true
+			}
+		case dal.JoinRight:
+			if i <= k {
+				return true
+			}
+		case dal.JoinFull:
+			if i <= k+1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conjuncts flattens the AND groups of a condition into its conjuncts.
+func conjuncts(cond dal.Condition) []dal.Condition {
+	if cond == nil {
+		return nil
+	}
+	if g, ok := cond.(dal.GroupCondition); ok && g.Operator() == dal.And {
+		var out []dal.Condition
+		for _, sub := range g.Conditions() {
+			out = append(out, conjuncts(sub)...)
+		}
+		return out
+	}
+	return []dal.Condition{cond}
+}
+
+// singleSource returns the index of the only source a condition references,
+// with an unqualified field referencing the base. It is false for a condition
+// referencing no source, several sources or an unknown one, and for a
+// condition it cannot see through, such as a subquery.
+func singleSource(cond dal.Condition, base string, index map[string]int) (int, bool) {
+	refs := make(map[string]bool)
+	if !conditionSources(cond, base, refs) || len(refs) != 1 {
+		return 0, false
+	}
+	for key := range refs {
+		i, ok := index[key]
+		return i, ok
+	}
+	return 0, false
+}
+
+// conditionSources adds the sources a condition references to refs; it is
+// false for a condition or expression shape it does not know.
+func conditionSources(cond dal.Condition, base string, refs map[string]bool) bool {
+	switch c := cond.(type) {
+	case dal.Comparison:
+		return expressionSources(c.Left, base, refs) && (c.Right == nil || expressionSources(c.Right, base, refs))
+	case dal.GroupCondition:
+		for _, sub := range c.Conditions() {
+			if !conditionSources(sub, base, refs) {
+				return false
+			}
+		}
+		return true
+	case dal.NotCondition:
+		return conditionSources(c.Condition(), base, refs)
+	default:
+		return false
+	}
+}
+
+func expressionSources(e dal.Expression, base string, refs map[string]bool) bool {
+	switch ex := e.(type) {
+	case dal.FieldRef:
+		if src := ex.Source(); src != "" {
+			refs[src] = true
+		} else {
+			refs[base] = true
+		}
+		return true
+	case dal.Constant, dal.Array:
+		return true
+	case dal.ScalarFunction:
+		for _, arg := range ex.Args {
+			if !expressionSources(arg, base, refs) {
+				return false
+			}
+		}
+		return true
+	case dal.Arithmetic:
+		return expressionSources(ex.Left, base, refs) && expressionSources(ex.Right, base, refs)
+	default:
+		return false
+	}
+}
+
+// candidateCondition picks, from the conditions filtering a source's rows, an
+// equality of one of its fields to a constant that the storage engine can
+// answer from its column strategy (see candidateRowsEngine), rewritten as the
+// unqualified field==value predicate the engine expects; nil if there is none.
+// Numeric constants are left to the scan: the strategies compare with ==, so
+// an int constant would miss a value decoded as float64 that the join's
+// number-aware equality matches.
+func candidateCondition(filters []dal.Condition, key string, isBase bool) dal.Condition {
+	for _, c := range filters {
+		cmp, ok := c.(dal.Comparison)
+		if !ok || cmp.Operator != dal.Equal {
+			continue
+		}
+		field, fieldOK := cmp.Left.(dal.FieldRef)
+		constant, constantOK := cmp.Right.(dal.Constant)
+		if !fieldOK || !constantOK {
+			field, fieldOK = cmp.Right.(dal.FieldRef)
+			constant, constantOK = cmp.Left.(dal.Constant)
+		}
+		if !fieldOK || !constantOK {
+			continue
+		}
+		if src := field.Source(); src != key && (src != "" || !isBase) {
+			continue
+		}
+		if _, isNumber := number(constant.Value); isNumber || constant.Value == nil || !reflect.TypeOf(constant.Value).Comparable() {
+			continue
+		}
+		return dal.NewComparison(dal.Field(field.Name()), dal.Equal, constant)
+	}
+	return nil
+}
+
+// equiKey is an ON equality usable as a hash-join key: probe is resolved
+// against the rows joined so far, build against a row of the joined source.
+type equiKey struct {
+	probe, build dal.Expression
+}
+
+// hashJoinKeys returns the ON equalities of a join that compare an expression
+// over the joined source alone with one over the earlier sources alone; a
+// join without any is run as a nested loop.
+func hashJoinKeys(on []dal.Condition, key string, base string, earlier map[string]bool) []equiKey {
+	var keys []equiKey
+	for _, c := range on {
+		for _, cc := range conjuncts(c) {
+			cmp, ok := cc.(dal.Comparison)
+			if !ok || cmp.Operator != dal.Equal {
+				continue
+			}
+			left, right := make(map[string]bool), make(map[string]bool)
+			if !expressionSources(cmp.Left, base, left) || !expressionSources(cmp.Right, base, right) {
+				continue
+			}
+			switch {
+			case onlySource(right, key) && withinSources(left, earlier):
+				keys = append(keys, equiKey{probe: cmp.Left, build: cmp.Right})
+			case onlySource(left, key) && withinSources(right, earlier):
+				keys = append(keys, equiKey{probe: cmp.Right, build: cmp.Left})
+			}
+		}
+	}
+	return keys
+}
+
+func onlySource(refs map[string]bool, key string) bool {
+	return len(refs) == 1 && refs[key]
+}
+
+func withinSources(refs, sources map[string]bool) bool {
+	if len(refs) == 0 {
+		return false
+	}
+	for src := range refs {
+		if !sources[src] {
+			return false
+		}
+	}
+	return true
+}
+
+// hashKeyOf resolves key expressions against a row and encodes the values as
+// a hash-table key. Equal values under the join's equality (elementEquals)
+// encode equally — numbers as float64 — so a bucket holds every possible
+// match; ON is re-evaluated for each candidate pair. It is false when a value
+// is absent or not comparable, as such a row matches nothing on equality.
+func hashKeyOf(exprs []dal.Expression, sources map[string]map[string]any, known map[string]bool) (string, bool, error) {
+	var sb strings.Builder
+	for i, e := range exprs {
+		v, present, err := resolveJoinExpr(e, sources, known)
+		if err != nil || !present {
+			return "", false, err
+		}
+		if f, isNumber := number(v); isNumber {
+			v = f + 0 // +0 folds -0 into 0
+		} else if v != nil && !reflect.TypeOf(v).Comparable() {
+			return "", false, nil
+		}
+		if i > 0 {
+			sb.WriteByte(0)
+		}
+		sb.WriteString(valueKey(v))
+	}
+	return sb.String(), true, nil
+}
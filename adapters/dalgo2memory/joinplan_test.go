@@ -0,0 +1,195 @@
+package dalgo2memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanJoin_Pushdown(t *testing.T) {
+	sources := []joinSource{{collection: "users", key: "u"}, {collection: "orders", key: "o"}}
+	activeUser := dal.NewComparison(dal.NewFieldRef("u", "status"), dal.Equal, dal.Constant{Value: "active"})
+	unqualifiedActive := dal.WhereField("status", dal.Equal, "active")
+	shippedOrder := dal.NewComparison(dal.NewFieldRef("o", "status"), dal.Equal, dal.Constant{Value: "shipped"})
+	where := dal.NewGroupCondition(dal.And, activeUser, dal.NewGroupCondition(dal.And, unqualifiedActive, shippedOrder), onUserEqOrder())
+
+	t.Run("INNER pushes single-source conjuncts to both sides", func(t *testing.T) {
+		joins := []dal.JoinedSource{dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder(), shippedOrder)}
+		plan := planJoin(where, joins, sources)
+		assert.Equal(t, []dal.Condition{activeUser, unqualifiedActive}, plan.filters[0])
+		assert.Equal(t, []dal.Condition{shippedOrder, shippedOrder}, plan.filters[1], "the WHERE conjunct, then the ON one")
+		assert.Equal(t, onUserEqOrder(), plan.residual)
+	})
+
+	t.Run("outer joins keep conjuncts on null-filled sources", func(t *testing.T) {
+		joins := []dal.JoinedSource{dal.NewJoinedSource(ordersAlias(), dal.JoinLeft, onUserEqOrder(), shippedOrder)}
+		plan := planJoin(where, joins, sources)
+		assert.Equal(t, []dal.Condition{activeUser, unqualifiedActive}, plan.filters[0])
+		assert.Equal(t, []dal.Condition{shippedOrder}, plan.filters[1], "only the ON conjunct")
+		assert.Equal(t, dal.NewGroupCondition(dal.And, shippedOrder, onUserEqOrder()), plan.residual)
+
+		joins = []dal.JoinedSource{dal.NewJoinedSource(ordersAlias(), dal.JoinFull, onUserEqOrder(), shippedOrder)}
+		plan = planJoin(where, joins, sources)
+		assert.Equal(t, [][]dal.Condition{nil, nil}, plan.filters)
+		assert.Equal(t, where, plan.residual, "nothing pushed, WHERE kept as is")
+	})
+
+	t.Run("subqueries and unknown sources stay in WHERE", func(t *testing.T) {
+		sub := dal.Exists(dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().SelectKeysOnly(reflect.String))
+		unknown := dal.NewComparison(dal.NewFieldRef("x", "y"), dal.Equal, dal.Constant{Value: 1})
+		joins := []dal.JoinedSource{dal.NewJoinedSource(ordersAlias(), dal.JoinInner, onUserEqOrder())}
+		plan := planJoin(dal.NewGroupCondition(dal.And, sub, unknown, activeUser), joins, sources)
+		assert.Equal(t, []dal.Condition{activeUser}, plan.filters[0])
+		assert.Equal(t, dal.NewGroupCondition(dal.And, sub, unknown), plan.residual)
+	})
+}
+
+func TestNullFilled(t *testing.T) {
+	join := func(joinType dal.JoinType) dal.JoinedSource {
+		return dal.NewJoinedSource(ordersAlias(), joinType)
+	}
+	for _, tt := range []struct {
+		joins []dal.JoinedSource
+		want  []bool // per source, base first
+	}{
+		{[]dal.JoinedSource{join(dal.JoinInner), join(dal.JoinCross)}, []bool{false, false, false}},
+		{[]dal.JoinedSource{join(dal.JoinLeft), join(dal.JoinInner)}, []bool{false, true, false}},
+		{[]dal.JoinedSource{join(dal.JoinInner), join(dal.JoinRight)}, []bool{true, true, false}},
+		{[]dal.JoinedSource{join(dal.JoinFull), join(dal.JoinInner)}, []bool{true, true, false}},
+	} {
+		for i, want := range tt.want {
+			assert.Equal(t, want, nullFilled(i, tt.joins), "source %d of %v", i, tt.joins)
+		}
+	}
+}
+
+func TestHashKeyOf(t *testing.T) {
+	known := map[string]bool{"s": true}
+	key := func(v any) (string, bool) {
+		k, ok, err := hashKeyOf([]dal.Expression{dal.NewFieldRef("s", "v")}, map[string]map[string]any{"s": {"v": v}}, known)
+		require.NoError(t, err)
+		return k, ok
+	}
+	one, _ := key(1)
+	oneFloat, _ := key(1.0)
+	assert.Equal(t, one, oneFloat, "numbers key by value")
+	zero, _ := key(0.0)
+	negativeZero, _ := key(math.Copysign(0, -1))
+	assert.Equal(t, zero, negativeZero)
+	text, _ := key("1")
+	assert.NotEqual(t, one, text)
+	_, ok := key([]int{1})
+	assert.False(t, ok, "a non-comparable value matches nothing")
+	_, ok, err := hashKeyOf([]dal.Expression{dal.NewFieldRef("s", "missing")}, map[string]map[string]any{"s": {}}, known)
+	require.NoError(t, err)
+	assert.False(t, ok, "an absent value matches nothing")
+}
+
+// seedAccountsPayments loads n accounts and a payment for each, keyed by the
+// account number and carrying an amount equal to it.
+func seedAccountsPayments(t *testing.T, n int) (*database, context.Context) {
+	t.Helper()
+	db := NewDB().(*database)
+	ctx := context.Background()
+	for i := range n {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("accounts", fmt.Sprintf("a%05d", i)), &map[string]any{"number": i, "limit": 10})))
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("payments", fmt.Sprintf("p%05d", i)), &map[string]any{"account": i, "amount": i})))
+	}
+	return db, ctx
+}
+
+func TestExecuteJoin_HashAndNestedLoop(t *testing.T) {
+	accounts := dal.NewRootCollectionRef("accounts", "a")
+	payments := dal.NewRootCollectionRef("payments", "p")
+	sameAccount := dal.NewComparison(dal.NewFieldRef("a", "number"), dal.Equal, dal.NewFieldRef("p", "account"))
+	overLimit := dal.NewComparison(dal.NewFieldRef("p", "amount"), dal.GreaterThen, dal.NewFieldRef("a", "limit"))
+	count := func(t *testing.T, db *database, ctx context.Context, joinType dal.JoinType, where dal.Condition, on ...dal.Condition) int {
+		t.Helper()
+		q := dal.From(accounts).Join(dal.NewJoinedSource(payments, joinType, on...)).NewQuery().Where(where).SelectIntoRecord(intoMapRecord())
+		return len(runJoinQuery(t, db, ctx, q))
+	}
+
+	t.Run("equi-join over thousands of rows", func(t *testing.T) {
+		db, ctx := seedAccountsPayments(t, 5000)
+		assert.Equal(t, 5000, count(t, db, ctx, dal.JoinInner, nil, sameAccount))
+		assert.Equal(t, 4989, count(t, db, ctx, dal.JoinInner, nil, sameAccount, overLimit), "equality plus a residual ON predicate")
+		assert.Equal(t, 1, count(t, db, ctx, dal.JoinInner, dal.NewComparison(dal.NewFieldRef("p", "amount"), dal.Equal, dal.Constant{Value: 42}), sameAccount))
+	})
+
+	t.Run("computed key", func(t *testing.T) {
+		db, ctx := seedAccountsPayments(t, 10)
+		nextAccount := dal.NewComparison(dal.Add(dal.NewFieldRef("a", "number"), dal.Constant{Value: 1}), dal.Equal, dal.NewFieldRef("p", "account"))
+		assert.Equal(t, 9, count(t, db, ctx, dal.JoinInner, nil, nextAccount))
+		assert.Equal(t, 10, count(t, db, ctx, dal.JoinLeft, nil, nextAccount))
+		assert.Equal(t, 11, count(t, db, ctx, dal.JoinFull, nil, nextAccount))
+	})
+
+	t.Run("non-equi join falls back to nested loop", func(t *testing.T) {
+		db, ctx := seedAccountsPayments(t, 20)
+		assert.Equal(t, 20*9, count(t, db, ctx, dal.JoinInner, nil, overLimit))
+		lessThan := dal.NewComparison(dal.NewFieldRef("a", "number"), dal.LessThen, dal.NewFieldRef("p", "account"))
+		assert.Equal(t, 20*19/2+1, count(t, db, ctx, dal.JoinLeft, nil, lessThan), "the last account matches none")
+	})
+}
+
+// A join consults the columnar strategy for an equality pushed to a source,
+// from WHERE or from the ON conditions of an INNER join, and returns what a
+// serialized collection returns.
+func TestExecuteJoin_CandidatePruning(t *testing.T) {
+	ctx := context.Background()
+	colDB := NewDB(WithSchema(true, WithCollection[queryRow]("q", nil, WithColumnarStorage()))).(*database)
+	eng := colDB.engine("q").(*columnarEngine)
+	stgy := newRecordingStrategy(true, func(slot int) bool { return eng.live[slot] })
+	eng.byName["Group"].strategy = stgy
+	eng.byName["Group"].defaultStgy = nil
+	serDB := NewDB().(*database)
+	seedQueryRows(t, colDB, "q")
+	seedQueryRows(t, serDB, "q")
+	for _, db := range []*database{colDB, serDB} {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("ranks", "low"), &map[string]any{"max": 2})))
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("ranks", "high"), &map[string]any{"max": 9})))
+	}
+	inGroup := func(group string) dal.Condition {
+		return dal.NewComparison(dal.NewFieldRef("q", "Group"), dal.Equal, dal.Constant{Value: group})
+	}
+	withinRank := dal.NewComparison(dal.NewFieldRef("q", "Rank"), dal.LessOrEqual, dal.NewFieldRef("r", "max"))
+	columns := []dal.Column{{Expression: dal.NewFieldRef("q", "Name"), Alias: "name"}, {Expression: dal.NewFieldRef("r", "max"), Alias: "max"}}
+
+	run := func(db *database, q dal.Query) []map[string]any {
+		return runJoinQuery(t, db, ctx, q)
+	}
+	t.Run("WHERE on the base", func(t *testing.T) {
+		stgy.queried = nil
+		q := dal.From(dal.NewRootCollectionRef("q", "")).Join(dal.NewJoinedSource(dal.NewRootCollectionRef("ranks", "r"), dal.JoinInner, withinRank)).
+			NewQuery().Where(inGroup("a")).SelectColumns(columns...)
+		got := run(colDB, q)
+		assert.Equal(t, []any{"a"}, stgy.queried)
+		assert.Equal(t, run(serDB, q), got)
+		assert.Len(t, got, 4)
+	})
+	t.Run("ON of an INNER join", func(t *testing.T) {
+		stgy.queried = nil
+		q := dal.From(dal.NewRootCollectionRef("ranks", "r")).Join(dal.NewJoinedSource(dal.NewRootCollectionRef("q", ""), dal.JoinInner, withinRank, inGroup("b"))).
+			NewQuery().SelectColumns(columns...)
+		got := run(colDB, q)
+		assert.Equal(t, []any{"b"}, stgy.queried)
+		assert.Equal(t, run(serDB, q), got)
+		assert.Equal(t, []map[string]any{{"name": "bob", "max": 9.0}, {"name": "bob", "max": 2.0}}, got)
+	})
+	t.Run("not for the preserved side of a RIGHT join", func(t *testing.T) {
+		stgy.queried = nil
+		q := dal.From(dal.NewRootCollectionRef("ranks", "r")).Join(dal.NewJoinedSource(dal.NewRootCollectionRef("q", ""), dal.JoinRight, withinRank, inGroup("b"))).
+			NewQuery().SelectColumns(columns...)
+		got := run(colDB, q)
+		assert.Empty(t, stgy.queried)
+		assert.Equal(t, run(serDB, q), got)
+		assert.Len(t, got, 5, "bob twice, then the other rows null-filled")
+	})
+}
@@ -0,0 +1,160 @@
+package dalgo2memory
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// applyOperator applies a comparison operator to a resolved left value and a
+// normalized right operand (a slice for the array operators), for both WHERE
+// and HAVING. A nil (or missing) left value only satisfies IsNull and an
+// explicit equality with nil, as a NULL compares as unknown in SQL and is
+// excluded by Firestore's "!=" and "not-in". The base ordering operators defer
+// to compareOp.
+func applyOperator(op dal.Operator, l, r any) bool {
+	switch op {
+	case dal.IsNull:
+		return l == nil
+	case dal.IsNotNull:
+		return l != nil
+	}
+	if l == nil {
+		return op == dal.Equal && r == nil
+	}
+	switch op {
+	case dal.NotEqual:
+		return !elementEquals(l, r)
+	case dal.In:
+		return fieldContains(r, l)
+	case dal.NotIn:
+		rv := reflect.ValueOf(r)
+		return rv.Kind() == reflect.Slice && !fieldContains(r, l)
+	case dal.Between:
+		rv := reflect.ValueOf(r)
+		if rv.Kind() != reflect.Slice || rv.Len() != 2 {
+			return false
+		}
+		return compare(l, rv.Index(0).Interface()) >= 0 && compare(l, rv.Index(1).Interface()) <= 0
+	case dal.StartsWith:
+		s, ok := l.(string)
+		prefix, isString := r.(string)
+		return ok && isString && strings.HasPrefix(s, prefix)
+	case dal.Like:
+		s, ok := l.(string)
+		pattern, isString := r.(string)
+		return ok && isString && likeMatch(s, pattern)
+	case dal.ArrayContains:
+		return fieldContains(l, r)
+	case dal.ArrayContainsAny:
+		return fieldContainsAny(l, r)
+	default:
+		return compareOp(op, l, r)
+	}
+}
+
+// truth is the value of a condition in SQL's three-valued logic. A comparison
+// whose left operand is NULL is unknown rather than false, so that negating
+// it does not match either: NOT (x = 5) agrees with x != 5 and x NOT IN (5)
+// on records where x is null or missing.
+type truth int8
+
+const (
+	isFalse truth = iota
+	isUnknown
+	isTrue
+)
+
+func truthOf(match bool) truth {
+	if match {
+		return isTrue
+	}
+	return isFalse
+}
+
+// comparisonTruth is the truth of a comparison that matched or not, given
+// its resolved left operand.
+func comparisonTruth(op dal.Operator, l any, match bool) truth {
+	if !match && l == nil && !dal.IsUnaryOperator(op) {
+		return isUnknown
+	}
+	return truthOf(match)
+}
+
+func (t truth) not() truth {
+	return isTrue - t
+}
+
+func (t truth) and(other truth) truth {
+	return min(t, other)
+}
+
+func (t truth) or(other truth) truth {
+	return max(t, other)
+}
+
+// unknownCondition is a condition known to be unknown whatever the row, e.g.
+// a subquery comparison with a NULL outer value: neither it nor its negation
+// matches.
+type unknownCondition struct {
+	dal.Condition
+}
+
+// likeToken is one element of a parsed LIKE pattern: a literal rune, or a
+// wildcard matching a single rune (_) or any run of runes (%).
+type likeToken struct {
+	r         rune
+	wildcard  bool
+	matchMany bool
+}
+
+// likeMatch reports whether s matches a SQL LIKE pattern (see dal.Like). It
+// is case-sensitive and uses the greedy wildcard algorithm, backtracking only
+// to the most recent %.
+func likeMatch(s, pattern string) bool {
+	tokens := make([]likeToken, 0, len(pattern))
+	for escaped, rest := false, pattern; rest != ""; {
+		c, size := utf8.DecodeRuneInString(rest)
+		rest = rest[size:]
+		switch {
+		case escaped:
+			tokens = append(tokens, likeToken{r: c})
+			escaped = false
+		case c == '\\':
+			escaped = true
+			if rest == "" { // a trailing backslash matches itself
+				tokens = append(tokens, likeToken{r: c})
+			}
+		case c == '%':
+			tokens = append(tokens, likeToken{wildcard: true, matchMany: true})
+		case c == '_':
+			tokens = append(tokens, likeToken{wildcard: true})
+		default:
+			tokens = append(tokens, likeToken{r: c})
+		}
+	}
+	runes := []rune(s)
+	i, t := 0, 0
+	star, starAt := -1, 0
+	for i < len(runes) {
+		switch {
+		case t < len(tokens) && tokens[t].matchMany:
+			star, starAt = t, i
+			t++
+		case t < len(tokens) && (tokens[t].wildcard || tokens[t].r == runes[i]):
+			i++
+			t++
+		case star >= 0:
+			starAt++
+			i, t = starAt, star+1
+		default:
+			return false
+		}
+	}
+	for t < len(tokens) && tokens[t].matchMany {
+		t++
+	}
+	return t == len(tokens)
+}
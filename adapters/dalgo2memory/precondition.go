@@ -0,0 +1,96 @@
+package dalgo2memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+)
+
+// WithClock sets the clock the database stamps record writes with (default
+// time.Now). Stamps are kept strictly increasing per database, so a clock that
+// returns the same instant twice still yields distinct update times.
+func WithClock(clock func() time.Time) Option {
+	return func(db *database) {
+		db.clock = clock
+	}
+}
+
+// LastUpdateTime returns the time the record under key was last written
+// (Set, Insert or Update) in a dalgo2memory database — the value to pass to
+// dal.WithLastUpdateTimePrecondition for an optimistic-concurrency update.
+// It returns a not-found error when no such record is stored and
+// dal.ErrNotSupported when db is not a dalgo2memory database. It must not be
+// called from inside a transaction worker of the same database.
+func LastUpdateTime(db dal.DB, key *record.Key) (time.Time, error) {
+	mdb, ok := db.(*database)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %T is not a dalgo2memory database", dal.ErrNotSupported, db)
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	t, ok := mdb.updateTimes[key.Collection()][keyID(key)]
+	if !ok || !mdb.engine(key.Collection()).exists(keyID(key)) {
+		return time.Time{}, dal.NewErrNotFoundByKey(key, nil)
+	}
+	return t, nil
+}
+
+// now reads the database clock (see WithClock).
+func (db *database) now() time.Time {
+	if db.clock != nil {
+		return db.clock()
+	}
+	return time.Now()
+}
+
+// touch stamps a successful write to the record stored under id.
+func (db *database) touch(collection, id string) {
+	t := db.now()
+	if !t.After(db.lastStamp) {
+		t = db.lastStamp.Add(time.Nanosecond)
+	}
+	db.lastStamp = t
+	if db.updateTimes == nil {
+		db.updateTimes = make(map[string]map[string]time.Time)
+	}
+	times := db.updateTimes[collection]
+	if times == nil {
+		times = make(map[string]time.Time)
+		db.updateTimes[collection] = times
+	}
+	times[id] = t
+}
+
+// forget drops the update time of a deleted record.
+func (db *database) forget(collection, id string) {
+	delete(db.updateTimes[collection], id)
+}
+
+// checkPreconditions evaluates update preconditions against the stored record
+// under key. A failed precondition is reported as dal.ErrPreconditionFailed;
+// when it failed because the record does not exist the error also matches
+// record.ErrRecordNotFound.
+func (s session) checkPreconditions(key *record.Key, preconditions []dal.Precondition) error {
+	if len(preconditions) == 0 {
+		return nil
+	}
+	p := dal.GetPreconditions(preconditions...)
+	expected := p.LastUpdateTime()
+	if !p.Exists() && expected.IsZero() {
+		return nil
+	}
+	collection, id := key.Collection(), keyID(key)
+	if !s.db.engine(collection).exists(id) {
+		return fmt.Errorf("%w: %w", dal.ErrPreconditionFailed, dal.NewErrNotFoundByKey(key, nil))
+	}
+	if expected.IsZero() {
+		return nil
+	}
+	if actual := s.db.updateTimes[collection][id]; !actual.Equal(expected) {
+		return fmt.Errorf("%w: record %v was last updated at %s, not at %s",
+			dal.ErrPreconditionFailed, key, actual.Format(time.RFC3339Nano), expected.Format(time.RFC3339Nano))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedClock returns a clock that always reports the same instant, so the
+// database's strictly-increasing stamping is observable.
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestLastUpdateTime_StampsWrites(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := NewDB(WithClock(fixedClock(base))).(*database)
+	key := record.NewKeyWithID("Things", "one")
+
+	_, err := LastUpdateTime(db, key)
+	require.True(t, record.IsNotFound(err))
+
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key, &thing{Name: "first"})))
+	first, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+	require.Equal(t, base, first)
+
+	require.NoError(t, db.Update(ctx, key, []update.Update{update.ByFieldName("Count", 1)}))
+	second, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+	require.True(t, second.After(first), "stamps are strictly increasing")
+
+	require.NoError(t, db.Delete(ctx, key))
+	_, err = LastUpdateTime(db, key)
+	require.True(t, record.IsNotFound(err))
+
+	_, err = LastUpdateTime(fakeDB{}, key)
+	require.ErrorIs(t, err, dal.ErrNotSupported)
+}
+
+// fakeDB is a dal.DB that is not a dalgo2memory database.
+type fakeDB struct{ dal.DB }
+
+func TestUpdate_LastUpdateTimePrecondition(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB().(*database)
+	key := record.NewKeyWithID("Things", "one")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key, &thing{Name: "first"})))
+	readAt, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+
+	// A concurrent writer changes the record after it was read.
+	require.NoError(t, db.Update(ctx, key, []update.Update{update.ByFieldName("Count", 1)}))
+
+	err = db.Update(ctx, key, []update.Update{update.ByFieldName("Name", "stale")}, dal.WithLastUpdateTimePrecondition(readAt))
+	require.ErrorIs(t, err, dal.ErrPreconditionFailed)
+	require.False(t, record.IsNotFound(err))
+	var data thing
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(key, &data)))
+	require.Equal(t, "first", data.Name, "a failed precondition leaves the record unchanged")
+
+	current, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateRecord(ctx, record.NewRecordWithData(key, &thing{}),
+		[]update.Update{update.ByFieldName("Name", "fresh")}, dal.WithLastUpdateTimePrecondition(current)))
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(key, &data)))
+	require.Equal(t, "fresh", data.Name)
+}
+
+func TestUpdate_ExistsPrecondition(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB().(*database)
+	existing := record.NewKeyWithID("Things", "one")
+	missing := record.NewKeyWithID("Things", "missing")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(existing, &thing{Name: "first"})))
+
+	require.NoError(t, db.Update(ctx, existing, []update.Update{update.ByFieldName("Count", 1)}, dal.WithExistsPrecondition()))
+
+	err := db.Update(ctx, missing, []update.Update{update.ByFieldName("Count", 1)}, dal.WithExistsPrecondition())
+	require.ErrorIs(t, err, dal.ErrPreconditionFailed)
+	require.True(t, record.IsNotFound(err))
+
+	err = db.Update(ctx, missing, []update.Update{update.ByFieldName("Count", 1)},
+		dal.WithLastUpdateTimePrecondition(time.Now()))
+	require.ErrorIs(t, err, dal.ErrPreconditionFailed)
+}
+
+func TestUpdateMulti_PreconditionsPerKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB().(*database)
+	keys := []*record.Key{record.NewKeyWithID("Things", "one"), record.NewKeyWithID("Things", "missing")}
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(keys[0], &thing{Name: "first"})))
+
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.UpdateMulti(ctx, keys, []update.Update{update.ByFieldName("Count", 7)}, dal.WithExistsPrecondition())
+	})
+	require.True(t, errors.Is(err, dal.ErrPreconditionFailed))
+
+	var data thing
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(keys[0], &data)))
+	require.Equal(t, 0, data.Count, "the failed transaction rolled back the first key's update")
+}
+
+func TestUpdate_RolledBackTransactionRestoresUpdateTime(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB().(*database)
+	key := record.NewKeyWithID("Things", "one")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key, &thing{Name: "first"})))
+	readAt, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+
+	err = db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		if err := tx.Update(ctx, key, []update.Update{update.ByFieldName("Count", 1)}); err != nil {
+			return err
+		}
+		return errWorkerFailed
+	})
+	require.ErrorIs(t, err, errWorkerFailed)
+
+	afterRollback, err := LastUpdateTime(db, key)
+	require.NoError(t, err)
+	require.Equal(t, readAt, afterRollback)
+	require.NoError(t, db.Update(ctx, key, []update.Update{update.ByFieldName("Name", "ok")}, dal.WithLastUpdateTimePrecondition(readAt)))
+}
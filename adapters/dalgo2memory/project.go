@@ -6,15 +6,22 @@ import (
 	"github.com/dal-go/dalgo/dal"
 )
 
-// validateColumns rejects a projected column whose expression is not a
-// FieldRef, or whose non-empty Source() names no recordset in the query,
-// before any rows are produced -- consistent with the WHERE/ORDER BY
-// unresolvable-source behavior. An empty columns list is valid (no projection).
+// validateColumns rejects a projected column whose expression is neither a
+// FieldRef nor a computed expression (see validateExpression), or whose
+// non-empty Source() names no recordset in the query, before any rows are
+// produced -- consistent with the WHERE/ORDER BY unresolvable-source behavior.
+// An empty columns list is valid (no projection).
 func validateColumns(columns []dal.Column, known map[string]bool) error {
 	for _, col := range columns {
+		if isComputed(col.Expression) {
+			if err := validateExpression(col.Expression, known); err != nil {
+				return err
+			}
+			continue
+		}
 		f, ok := col.Expression.(dal.FieldRef)
 		if !ok {
-			return fmt.Errorf("dalgo2memory: projected column %q is not a field reference", col.String())
+			return fmt.Errorf("dalgo2memory: projected column %q is not a field reference or a computed expression", col.String())
 		}
 		if src := f.Source(); src != "" && !known[src] {
 			return fmt.Errorf("dalgo2memory: projected column %q references unknown source %q", f.Name(), src)
@@ -34,24 +41,31 @@ func baseSources(base dal.RecordsetSource, data map[string]any) map[string]map[s
 	return src
 }
 
-// columnKey returns the output map key for a projected column: its Alias,
-// falling back to the column's field name when the alias is empty.
-func columnKey(col dal.Column, f dal.FieldRef) string {
-	if col.Alias != "" {
-		return col.Alias
-	}
-	return f.Name()
-}
-
 // projectRow builds the projected output map for one row: one entry per
-// selected column keyed by alias/field-name, resolving each column's FieldRef
+// selected column keyed as columnOutKey does, resolving each column's FieldRef
 // against the per-source data (empty Source() -> base; alias/name -> that
-// source). Each column expression is expected to be a resolvable FieldRef.
+// source) and evaluating computed columns. Each column expression is expected
+// to have passed validateColumns.
 func projectRow(columns []dal.Column, sources map[string]map[string]any) map[string]any {
 	out := make(map[string]any, len(columns))
 	for _, col := range columns {
-		f := col.Expression.(dal.FieldRef)
-		out[columnKey(col, f)] = sources[f.Source()][f.Name()]
+		out[columnOutKey(col)] = sourcesValue(sources, col.Expression)
 	}
 	return out
 }
+
+// distinctRows keeps the first of the rows that share a value (see valueKey),
+// implementing SELECT DISTINCT over already ordered rows.
+func distinctRows[T any](rows []T, valueOf func(T) any) []T {
+	seen := make(map[string]bool, len(rows))
+	kept := rows[:0:0]
+	for _, row := range rows {
+		k := valueKey(valueOf(row))
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		kept = append(kept, row)
+	}
+	return kept
+}
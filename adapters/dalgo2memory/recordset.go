@@ -15,8 +15,8 @@ import (
 // ExecuteQueryToRecordsetReader executes a structured query and exposes its
 // result as a columnar recordset — the read path DataTug uses for tabular
 // query results. It reuses the records pipeline (WHERE, GROUP BY, HAVING,
-// column projection, joins, ORDER BY, LIMIT/OFFSET all already applied there),
-// then pivots the resulting rows into columns.
+// column projection, joins, ORDER BY, StartFrom/OFFSET/LIMIT all already
+// applied there), then pivots the resulting rows into columns.
 //
 // Columns are derived from the query's explicit SELECT columns when present
 // (projection and GROUP BY aggregation); otherwise from the sorted union of
@@ -42,6 +42,12 @@ func (s session) ExecuteQueryToRecordsetReader(ctx context.Context, query dal.Qu
 // columnar recordset reader. Split out so the read/convert error paths are
 // directly testable with an injected reader.
 func buildRecordsetReader(ctx context.Context, q dal.StructuredQuery, reader dal.RecordsReader, options ...recordset.Option) (dal.RecordsetReader, error) {
+	// The adapter's own reader carries one cursor per record; an injected
+	// reader has none, and its rows report an empty cursor.
+	var cursors []string
+	if r, ok := reader.(*recordsReader); ok {
+		cursors = r.cursors
+	}
 	records, err := dal.ReadAllToRecords(ctx, reader)
 	if err != nil {
 		return nil, err
@@ -73,7 +79,7 @@ func buildRecordsetReader(ctx context.Context, q dal.StructuredQuery, reader dal
 			}
 		}
 	}
-	return &columnarReader{rs: rs}, nil
+	return &columnarReader{rs: rs, cursors: cursors}, nil
 }
 
 // inferColumn picks a column type for a named field from its values across all
@@ -178,9 +184,11 @@ func recordsetName(q dal.StructuredQuery, options ...recordset.Option) string {
 
 // columnarReader is a dal.RecordsetReader over a fully-built ColumnarRecordset:
 // the recordset is populated up front and Next walks its rows in order.
+// cursors holds the records pipeline's cursor for each row.
 type columnarReader struct {
-	rs  *recordset.ColumnarRecordset
-	pos int
+	rs      *recordset.ColumnarRecordset
+	pos     int
+	cursors []string
 }
 
 var _ dal.RecordsetReader = (*columnarReader)(nil)
@@ -196,6 +204,13 @@ func (r *columnarReader) Next() (recordset.Row, recordset.Recordset, error) {
 	return row, r.rs, nil
 }
 
-func (r *columnarReader) Cursor() (string, error) { return "", nil }
+// Cursor returns the position after the last row returned by Next, or an
+// empty string before the first Next.
+func (r *columnarReader) Cursor() (string, error) {
+	if r.pos == 0 || r.pos > len(r.cursors) {
+		return "", nil
+	}
+	return r.cursors[r.pos-1], nil
+}
 
 func (r *columnarReader) Close() error { return nil }
@@ -0,0 +1,362 @@
+package dalgo2memory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// isComputed reports whether e is a scalar function call or an arithmetic
+// expression, whose value is computed per row from its operands.
+func isComputed(e dal.Expression) bool {
+	switch e.(type) {
+	case dal.ScalarFunction, dal.Arithmetic:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateExpression rejects, before any row is evaluated, a scalar function
+// this adapter does not know or that has the wrong number of arguments, and a
+// FieldRef whose non-empty Source() names no recordset in the query. It walks
+// computed expressions recursively; other expressions are accepted as is.
+func validateExpression(e dal.Expression, known map[string]bool) error {
+	switch ex := e.(type) {
+	case dal.FieldRef:
+		if src := ex.Source(); src != "" && !known[src] {
+			return fmt.Errorf("dalgo2memory: field %q references unknown source %q", ex.Name(), src)
+		}
+	case dal.ScalarFunction:
+		if err := validateScalarArity(ex); err != nil {
+			return err
+		}
+		for _, arg := range ex.Args {
+			if err := validateExpression(arg, known); err != nil {
+				return err
+			}
+		}
+	case dal.Arithmetic:
+		switch ex.Operator {
+		case dal.Addition, dal.Subtraction, dal.Multiplication, dal.Division:
+		default:
+			return fmt.Errorf("dalgo2memory: unsupported arithmetic operator %q", ex.Operator)
+		}
+		if err := validateExpression(ex.Left, known); err != nil {
+			return err
+		}
+		return validateExpression(ex.Right, known)
+	}
+	return nil
+}
+
+func validateScalarArity(f dal.ScalarFunction) error {
+	switch f.Name {
+	case dal.LOWER, dal.UPPER, dal.LENGTH:
+		if len(f.Args) != 1 {
+			return fmt.Errorf("dalgo2memory: %s expects 1 argument, got %d", f.Name, len(f.Args))
+		}
+	case dal.COALESCE, dal.CONCAT:
+		if len(f.Args) == 0 {
+			return fmt.Errorf("dalgo2memory: %s expects at least 1 argument", f.Name)
+		}
+	case dal.DATE_TRUNC, dal.EXTRACT:
+		if len(f.Args) != 2 {
+			return fmt.Errorf("dalgo2memory: %s expects 2 arguments, got %d", f.Name, len(f.Args))
+		}
+		unit, ok := f.Args[0].(dal.Constant)
+		if !ok || !isDatePart(unit.Value) {
+			return fmt.Errorf("dalgo2memory: %s expects a date part as its first argument, got %v", f.Name, f.Args[0])
+		}
+	default:
+		return fmt.Errorf("dalgo2memory: unsupported scalar function %q", f.Name)
+	}
+	return nil
+}
+
+func isDatePart(v any) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	switch dal.DatePart(s) {
+	case dal.DatePartYear, dal.DatePartQuarter, dal.DatePartMonth, dal.DatePartWeek,
+		dal.DatePartDay, dal.DatePartHour, dal.DatePartMinute, dal.DatePartSecond:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateConditionExpressions applies validateExpression to every computed
+// operand of every comparison in a condition tree.
+func validateConditionExpressions(cond dal.Condition, known map[string]bool) error {
+	switch c := cond.(type) {
+	case dal.Comparison:
+		for _, operand := range []dal.Expression{c.Left, c.Right} {
+			if isComputed(operand) {
+				if err := validateExpression(operand, known); err != nil {
+					return err
+				}
+			}
+		}
+	case dal.GroupCondition:
+		for _, sub := range c.Conditions() {
+			if err := validateConditionExpressions(sub, known); err != nil {
+				return err
+			}
+		}
+	case dal.NotCondition:
+		return validateConditionExpressions(c.Condition(), known)
+	}
+	return nil
+}
+
+// evalComputed evaluates a computed expression (see isComputed), resolving each
+// operand with resolve, so one implementation serves WHERE, projection, ORDER
+// BY, GROUP BY and HAVING, each with its own operand resolution. As in SQL a
+// NULL operand yields NULL (except in COALESCE), and so does an operand of the
+// wrong type — a non-string for LOWER, a non-number for arithmetic, an
+// unparsable timestamp — since the row-level callers cannot fail a query.
+// Callers validate expressions with validateExpression first.
+func evalComputed(e dal.Expression, resolve func(dal.Expression) (any, error)) (any, error) {
+	switch ex := e.(type) {
+	case dal.Arithmetic:
+		l, err := resolve(ex.Left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := resolve(ex.Right)
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic(ex.Operator, l, r), nil
+	case dal.ScalarFunction:
+		args := make([]any, len(ex.Args))
+		for i, arg := range ex.Args {
+			v, err := resolve(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		return scalarFunction(ex.Name, args), nil
+	default:
+		return nil, fmt.Errorf("dalgo2memory: %T is not a computed expression", e)
+	}
+}
+
+func scalarFunction(name string, args []any) any {
+	switch name {
+	case dal.COALESCE:
+		for _, v := range args {
+			if v != nil {
+				return v
+			}
+		}
+		return nil
+	case dal.CONCAT:
+		var sb strings.Builder
+		for _, v := range args {
+			if v == nil {
+				return nil
+			}
+			if s, ok := v.(string); ok {
+				sb.WriteString(s)
+			} else {
+				sb.WriteString(fmt.Sprint(v))
+			}
+		}
+		return sb.String()
+	case dal.LOWER:
+		if s, ok := args[0].(string); ok {
+			return strings.ToLower(s)
+		}
+	case dal.UPPER:
+		if s, ok := args[0].(string); ok {
+			return strings.ToUpper(s)
+		}
+	case dal.LENGTH:
+		if s, ok := args[0].(string); ok {
+			return utf8.RuneCountInString(s)
+		}
+		if rv := reflect.ValueOf(args[0]); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			return rv.Len()
+		}
+	case dal.DATE_TRUNC:
+		return dateTrunc(dal.DatePart(fmt.Sprint(args[0])), args[1])
+	case dal.EXTRACT:
+		if t, ok := asTime(args[1]); ok {
+			return extract(dal.DatePart(fmt.Sprint(args[0])), t)
+		}
+	}
+	return nil
+}
+
+// arithmetic applies an arithmetic operator to two numbers. The result is an
+// int64 when both operands are integers (except for division) and a float64
+// otherwise; division by zero yields NULL.
+func arithmetic(op dal.ArithmeticOperator, l, r any) any {
+	lf, lok := number(l)
+	rf, rok := number(r)
+	if !lok || !rok {
+		return nil
+	}
+	if op != dal.Division && isInteger(l) && isInteger(r) {
+		li, ri := int64(lf), int64(rf)
+		switch op {
+		case dal.Addition:
+			return li + ri
+		case dal.Subtraction:
+			return li - ri
+		case dal.Multiplication:
+			return li * ri
+		}
+	}
+	switch op {
+	case dal.Addition:
+		return lf + rf
+	case dal.Subtraction:
+		return lf - rf
+	case dal.Multiplication:
+		return lf * rf
+	case dal.Division:
+		if rf == 0 {
+			return nil
+		}
+		return lf / rf
+	default:
+		return nil
+	}
+}
+
+func isInteger(v any) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// asTime reads a timestamp: a time.Time, or the RFC 3339 string a time.Time
+// is stored as in serialized rows.
+func asTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// dateTrunc truncates a timestamp in its own location and returns it in the
+// representation it came in (time.Time, or an RFC 3339 string), so the result
+// compares against query constants the same way the stored value does.
+func dateTrunc(unit dal.DatePart, v any) any {
+	t, ok := asTime(v)
+	if !ok {
+		return nil
+	}
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case dal.DatePartYear:
+		t = time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+	case dal.DatePartQuarter:
+		t = time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+	case dal.DatePartMonth:
+		t = time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case dal.DatePartWeek:
+		offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+		t = time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+	case dal.DatePartDay:
+		t = time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case dal.DatePartHour:
+		t = time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+	case dal.DatePartMinute:
+		t = time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+	case dal.DatePartSecond:
+		t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, loc)
+	default:
+		return nil
+	}
+	if _, isString := v.(string); isString {
+		return t.Format(time.RFC3339Nano)
+	}
+	return t
+}
+
+func extract(part dal.DatePart, t time.Time) any {
+	switch part {
+	case dal.DatePartYear:
+		return t.Year()
+	case dal.DatePartQuarter:
+		return (int(t.Month())-1)/3 + 1
+	case dal.DatePartMonth:
+		return int(t.Month())
+	case dal.DatePartWeek:
+		_, week := t.ISOWeek()
+		return week
+	case dal.DatePartDay:
+		return t.Day()
+	case dal.DatePartHour:
+		return t.Hour()
+	case dal.DatePartMinute:
+		return t.Minute()
+	case dal.DatePartSecond:
+		return t.Second()
+	default:
+		return nil
+	}
+}
+
+// rowValue resolves an expression against a single-source WHERE row the way
+// matchesComparison does: a FieldRef by name, constants normalized like stored
+// values, computed expressions recursively.
+func rowValue(data map[string]any, e dal.Expression) (any, error) {
+	switch ex := e.(type) {
+	case nil:
+		return nil, nil
+	case dal.FieldRef:
+		return data[ex.Name()], nil
+	case dal.Constant:
+		return normalizeConstant(ex.Value), nil
+	case dal.Array:
+		return normalizeConstant(ex.Value), nil
+	case dal.ScalarFunction, dal.Arithmetic:
+		return evalComputed(e, func(arg dal.Expression) (any, error) {
+			return rowValue(data, arg)
+		})
+	default:
+		return nil, fmt.Errorf("dalgo2memory: unsupported expression %T", e)
+	}
+}
+
+// sourcesValue resolves an expression against per-source row data for ORDER BY
+// and projection, whose sources were validated up front: a FieldRef from its
+// source, a Constant to its value normalized as in rowValue, computed
+// expressions recursively; anything else is nil.
+func sourcesValue(sources map[string]map[string]any, e dal.Expression) any {
+	switch ex := e.(type) {
+	case dal.FieldRef:
+		return sources[ex.Source()][ex.Name()]
+	case dal.Constant:
+		return normalizeConstant(ex.Value)
+	case dal.ScalarFunction, dal.Arithmetic:
+		v, _ := evalComputed(e, func(arg dal.Expression) (any, error) {
+			return sourcesValue(sources, arg), nil
+		})
+		return v
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package dalgo2memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedContacts loads rows with mixed-case names, an optional nick, prices and
+// quantities, and creation timestamps spread over two months.
+func seedContacts(t *testing.T) (*database, context.Context) {
+	t.Helper()
+	db := NewDB().(*database)
+	ctx := context.Background()
+	rows := []map[string]any{
+		{"first": "Alice", "last": "Smith", "nick": "Al", "price": 10, "qty": 3, "created": time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"first": "bob", "last": "Jones", "nick": nil, "price": 2.5, "qty": 4, "created": time.Date(2024, 1, 20, 8, 0, 0, 0, time.UTC)},
+		{"first": "Carol", "last": "Doe", "nick": nil, "price": 7, "qty": 1, "created": time.Date(2024, 2, 3, 23, 59, 0, 0, time.UTC)},
+	}
+	for i, row := range rows {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("contacts", string(rune('1'+i))), &row)))
+	}
+	return db, ctx
+}
+
+func contactsQuery() *dal.QueryBuilder {
+	return dal.From(dal.NewRootCollectionRef("contacts", "")).NewQuery()
+}
+
+func firstNames(rows []map[string]any) []any {
+	names := make([]any, len(rows))
+	for i, r := range rows {
+		names[i] = r["first"]
+	}
+	return names
+}
+
+func TestScalarFunctions_Where(t *testing.T) {
+	db, ctx := seedContacts(t)
+	for _, tt := range []struct {
+		name  string
+		where dal.Condition
+		want  []any
+	}{
+		{"lower", dal.NewComparison(dal.Lower(dal.Field("first")), dal.Equal, dal.String("bob")), []any{"bob"}},
+		{"upper", dal.NewComparison(dal.Upper(dal.Field("first")), dal.Equal, dal.String("ALICE")), []any{"Alice"}},
+		{"length", dal.NewComparison(dal.Length(dal.Field("last")), dal.GreaterThen, dal.NewConstant(3)), []any{"Alice", "bob"}},
+		{"coalesce", dal.NewComparison(dal.Coalesce(dal.Field("nick"), dal.Field("first")), dal.Equal, dal.String("Carol")), []any{"Carol"}},
+		{"concat", dal.NewComparison(dal.Concat(dal.Field("first"), dal.String(" "), dal.Field("last")), dal.Equal, dal.String("bob Jones")), []any{"bob"}},
+		{"arithmetic", dal.NewComparison(dal.Multiply(dal.Field("price"), dal.Field("qty")), dal.GreaterOrEqual, dal.NewConstant(10)), []any{"Alice", "bob"}},
+		{"extract", dal.NewComparison(dal.Extract(dal.DatePartMonth, dal.Field("created")), dal.GreaterOrEqual, dal.NewConstant(2)), []any{"Carol"}},
+		{"null_nick", dal.NewComparison(dal.Upper(dal.Field("nick")), dal.IsNull, nil), []any{"bob", "Carol"}},
+		{"not", dal.Not(dal.NewComparison(dal.Lower(dal.Field("first")), dal.In, dal.NewArray([]string{"alice", "bob"}))), []any{"Carol"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q := contactsQuery().Where(tt.where).SelectColumns(dal.Column{Expression: dal.Field("first")})
+			rows := runProjection(t, db, ctx, q)
+			assert.ElementsMatch(t, tt.want, firstNames(rows))
+		})
+	}
+}
+
+func TestScalarFunctions_ProjectionAndOrder(t *testing.T) {
+	db, ctx := seedContacts(t)
+	q := contactsQuery().
+		OrderBy(dal.Descending(dal.Multiply(dal.Field("price"), dal.Field("qty")))).
+		SelectColumns(
+			dal.Column{Alias: "name", Expression: dal.Concat(dal.Upper(dal.Field("first")), dal.String(" "), dal.Field("last"))},
+			dal.Column{Alias: "total", Expression: dal.Multiply(dal.Field("price"), dal.Field("qty"))},
+			dal.Column{Expression: dal.Coalesce(dal.Field("nick"), dal.String("-"))},
+			dal.Column{Alias: "day", Expression: dal.DateTrunc(dal.DatePartDay, dal.Field("created"))},
+		)
+	rows := runProjection(t, db, ctx, q)
+	require.Len(t, rows, 3)
+	assert.Equal(t, map[string]any{
+		"name":                "ALICE Smith",
+		"total":               30.0,
+		"COALESCE(nick, '-')": "Al",
+		"day":                 "2024-01-15T00:00:00Z", // stored rows keep timestamps as RFC 3339 text
+	}, rows[0])
+	assert.Equal(t, "BOB Jones", rows[1]["name"])
+	assert.Equal(t, 10.0, rows[1]["total"])
+	assert.Equal(t, "-", rows[1]["COALESCE(nick, '-')"])
+	assert.Equal(t, "CAROL Doe", rows[2]["name"])
+}
+
+func TestScalarFunctions_GroupByComputedKey(t *testing.T) {
+	db, ctx := seedContacts(t)
+	month := dal.Extract(dal.DatePartMonth, dal.Field("created"))
+	total := dal.SumAs(dal.Multiply(dal.Field("price"), dal.Field("qty")), "total")
+	q := contactsQuery().
+		GroupBy(month).
+		Having(dal.NewComparison(total.Expression, dal.GreaterThen, dal.NewConstant(10))).
+		SelectColumns(
+			dal.Column{Alias: "month", Expression: month},
+			dal.Column{Alias: "next", Expression: dal.Add(month, dal.NewConstant(1))},
+			total,
+		)
+	rows := runProjection(t, db, ctx, q)
+	require.Len(t, rows, 1)
+	assert.EqualValues(t, 1, rows[0]["month"])
+	assert.EqualValues(t, 2, rows[0]["next"])
+	assert.EqualValues(t, 40, rows[0]["total"])
+}
+
+func TestScalarFunctions_ValidationErrors(t *testing.T) {
+	db, ctx := seedContacts(t)
+	unknown := dal.ScalarFunction{Name: "SOUNDEX", Args: []dal.Expression{dal.Field("first")}}
+	for name, q := range map[string]dal.Query{
+		"where":     contactsQuery().Where(dal.NewComparison(unknown, dal.Equal, dal.String("x"))).SelectIntoRecord(intoMapRecord()),
+		"column":    contactsQuery().SelectColumns(dal.Column{Alias: "s", Expression: unknown}),
+		"order":     contactsQuery().OrderBy(dal.Ascending(unknown)).SelectIntoRecord(intoMapRecord()),
+		"arity":     contactsQuery().SelectColumns(dal.Column{Alias: "s", Expression: dal.ScalarFunction{Name: dal.LOWER}}),
+		"date_part": contactsQuery().SelectColumns(dal.Column{Alias: "s", Expression: dal.DateTrunc("fortnight", dal.Field("created"))}),
+		"source":    contactsQuery().SelectColumns(dal.Column{Alias: "s", Expression: dal.Lower(dal.NewFieldRef("x", "first"))}),
+		"operator":  contactsQuery().SelectColumns(dal.Column{Alias: "s", Expression: dal.Arithmetic{Operator: "%", Left: dal.Field("qty"), Right: dal.NewConstant(2)}}),
+		"ungrouped": contactsQuery().GroupBy(dal.Field("last")).SelectColumns(dal.Column{Alias: "s", Expression: dal.Lower(dal.Field("first"))}),
+		"group_key": contactsQuery().GroupBy(unknown).SelectColumns(dal.Column{Alias: "s", Expression: unknown}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.ExecuteQueryToRecordsReader(ctx, q)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEvalScalarValues(t *testing.T) {
+	ts := time.Date(2024, 5, 16, 13, 45, 30, 500, time.UTC) // a Thursday
+	for _, tt := range []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"int_add", arithmetic(dal.Addition, 2, 3), int64(5)},
+		{"mixed_sub", arithmetic(dal.Subtraction, 2, 0.5), 1.5},
+		{"int_div", arithmetic(dal.Division, 7, 2), 3.5},
+		{"div_zero", arithmetic(dal.Division, 7, 0), nil},
+		{"non_number", arithmetic(dal.Addition, "a", 1), nil},
+		{"null_operand", arithmetic(dal.Multiplication, nil, 1), nil},
+		{"concat_null", scalarFunction(dal.CONCAT, []any{"a", nil}), nil},
+		{"concat_number", scalarFunction(dal.CONCAT, []any{"a", 1}), "a1"},
+		{"length_runes", scalarFunction(dal.LENGTH, []any{"héllo"}), 5},
+		{"length_slice", scalarFunction(dal.LENGTH, []any{[]any{1, 2}}), 2},
+		{"lower_non_string", scalarFunction(dal.LOWER, []any{1}), nil},
+		{"coalesce_all_null", scalarFunction(dal.COALESCE, []any{nil, nil}), nil},
+		{"trunc_year", dateTrunc(dal.DatePartYear, ts), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"trunc_quarter", dateTrunc(dal.DatePartQuarter, ts), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"trunc_month", dateTrunc(dal.DatePartMonth, ts), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"trunc_week", dateTrunc(dal.DatePartWeek, ts), time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"trunc_hour", dateTrunc(dal.DatePartHour, ts), time.Date(2024, 5, 16, 13, 0, 0, 0, time.UTC)},
+		{"trunc_minute", dateTrunc(dal.DatePartMinute, ts), time.Date(2024, 5, 16, 13, 45, 0, 0, time.UTC)},
+		{"trunc_second", dateTrunc(dal.DatePartSecond, ts), time.Date(2024, 5, 16, 13, 45, 30, 0, time.UTC)},
+		{"trunc_string", dateTrunc(dal.DatePartDay, "2024-05-16T13:45:30Z"), "2024-05-16T00:00:00Z"},
+		{"trunc_invalid", dateTrunc(dal.DatePartDay, "yesterday"), nil},
+		{"extract_quarter", extract(dal.DatePartQuarter, ts), 2},
+		{"extract_week", extract(dal.DatePartWeek, ts), 20},
+		{"extract_day", extract(dal.DatePartDay, ts), 16},
+		{"extract_hour", extract(dal.DatePartHour, ts), 13},
+		{"extract_minute", extract(dal.DatePartMinute, ts), 45},
+		{"extract_second", extract(dal.DatePartSecond, ts), 30},
+		{"extract_from_string", scalarFunction(dal.EXTRACT, []any{"year", "2024-05-16T13:45:30Z"}), 2024},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.got)
+		})
+	}
+}
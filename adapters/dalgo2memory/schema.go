@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+
+	"github.com/dal-go/dalgo/dbschema"
 )
 
 // Option configures an in-memory database created by NewDB.
@@ -29,6 +31,8 @@ type collectionDef struct {
 	// newEngine builds the storage engine backing the collection. It is set by
 	// a CollectionOption (default: Serialized) and consumed by WithSchema.
 	newEngine engineFactory
+	// uniques are the unique indexes declared by WithUniqueIndex.
+	uniques []indexSpec
 }
 
 // engineFactory builds a storage engine for a collection given its name, its
@@ -39,16 +43,21 @@ type collectionDef struct {
 type engineFactory func(collection string, factory func() any, schemaRefBreaking bool) storageEngine
 
 // CollectionOption configures a collection definition produced by WithCollection
-// — currently the per-collection storage-engine selection. Pass it as a trailing
+// — its storage-engine selection and unique indexes. Pass it as a trailing
 // argument to WithCollection.
 type CollectionOption func(*collectionDef)
 
-// WithSerializedStorage selects the Serialized storage engine for a collection.
-// It is the default engine, so this option states the default explicitly; an
-// option-less collection behaves identically.
-func WithSerializedStorage() CollectionOption {
+// WithSerializedStorage selects the Serialized storage engine for a collection,
+// with optional secondary indexes (see WithIndex). It is the default engine, so
+// without options it states the default explicitly; an option-less collection
+// behaves identically.
+func WithSerializedStorage(opts ...SerializedOption) CollectionOption {
 	return func(def *collectionDef) {
-		def.newEngine = serializedEngineFactory
+		var cfg serializedConfig
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+		def.newEngine = newSerializedEngineFactory(cfg)
 	}
 }
 
@@ -151,25 +160,34 @@ func WithSchema(allowUndefinedCollections bool, collections ...collectionDef) Op
 	return func(db *database) {
 		factories := make(map[string]func() any, len(collections))
 		engines := make(map[string]engineFactory, len(collections))
+		uniques := make(map[string][]indexSpec)
 		for _, c := range collections {
 			factories[c.name] = c.newRecord
 			if c.newEngine != nil {
 				engines[c.name] = c.newEngine
 			}
+			if len(c.uniques) > 0 {
+				uniques[c.name] = c.uniques
+			}
 		}
 		db.schema = &memorySchema{
 			collections:    factories,
 			engines:        engines,
+			uniques:        uniques,
+			defs:           make(map[string]dbschema.CollectionDef),
 			allowUndefined: allowUndefinedCollections,
 		}
 	}
 }
 
-// memorySchema holds the registered record factories and per-collection engine
-// choices for the in-memory database.
+// memorySchema holds the registered record factories, per-collection engine
+// choices and unique indexes for the in-memory database, and the definitions
+// of the collections created or altered by DDL (see CreateCollection).
 type memorySchema struct {
 	collections    map[string]func() any
 	engines        map[string]engineFactory
+	uniques        map[string][]indexSpec
+	defs           map[string]dbschema.CollectionDef
 	allowUndefined bool
 }
 
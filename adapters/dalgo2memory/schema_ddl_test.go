@@ -0,0 +1,304 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectionRef(name string) *dal.CollectionRef {
+	ref := dal.NewRootCollectionRef(name, "")
+	return &ref
+}
+
+func TestSchemaReader(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(true, WithCollection[account]("accounts", nil,
+		WithSerializedStorage(WithIndex("byTeam", "team")),
+		WithUniqueIndex("byEmail", "email"),
+	))).(*database)
+	user := record.NewKeyWithID("users", "u1")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithParentAndID(user, "notes", "n1"), map[string]any{"text": "hi", "n": 1, "tag": nil})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("notes", "n2"), map[string]any{"text": "ho", "n": 1.5, "done": true})))
+	_, err := db.Exists(ctx, record.NewKeyWithID("empty", "e1"))
+	require.NoError(t, err)
+
+	refs, err := dbschema.ListCollections(ctx, db, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []dal.CollectionRef{dal.NewRootCollectionRef("accounts", ""), dal.NewRootCollectionRef("notes", "")}, refs,
+		"a collection read but never written does not exist")
+	refs, err = dbschema.ListCollections(ctx, db, user)
+	require.NoError(t, err)
+	assert.Equal(t, []dal.CollectionRef{dal.NewCollectionRef("notes", "", user)}, refs)
+
+	def, err := dbschema.DescribeCollection(ctx, db, collectionRef("accounts"))
+	require.NoError(t, err)
+	assert.Equal(t, &dbschema.CollectionDef{
+		Name: "accounts",
+		Fields: []dbschema.FieldDef{
+			{Name: "email", Type: dbschema.String},
+			{Name: "team", Type: dbschema.String, Nullable: true},
+			{Name: "number", Type: dbschema.Float, Nullable: true},
+		},
+		Indexes: []dbschema.IndexDef{
+			{Name: "byTeam", Collection: "accounts", Fields: []dal.FieldName{"team"}},
+			{Name: "byEmail", Collection: "accounts", Fields: []dal.FieldName{"email"}, Unique: true},
+		},
+	}, def)
+
+	def, err = dbschema.DescribeCollection(ctx, db, collectionRef("notes"))
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{
+		{Name: "done", Type: dbschema.Bool, Nullable: true},
+		{Name: "n", Type: dbschema.Float},
+		{Name: "tag", Type: dbschema.Null, Nullable: true},
+		{Name: "text", Type: dbschema.String},
+	}, def.Fields)
+	assert.Empty(t, def.Indexes)
+
+	_, err = dbschema.DescribeCollection(ctx, db, collectionRef("missing"))
+	assert.Error(t, err)
+	_, err = dbschema.ListIndexes(ctx, db, collectionRef("missing"))
+	assert.Error(t, err)
+	_, err = dbschema.ListConstraints(ctx, db, collectionRef("accounts"))
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+	_, err = dbschema.ListReferrers(ctx, db, collectionRef("accounts"))
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+}
+
+func TestSchemaModifier(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB().(*database)
+	assert.True(t, ddl.SupportsTransactionalDDL(db))
+	players := dbschema.CollectionDef{
+		Name: "players",
+		Fields: []dbschema.FieldDef{
+			{Name: "name", Type: dbschema.String},
+			{Name: "team", Type: dbschema.String},
+		},
+		Indexes: []dbschema.IndexDef{{Name: "byTeam", Fields: []dal.FieldName{"team"}}},
+	}
+	require.NoError(t, ddl.CreateCollection(ctx, db, players))
+	assert.Error(t, ddl.CreateCollection(ctx, db, players))
+	require.NoError(t, ddl.CreateCollection(ctx, db, players, ddl.IfNotExists()))
+	indexes, err := dbschema.ListIndexes(ctx, db, collectionRef("players"))
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.IndexDef{{Name: "byTeam", Collection: "players", Fields: []dal.FieldName{"team"}}}, indexes)
+
+	key := func(id string) *record.Key { return record.NewKeyWithID("players", id) }
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key("p1"), map[string]any{"name": "Ann", "team": "red"})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key("p2"), map[string]any{"name": "Bob", "team": "blue", "score": 3})))
+	load := func(id string) map[string]any {
+		data := map[string]any{}
+		require.NoError(t, db.Get(ctx, record.NewRecordWithData(key(id), &data)))
+		return data
+	}
+
+	err = ddl.AlterCollection(ctx, db, "players",
+		ddl.AddField(dbschema.FieldDef{Name: "score", Type: dbschema.Int, Default: dbschema.DefaultLiteral{Value: 0}}),
+		ddl.RenameField("team", "squad"),
+		ddl.AddIndex(dbschema.IndexDef{Name: "byName", Fields: []dal.FieldName{"name"}, Unique: true}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Ann", "squad": "red", "score": 0.0}, load("p1"))
+	assert.Equal(t, map[string]any{"name": "Bob", "squad": "blue", "score": 3.0}, load("p2"), "a record holding the field keeps its value")
+	def, err := dbschema.DescribeCollection(ctx, db, collectionRef("players"))
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{
+		{Name: "name", Type: dbschema.String},
+		{Name: "squad", Type: dbschema.String},
+		{Name: "score", Type: dbschema.Int, Default: dbschema.DefaultLiteral{Value: 0}},
+	}, def.Fields)
+	assert.Equal(t, []dbschema.IndexDef{
+		{Name: "byTeam", Collection: "players", Fields: []dal.FieldName{"squad"}},
+		{Name: "byName", Collection: "players", Fields: []dal.FieldName{"name"}, Unique: true},
+	}, def.Indexes)
+
+	plan, err := dal.ExplainQuery(ctx, db, dal.From(dal.NewRootCollectionRef("players", "")).NewQuery().
+		WhereField("squad", dal.Equal, "red").SelectKeysOnly(reflect.String))
+	require.NoError(t, err)
+	lookups := plan.Find(dal.PlanIndexLookup)
+	require.Len(t, lookups, 1, plan.String())
+	assert.Equal(t, "byTeam", lookups[0].Index, "the renamed field is looked up in the index")
+	err = db.Set(ctx, record.NewRecordWithData(key("p3"), map[string]any{"name": "Ann", "squad": "red"}))
+	assert.ErrorIs(t, err, dal.ErrUniqueViolation, "the added unique index is enforced")
+
+	err = ddl.AlterCollection(ctx, db, "players",
+		ddl.DropIndex("byName"),
+		ddl.DropField("score"),
+		ddl.DropField("squad"),
+	)
+	assert.ErrorContains(t, err, `used by index "byTeam"`)
+	assert.Equal(t, map[string]any{"name": "Ann", "squad": "red", "score": 0.0}, load("p1"), "a failed batch is rolled back")
+	indexes, err = dbschema.ListIndexes(ctx, db, collectionRef("players"))
+	require.NoError(t, err)
+	assert.Len(t, indexes, 2)
+
+	err = ddl.AlterCollection(ctx, db, "players",
+		ddl.DropIndex("byName"),
+		ddl.DropIndex("byTeam"),
+		ddl.DropIndex("byTeam", ddl.IfExists()),
+		ddl.DropField("squad"),
+		ddl.DropField("missing", ddl.IfExists()),
+		ddl.AddField(dbschema.FieldDef{Name: "name", Type: dbschema.String}, ddl.IfNotExists()),
+		ddl.ModifyField("score", dbschema.FieldDef{Name: "points", Type: dbschema.Float}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Bob", "points": 3.0}, load("p2"))
+
+	err = ddl.AlterCollection(ctx, db, "players", ddl.ModifyField("name", dbschema.FieldDef{Type: dbschema.Int}))
+	assert.ErrorContains(t, err, "field name: expected int, got string")
+	err = ddl.AlterCollection(ctx, db, "players", ddl.AddField(dbschema.FieldDef{Name: "rank", Type: dbschema.Int}))
+	assert.ErrorContains(t, err, "not nullable and has no default")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(key("p3"), map[string]any{"name": "Ann"})))
+	err = ddl.AlterCollection(ctx, db, "players", ddl.AddIndex(dbschema.IndexDef{Name: "byName", Fields: []dal.FieldName{"name"}, Unique: true}))
+	var violation *dal.UniqueViolationError
+	require.ErrorAs(t, err, &violation, "a unique index is not added over duplicates")
+	assert.Equal(t, []any{"Ann"}, violation.Values)
+
+	require.NoError(t, ddl.DropCollection(ctx, db, "players"))
+	assert.Error(t, ddl.DropCollection(ctx, db, "players"))
+	require.NoError(t, ddl.DropCollection(ctx, db, "players", ddl.IfExists()))
+	refs, err := dbschema.ListCollections(ctx, db, nil)
+	require.NoError(t, err)
+	assert.Empty(t, refs)
+	exists, err := db.Exists(ctx, key("p1"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSchemaModifier_InferredAndTyped(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(false,
+		WithCollection[account]("accounts", nil),
+		WithCollection[account]("columns", nil, WithColumnarStorage()),
+		WithCollection[map[string]any]("logs", nil),
+	)).(*database)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("logs", "l1"), map[string]any{"msg": "up"})))
+
+	err := ddl.AlterCollection(ctx, db, "logs", ddl.AddField(dbschema.FieldDef{Name: "level", Type: dbschema.String, Nullable: true}))
+	require.NoError(t, err)
+	def, err := dbschema.DescribeCollection(ctx, db, collectionRef("logs"))
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{
+		{Name: "msg", Type: dbschema.String},
+		{Name: "level", Type: dbschema.String, Nullable: true},
+	}, def.Fields, "an altered collection keeps its inferred fields")
+
+	err = ddl.AlterCollection(ctx, db, "accounts", ddl.DropField("team"))
+	assert.ErrorIs(t, err, dal.ErrNotSupported, "the fields of a typed collection follow its type")
+	require.NoError(t, ddl.AlterCollection(ctx, db, "accounts", ddl.AddIndex(dbschema.IndexDef{Name: "byTeam", Fields: []dal.FieldName{"team"}})))
+	err = ddl.AlterCollection(ctx, db, "columns", ddl.AddIndex(dbschema.IndexDef{Name: "byTeam", Fields: []dal.FieldName{"team"}}))
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+	require.NoError(t, ddl.AlterCollection(ctx, db, "columns", ddl.AddIndex(dbschema.IndexDef{Name: "byEmail", Fields: []dal.FieldName{"email"}, Unique: true})))
+	assert.Error(t, ddl.AlterCollection(ctx, db, "missing", ddl.DropIndex("i")))
+
+	require.NoError(t, ddl.DropCollection(ctx, db, "accounts"))
+	err = db.Set(ctx, record.NewRecordWithData(accountKey("a1"), &account{Email: "ann@example.com"}))
+	assert.Error(t, err, "a dropped collection is no longer defined in the schema")
+}
+
+func TestSchemaModifier_CompositeTypes(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB().(*database)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("posts", "p1"), map[string]any{
+		"status": "draft",
+		"tags":   []string{"go", "db"},
+		"author": map[string]any{"name": "ann"},
+		"at":     map[string]any{"latitude": 52.37, "longitude": 4.89},
+		"id":     "123e4567-e89b-12d3-a456-426614174000",
+	})))
+	def, err := dbschema.DescribeCollection(ctx, db, collectionRef("posts"))
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{
+		{Name: "at", Type: dbschema.Object, Fields: []dbschema.FieldDef{
+			{Name: "latitude", Type: dbschema.Float}, {Name: "longitude", Type: dbschema.Float}}},
+		{Name: "author", Type: dbschema.Object, Fields: []dbschema.FieldDef{{Name: "name", Type: dbschema.String}}},
+		{Name: "id", Type: dbschema.UUID},
+		{Name: "status", Type: dbschema.String},
+		{Name: "tags", Type: dbschema.Array, Elem: &dbschema.FieldDef{Type: dbschema.String}},
+	}, def.Fields, "inferred as dbschema.ProfileCollection infers them")
+
+	modify := func(f dbschema.FieldDef) error {
+		return ddl.AlterCollection(ctx, db, "posts", ddl.ModifyField(f.Name, f))
+	}
+	require.NoError(t, modify(dbschema.FieldDef{Name: "status", Type: dbschema.Enum, Values: []string{"draft", "live"}}))
+	require.NoError(t, modify(dbschema.FieldDef{Name: "tags", Type: dbschema.Array, Elem: &dbschema.FieldDef{Type: dbschema.String}}))
+	require.NoError(t, modify(dbschema.FieldDef{Name: "author", Type: dbschema.Object, Fields: []dbschema.FieldDef{
+		{Name: "name", Type: dbschema.String}, {Name: "email", Type: dbschema.String, Nullable: true}}}))
+	require.NoError(t, modify(dbschema.FieldDef{Name: "at", Type: dbschema.GeoPoint}))
+	require.NoError(t, modify(dbschema.FieldDef{Name: "id", Type: dbschema.UUID}))
+
+	assert.ErrorContains(t, modify(dbschema.FieldDef{Name: "status", Type: dbschema.Enum, Values: []string{"live"}}),
+		`field status: "draft" is not one of enum('live')`)
+	assert.Error(t, modify(dbschema.FieldDef{Name: "tags", Type: dbschema.Array, Elem: &dbschema.FieldDef{Type: dbschema.Int}}))
+	assert.Error(t, modify(dbschema.FieldDef{Name: "author", Type: dbschema.Object, Fields: []dbschema.FieldDef{{Name: "email", Type: dbschema.String}}}))
+	assert.Error(t, modify(dbschema.FieldDef{Name: "status", Type: dbschema.UUID}))
+	assert.NoError(t, modify(dbschema.FieldDef{Name: "status", Type: dbschema.JSON}))
+}
+
+func TestSchemaModifier_CreatedCollectionFactory(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(false)).(*database)
+	require.NoError(t, ddl.CreateCollection(ctx, db, dbschema.CollectionDef{Name: "tags", Fields: []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}}))
+	factory, err := db.recordFactory("tags")
+	require.NoError(t, err)
+	require.NotNil(t, factory, "records of a created collection read into maps")
+	assert.IsType(t, new(map[string]any), factory())
+
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("tags", "t1"), map[string]any{"label": "go"})))
+	records, err := dal.ExecuteQueryAndReadAllToRecords(ctx, dal.From(dal.NewRootCollectionRef("tags", "")).NewQuery().SelectKeysOnly(reflect.String), db)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, &map[string]any{"label": "go"}, records[0].Data())
+}
+
+func TestSchemaModifier_InTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB().(*database)
+	tags := dbschema.CollectionDef{Name: "tags", Fields: []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}}
+	t1 := record.NewKeyWithID("tags", "t1")
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		if err := db.CreateCollection(ctx, tags); err != nil {
+			return err
+		}
+		return tx.Set(ctx, record.NewRecordWithData(t1, map[string]any{"label": "go"}))
+	})
+	require.NoError(t, err, "DDL runs in a read-write transaction")
+	exists, err := db.Exists(ctx, t1)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	failure := errors.New("abort")
+	err = db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		require.NoError(t, db.AlterCollection(ctx, "tags", ddl.AddField(dbschema.FieldDef{Name: "n", Type: dbschema.Int, Default: dbschema.DefaultLiteral{Value: 1}})))
+		require.NoError(t, db.DropCollection(ctx, "tags"))
+		require.NoError(t, db.CreateCollection(ctx, dbschema.CollectionDef{Name: "tags"}))
+		require.NoError(t, tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("tags", "t2"), map[string]any{})))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+	def, err := db.DescribeCollection(ctx, collectionRef("tags"))
+	require.NoError(t, err, "the rollback restores the dropped collection")
+	assert.Equal(t, tags.Fields, def.Fields)
+	var data map[string]any
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(t1, &data)))
+	assert.Equal(t, map[string]any{"label": "go"}, data)
+	exists, err = db.Exists(ctx, record.NewKeyWithID("tags", "t2"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = db.RunReadonlyTransaction(ctx, func(ctx context.Context, tx dal.ReadTransaction) error {
+		assert.ErrorIs(t, db.DropCollection(ctx, "tags"), ErrDDLInTransaction)
+		return nil
+	})
+	require.NoError(t, err)
+}
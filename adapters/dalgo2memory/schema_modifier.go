@@ -0,0 +1,476 @@
+package dalgo2memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record/update"
+)
+
+var _ ddl.SchemaModifier = (*database)(nil)
+var _ ddl.TransactionalDDL = (*database)(nil)
+
+// SupportsTransactionalDDL reports true: a CreateCollection or
+// AlterCollection that fails leaves the database as it was before the call.
+func (db *database) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// newMapRecord is the record factory of the schemaless collections DDL
+// declares: their records read into maps.
+func newMapRecord() any {
+	return new(map[string]any)
+}
+
+// CreateCollection declares a schemaless collection stored by the Serialized
+// engine, with the fields of c and its indexes, unique or not, as real
+// indexes. The fields describe the collection (see DescribeCollection) but
+// are not enforced on writes, and its records read into map[string]any.
+// Creating a collection that exists (see ListCollections) fails unless
+// ddl.IfNotExists is given.
+//
+// Like the other DDL methods, called with the context of a read-write
+// transaction of the database it runs in the transaction, which undoes it when
+// it rolls back, and with the context of a read-only one it fails with
+// ErrDDLInTransaction.
+func (db *database) CreateCollection(ctx context.Context, c dbschema.CollectionDef, opts ...ddl.Option) error {
+	if c.Name == "" {
+		return fmt.Errorf("collection name is required")
+	}
+	return db.runDDL(ctx, c.Name, func() error {
+		return db.createCollection(ctx, c, opts...)
+	})
+}
+
+// runDDL runs the DDL apply of a collection under the database write lock or,
+// when ctx is the context of a read-write transaction of the database, which
+// holds the lock, in the transaction: the records of the collection are
+// snapshotted as for a write, and the schema and engine of the collection are
+// restored when the transaction rolls back.
+func (db *database) runDDL(ctx context.Context, collection string, apply func() error) error {
+	tx, inTransaction := db.transactionOf(ctx)
+	if !inTransaction {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return apply()
+	}
+	if tx == nil {
+		return ErrDDLInTransaction
+	}
+	if db.hasCollection(collection) {
+		session{db: db, txState: tx}.writeEngine(collection)
+	}
+	schema := cloneMemorySchema(db.schema)
+	eng, existed := db.collections[collection]
+	tx.ddlRestores = append(tx.ddlRestores, func() {
+		db.schema = schema
+		if existed {
+			db.collections[collection] = eng
+		} else {
+			delete(db.collections, collection)
+		}
+	})
+	return apply()
+}
+
+func (db *database) createCollection(ctx context.Context, c dbschema.CollectionDef, opts ...ddl.Option) error {
+	if db.hasCollection(c.Name) {
+		if ddl.ResolveOptions(opts...).IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("collection %q already exists", c.Name)
+	}
+	saved := cloneMemorySchema(db.schema)
+	schema := db.declaredSchema()
+	schema.collections[c.Name] = newMapRecord
+	delete(schema.engines, c.Name)
+	delete(schema.uniques, c.Name)
+	delete(db.collections, c.Name) // left by a read of the collection
+	def := cloneCollectionDef(c)
+	def.Indexes = nil
+	schema.defs[c.Name] = def
+	a := &alterApplier{s: session{db: db}, collection: c.Name, def: def}
+	for _, idx := range c.Indexes {
+		if err := a.ApplyAddIndex(ctx, idx, ddl.Options{}); err != nil {
+			db.schema = saved
+			delete(db.collections, c.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+// DropCollection drops a collection: its records, its indexes and its
+// declaration, so that a schema that does not allow undefined collections
+// rejects it from then on. Dropping a collection that does not exist fails
+// unless ddl.IfExists is given.
+func (db *database) DropCollection(ctx context.Context, name string, opts ...ddl.Option) error {
+	return db.runDDL(ctx, name, func() error {
+		return db.dropCollection(name, opts...)
+	})
+}
+
+func (db *database) dropCollection(name string, opts ...ddl.Option) error {
+	if !db.hasCollection(name) {
+		if ddl.ResolveOptions(opts...).IfExists {
+			return nil
+		}
+		return collectionNotFound(name)
+	}
+	if db.schema != nil {
+		delete(db.schema.collections, name)
+		delete(db.schema.engines, name)
+		delete(db.schema.uniques, name)
+		delete(db.schema.defs, name)
+	}
+	delete(db.collections, name)
+	delete(db.uniques, name)
+	delete(db.updateTimes, name)
+	return nil
+}
+
+// AlterCollection applies ops to a collection as one transaction: when an op
+// fails, the records, indexes and definition of the collection are restored
+// and the op's error is returned.
+//
+// Field operations rewrite the records: AddField sets the default of the
+// field, if it has one, in every record that lacks it (a field that is not
+// nullable needs one unless the collection is empty), DropField removes the
+// field from every record, and RenameField moves it. ModifyField checks that
+// every stored value conforms to the new definition and renames the field
+// when the name changes. A field used by an index cannot be dropped, and
+// renaming it renames it in the index. Field operations are not supported on
+// a collection registered with a Go struct type, whose fields follow the type.
+//
+// AddIndex adds a unique index (see WithUniqueIndex), failing with a
+// *dal.UniqueViolationError when the records already duplicate a key, or a
+// secondary index of a serialized collection (see WithIndex). DropIndex drops
+// either.
+//
+// A collection altered by DDL keeps the resulting definition, which
+// DescribeCollection reports from then on.
+func (db *database) AlterCollection(ctx context.Context, name string, ops ...ddl.AlterOp) error {
+	return db.runDDL(ctx, name, func() error {
+		return db.alterCollection(ctx, name, ops...)
+	})
+}
+
+func (db *database) alterCollection(ctx context.Context, name string, ops ...ddl.AlterOp) error {
+	def, err := db.collectionDef(name)
+	if err != nil {
+		return err
+	}
+	saved := cloneMemorySchema(db.schema)
+	tx := &transactionState{}
+	a := &alterApplier{s: session{db: db, txState: tx}, collection: name, def: def}
+	for _, op := range ops {
+		if err := op.ApplyTo(ctx, a); err != nil {
+			tx.rollback()
+			db.schema = saved
+			return err
+		}
+	}
+	if a.fieldsAltered {
+		schema := db.declaredSchema()
+		if _, ok := schema.collections[name]; !ok {
+			schema.collections[name] = newMapRecord
+		}
+		schema.defs[name] = a.def
+	}
+	return nil
+}
+
+// declaredSchema returns the schema of the database, first registering an
+// empty one that allows undefined collections when it has none.
+func (db *database) declaredSchema() *memorySchema {
+	if db.schema == nil {
+		db.schema = &memorySchema{
+			collections:    make(map[string]func() any),
+			engines:        make(map[string]engineFactory),
+			uniques:        make(map[string][]indexSpec),
+			defs:           make(map[string]dbschema.CollectionDef),
+			allowUndefined: true,
+		}
+	}
+	return db.schema
+}
+
+// alterApplier applies the ops of an AlterCollection, or the inline indexes
+// of a CreateCollection, to a collection and to its definition.
+type alterApplier struct {
+	s             session
+	collection    string
+	def           dbschema.CollectionDef
+	fieldsAltered bool
+}
+
+var _ ddl.Applier = (*alterApplier)(nil)
+
+func (a *alterApplier) notSupported(op, reason string) error {
+	return &dbschema.NotSupportedError{Op: op, Backend: a.s.db.Adapter().Name(), Reason: reason}
+}
+
+// fieldEngine returns the engine of the collection for a field operation,
+// which rewrites its records.
+func (a *alterApplier) fieldEngine(op string) (storageEngine, error) {
+	factory, _ := a.s.db.recordFactory(a.collection)
+	if t, err := structTypeOf(factory); err == nil {
+		return nil, a.notSupported(op, fmt.Sprintf("the fields of collection %q follow Go type %v", a.collection, t))
+	}
+	a.fieldsAltered = true
+	return a.s.writeEngine(a.collection), nil
+}
+
+func (a *alterApplier) field(name dal.FieldName) int {
+	return slices.IndexFunc(a.def.Fields, func(f dbschema.FieldDef) bool { return f.Name == name })
+}
+
+// rewrite applies the updates returned by rewrite for each stored record,
+// skipping the records it returns none for.
+func (a *alterApplier) rewrite(eng storageEngine, updates func(data map[string]any) []update.Update) error {
+	rows, err := eng.rows()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if u := updates(row.data); len(u) > 0 {
+			if err := eng.update(row.id, u); err != nil {
+				return err
+			}
+			a.s.db.touch(a.collection, row.id)
+		}
+	}
+	delete(a.s.db.uniques, a.collection)
+	return nil
+}
+
+func (a *alterApplier) ApplyAddField(_ context.Context, f dbschema.FieldDef, opts ddl.Options) error {
+	if a.field(f.Name) >= 0 {
+		if opts.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("field %q already exists in collection %q", f.Name, a.collection)
+	}
+	eng, err := a.fieldEngine("AddField")
+	if err != nil {
+		return err
+	}
+	var value any
+	switch d := f.Default.(type) {
+	case dbschema.DefaultLiteral:
+		value = d.Value
+	case dbschema.DefaultCurrentTimestamp:
+		value = a.s.db.now().UTC().Format(time.RFC3339Nano)
+	}
+	if value == nil && !f.Nullable {
+		rows, err := eng.rows()
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			return fmt.Errorf("field %q of collection %q is not nullable and has no default", f.Name, a.collection)
+		}
+	}
+	if value != nil {
+		err = a.rewrite(eng, func(data map[string]any) []update.Update {
+			if _, ok := data[string(f.Name)]; ok {
+				return nil
+			}
+			return []update.Update{update.ByFieldName(string(f.Name), value)}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	a.def.Fields = append(slices.Clip(a.def.Fields), f)
+	return nil
+}
+
+func (a *alterApplier) ApplyDropField(_ context.Context, name dal.FieldName, opts ddl.Options) error {
+	i := a.field(name)
+	if i < 0 {
+		if opts.IfExists {
+			return nil
+		}
+		return fmt.Errorf("field %q does not exist in collection %q", name, a.collection)
+	}
+	for _, idx := range a.s.db.indexDefs(a.collection) {
+		if slices.Contains(idx.Fields, name) {
+			return fmt.Errorf("field %q of collection %q is used by index %q", name, a.collection, idx.Name)
+		}
+	}
+	eng, err := a.fieldEngine("DropField")
+	if err != nil {
+		return err
+	}
+	err = a.rewrite(eng, func(data map[string]any) []update.Update {
+		if _, ok := data[string(name)]; !ok {
+			return nil
+		}
+		return []update.Update{update.ByFieldName(string(name), update.DeleteField)}
+	})
+	if err != nil {
+		return err
+	}
+	a.def.Fields = slices.Delete(slices.Clone(a.def.Fields), i, i+1)
+	return nil
+}
+
+func (a *alterApplier) ApplyModifyField(ctx context.Context, name dal.FieldName, newDef dbschema.FieldDef, _ ddl.Options) error {
+	if newDef.Name == "" {
+		newDef.Name = name
+	}
+	if newDef.Name != name {
+		if err := a.ApplyRenameField(ctx, name, newDef.Name, ddl.Options{}); err != nil {
+			return err
+		}
+	}
+	i := a.field(newDef.Name)
+	if i < 0 {
+		return fmt.Errorf("field %q does not exist in collection %q", name, a.collection)
+	}
+	eng, err := a.fieldEngine("ModifyField")
+	if err != nil {
+		return err
+	}
+	rows, err := eng.rows()
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var c dbschema.Checker
+		c.Value(string(newDef.Name), newDef, row.data[string(newDef.Name)])
+		if len(c.Violations) > 0 {
+			return fmt.Errorf("collection %q: record %q does not conform to the new definition of field %s", a.collection, row.id, c.Violations[0])
+		}
+	}
+	a.def.Fields = slices.Clone(a.def.Fields)
+	a.def.Fields[i] = newDef
+	return nil
+}
+
+func (a *alterApplier) ApplyRenameField(_ context.Context, oldName, newName dal.FieldName, _ ddl.Options) error {
+	i := a.field(oldName)
+	if i < 0 {
+		return fmt.Errorf("field %q does not exist in collection %q", oldName, a.collection)
+	}
+	if a.field(newName) >= 0 {
+		return fmt.Errorf("field %q already exists in collection %q", newName, a.collection)
+	}
+	eng, err := a.fieldEngine("RenameField")
+	if err != nil {
+		return err
+	}
+	err = a.rewrite(eng, func(data map[string]any) []update.Update {
+		v, ok := data[string(oldName)]
+		if !ok {
+			return nil
+		}
+		return []update.Update{
+			update.ByFieldName(string(newName), v),
+			update.ByFieldName(string(oldName), update.DeleteField),
+		}
+	})
+	if err != nil {
+		return err
+	}
+	a.def.Fields = slices.Clone(a.def.Fields)
+	a.def.Fields[i].Name = newName
+	a.def.PrimaryKey = slices.Clone(a.def.PrimaryKey)
+	for j, field := range a.def.PrimaryKey {
+		if field == oldName {
+			a.def.PrimaryKey[j] = newName
+		}
+	}
+	rename := func(specs []indexSpec) []indexSpec {
+		renamed := make([]indexSpec, len(specs))
+		for j, spec := range specs {
+			renamed[j] = indexSpec{name: spec.name, fields: slices.Clone(spec.fields)}
+			for k, field := range spec.fields {
+				if field == string(oldName) {
+					renamed[j].fields[k] = string(newName)
+				}
+			}
+		}
+		return renamed
+	}
+	if serialized, ok := eng.(*serializedEngine); ok {
+		serialized.setIndexes(rename(serialized.indexSpecs()))
+	}
+	if uniques := a.s.db.schema; uniques != nil && len(uniques.uniques[a.collection]) > 0 {
+		uniques.uniques[a.collection] = rename(uniques.uniques[a.collection])
+	}
+	return nil
+}
+
+func (a *alterApplier) hasIndex(name string) bool {
+	return slices.ContainsFunc(a.s.db.indexDefs(a.collection), func(idx dbschema.IndexDef) bool { return idx.Name == name })
+}
+
+func (a *alterApplier) ApplyAddIndex(_ context.Context, idx dbschema.IndexDef, opts ddl.Options) error {
+	if a.hasIndex(idx.Name) {
+		if opts.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("index %q already exists on collection %q", idx.Name, a.collection)
+	}
+	if idx.Name == "" || len(idx.Fields) == 0 {
+		return fmt.Errorf("index %q on collection %q needs a name and at least one field", idx.Name, a.collection)
+	}
+	spec := indexSpec{name: idx.Name, fields: make([]string, len(idx.Fields))}
+	for i, field := range idx.Fields {
+		spec.fields[i] = string(field)
+	}
+	eng := a.s.writeEngine(a.collection)
+	if idx.Unique {
+		rows, err := eng.rows()
+		if err != nil {
+			return err
+		}
+		ix := &uniqueIndex{indexSpec: spec, byKey: make(map[string]uniqueHolder), byID: make(map[string]string)}
+		for _, row := range rows {
+			if err := ix.check(a.collection, row.id, row.data); err != nil {
+				return err
+			}
+			ix.put(row.id, row.key, row.data)
+		}
+		schema := a.s.db.declaredSchema()
+		schema.uniques[a.collection] = append(slices.Clip(schema.uniques[a.collection]), spec)
+		delete(a.s.db.uniques, a.collection)
+		return nil
+	}
+	serialized, ok := eng.(*serializedEngine)
+	if !ok {
+		return a.notSupported("AddIndex", fmt.Sprintf("collection %q does not use serialized storage, which secondary indexes need", a.collection))
+	}
+	serialized.setIndexes(append(serialized.indexSpecs(), spec))
+	return nil
+}
+
+func (a *alterApplier) ApplyDropIndex(_ context.Context, name string, opts ddl.Options) error {
+	eng := a.s.writeEngine(a.collection)
+	if serialized, ok := eng.(*serializedEngine); ok {
+		specs := serialized.indexSpecs()
+		if i := slices.IndexFunc(specs, func(spec indexSpec) bool { return spec.name == name }); i >= 0 {
+			serialized.setIndexes(slices.Delete(specs, i, i+1))
+			return nil
+		}
+	}
+	if schema := a.s.db.schema; schema != nil {
+		specs := schema.uniques[a.collection]
+		if i := slices.IndexFunc(specs, func(spec indexSpec) bool { return spec.name == name }); i >= 0 {
+			schema.uniques[a.collection] = slices.Delete(slices.Clone(specs), i, i+1)
+			delete(a.s.db.uniques, a.collection)
+			return nil
+		}
+	}
+	if opts.IfExists {
+		return nil
+	}
+	return fmt.Errorf("index %q does not exist on collection %q", name, a.collection)
+}
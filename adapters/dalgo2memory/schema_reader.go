@@ -0,0 +1,205 @@
+package dalgo2memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/record"
+)
+
+var _ dbschema.SchemaReader = (*database)(nil)
+
+// ListCollections returns the collections of the database, sorted by name:
+// the ones declared by WithSchema or created by DDL, and every other one that
+// holds a record. With a parent key it returns, scoped to that parent, the
+// collections holding a record that is a direct child of it.
+func (db *database) ListCollections(_ context.Context, parent *record.Key) ([]dal.CollectionRef, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	var names []string
+	if parent == nil {
+		names = db.collectionNames()
+	} else {
+		for _, name := range db.collectionNames() {
+			rows, err := db.engine(name).rows()
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(rows, func(row engineRow) bool { return isChildOf(row.key, parent) }) {
+				names = append(names, name)
+			}
+		}
+	}
+	refs := make([]dal.CollectionRef, len(names))
+	for i, name := range names {
+		refs[i] = dal.NewCollectionRef(name, "", parent)
+	}
+	return refs, nil
+}
+
+// DescribeCollection returns the definition of a collection, whatever its
+// parent:
+//
+//   - a collection created or altered by DDL has the fields it was given;
+//   - a collection registered with a Go struct type has one field per
+//     serialized field of the type;
+//   - any other collection has the fields its records hold, inferred from
+//     their values by a dbschema.Profiler, in name order.
+//
+// Its indexes are the secondary indexes of a serialized collection and its
+// unique indexes. A collection has no primary key field, as records are keyed
+// by id, unless DDL declared one.
+func (db *database) DescribeCollection(_ context.Context, ref *dal.CollectionRef) (*dbschema.CollectionDef, error) {
+	if ref == nil {
+		return nil, fmt.Errorf("collection ref is required")
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	def, err := db.collectionDef(ref.Name())
+	if err != nil {
+		return nil, err
+	}
+	def.Indexes = db.indexDefs(ref.Name())
+	return &def, nil
+}
+
+// ListIndexes returns the indexes DescribeCollection reports inline.
+func (db *database) ListIndexes(_ context.Context, ref *dal.CollectionRef) ([]dbschema.IndexDef, error) {
+	if ref == nil {
+		return nil, fmt.Errorf("collection ref is required")
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if !db.hasCollection(ref.Name()) {
+		return nil, collectionNotFound(ref.Name())
+	}
+	return db.indexDefs(ref.Name()), nil
+}
+
+// ListConstraints is not supported: the in-memory database has no
+// constraints besides its unique indexes, which ListIndexes reports.
+func (db *database) ListConstraints(context.Context, *dal.CollectionRef) ([]dbschema.ConstraintDef, error) {
+	return nil, &dbschema.NotSupportedError{Op: "ListConstraints", Backend: db.Adapter().Name(), Reason: "unique indexes are reported by ListIndexes"}
+}
+
+// ListReferrers is not supported: the in-memory database has no foreign keys.
+func (db *database) ListReferrers(context.Context, *dal.CollectionRef) ([]dbschema.Referrer, error) {
+	return nil, &dbschema.NotSupportedError{Op: "ListReferrers", Backend: db.Adapter().Name(), Reason: "no foreign keys"}
+}
+
+func collectionNotFound(name string) error {
+	return fmt.Errorf("collection %q does not exist", name)
+}
+
+// collectionNames returns, sorted, the names of the collections that exist
+// (see hasCollection).
+func (db *database) collectionNames() []string {
+	var names []string
+	if db.schema != nil {
+		names = slices.Collect(maps.Keys(db.schema.collections))
+	}
+	for name := range db.collections {
+		if db.hasCollection(name) && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasCollection reports whether a collection exists: it is declared by
+// WithSchema or DDL, or holds a record.
+func (db *database) hasCollection(name string) bool {
+	if db.schema != nil {
+		if _, ok := db.schema.collections[name]; ok {
+			return true
+		}
+	}
+	eng, ok := db.collections[name]
+	if !ok {
+		return false
+	}
+	if counter, ok := eng.(rowCounter); ok {
+		return counter.count() > 0
+	}
+	rows, err := eng.rows()
+	return err == nil && len(rows) > 0
+}
+
+// collectionDef returns the definition of a collection, without its indexes
+// (see DescribeCollection).
+func (db *database) collectionDef(name string) (dbschema.CollectionDef, error) {
+	if !db.hasCollection(name) {
+		return dbschema.CollectionDef{}, collectionNotFound(name)
+	}
+	if db.schema != nil {
+		if def, ok := db.schema.defs[name]; ok {
+			return cloneCollectionDef(def), nil
+		}
+	}
+	factory, _ := db.recordFactory(name)
+	if t, err := structTypeOf(factory); err == nil {
+		def, err := dbschema.FromType(name, t)
+		if err != nil {
+			return dbschema.CollectionDef{}, err
+		}
+		def.Indexes = nil // declared by tags, not maintained by the engine
+		return def, nil
+	}
+	rows, err := db.engine(name).rows()
+	if err != nil {
+		return dbschema.CollectionDef{}, err
+	}
+	profiler := dbschema.NewProfiler(name)
+	for _, row := range rows {
+		if err = profiler.Add(row.data); err != nil {
+			return dbschema.CollectionDef{}, fmt.Errorf("collection %q: record %v: %w", name, row.key, err)
+		}
+	}
+	return profiler.Profile().Collection, nil
+}
+
+// indexDefs returns the secondary indexes of a serialized collection followed
+// by its unique indexes. Columnar column strategies are not reported.
+func (db *database) indexDefs(name string) []dbschema.IndexDef {
+	var defs []dbschema.IndexDef
+	if eng, ok := db.engine(name).(*serializedEngine); ok {
+		for _, spec := range eng.indexSpecs() {
+			defs = append(defs, spec.indexDef(name, false))
+		}
+	}
+	if db.schema != nil {
+		for _, spec := range db.schema.uniques[name] {
+			defs = append(defs, spec.indexDef(name, true))
+		}
+	}
+	return defs
+}
+
+func (spec indexSpec) indexDef(collection string, unique bool) dbschema.IndexDef {
+	fields := make([]dal.FieldName, len(spec.fields))
+	for i, field := range spec.fields {
+		fields[i] = dal.FieldName(field)
+	}
+	return dbschema.IndexDef{Name: spec.name, Collection: collection, Fields: fields, Unique: unique}
+}
+
+func cloneCollectionDef(def dbschema.CollectionDef) dbschema.CollectionDef {
+	def.Fields = slices.Clone(def.Fields)
+	def.PrimaryKey = slices.Clone(def.PrimaryKey)
+	def.Indexes = slices.Clone(def.Indexes)
+	return def
+}
+
+func cloneCollectionDefs(defs map[string]dbschema.CollectionDef) map[string]dbschema.CollectionDef {
+	clone := make(map[string]dbschema.CollectionDef, len(defs))
+	for name, def := range defs {
+		clone[name] = cloneCollectionDef(def)
+	}
+	return clone
+}
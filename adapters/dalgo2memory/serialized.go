@@ -3,6 +3,7 @@ package dalgo2memory
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 
 	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/record"
@@ -23,19 +24,24 @@ type serializedEngine struct {
 	factory    func() any
 	records    map[string][]byte
 	keys       map[string]*record.Key // full key (with parent chain) per stored id
+	indexes    []*secondaryIndex      // declared with WithIndex
 }
 
 var _ storageEngine = (*serializedEngine)(nil)
 
 // newSerializedEngine builds a Serialized engine for a collection with the
-// given record-type factory (nil when schemaless).
-func newSerializedEngine(collection string, factory func() any) *serializedEngine {
-	return &serializedEngine{
+// given record-type factory (nil when schemaless) and secondary indexes.
+func newSerializedEngine(collection string, factory func() any, indexes ...indexSpec) *serializedEngine {
+	e := &serializedEngine{
 		collection: collection,
 		factory:    factory,
 		records:    make(map[string][]byte),
 		keys:       make(map[string]*record.Key),
 	}
+	for _, spec := range indexes {
+		e.indexes = append(e.indexes, newSecondaryIndex(spec))
+	}
+	return e
 }
 
 func (e *serializedEngine) exists(id string) bool {
@@ -43,6 +49,11 @@ func (e *serializedEngine) exists(id string) bool {
 	return ok
 }
 
+// count is the number of stored rows.
+func (e *serializedEngine) count() int {
+	return len(e.records)
+}
+
 func (e *serializedEngine) store(id string, record record.Record, overwrite bool) error {
 	if !overwrite {
 		if _, ok := e.records[id]; ok {
@@ -58,6 +69,7 @@ func (e *serializedEngine) store(id string, record record.Record, overwrite bool
 			return err
 		}
 	}
+	e.reindex(id, b)
 	e.records[id] = b
 	e.keys[id] = record.Key()
 	return nil
@@ -72,6 +84,7 @@ func (e *serializedEngine) load(id string, record record.Record) error {
 }
 
 func (e *serializedEngine) delete(id string) {
+	e.reindex(id, nil)
 	delete(e.records, id)
 	delete(e.keys, id)
 }
@@ -97,26 +110,50 @@ func (e *serializedEngine) update(id string, updates []update.Update) error {
 			return err
 		}
 	}
+	e.reindex(id, next)
 	e.records[id] = next
 	return nil
 }
 
 func (e *serializedEngine) rows() ([]engineRow, error) {
 	rows := make([]engineRow, 0, len(e.records))
-	for id, b := range e.records {
-		var data map[string]any
-		if err := json.Unmarshal(b, &data); err != nil {
+	for id := range e.records {
+		row, err := e.row(id)
+		if err != nil {
 			return nil, err
 		}
-		raw := b
-		rows = append(rows, engineRow{
-			id:   id,
-			data: data,
-			materialize: func(target any) error {
-				return json.Unmarshal(raw, target)
-			},
-			key: e.keys[id],
-		})
+		rows = append(rows, row)
 	}
 	return rows, nil
 }
+
+// row decodes the stored row with the given id.
+func (e *serializedEngine) row(id string) (engineRow, error) {
+	raw := e.records[id]
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return engineRow{}, err
+	}
+	return engineRow{
+		id:   id,
+		data: data,
+		materialize: func(target any) error {
+			return json.Unmarshal(raw, target)
+		},
+		key: e.keys[id],
+	}, nil
+}
+
+// snapshot copies the id->bytes and id->key maps and the indexes. Stored byte
+// slices and keys are replaced rather than mutated on write, so sharing them
+// is safe.
+func (e *serializedEngine) snapshot() func() {
+	records := maps.Clone(e.records)
+	keys := maps.Clone(e.keys)
+	indexes := cloneIndexes(e.indexes)
+	return func() {
+		e.records = records
+		e.keys = keys
+		e.indexes = indexes
+	}
+}
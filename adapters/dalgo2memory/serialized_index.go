@@ -0,0 +1,784 @@
+package dalgo2memory
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// SerializedOption configures a collection stored by the Serialized engine. It
+// is passed to WithSerializedStorage.
+type SerializedOption func(*serializedConfig)
+
+// serializedConfig is the per-collection configuration of a Serialized engine.
+type serializedConfig struct {
+	indexes []indexSpec
+}
+
+// indexSpec declares a secondary index: its name and the stored fields it is
+// keyed on, in order.
+type indexSpec struct {
+	name   string
+	fields []string
+}
+
+// WithIndex declares a secondary index named name on one or more top-level
+// fields of a serialized collection, named as they are stored (the JSON field
+// names). The index is maintained on every write and lets a query read only
+// the records it selects instead of decoding the whole collection:
+//
+//   - equalities on a leading run of its fields, where an IN (array-contains-any
+//     in this adapter, see matchesWhere) or array-contains condition counts as
+//     one equality per value;
+//   - a range (>, >=, <, <=, Between) on the field that follows that run;
+//   - an ORDER BY on the fields that follow that run, all in the same
+//     direction, with a LIMIT: the records are read in index order and the
+//     reading stops once the page is full. This needs every indexed value of
+//     the ordered fields to be a number, or every one a string.
+//
+// A field holding an array is indexed under the array and under each of its
+// elements. A composite index is a single index on several fields, e.g.
+// WithIndex("byTeamAndAge", "team", "age"); declaring the same name again
+// replaces the earlier declaration. WithIndex panics when no field is given.
+func WithIndex(name string, fields ...string) SerializedOption {
+	if len(fields) == 0 {
+		panic(fmt.Sprintf("dalgo2memory: index %q has no fields", name))
+	}
+	spec := indexSpec{name: name, fields: slices.Clone(fields)}
+	return func(cfg *serializedConfig) {
+		for i, existing := range cfg.indexes {
+			if existing.name == name {
+				cfg.indexes[i] = spec
+				return
+			}
+		}
+		cfg.indexes = append(cfg.indexes, spec)
+	}
+}
+
+// newSerializedEngineFactory returns the engineFactory for a Serialized
+// collection with the given configuration: serializedEngineFactory when it
+// declares no index.
+func newSerializedEngineFactory(cfg serializedConfig) engineFactory {
+	if len(cfg.indexes) == 0 {
+		return serializedEngineFactory
+	}
+	return cfg.newEngine
+}
+
+// newEngine is the engineFactory of a Serialized collection with indexes.
+func (cfg serializedConfig) newEngine(collection string, factory func() any, _ bool) storageEngine {
+	return newSerializedEngine(collection, factory, cfg.indexes...)
+}
+
+// Value kinds, in index order: every value of a lower kind sorts before every
+// value of a higher one. Stored values are decoded JSON, so a value is nil (or
+// absent), a bool, a float64, a string, or an array or object (kindOther).
+const (
+	kindNil = iota
+	kindBool
+	kindNumber
+	kindString
+	kindOther
+	valueKinds
+)
+
+func kindOf(v any) int {
+	switch v.(type) {
+	case nil:
+		return kindNil
+	case bool:
+		return kindBool
+	case string:
+		return kindString
+	}
+	if _, ok := number(v); ok {
+		return kindNumber
+	}
+	return kindOther
+}
+
+// compareIndexValues orders two values by kind, then within a kind: false
+// before true, numbers by value, strings bytewise, and arrays and objects by
+// their printed form.
+func compareIndexValues(a, b any) int {
+	ka, kb := kindOf(a), kindOf(b)
+	if ka != kb {
+		return cmp.Compare(ka, kb)
+	}
+	switch ka {
+	case kindBool:
+		if a == b {
+			return 0
+		}
+		if a == false {
+			return -1
+		}
+		return 1
+	case kindNumber:
+		af, _ := number(a)
+		bf, _ := number(b)
+		return cmp.Compare(af, bf)
+	case kindString:
+		return strings.Compare(a.(string), b.(string))
+	case kindOther:
+		return strings.Compare(valueKey(a), valueKey(b))
+	default:
+		return 0
+	}
+}
+
+// compareIndexKeys compares the first len(prefix) values of key with prefix.
+func compareIndexKeys(key, prefix []any) int {
+	for i, v := range prefix {
+		if c := compareIndexValues(key[i], v); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// indexEntry is one key of a record in a secondary index.
+type indexEntry struct {
+	key []any
+	id  string
+}
+
+func compareEntries(a, b indexEntry) int {
+	if c := compareIndexKeys(a.key, b.key); c != 0 {
+		return c
+	}
+	return strings.Compare(a.id, b.id)
+}
+
+// secondaryIndex is a declared index of a Serialized collection. Writes only
+// record a record's keys and mark it stale; the first read after them merges
+// the stale records into the entries, sorted by key and then by record id, so
+// that loading many records does not re-sort the index for each of them.
+type secondaryIndex struct {
+	indexSpec
+	byID  map[string][][]any // keys per record id
+	size  int                // number of keys over all records
+	kinds [][valueKinds]int  // per field, the number of key values of each kind
+
+	// mu guards merging the stale records on a read, as reads run
+	// concurrently; writes are exclusive with reads and need no lock.
+	mu      sync.Mutex
+	entries []indexEntry // replaced, never modified, by a merge
+	stale   map[string]bool
+}
+
+func newSecondaryIndex(spec indexSpec) *secondaryIndex {
+	return &secondaryIndex{
+		indexSpec: spec,
+		byID:      make(map[string][][]any),
+		kinds:     make([][valueKinds]int, len(spec.fields)),
+		stale:     make(map[string]bool),
+	}
+}
+
+// clone copies the index so that writes to either copy do not affect the
+// other. Keys and merged entries are never modified, so they are shared.
+func (ix *secondaryIndex) clone() *secondaryIndex {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return &secondaryIndex{
+		indexSpec: ix.indexSpec,
+		byID:      maps.Clone(ix.byID),
+		size:      ix.size,
+		kinds:     slices.Clone(ix.kinds),
+		entries:   ix.entries,
+		stale:     maps.Clone(ix.stale),
+	}
+}
+
+// put sets the keys of a record from its decoded data.
+func (ix *secondaryIndex) put(id string, data map[string]any) {
+	ix.remove(id)
+	keys := ix.keysOf(data)
+	ix.byID[id] = keys
+	ix.count(keys, 1)
+	ix.stale[id] = true
+}
+
+// remove drops the keys of a record, if it has any.
+func (ix *secondaryIndex) remove(id string) {
+	if keys, ok := ix.byID[id]; ok {
+		ix.count(keys, -1)
+		delete(ix.byID, id)
+		ix.stale[id] = true
+	}
+}
+
+func (ix *secondaryIndex) count(keys [][]any, delta int) {
+	for _, key := range keys {
+		for f, v := range key {
+			ix.kinds[f][kindOf(v)] += delta
+		}
+		ix.size += delta
+	}
+}
+
+// sorted returns the entries of the index, first merging in the keys of the
+// records written since the last read.
+func (ix *secondaryIndex) sorted() []indexEntry {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if len(ix.stale) == 0 {
+		return ix.entries
+	}
+	kept := make([]indexEntry, 0, ix.size)
+	for _, entry := range ix.entries {
+		if !ix.stale[entry.id] {
+			kept = append(kept, entry)
+		}
+	}
+	var added []indexEntry
+	for id := range ix.stale {
+		for _, key := range ix.byID[id] {
+			added = append(added, indexEntry{key: key, id: id})
+		}
+	}
+	slices.SortFunc(added, compareEntries)
+	merged := make([]indexEntry, 0, len(kept)+len(added))
+	for len(kept) > 0 && len(added) > 0 {
+		if compareEntries(kept[0], added[0]) <= 0 {
+			merged, kept = append(merged, kept[0]), kept[1:]
+		} else {
+			merged, added = append(merged, added[0]), added[1:]
+		}
+	}
+	merged = append(append(merged, kept...), added...)
+	ix.entries = merged
+	clear(ix.stale)
+	return merged
+}
+
+// keysOf returns the distinct keys a record's decoded data has in the index:
+// one per combination of the values of its fields (see indexValues).
+func (ix *secondaryIndex) keysOf(data map[string]any) [][]any {
+	keys := [][]any{{}}
+	for _, field := range ix.fields {
+		values := indexValues(data[field])
+		next := make([][]any, 0, len(keys)*len(values))
+		for _, key := range keys {
+			for _, v := range values {
+				next = append(next, append(slices.Clip(key), v))
+			}
+		}
+		keys = next
+	}
+	if len(keys) > 1 {
+		slices.SortFunc(keys, compareIndexKeys)
+		keys = slices.CompactFunc(keys, func(a, b []any) bool { return compareIndexKeys(a, b) == 0 })
+	}
+	return keys
+}
+
+// indexValues returns the values a field is indexed under: the value itself
+// and, for an array, each of its elements, so that an array-contains lookup
+// finds the record.
+func indexValues(v any) []any {
+	elements, ok := v.([]any)
+	if !ok {
+		return []any{v}
+	}
+	return append([]any{v}, elements...)
+}
+
+// prefixRange returns the bounds of the sorted entries whose key starts with
+// prefix.
+func prefixRange(entries []indexEntry, prefix []any) (lo, hi int) {
+	lo = sort.Search(len(entries), func(i int) bool { return compareIndexKeys(entries[i].key, prefix) >= 0 })
+	hi = lo + sort.Search(len(entries)-lo, func(i int) bool { return compareIndexKeys(entries[lo+i].key, prefix) > 0 })
+	return lo, hi
+}
+
+// indexBound is one end of a range condition on an indexed field.
+type indexBound struct {
+	value     any
+	inclusive bool
+}
+
+// fieldPredicate is what the conjuncts of a WHERE condition require of one
+// field: one of points (a single value for an equality, several for IN or
+// array-contains), and a range from lower to upper.
+type fieldPredicate struct {
+	points       []any
+	equality     bool
+	lower, upper *indexBound
+}
+
+// indexPredicates collects, by field, the conjuncts of a WHERE condition that
+// an index can answer, with the same reading of each shape as matchesWhere:
+// an equality to a constant, a range (>, >=, <, <=, Between) to a number or a
+// string, and an array-contains or array-contains-any (FieldRef vs dal.Array,
+// Constant In FieldRef). Other conjuncts are left to the filter that runs
+// over the rows an index selects, so ignoring them is safe.
+func indexPredicates(condition dal.Condition) map[string]*fieldPredicate {
+	preds := make(map[string]*fieldPredicate)
+	at := func(field string) *fieldPredicate {
+		p, ok := preds[field]
+		if !ok {
+			p = &fieldPredicate{}
+			preds[field] = p
+		}
+		return p
+	}
+	for _, c := range conjuncts(condition) {
+		comparison, ok := c.(dal.Comparison)
+		if !ok || isComputed(comparison.Left) || isComputed(comparison.Right) {
+			continue
+		}
+		switch left := comparison.Left.(type) {
+		case dal.FieldRef:
+			switch right := comparison.Right.(type) {
+			case dal.Constant:
+				v, ok := indexable(right.Value)
+				if !ok {
+					continue
+				}
+				switch comparison.Operator {
+				case dal.Equal:
+					if p := at(left.Name()); !p.equality {
+						p.points, p.equality = []any{v}, true
+					}
+				case dal.ArrayContains:
+					at(left.Name()).contains([]any{v})
+				case dal.GreaterThen, dal.GreaterOrEqual:
+					if p := at(left.Name()); p.lower == nil && rangeKind(v) {
+						p.lower = &indexBound{value: v, inclusive: comparison.Operator == dal.GreaterOrEqual}
+					}
+				case dal.LessThen, dal.LessOrEqual:
+					if p := at(left.Name()); p.upper == nil && rangeKind(v) {
+						p.upper = &indexBound{value: v, inclusive: comparison.Operator == dal.LessOrEqual}
+					}
+				}
+			case dal.Array:
+				switch comparison.Operator {
+				case dal.NotIn:
+					continue
+				case dal.Between:
+					bounds, ok := normalizeConstant(right.Value).([]any)
+					if !ok || len(bounds) != 2 {
+						continue
+					}
+					p := at(left.Name())
+					if p.lower == nil && rangeKind(bounds[0]) {
+						p.lower = &indexBound{value: bounds[0], inclusive: true}
+					}
+					if p.upper == nil && rangeKind(bounds[1]) {
+						p.upper = &indexBound{value: bounds[1], inclusive: true}
+					}
+				default:
+					if values, ok := containedValues(right.Value); ok {
+						at(left.Name()).contains(values)
+					}
+				}
+			}
+		case dal.Constant:
+			right, ok := comparison.Right.(dal.FieldRef)
+			if !ok || comparison.Operator != dal.In {
+				continue
+			}
+			if v, ok := indexable(left.Value); ok {
+				at(right.Name()).contains([]any{v})
+			}
+		}
+	}
+	return preds
+}
+
+func (p *fieldPredicate) contains(values []any) {
+	if p.points == nil {
+		p.points = values
+	}
+}
+
+// indexable normalizes a constant the way matchesWhere does and reports
+// whether an index can look it up: nil, a bool, a number or a string.
+func indexable(v any) (any, bool) {
+	v = normalizeConstant(v)
+	return v, kindOf(v) != kindOther
+}
+
+// rangeKind reports whether a range bound can be looked up: compare orders
+// numbers numerically and strings bytewise, but anything else by its printed
+// form, which the index order does not follow.
+func rangeKind(v any) bool {
+	k := kindOf(v)
+	return k == kindNumber || k == kindString
+}
+
+// containedValues returns the values an array-contains-any condition looks
+// for, skipping those that no stored element can equal (see elementEquals).
+func containedValues(values any) ([]any, bool) {
+	rv := reflect.ValueOf(values)
+	if !rv.IsValid() || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return nil, false
+	}
+	points := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if v, ok := indexable(rv.Index(i).Interface()); ok {
+			points = append(points, v)
+		}
+	}
+	return points, true
+}
+
+// indexLookup is how an index answers a WHERE condition: the values each of
+// its leading fields may take, and a range on the field after them.
+type indexLookup struct {
+	index        *secondaryIndex
+	points       [][]any
+	lower, upper *indexBound
+}
+
+// score ranks lookups by how many index fields they constrain, an equality
+// counting for more than a range.
+func (l indexLookup) score() int {
+	score := 2 * len(l.points)
+	if l.lower != nil || l.upper != nil {
+		score++
+	}
+	return score
+}
+
+// planLookup picks the index whose lookup constrains the most fields for the
+// condition, the first declared on a tie; false when no index constrains any.
+func (e *serializedEngine) planLookup(condition dal.Condition) (indexLookup, bool) {
+	if len(e.indexes) == 0 || condition == nil {
+		return indexLookup{}, false
+	}
+	preds := indexPredicates(condition)
+	var best indexLookup
+	for _, ix := range e.indexes {
+		l := indexLookup{index: ix}
+		for _, field := range ix.fields {
+			p := preds[field]
+			if p == nil {
+				break
+			}
+			if p.points != nil {
+				l.points = append(l.points, p.points)
+				continue
+			}
+			l.lower, l.upper = p.lower, p.upper
+			if l.lower != nil && l.upper != nil && kindOf(l.lower.value) != kindOf(l.upper.value) {
+				l.upper = nil
+			}
+			break
+		}
+		if l.score() > best.score() {
+			best = l
+		}
+	}
+	return best, best.score() > 0
+}
+
+// ids returns, sorted, the ids of the records the lookup selects. Within the
+// entries of each combination of leading values, a range keeps the values of
+// its bound's kind that are in range and every value of another kind: compare
+// orders values of different kinds by their printed form, so those may match.
+func (l indexLookup) ids() []string {
+	entries := l.index.sorted()
+	seen := make(map[string]bool)
+	var ids []string
+	collect := func(from, to int) {
+		for _, entry := range entries[from:to] {
+			if !seen[entry.id] {
+				seen[entry.id] = true
+				ids = append(ids, entry.id)
+			}
+		}
+	}
+	for _, prefix := range combinations(l.points) {
+		lo, hi := prefixRange(entries, prefix)
+		bound := l.lower
+		if bound == nil {
+			bound = l.upper
+		}
+		if bound == nil {
+			collect(lo, hi)
+			continue
+		}
+		k, kind := len(prefix), kindOf(bound.value)
+		kindLo := lo + sort.Search(hi-lo, func(i int) bool { return kindOf(entries[lo+i].key[k]) >= kind })
+		kindHi := kindLo + sort.Search(hi-kindLo, func(i int) bool { return kindOf(entries[kindLo+i].key[k]) > kind })
+		from, to := kindLo, kindHi
+		if l.lower != nil {
+			from = kindLo + sort.Search(kindHi-kindLo, func(i int) bool {
+				c := compareIndexValues(entries[kindLo+i].key[k], l.lower.value)
+				return c > 0 || c == 0 && l.lower.inclusive
+			})
+		}
+		if l.upper != nil {
+			to = from + sort.Search(kindHi-from, func(i int) bool {
+				c := compareIndexValues(entries[from+i].key[k], l.upper.value)
+				return c > 0 || c == 0 && !l.upper.inclusive
+			})
+		}
+		collect(lo, kindLo)
+		collect(from, to)
+		collect(kindHi, hi)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// combinations returns every way of picking one value from each list.
+func combinations(lists [][]any) [][]any {
+	out := [][]any{{}}
+	for _, values := range lists {
+		next := make([][]any, 0, len(out)*len(values))
+		for _, prefix := range out {
+			for _, v := range values {
+				next = append(next, append(slices.Clip(prefix), v))
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+// planOrdered picks the index that reads the rows of a WHERE condition in
+// ORDER BY order: one on the ORDER BY fields, optionally after fields the
+// condition sets equal, with the longest such prefix winning. The ORDER BY
+// must list plain fields in a single direction, and the index must hold only
+// numbers or only strings for each of them, so that its order is the order
+// compare gives. It returns the index and the values of its prefix.
+func (e *serializedEngine) planOrdered(orderBy []dal.OrderExpression, condition dal.Condition) (*secondaryIndex, []any, bool) {
+	if len(e.indexes) == 0 || len(orderBy) == 0 {
+		return nil, nil, false
+	}
+	fields := make([]string, len(orderBy))
+	for i, oe := range orderBy {
+		field, ok := oe.Expression().(dal.FieldRef)
+		if !ok || oe.Descending() != orderBy[0].Descending() {
+			return nil, nil, false
+		}
+		fields[i] = field.Name()
+	}
+	preds := indexPredicates(condition)
+	var best *secondaryIndex
+	var bestPrefix []any
+	for _, ix := range e.indexes {
+		var prefix []any
+		for _, field := range ix.fields {
+			p := preds[field]
+			if p == nil || !p.equality {
+				break
+			}
+			prefix = append(prefix, p.points[0])
+		}
+		for n := len(prefix); n >= 0; n-- {
+			if n+len(fields) <= len(ix.fields) && slices.Equal(ix.fields[n:n+len(fields)], fields) && ix.orderable(n, len(fields)) {
+				if best == nil || n > len(bestPrefix) {
+					best, bestPrefix = ix, prefix[:n]
+				}
+				break
+			}
+		}
+	}
+	return best, bestPrefix, best != nil
+}
+
+// orderable reports whether every entry value of the fields from..from+width
+// is a number, or every one a string.
+func (ix *secondaryIndex) orderable(from, width int) bool {
+	for _, kinds := range ix.kinds[from : from+width] {
+		if kinds[kindNumber] != ix.size && kinds[kindString] != ix.size {
+			return false
+		}
+	}
+	return true
+}
+
+// scanOrdered visits, until visit returns false, the ids of the records whose
+// key starts with prefix, ordered by the width fields after the prefix and
+// then, like orderBySources, by id.
+func (ix *secondaryIndex) scanOrdered(prefix []any, width int, descending bool, visit func(id string) (bool, error)) error {
+	entries := ix.sorted()
+	lo, hi := prefixRange(entries, prefix)
+	n := len(prefix)
+	same := func(i, j int) bool {
+		return compareIndexKeys(entries[i].key[n:n+width], entries[j].key[n:n+width]) == 0
+	}
+	seen := make(map[string]bool)
+	visitGroup := func(from, to int) (bool, error) {
+		ids := make([]string, 0, to-from)
+		for _, entry := range entries[from:to] {
+			if !seen[entry.id] {
+				seen[entry.id] = true
+				ids = append(ids, entry.id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if more, err := visit(id); err != nil || !more {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if !descending {
+		for from := lo; from < hi; {
+			to := from + 1
+			for to < hi && same(from, to) {
+				to++
+			}
+			if more, err := visitGroup(from, to); err != nil || !more {
+				return err
+			}
+			from = to
+		}
+		return nil
+	}
+	for to := hi; to > lo; {
+		from := to - 1
+		for from > lo && same(from-1, to-1) {
+			from--
+		}
+		if more, err := visitGroup(from, to); err != nil || !more {
+			return err
+		}
+		to = from
+	}
+	return nil
+}
+
+func cloneIndexes(indexes []*secondaryIndex) []*secondaryIndex {
+	clones := make([]*secondaryIndex, len(indexes))
+	for i, ix := range indexes {
+		clones[i] = ix.clone()
+	}
+	return clones
+}
+
+// indexSpecs returns the declarations of the engine's indexes.
+func (e *serializedEngine) indexSpecs() []indexSpec {
+	specs := make([]indexSpec, len(e.indexes))
+	for i, ix := range e.indexes {
+		specs[i] = ix.indexSpec
+	}
+	return specs
+}
+
+// setIndexes replaces the engine's indexes with ones built from its stored
+// records, keeping those whose declaration is unchanged.
+func (e *serializedEngine) setIndexes(specs []indexSpec) {
+	indexes := make([]*secondaryIndex, len(specs))
+	for i, spec := range specs {
+		for _, ix := range e.indexes {
+			if ix.name == spec.name && slices.Equal(ix.fields, spec.fields) {
+				indexes[i] = ix
+			}
+		}
+		if indexes[i] == nil {
+			indexes[i] = newSecondaryIndex(spec)
+			for id, b := range e.records {
+				indexes[i].put(id, decodeIndexed(b))
+			}
+		}
+	}
+	e.indexes = indexes
+}
+
+// reindex sets the keys of a record in every index from its stored bytes, or
+// removes them when next is nil (the record is deleted).
+func (e *serializedEngine) reindex(id string, next []byte) {
+	if len(e.indexes) == 0 {
+		return
+	}
+	var data map[string]any
+	if next != nil {
+		data = decodeIndexed(next)
+	}
+	for _, ix := range e.indexes {
+		if next == nil {
+			ix.remove(id)
+		} else {
+			ix.put(id, data)
+		}
+	}
+}
+
+// decodeIndexed decodes stored bytes for indexing. Stored data that is not a
+// JSON object, which queries cannot read either, has no indexed fields.
+func decodeIndexed(b []byte) map[string]any {
+	var data map[string]any
+	_ = json.Unmarshal(b, &data)
+	return data
+}
+
+// candidateRows returns the rows the best index lookup for the condition
+// selects (see planLookup), or ok=false when no index answers it.
+func (e *serializedEngine) candidateRows(condition dal.Condition) ([]engineRow, bool, error) {
+	l, ok := e.planLookup(condition)
+	if !ok {
+		return nil, false, nil
+	}
+	ids := l.ids()
+	rows := make([]engineRow, 0, len(ids))
+	for _, id := range ids {
+		row, err := e.row(id)
+		if err != nil {
+			return nil, false, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, true, nil
+}
+
+func (e *serializedEngine) candidateCount(condition dal.Condition) (string, int, bool, error) {
+	l, ok := e.planLookup(condition)
+	if !ok {
+		return "", 0, false, nil
+	}
+	return l.index.name, len(l.ids()), true, nil
+}
+
+// orderedIndex counts the index entries in range, which are as many as the
+// rows unless an indexed field holds an array.
+func (e *serializedEngine) orderedIndex(orderBy []dal.OrderExpression, condition dal.Condition) (string, int, bool) {
+	ix, prefix, ok := e.planOrdered(orderBy, condition)
+	if !ok {
+		return "", 0, false
+	}
+	lo, hi := prefixRange(ix.sorted(), prefix)
+	return ix.name, hi - lo, true
+}
+
+func (e *serializedEngine) orderedRows(orderBy []dal.OrderExpression, condition dal.Condition, n int, keep func(engineRow) (bool, error)) ([]engineRow, bool, error) {
+	ix, prefix, ok := e.planOrdered(orderBy, condition)
+	if !ok {
+		return nil, false, nil
+	}
+	var rows []engineRow
+	err := ix.scanOrdered(prefix, len(orderBy), orderBy[0].Descending(), func(id string) (bool, error) {
+		row, err := e.row(id)
+		if err != nil {
+			return false, err
+		}
+		kept, err := keep(row)
+		if err != nil {
+			return false, err
+		}
+		if kept {
+			rows = append(rows, row)
+		}
+		return len(rows) < n, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return rows, true, nil
+}
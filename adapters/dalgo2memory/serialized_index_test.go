@@ -0,0 +1,270 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type member struct {
+	Team string   `json:"team"`
+	Age  int      `json:"age"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func indexedMembersDB() *database {
+	return NewDB(WithSchema(true, WithCollection[member]("members", nil, WithSerializedStorage(
+		WithIndex("byTeamAge", "team", "age"),
+		WithIndex("byAge", "age"),
+		WithIndex("byTags", "tags"),
+	)))).(*database)
+}
+
+func seedMembers(t *testing.T, dbs ...*database) {
+	t.Helper()
+	members := map[string]member{
+		"m1": {Team: "a", Age: 31, Tags: []string{"go", "sql"}},
+		"m2": {Team: "b", Age: 25},
+		"m3": {Team: "a", Age: 25, Tags: []string{"rust"}},
+		"m4": {Team: "a", Age: 40},
+		"m5": {Team: "c", Age: 19, Tags: []string{"go"}},
+		"m6": {Team: "b", Age: 31},
+	}
+	for _, db := range dbs {
+		for id, m := range members {
+			require.NoError(t, db.Set(context.Background(), record.NewRecordWithData(record.NewKeyWithID("members", id), &m)))
+		}
+	}
+}
+
+func memberIDs(t *testing.T, executor dal.QueryExecutor, q dal.Query) []string {
+	t.Helper()
+	reader, err := executor.ExecuteQueryToRecordsReader(context.Background(), q)
+	require.NoError(t, err)
+	var ids []string
+	for _, r := range readAll(t, reader) {
+		ids = append(ids, fmt.Sprint(r.Key().ID))
+	}
+	return ids
+}
+
+func TestSerializedIndex_Lookups(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedMembersDB(), NewDB().(*database)
+	seedMembers(t, db, plain)
+	members := func() *dal.QueryBuilder { return dal.From(dal.NewRootCollectionRef("members", "")).NewQuery() }
+	teamA := dal.WhereField("team", dal.Equal, "a")
+
+	for _, tt := range []struct {
+		name  string
+		query dal.Query
+		index string
+		rows  int
+		ids   []string
+	}{
+		{"equality on a leading field", members().Where(teamA).SelectKeysOnly(reflect.String), "byTeamAge", 3, []string{"m1", "m3", "m4"}},
+		{"equality and range", members().Where(teamA, dal.WhereField("age", dal.GreaterOrEqual, 31)).SelectKeysOnly(reflect.String),
+			"byTeamAge", 2, []string{"m1", "m4"}},
+		{"range", members().WhereField("age", dal.LessThen, 31).SelectKeysOnly(reflect.String), "byAge", 3, []string{"m2", "m3", "m5"}},
+		{"between", members().Where(dal.NewComparison(dal.Field("age"), dal.Between, dal.Array{Value: []any{25, 31}})).SelectKeysOnly(reflect.String),
+			"byAge", 4, []string{"m1", "m2", "m3", "m6"}},
+		{"in", members().Where(dal.NewComparison(dal.Field("tags"), dal.In, dal.Array{Value: []any{"go", "rust"}})).SelectKeysOnly(reflect.String),
+			"byTags", 3, []string{"m1", "m3", "m5"}},
+		{"array contains", members().Where(dal.NewComparison(dal.Constant{Value: "sql"}, dal.In, dal.Field("tags"))).SelectKeysOnly(reflect.String),
+			"byTags", 1, []string{"m1"}},
+		{"an integer constant finds a decoded float", members().WhereField("age", dal.Equal, 25).SelectKeysOnly(reflect.String),
+			"byAge", 2, []string{"m2", "m3"}},
+		{"a string bound keeps every number", members().WhereField("age", dal.GreaterThen, "3").SelectKeysOnly(reflect.String),
+			"byAge", 6, []string{"m1", "m4", "m6"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ids, memberIDs(t, db, tt.query))
+			assert.Equal(t, memberIDs(t, plain, tt.query), memberIDs(t, db, tt.query), "same result as a scan")
+			plan, err := dal.ExplainQuery(ctx, db, tt.query)
+			require.NoError(t, err)
+			lookups := plan.Find(dal.PlanIndexLookup)
+			require.Len(t, lookups, 1, plan.String())
+			assert.Equal(t, tt.index, lookups[0].Index)
+			assert.Equal(t, tt.rows, lookups[0].EstimatedRows)
+		})
+	}
+
+	t.Run("no index", func(t *testing.T) {
+		q := members().WhereField("age", dal.NotEqual, 25).SelectKeysOnly(reflect.String)
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Find(dal.PlanIndexLookup))
+		assert.Equal(t, []string{"m1", "m4", "m5", "m6"}, memberIDs(t, db, q))
+	})
+}
+
+func TestSerializedIndex_OrderByLimit(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedMembersDB(), NewDB().(*database)
+	seedMembers(t, db, plain)
+	members := func() *dal.QueryBuilder { return dal.From(dal.NewRootCollectionRef("members", "")).NewQuery() }
+
+	q := members().WhereField("team", dal.Equal, "a").OrderBy(dal.DescendingField("age")).Limit(2).SelectKeysOnly(reflect.String)
+	plan, err := dal.ExplainQuery(ctx, db, q)
+	require.NoError(t, err)
+	assert.Equal(t, `INDEX_LOOKUP members USING byTeamAge WHERE team = 'a' BY age DESC LIMIT 2 (rows: 2)
+`, plan.String())
+	assert.Empty(t, plan.Find(dal.PlanLimit), "the limit is pushed down to the read")
+	plan, err = dal.ExplainQuery(ctx, db, members().OrderBy(dal.AscendingField("age")).Offset(1).Limit(3).SelectKeysOnly(reflect.String))
+	require.NoError(t, err)
+	assert.Equal(t, dal.PlanIndexLookup, plan.Root.Operation)
+	assert.Equal(t, 3, plan.Root.Limit)
+	assert.Equal(t, 1, plan.Root.Offset)
+	assert.Equal(t, []string{"m4", "m1"}, memberIDs(t, db, q))
+
+	for _, q := range []dal.Query{
+		members().OrderBy(dal.AscendingField("age")).Offset(1).Limit(3).SelectKeysOnly(reflect.String),
+		members().OrderBy(dal.DescendingField("age")).Limit(3).SelectKeysOnly(reflect.String),
+		members().WhereField("team", dal.Equal, "b").OrderBy(dal.AscendingField("age")).Limit(1).SelectKeysOnly(reflect.String),
+		members().WhereField("team", dal.NotEqual, "a").OrderBy(dal.AscendingField("age")).Limit(2).SelectKeysOnly(reflect.String),
+		members().OrderBy(dal.DescendingField("team"), dal.DescendingField("age")).Limit(4).SelectKeysOnly(reflect.String),
+	} {
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Find(dal.PlanSort), plan.String())
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), plan.String())
+	}
+
+	for name, q := range map[string]dal.Query{
+		"no limit":         members().OrderBy(dal.AscendingField("age")).SelectKeysOnly(reflect.String),
+		"mixed directions": members().OrderBy(dal.AscendingField("team"), dal.DescendingField("age")).Limit(2).SelectKeysOnly(reflect.String),
+		"not indexed":      members().OrderBy(dal.AscendingField("nick")).Limit(2).SelectKeysOnly(reflect.String),
+		"an array field":   members().OrderBy(dal.AscendingField("tags")).Limit(2).SelectKeysOnly(reflect.String),
+	} {
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Len(t, plan.Find(dal.PlanSort), 1, name)
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), name)
+	}
+}
+
+func TestSerializedIndex_Maintained(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedMembersDB(), NewDB().(*database)
+	seedMembers(t, db, plain)
+	teamA := dal.From(dal.NewRootCollectionRef("members", "")).NewQuery().
+		WhereField("team", dal.Equal, "a").SelectKeysOnly(reflect.String)
+
+	for _, d := range []*database{db, plain} {
+		require.NoError(t, d.Update(ctx, record.NewKeyWithID("members", "m2"), []update.Update{update.ByFieldName("team", "a")}))
+		require.NoError(t, d.Delete(ctx, record.NewKeyWithID("members", "m3")))
+		require.NoError(t, d.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("members", "m4"), &member{Team: "c", Age: 40})))
+	}
+	assert.Equal(t, []string{"m1", "m2"}, memberIDs(t, db, teamA))
+	assert.Equal(t, memberIDs(t, plain, teamA), memberIDs(t, db, teamA))
+
+	errRollback := errors.New("rollback")
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		require.NoError(t, tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("members", "m7"), &member{Team: "a"})))
+		require.NoError(t, tx.Delete(ctx, record.NewKeyWithID("members", "m1")))
+		assert.Equal(t, []string{"m2", "m7"}, memberIDs(t, tx, teamA))
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	assert.Equal(t, []string{"m1", "m2"}, memberIDs(t, db, teamA), "a rolled back transaction restores the index")
+
+	checkpoint, err := NewBranchingProvider().Capture(ctx, db)
+	require.NoError(t, err)
+	branch := mustBranch(t, checkpoint)
+	defer closeTestBranch(t, branch)
+	require.NoError(t, branch.DB().(*database).Delete(ctx, record.NewKeyWithID("members", "m2")))
+	assert.Equal(t, []string{"m1"}, memberIDs(t, branch.DB(), teamA))
+	assert.Equal(t, []string{"m1", "m2"}, memberIDs(t, db, teamA), "a branch has its own index")
+	plan, err := dal.ExplainQuery(ctx, branch.DB(), teamA)
+	require.NoError(t, err)
+	assert.Len(t, plan.Find(dal.PlanIndexLookup), 1)
+}
+
+// TestSerializedIndex_MatchesScan runs random queries over records whose
+// indexed fields hold values of every kind (a and b) or only numbers (n, so
+// that an index can order by it), comparing each result with that of an
+// unindexed collection.
+func TestSerializedIndex_MatchesScan(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(true, WithCollection[map[string]any]("things", nil, WithSerializedStorage(
+		WithIndex("byA", "a"),
+		WithIndex("byBA", "b", "a"),
+		WithIndex("byN", "n"),
+		WithIndex("byBN", "b", "n"),
+	)))).(*database)
+	plain := NewDB().(*database)
+	rnd := rand.New(rand.NewSource(1))
+	values := []any{nil, true, false, 0, 1, 2.5, -3, "", "a", "b", "10", []any{1, "a"}, []any{}, map[string]any{"x": 1}}
+	randomValue := func() any { return values[rnd.Intn(len(values))] }
+	for i := range 200 {
+		data := map[string]any{}
+		for _, field := range []string{"a", "b"} {
+			if rnd.Intn(6) > 0 {
+				data[field] = randomValue()
+			}
+		}
+		data["n"] = rnd.Intn(20)
+		key := record.NewKeyWithID("things", fmt.Sprintf("t%03d", i))
+		for _, d := range []*database{db, plain} {
+			require.NoError(t, d.Set(ctx, record.NewRecordWithData(key, data)))
+		}
+	}
+	scalar := func() any {
+		for {
+			if v := randomValue(); reflect.TypeOf(v) == nil || reflect.TypeOf(v).Comparable() {
+				return v
+			}
+		}
+	}
+	operators := []dal.Operator{dal.Equal, dal.GreaterThen, dal.GreaterOrEqual, dal.LessThen, dal.LessOrEqual}
+	randomCondition := func(field string) dal.Condition {
+		switch rnd.Intn(4) {
+		case 0:
+			return dal.NewComparison(dal.Field(field), dal.In, dal.Array{Value: []any{scalar(), scalar()}})
+		case 1:
+			return dal.NewComparison(dal.Field(field), dal.Between, dal.Array{Value: []any{scalar(), scalar()}})
+		default:
+			return dal.WhereField(field, operators[rnd.Intn(len(operators))], scalar())
+		}
+	}
+	for i := range 300 {
+		var conditions []dal.Condition
+		for _, field := range []string{"a", "b", "n"} {
+			if rnd.Intn(3) > 0 {
+				conditions = append(conditions, randomCondition(field))
+			}
+		}
+		var qb dal.IQueryBuilder = dal.From(dal.NewRootCollectionRef("things", "")).NewQuery()
+		if len(conditions) > 0 {
+			qb = qb.Where(conditions...)
+		}
+		switch rnd.Intn(4) {
+		case 0:
+			qb = qb.OrderBy(dal.AscendingField("a")).Limit(1 + rnd.Intn(5))
+		case 1:
+			qb = qb.OrderBy(dal.AscendingField("n")).Offset(rnd.Intn(3)).Limit(1 + rnd.Intn(5))
+		case 2:
+			qb = qb.OrderBy(dal.DescendingField("n")).Limit(1 + rnd.Intn(5))
+		}
+		q := qb.SelectKeysOnly(reflect.String)
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), "query %d: %v", i, q)
+	}
+}
+
+func TestWithIndex(t *testing.T) {
+	assert.Panics(t, func() { WithIndex("empty") })
+	var cfg serializedConfig
+	WithIndex("i", "a")(&cfg)
+	WithIndex("j", "b")(&cfg)
+	WithIndex("i", "c", "d")(&cfg)
+	assert.Equal(t, []indexSpec{{name: "i", fields: []string{"c", "d"}}, {name: "j", fields: []string{"b"}}}, cfg.indexes)
+}
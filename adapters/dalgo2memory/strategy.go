@@ -4,10 +4,9 @@ import "reflect"
 
 // SlotSet is a set of per-row slot indices. The columnar engine assigns each
 // live record a stable slot shared across all of a collection's column slices;
-// a ColumnStrategy's equality read side returns the slots whose column equals a
-// queried value. A set (rather than a slice) is returned so that, when the
-// adapter grows multi-predicate AND WHERE, per-predicate sets can be
-// intersected.
+// a ColumnStrategy's read side returns the slots whose column equals a queried
+// value (or lies in a queried range). A set (rather than a slice) is returned
+// so that the sets of the conjuncts of an AND WHERE can be intersected.
 type SlotSet map[int]struct{}
 
 // ColumnStrategy backs a single column of a columnar collection. It is exported
@@ -33,6 +32,42 @@ type ColumnStrategy interface {
 	EqualSlots(value any) (slots SlotSet, ok bool)
 }
 
+// Bound is one end of the range a RangeStrategy is asked for. Value is a
+// float64 for a numeric column and a string for a string column.
+type Bound struct {
+	Value     any
+	Inclusive bool
+}
+
+// RangeStrategy is the optional extension of a ColumnStrategy that answers
+// range predicates (>, >=, <, <=, Between) on its column. The engine only
+// consults it for a column of a Go numeric or string type (without a custom
+// JSON or text encoding), whose values the query path orders numerically or
+// bytewise, and only with bounds of the column's kind.
+type RangeStrategy interface {
+	ColumnStrategy
+
+	// RangeSlots returns the live slots whose column lies between lower and
+	// upper, with ok=true; a nil bound leaves that end of the range open.
+	// Returning ok=false signals "no opinion": the engine falls back to
+	// scanning.
+	RangeSlots(lower, upper *Bound) (slots SlotSet, ok bool)
+}
+
+// OrderedStrategy is the optional extension of a ColumnStrategy that iterates
+// the live slots in column order, so that a query ordered by the column with a
+// LIMIT reads only the rows up to the end of its page. The engine consults it
+// for the same columns as a RangeStrategy.
+type OrderedStrategy interface {
+	ColumnStrategy
+
+	// OrderedSlots calls yield with the live slots grouped by column value, the
+	// groups in ascending order of the value (descending when descending is
+	// true), until yield returns false. Returning ok=false, before calling
+	// yield, signals "no opinion": the engine sorts the rows itself.
+	OrderedSlots(descending bool, yield func(slots []int) bool) (ok bool)
+}
+
 // typedSliceStrategy is the default per-column strategy. It does not keep its
 // own copy of the column's values; instead it scans the engine's typed column
 // slice on read, treating a slot as a candidate only when it is live. For a
@@ -0,0 +1,148 @@
+package dalgo2memory
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// bitmapValue is one distinct value of a bitmapStrategy's column and the
+// bitmap of the slots holding it.
+type bitmapValue struct {
+	key   any
+	slots *roaring.Bitmap
+}
+
+// bitmapStrategy keeps a roaring bitmap of slots per distinct column value,
+// which suits a column with few distinct values: an equality reads one
+// bitmap, a range and an ordered scan walk the distinct values in order. The
+// distinct values are sorted lazily, on the first read after a write adds or
+// drops one.
+type bitmapStrategy struct {
+	bySlot  map[int]any
+	byValue map[string]*bitmapValue // by valueKey of the key
+	kinds   [valueKinds]int         // the number of slots whose key is of each kind
+
+	// mu guards sorting the distinct values on a read, as reads run
+	// concurrently; writes are exclusive with reads and need no lock.
+	mu     sync.Mutex
+	values []*bitmapValue // sorted by key; nil once a write adds or drops one
+}
+
+var _ RangeStrategy = (*bitmapStrategy)(nil)
+var _ OrderedStrategy = (*bitmapStrategy)(nil)
+
+// NewBitmapStrategy returns a ColumnStrategy that keeps a roaring bitmap of
+// slots per distinct value of a columnar column, for WithColumnStrategy, as
+// suits a low-cardinality column such as a status or a flag. Like
+// NewSortedIndexStrategy it answers equality and range predicates and reads
+// the rows of a query ordered by the column with a LIMIT in order. A strategy
+// backs a single column of a single collection.
+func NewBitmapStrategy() ColumnStrategy {
+	return &bitmapStrategy{bySlot: make(map[int]any), byValue: make(map[string]*bitmapValue)}
+}
+
+func (s *bitmapStrategy) SetValue(slot int, value any) {
+	s.ClearValue(slot)
+	key := strategyKey(value)
+	s.bySlot[slot] = key
+	s.kinds[kindOf(key)]++
+	vk := valueKey(key)
+	v, ok := s.byValue[vk]
+	if !ok {
+		v = &bitmapValue{key: key, slots: roaring.New()}
+		s.byValue[vk] = v
+		s.values = nil
+	}
+	v.slots.Add(uint32(slot))
+}
+
+func (s *bitmapStrategy) ClearValue(slot int) {
+	key, ok := s.bySlot[slot]
+	if !ok {
+		return
+	}
+	s.kinds[kindOf(key)]--
+	delete(s.bySlot, slot)
+	vk := valueKey(key)
+	v := s.byValue[vk]
+	v.slots.Remove(uint32(slot))
+	if v.slots.IsEmpty() {
+		delete(s.byValue, vk)
+		s.values = nil
+	}
+}
+
+// sorted returns the distinct values, sorting them if a write added or
+// dropped one since the last read.
+func (s *bitmapStrategy) sorted() []*bitmapValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make([]*bitmapValue, 0, len(s.byValue))
+		for _, v := range s.byValue {
+			s.values = append(s.values, v)
+		}
+		slices.SortFunc(s.values, func(a, b *bitmapValue) int { return compareIndexValues(a.key, b.key) })
+	}
+	return s.values
+}
+
+func (s *bitmapStrategy) EqualSlots(value any) (SlotSet, bool) {
+	key, ok := equalityKey(value)
+	if !ok {
+		return nil, false
+	}
+	slots := make(SlotSet)
+	if v, ok := s.byValue[valueKey(key)]; ok {
+		addBitmap(slots, v.slots)
+	}
+	return slots, true
+}
+
+// RangeSlots returns the slots of the values of the bounds' kind that are in
+// range and, as the query path compares values of different kinds by their
+// printed form, of every value of another kind.
+func (s *bitmapStrategy) RangeSlots(lower, upper *Bound) (SlotSet, bool) {
+	kind, ok := rangeBoundsKind(lower, upper)
+	if !ok {
+		return nil, false
+	}
+	slots := make(SlotSet)
+	for _, v := range s.sorted() {
+		if kind < 0 || kindOf(v.key) != kind || inRange(v.key, lower, upper) {
+			addBitmap(slots, v.slots)
+		}
+	}
+	return slots, true
+}
+
+// OrderedSlots has an opinion only while every key is a number, or every one
+// a string, whose order the distinct values are sorted in.
+func (s *bitmapStrategy) OrderedSlots(descending bool, yield func(slots []int) bool) bool {
+	if !orderedKinds(s.kinds, len(s.bySlot)) {
+		return false
+	}
+	values := s.sorted()
+	for i := range values {
+		v := values[i]
+		if descending {
+			v = values[len(values)-1-i]
+		}
+		slots := make([]int, 0, v.slots.GetCardinality())
+		for it := v.slots.Iterator(); it.HasNext(); {
+			slots = append(slots, int(it.Next()))
+		}
+		if !yield(slots) {
+			break
+		}
+	}
+	return true
+}
+
+func addBitmap(slots SlotSet, bitmap *roaring.Bitmap) {
+	for it := bitmap.Iterator(); it.HasNext(); {
+		slots[int(it.Next())] = struct{}{}
+	}
+}
@@ -0,0 +1,256 @@
+package dalgo2memory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type playerName string
+
+type player struct {
+	Team  string     `json:"team"`
+	Score int        `json:"score"`
+	Name  playerName `json:"name"`
+	Ratio float32    `json:"ratio"`
+}
+
+func indexedPlayersDB() *database {
+	return NewDB(WithSchema(true, WithCollection[player]("players", nil, WithColumnarStorage(
+		WithColumnStrategy("team", NewBitmapStrategy()),
+		WithColumnStrategy("score", NewSortedIndexStrategy()),
+		WithColumnStrategy("name", NewSortedIndexStrategy()),
+		WithColumnStrategy("ratio", NewSortedIndexStrategy()),
+	)))).(*database)
+}
+
+func seedPlayers(t *testing.T, dbs ...*database) {
+	t.Helper()
+	players := map[string]player{
+		"p1": {Team: "red", Score: 30, Name: "ann"},
+		"p2": {Team: "blue", Score: 10, Name: "bob"},
+		"p3": {Team: "red", Score: 20, Name: "cid"},
+		"p4": {Team: "red", Score: 30, Name: "dan"},
+		"p5": {Team: "blue", Score: 50, Name: "eve"},
+	}
+	for _, db := range dbs {
+		for id, p := range players {
+			require.NoError(t, db.Set(context.Background(), record.NewRecordWithData(record.NewKeyWithID("players", id), &p)))
+		}
+	}
+}
+
+func TestColumnStrategies_Lookups(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedPlayersDB(), NewDB().(*database)
+	seedPlayers(t, db, plain)
+	players := func() *dal.QueryBuilder { return dal.From(dal.NewRootCollectionRef("players", "")).NewQuery() }
+
+	for _, tt := range []struct {
+		name  string
+		query dal.Query
+		index string
+		rows  int
+		ids   []string
+	}{
+		{"bitmap equality", players().WhereField("team", dal.Equal, "blue").SelectKeysOnly(reflect.String), "team", 2, []string{"p2", "p5"}},
+		{"range", players().WhereField("score", dal.GreaterThen, 20).SelectKeysOnly(reflect.String), "score", 3, []string{"p1", "p4", "p5"}},
+		{"between", players().Where(dal.NewComparison(dal.Field("score"), dal.Between, dal.Array{Value: []any{10, 20}})).SelectKeysOnly(reflect.String), "score", 2, []string{"p2", "p3"}},
+		{"range on a custom string type", players().WhereField("name", dal.LessThen, "cid").SelectKeysOnly(reflect.String), "name", 2, []string{"p1", "p2"}},
+		{"intersected conjuncts", players().Where(dal.WhereField("team", dal.Equal, "red"), dal.WhereField("score", dal.LessOrEqual, 25)).SelectKeysOnly(reflect.String), "team, score", 1, []string{"p3"}},
+		{"bound of another kind", players().WhereField("score", dal.GreaterThen, "2").SelectKeysOnly(reflect.String), "", 5, []string{"p1", "p3", "p4", "p5"}},
+		{"float32 column", players().WhereField("ratio", dal.GreaterOrEqual, 0).SelectKeysOnly(reflect.String), "", 5, []string{"p1", "p2", "p3", "p4", "p5"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := dal.ExplainQuery(ctx, db, tt.query)
+			require.NoError(t, err)
+			if tt.index == "" {
+				assert.Empty(t, plan.Find(dal.PlanIndexLookup))
+				assert.Equal(t, tt.rows, plan.Find(dal.PlanScan)[0].EstimatedRows)
+			} else {
+				lookups := plan.Find(dal.PlanIndexLookup)
+				require.Len(t, lookups, 1)
+				assert.Equal(t, tt.index, lookups[0].Index)
+				assert.Equal(t, tt.rows, lookups[0].EstimatedRows)
+			}
+			assert.Equal(t, tt.ids, memberIDs(t, db, tt.query))
+			assert.Equal(t, memberIDs(t, plain, tt.query), memberIDs(t, db, tt.query))
+		})
+	}
+}
+
+func TestColumnStrategies_OrderByLimit(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedPlayersDB(), NewDB().(*database)
+	seedPlayers(t, db, plain)
+	players := func() *dal.QueryBuilder { return dal.From(dal.NewRootCollectionRef("players", "")).NewQuery() }
+
+	q := players().WhereField("team", dal.Equal, "red").OrderBy(dal.DescendingField("score")).Limit(2).SelectKeysOnly(reflect.String)
+	plan, err := dal.ExplainQuery(ctx, db, q)
+	require.NoError(t, err)
+	assert.Equal(t, `INDEX_LOOKUP players USING score WHERE team = 'red' BY score DESC LIMIT 2 (rows: 2)
+`, plan.String())
+	assert.Equal(t, []string{"p1", "p4"}, memberIDs(t, db, q), "ties are ordered by id")
+
+	for _, q := range []dal.Query{
+		players().OrderBy(dal.AscendingField("score"), dal.DescendingField("name")).Offset(1).Limit(3).SelectKeysOnly(reflect.String),
+		players().OrderBy(dal.AscendingField("team"), dal.AscendingField("score")).Limit(3).SelectKeysOnly(reflect.String),
+		players().OrderBy(dal.DescendingField("name")).Limit(1).SelectKeysOnly(reflect.String),
+		players().OrderBy(dal.AscendingField("score"), dal.AscendingField("team")).Limit(2).SelectKeysOnly(reflect.String),
+	} {
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Find(dal.PlanSort), q.String())
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), q.String())
+	}
+
+	for _, q := range []dal.Query{
+		players().OrderBy(dal.AscendingField("score")).SelectKeysOnly(reflect.String),
+		players().OrderBy(dal.AscendingField("ratio")).Limit(2).SelectKeysOnly(reflect.String),
+	} {
+		plan, err := dal.ExplainQuery(ctx, db, q)
+		require.NoError(t, err)
+		assert.NotEmpty(t, plan.Find(dal.PlanSort), q.String())
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), q.String())
+	}
+}
+
+func TestColumnStrategies_MixedKinds(t *testing.T) {
+	for _, newStrategy := range []func() ColumnStrategy{NewSortedIndexStrategy, NewBitmapStrategy} {
+		s := newStrategy()
+		s.SetValue(0, 1)
+		s.SetValue(1, 2.5)
+		s.SetValue(2, int64(2))
+		s.SetValue(3, float32(0.5))
+		s.SetValue(4, 3)
+		s.ClearValue(4)
+
+		var groups [][]int
+		ordered := s.(OrderedStrategy).OrderedSlots(true, func(slots []int) bool {
+			groups = append(groups, slots)
+			return len(groups) < 3
+		})
+		assert.True(t, ordered)
+		assert.Equal(t, [][]int{{1}, {2}, {0}}, groups)
+
+		slots, ok := s.(RangeStrategy).RangeSlots(&Bound{Value: 1.0, Inclusive: false}, &Bound{Value: 2.0, Inclusive: true})
+		assert.True(t, ok)
+		assert.Equal(t, SlotSet{2: {}}, slots)
+		slots, ok = s.EqualSlots(2.0)
+		assert.True(t, ok)
+		assert.Equal(t, SlotSet{2: {}}, slots)
+
+		s.SetValue(5, "x")
+		s.SetValue(6, []any{1})
+		assert.False(t, s.(OrderedStrategy).OrderedSlots(false, func([]int) bool { return true }), "mixed kinds have no order")
+		slots, ok = s.(RangeStrategy).RangeSlots(nil, &Bound{Value: 1.0, Inclusive: true})
+		assert.True(t, ok)
+		assert.Equal(t, SlotSet{0: {}, 3: {}, 5: {}, 6: {}}, slots, "values of other kinds are candidates")
+		_, ok = s.(RangeStrategy).RangeSlots(&Bound{Value: 1.0}, &Bound{Value: "z"})
+		assert.False(t, ok)
+		_, ok = s.EqualSlots([]any{1})
+		assert.False(t, ok)
+	}
+}
+
+func TestColumnStrategies_Maintained(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedPlayersDB(), NewDB().(*database)
+	seedPlayers(t, db, plain)
+	q := dal.From(dal.NewRootCollectionRef("players", "")).NewQuery().
+		WhereField("score", dal.GreaterOrEqual, 30).OrderBy(dal.AscendingField("score")).Limit(10).SelectKeysOnly(reflect.String)
+	check := func(msg string) {
+		t.Helper()
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), msg)
+	}
+	for _, d := range []*database{db, plain} {
+		require.NoError(t, d.Update(ctx, record.NewKeyWithID("players", "p2"), []update.Update{update.ByFieldName("score", 40)}))
+		require.NoError(t, d.Delete(ctx, record.NewKeyWithID("players", "p5")))
+	}
+	check("after update and delete")
+	for _, d := range []*database{db, plain} {
+		for _, id := range []string{"p1", "p3", "p4"} {
+			require.NoError(t, d.Delete(ctx, record.NewKeyWithID("players", id)))
+		}
+	}
+	check("after compaction")
+
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		p := player{Team: "red", Score: 99}
+		require.NoError(t, tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("players", "p9"), &p)))
+		return fmt.Errorf("rolled back")
+	})
+	require.Error(t, err)
+	check("after a rollback")
+}
+
+func TestColumnStrategies_MatchScan(t *testing.T) {
+	ctx := context.Background()
+	db, plain := indexedPlayersDB(), NewDB().(*database)
+	rnd := rand.New(rand.NewSource(1))
+	teams := []string{"red", "blue", "green", ""}
+	names := []playerName{"a", "b", "ab", "B", ""}
+	for i := range 300 {
+		key := record.NewKeyWithID("players", fmt.Sprintf("p%03d", rnd.Intn(150)))
+		if i%7 == 0 {
+			for _, d := range []*database{db, plain} {
+				require.NoError(t, d.Delete(ctx, key))
+			}
+			continue
+		}
+		p := player{Team: teams[rnd.Intn(len(teams))], Score: rnd.Intn(20) - 5, Name: names[rnd.Intn(len(names))]}
+		for _, d := range []*database{db, plain} {
+			require.NoError(t, d.Set(ctx, record.NewRecordWithData(key, &p)))
+		}
+	}
+	operators := []dal.Operator{dal.Equal, dal.GreaterThen, dal.GreaterOrEqual, dal.LessThen, dal.LessOrEqual}
+	constant := func(field string) any {
+		switch field {
+		case "team":
+			return teams[rnd.Intn(len(teams))]
+		case "name":
+			return string(names[rnd.Intn(len(names))])
+		}
+		return []any{rnd.Intn(20) - 5, float64(rnd.Intn(40))/2 - 5, "3"}[rnd.Intn(3)]
+	}
+	fields := []string{"team", "score", "name"}
+	for i := range 300 {
+		var conditions []dal.Condition
+		for _, field := range fields {
+			switch rnd.Intn(4) {
+			case 0:
+				conditions = append(conditions, dal.NewComparison(dal.Field(field), dal.Between, dal.Array{Value: []any{constant(field), constant(field)}}))
+			case 1, 2:
+				conditions = append(conditions, dal.WhereField(field, operators[rnd.Intn(len(operators))], constant(field)))
+			}
+		}
+		var qb dal.IQueryBuilder = dal.From(dal.NewRootCollectionRef("players", "")).NewQuery()
+		if len(conditions) > 0 {
+			qb = qb.Where(conditions...)
+		}
+		if rnd.Intn(3) > 0 {
+			orderBy := slices.Clone(fields)
+			rnd.Shuffle(len(orderBy), func(i, j int) { orderBy[i], orderBy[j] = orderBy[j], orderBy[i] })
+			var keys []dal.OrderExpression
+			for _, field := range orderBy[:1+rnd.Intn(3)] {
+				if rnd.Intn(2) == 0 {
+					keys = append(keys, dal.AscendingField(field))
+				} else {
+					keys = append(keys, dal.DescendingField(field))
+				}
+			}
+			qb = qb.OrderBy(keys...).Offset(rnd.Intn(3)).Limit(1 + rnd.Intn(10))
+		}
+		q := qb.SelectKeysOnly(reflect.String)
+		assert.Equal(t, memberIDs(t, plain, q), memberIDs(t, db, q), "query %d: %v", i, q)
+	}
+}
@@ -0,0 +1,268 @@
+package dalgo2memory
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// strategyKey returns the value the built-in index strategies keep for a
+// column value: its JSON-normalized form, the one the query path compares
+// (nil, a bool, a float64, a string, or an array or object).
+func strategyKey(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string:
+		return v
+	case float64:
+		return v + 0 // -0 is indexed as 0, which it equals
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	v = normalizeConstant(v)
+	if f, ok := v.(float64); ok {
+		return f + 0
+	}
+	return v
+}
+
+// equalityKey returns the key an EqualSlots lookup searches for, with
+// ok=false for an array or object, which the index does not look up.
+func equalityKey(value any) (any, bool) {
+	key := strategyKey(value)
+	return key, kindOf(key) != kindOther
+}
+
+// rangeBoundsKind returns the kind of the bounds of a RangeSlots lookup, with
+// ok=false unless every given bound is a number, or every one a string.
+func rangeBoundsKind(lower, upper *Bound) (int, bool) {
+	kind := -1
+	for _, b := range []*Bound{lower, upper} {
+		if b == nil {
+			continue
+		}
+		k := kindOf(b.Value)
+		if k != kindNumber && k != kindString || kind >= 0 && k != kind {
+			return 0, false
+		}
+		kind = k
+	}
+	return kind, true
+}
+
+// inRange reports whether a key of the bounds' kind lies between them.
+func inRange(key any, lower, upper *Bound) bool {
+	if lower != nil {
+		if c := compareIndexValues(key, lower.Value); c < 0 || c == 0 && !lower.Inclusive {
+			return false
+		}
+	}
+	if upper != nil {
+		if c := compareIndexValues(key, upper.Value); c > 0 || c == 0 && !upper.Inclusive {
+			return false
+		}
+	}
+	return true
+}
+
+// slotEntry is one slot of a sortedIndexStrategy, with its column's key.
+type slotEntry struct {
+	key  any
+	slot int
+}
+
+func compareSlotEntries(a, b slotEntry) int {
+	if c := compareIndexValues(a.key, b.key); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.slot, b.slot)
+}
+
+// sortedIndexStrategy keeps the slots of a column sorted by value, answering
+// equality and ranges with a binary search and iterating the slots in column
+// order. Like a Serialized collection's secondary index, writes only record a
+// slot's key and mark it stale; the first read after them merges the stale
+// slots into the sorted entries.
+type sortedIndexStrategy struct {
+	bySlot map[int]any
+	kinds  [valueKinds]int // the number of keys of each kind
+
+	// mu guards merging the stale slots on a read, as reads run concurrently;
+	// writes are exclusive with reads and need no lock.
+	mu      sync.Mutex
+	entries []slotEntry // replaced, never modified, by a merge
+	stale   map[int]bool
+}
+
+var _ RangeStrategy = (*sortedIndexStrategy)(nil)
+var _ OrderedStrategy = (*sortedIndexStrategy)(nil)
+
+// NewSortedIndexStrategy returns a ColumnStrategy that keeps a columnar
+// column's slots sorted by value, for WithColumnStrategy. Besides equality it
+// answers range predicates (RangeStrategy) and reads the rows of a query
+// ordered by the column with a LIMIT in order (OrderedStrategy). A strategy
+// backs a single column of a single collection.
+func NewSortedIndexStrategy() ColumnStrategy {
+	return &sortedIndexStrategy{bySlot: make(map[int]any), stale: make(map[int]bool)}
+}
+
+func (s *sortedIndexStrategy) SetValue(slot int, value any) {
+	s.ClearValue(slot)
+	key := strategyKey(value)
+	s.bySlot[slot] = key
+	s.kinds[kindOf(key)]++
+	s.stale[slot] = true
+}
+
+func (s *sortedIndexStrategy) ClearValue(slot int) {
+	if key, ok := s.bySlot[slot]; ok {
+		s.kinds[kindOf(key)]--
+		delete(s.bySlot, slot)
+		s.stale[slot] = true
+	}
+}
+
+// sorted returns the entries, first merging in the slots written since the
+// last read.
+func (s *sortedIndexStrategy) sorted() []slotEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.stale) == 0 {
+		return s.entries
+	}
+	kept := make([]slotEntry, 0, len(s.bySlot))
+	for _, entry := range s.entries {
+		if !s.stale[entry.slot] {
+			kept = append(kept, entry)
+		}
+	}
+	var added []slotEntry
+	for slot := range s.stale {
+		if key, ok := s.bySlot[slot]; ok {
+			added = append(added, slotEntry{key: key, slot: slot})
+		}
+	}
+	slices.SortFunc(added, compareSlotEntries)
+	merged := make([]slotEntry, 0, len(kept)+len(added))
+	for len(kept) > 0 && len(added) > 0 {
+		if compareSlotEntries(kept[0], added[0]) <= 0 {
+			merged, kept = append(merged, kept[0]), kept[1:]
+		} else {
+			merged, added = append(merged, added[0]), added[1:]
+		}
+	}
+	merged = append(append(merged, kept...), added...)
+	s.entries = merged
+	clear(s.stale)
+	return merged
+}
+
+// searchEntries returns the index of the first entry for which after holds,
+// given that it holds for every entry after it.
+func searchEntries(entries []slotEntry, after func(key any) bool) int {
+	return sort.Search(len(entries), func(i int) bool { return after(entries[i].key) })
+}
+
+func (s *sortedIndexStrategy) EqualSlots(value any) (SlotSet, bool) {
+	key, ok := equalityKey(value)
+	if !ok {
+		return nil, false
+	}
+	entries := s.sorted()
+	lo := searchEntries(entries, func(k any) bool { return compareIndexValues(k, key) >= 0 })
+	hi := searchEntries(entries, func(k any) bool { return compareIndexValues(k, key) > 0 })
+	return slotsOf(entries[lo:hi]), true
+}
+
+// RangeSlots returns the slots whose keys of the bounds' kind are in range
+// and, as the query path compares values of different kinds by their printed
+// form, every slot whose key is of another kind.
+func (s *sortedIndexStrategy) RangeSlots(lower, upper *Bound) (SlotSet, bool) {
+	kind, ok := rangeBoundsKind(lower, upper)
+	if !ok {
+		return nil, false
+	}
+	entries := s.sorted()
+	if kind < 0 {
+		return slotsOf(entries), true
+	}
+	kindLo := searchEntries(entries, func(k any) bool { return kindOf(k) >= kind })
+	kindHi := searchEntries(entries, func(k any) bool { return kindOf(k) > kind })
+	from, to := kindLo, kindHi
+	if lower != nil {
+		from = kindLo + searchEntries(entries[kindLo:kindHi], func(k any) bool {
+			c := compareIndexValues(k, lower.Value)
+			return c > 0 || c == 0 && lower.Inclusive
+		})
+	}
+	if upper != nil {
+		to = from + searchEntries(entries[from:kindHi], func(k any) bool {
+			c := compareIndexValues(k, upper.Value)
+			return c > 0 || c == 0 && !upper.Inclusive
+		})
+	}
+	slots := slotsOf(entries[from:to])
+	for _, others := range [][]slotEntry{entries[:kindLo], entries[kindHi:]} {
+		for _, entry := range others {
+			slots[entry.slot] = struct{}{}
+		}
+	}
+	return slots, true
+}
+
+// OrderedSlots has an opinion only while every key is a number, or every one
+// a string, which the index orders the way the query path does.
+func (s *sortedIndexStrategy) OrderedSlots(descending bool, yield func(slots []int) bool) bool {
+	if !orderedKinds(s.kinds, len(s.bySlot)) {
+		return false
+	}
+	entries := s.sorted()
+	same := func(i, j int) bool { return compareIndexValues(entries[i].key, entries[j].key) == 0 }
+	group := func(from, to int) bool {
+		slots := make([]int, to-from)
+		for i, entry := range entries[from:to] {
+			slots[i] = entry.slot
+		}
+		return yield(slots)
+	}
+	if !descending {
+		for from := 0; from < len(entries); {
+			to := from + 1
+			for to < len(entries) && same(from, to) {
+				to++
+			}
+			if !group(from, to) {
+				break
+			}
+			from = to
+		}
+		return true
+	}
+	for to := len(entries); to > 0; {
+		from := to - 1
+		for from > 0 && same(from-1, to-1) {
+			from--
+		}
+		if !group(from, to) {
+			break
+		}
+		to = from
+	}
+	return true
+}
+
+// orderedKinds reports whether all of n keys counted by kind are numbers, or
+// all strings.
+func orderedKinds(kinds [valueKinds]int, n int) bool {
+	return kinds[kindNumber] == n || kinds[kindString] == n
+}
+
+func slotsOf(entries []slotEntry) SlotSet {
+	slots := make(SlotSet, len(entries))
+	for _, entry := range entries {
+		slots[entry.slot] = struct{}{}
+	}
+	return slots
+}
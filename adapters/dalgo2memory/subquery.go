@@ -0,0 +1,270 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// subqueryResult is a subquery condition already evaluated for one outer row;
+// bindSubqueries substitutes it for the condition so the ordinary WHERE
+// matchers can evaluate the rest of the tree.
+type subqueryResult bool
+
+func (v subqueryResult) String() string {
+	return strconv.FormatBool(bool(v))
+}
+
+// subqueryBinder evaluates the subquery conditions of one query's WHERE for
+// each outer row. Uncorrelated subqueries are executed once per query.
+type subqueryBinder struct {
+	ctx   context.Context
+	s     session
+	known map[string]bool // the outer query's source qualifiers
+	cache map[int]*subqueryRows
+}
+
+type subqueryRows struct {
+	exists bool
+	values []any // the selected column of an IN subquery
+}
+
+func newSubqueryBinder(ctx context.Context, s session, known map[string]bool) *subqueryBinder {
+	return &subqueryBinder{ctx: ctx, s: s, known: known, cache: make(map[int]*subqueryRows)}
+}
+
+// bind returns cond with each InSubquery and Exists condition replaced by its
+// subqueryResult for the outer row given by sources (per-source data, as the
+// join resolver takes). As in SQL, an IN is unknown, so that neither it nor
+// its negation matches, when its expression is NULL or when it finds no match
+// among values that include a NULL; an IN over no rows is false.
+func (b *subqueryBinder) bind(cond dal.Condition, sources map[string]map[string]any) (dal.Condition, error) {
+	n := 0
+	return b.bindCondition(cond, sources, &n)
+}
+
+func (b *subqueryBinder) bindCondition(cond dal.Condition, sources map[string]map[string]any, n *int) (dal.Condition, error) {
+	switch c := cond.(type) {
+	case dal.GroupCondition:
+		conds := c.Conditions()
+		bound := make([]dal.Condition, len(conds))
+		for i, sub := range conds {
+			var err error
+			if bound[i], err = b.bindCondition(sub, sources, n); err != nil {
+				return nil, err
+			}
+		}
+		return dal.NewGroupCondition(c.Operator(), bound...), nil
+	case dal.NotCondition:
+		bound, err := b.bindCondition(c.Condition(), sources, n)
+		if err != nil {
+			return nil, err
+		}
+		return dal.Not(bound), nil
+	case dal.ExistsCondition:
+		rows, err := b.run(c.Query(), sources, n, false)
+		if err != nil {
+			return nil, err
+		}
+		return subqueryResult(rows.exists), nil
+	case dal.InSubqueryCondition:
+		rows, err := b.run(c.Query(), sources, n, true)
+		if err != nil {
+			return nil, err
+		}
+		v, _, err := resolveJoinExpr(c.Expression(), sources, b.known)
+		if err != nil || len(rows.values) == 0 {
+			return subqueryResult(false), err
+		}
+		if v == nil {
+			return unknownCondition{c}, nil
+		}
+		null := false
+		for _, value := range rows.values {
+			if elementEquals(value, v) {
+				return subqueryResult(true), nil
+			}
+			null = null || value == nil
+		}
+		if null {
+			return unknownCondition{c}, nil
+		}
+		return subqueryResult(false), nil
+	default:
+		return cond, nil
+	}
+}
+
+// run executes the n-th subquery of the WHERE tree for the outer row, after
+// replacing its references to the outer row by their values.
+func (b *subqueryBinder) run(q dal.StructuredQuery, sources map[string]map[string]any, n *int, selectsColumn bool) (*subqueryRows, error) {
+	i := *n
+	*n++
+	if cached := b.cache[i]; cached != nil {
+		return cached, nil
+	}
+	if q == nil {
+		return nil, errors.New("dalgo2memory: subquery without a query")
+	}
+	if selectsColumn && len(q.Columns()) != 1 {
+		return nil, fmt.Errorf("dalgo2memory: IN subquery must select exactly one column, got %d", len(q.Columns()))
+	}
+	c := correlator{outer: b.known, sources: sources}
+	bound := c.correlateQuery(q)
+	reader, err := b.s.ExecuteQueryToRecordsReader(b.ctx, bound)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	rows := &subqueryRows{}
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, dal.ErrNoMoreRecords) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows.exists = true
+		if !selectsColumn {
+			break
+		}
+		for _, v := range rec.Data().(map[string]any) { // the only column
+			rows.values = append(rows.values, v)
+		}
+	}
+	if !c.correlated {
+		b.cache[i] = rows
+	}
+	return rows, nil
+}
+
+// correlatedQuery is a subquery whose WHERE had its outer references replaced
+// by constants; everything else is promoted from the original query.
+type correlatedQuery struct {
+	dal.StructuredQuery
+	where dal.Condition
+}
+
+func (q correlatedQuery) Where() dal.Condition { return q.where }
+
+// correlator replaces, in a subquery, each FieldRef whose Source() names an
+// outer source and none of the subquery's own by a Constant holding the outer
+// row's value, recording whether it replaced any.
+type correlator struct {
+	outer      map[string]bool
+	sources    map[string]map[string]any
+	correlated bool
+}
+
+func (c *correlator) correlateQuery(q dal.StructuredQuery) dal.StructuredQuery {
+	where := q.Where()
+	if where == nil {
+		return q
+	}
+	own := map[string]bool{"": true}
+	if from := q.From(); from != nil {
+		own[from.Base().Name()] = true
+		own[sourceKey(from.Base())] = true
+		for _, j := range from.Joins() {
+			own[j.Name()] = true
+			own[sourceKey(j)] = true
+		}
+	}
+	return correlatedQuery{StructuredQuery: q, where: c.correlateCondition(where, own)}
+}
+
+func (c *correlator) correlateCondition(cond dal.Condition, own map[string]bool) dal.Condition {
+	switch v := cond.(type) {
+	case dal.Comparison:
+		// As in SQL, comparing with a NULL outer value is unknown, rather
+		// than the equality with nil a Constant nil would test.
+		null := c.isNullOuter(v.Left, own) || c.isNullOuter(v.Right, own)
+		v.Left = c.correlateExpression(v.Left, own)
+		if v.Right != nil {
+			v.Right = c.correlateExpression(v.Right, own)
+		}
+		if null && !dal.IsUnaryOperator(v.Operator) {
+			return unknownCondition{v}
+		}
+		// The WHERE matchers expect the field on the left: o.userID = u.id
+		// written as u.id = o.userID becomes userID = <value>.
+		if _, isConstant := v.Left.(dal.Constant); isConstant {
+			if _, isField := v.Right.(dal.FieldRef); isField {
+				if op, ok := mirroredOperator(v.Operator); ok {
+					v.Left, v.Right, v.Operator = v.Right, v.Left, op
+				}
+			}
+		}
+		return v
+	case dal.GroupCondition:
+		conds := v.Conditions()
+		out := make([]dal.Condition, len(conds))
+		for i, sub := range conds {
+			out[i] = c.correlateCondition(sub, own)
+		}
+		return dal.NewGroupCondition(v.Operator(), out...)
+	case dal.NotCondition:
+		return dal.Not(c.correlateCondition(v.Condition(), own))
+	case dal.ExistsCondition:
+		return dal.Exists(c.correlateQuery(v.Query()))
+	case dal.InSubqueryCondition:
+		return dal.InSubquery(c.correlateExpression(v.Expression(), own), c.correlateQuery(v.Query()))
+	default:
+		return cond
+	}
+}
+
+// isNullOuter reports whether e is a reference to an outer field whose value
+// in the outer row is null or missing.
+func (c *correlator) isNullOuter(e dal.Expression, own map[string]bool) bool {
+	f, ok := e.(dal.FieldRef)
+	if src := f.Source(); !ok || own[src] || !c.outer[src] {
+		return false
+	}
+	return c.sources[f.Source()][f.Name()] == nil
+}
+
+func (c *correlator) correlateExpression(e dal.Expression, own map[string]bool) dal.Expression {
+	switch v := e.(type) {
+	case dal.FieldRef:
+		if src := v.Source(); !own[src] && c.outer[src] {
+			c.correlated = true
+			return dal.Constant{Value: c.sources[src][v.Name()]}
+		}
+	case dal.ScalarFunction:
+		args := make([]dal.Expression, len(v.Args))
+		for i, arg := range v.Args {
+			args[i] = c.correlateExpression(arg, own)
+		}
+		v.Args = args
+		return v
+	case dal.Arithmetic:
+		v.Left = c.correlateExpression(v.Left, own)
+		v.Right = c.correlateExpression(v.Right, own)
+		return v
+	}
+	return e
+}
+
+// mirroredOperator returns the operator that gives the same result with the
+// operands swapped.
+func mirroredOperator(op dal.Operator) (dal.Operator, bool) {
+	switch op {
+	case dal.Equal, dal.NotEqual:
+		return op, true
+	case dal.GreaterThen:
+		return dal.LessThen, true
+	case dal.LessThen:
+		return dal.GreaterThen, true
+	case dal.GreaterOrEqual:
+		return dal.LessOrEqual, true
+	case dal.LessOrEqual:
+		return dal.GreaterOrEqual, true
+	default:
+		return op, false
+	}
+}
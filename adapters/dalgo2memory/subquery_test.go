@@ -0,0 +1,186 @@
+package dalgo2memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedCustomers loads three users: ann has an open and a closed order, bob a
+// closed one and cid none; bob is also banned.
+func seedCustomers(t *testing.T) (*database, context.Context) {
+	t.Helper()
+	db := NewDB().(*database)
+	ctx := context.Background()
+	for id, data := range map[string]map[string]any{
+		"1": {"id": 1, "name": "ann"},
+		"2": {"id": 2, "name": "bob"},
+		"3": {"id": 3, "name": "cid"},
+	} {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", id), &data)))
+	}
+	for id, data := range map[string]map[string]any{
+		"a": {"userId": 1, "status": "open", "amount": 30},
+		"b": {"userId": 1, "status": "closed", "amount": 10},
+		"c": {"userId": 2, "status": "closed", "amount": 20},
+	} {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("orders", id), &data)))
+	}
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("bans", "x"), &map[string]any{"userId": 2})))
+	return db, ctx
+}
+
+func usersWhere(t *testing.T, db *database, ctx context.Context, where dal.Condition) []string {
+	t.Helper()
+	q := dal.From(dal.NewRootCollectionRef("users", "u")).NewQuery().
+		Where(where).
+		OrderBy(dal.AscendingField("id")).
+		SelectColumns(dal.Column{Expression: dal.Field("name")})
+	var names []string
+	for _, row := range runProjection(t, db, ctx, q) {
+		names = append(names, row["name"].(string))
+	}
+	return names
+}
+
+func ordersOf(user dal.Expression, status string) dal.StructuredQuery {
+	qb := dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("o", "userId"), dal.Equal, user))
+	if status != "" {
+		qb = qb.WhereField("status", dal.Equal, status)
+	}
+	return qb.SelectKeysOnly(reflect.String)
+}
+
+func TestSubquery_Exists(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	assert.Equal(t, []string{"ann"}, usersWhere(t, db, ctx, dal.Exists(ordersOf(dal.NewFieldRef("u", "id"), "open"))))
+	assert.Equal(t, []string{"ann", "bob"}, usersWhere(t, db, ctx, dal.Exists(ordersOf(dal.NewFieldRef("u", "id"), ""))))
+	assert.Equal(t, []string{"cid"}, usersWhere(t, db, ctx, dal.Not(dal.Exists(ordersOf(dal.NewFieldRef("u", "id"), "")))))
+
+	// The outer reference may be written on the left of the comparison.
+	mirrored := dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("u", "id"), dal.LessThen, dal.NewFieldRef("o", "userId"))).
+		SelectKeysOnly(reflect.String)
+	assert.Equal(t, []string{"ann"}, usersWhere(t, db, ctx, dal.Exists(mirrored)))
+
+	// An uncorrelated EXISTS is the same for every row.
+	none := dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().WhereField("status", dal.Equal, "lost").SelectKeysOnly(reflect.String)
+	assert.Empty(t, usersWhere(t, db, ctx, dal.Exists(none)))
+}
+
+// A NULL or missing outer value makes the correlated comparison unknown, so
+// it matches neither the inner rows missing the field nor, negated, any row.
+func TestSubquery_NullOuterValue(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "4"), &map[string]any{"name": "dan"})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "5"), &map[string]any{"id": nil, "name": "eve"})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("orders", "d"), &map[string]any{"status": "open"})))
+
+	assert.ElementsMatch(t, []string{"ann", "bob"}, usersWhere(t, db, ctx, dal.Exists(ordersOf(dal.NewFieldRef("u", "id"), ""))))
+	assert.ElementsMatch(t, []string{"cid", "dan", "eve"}, usersWhere(t, db, ctx, dal.Not(dal.Exists(ordersOf(dal.NewFieldRef("u", "id"), "")))))
+
+	otherUsers := dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(dal.Not(dal.NewComparison(dal.NewFieldRef("o", "userId"), dal.Equal, dal.NewFieldRef("u", "id")))).
+		SelectKeysOnly(reflect.String)
+	assert.ElementsMatch(t, []string{"ann", "bob", "cid"}, usersWhere(t, db, ctx, dal.Exists(otherUsers)))
+}
+
+func TestSubquery_In(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	buyers := dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().
+		SelectColumns(dal.Column{Expression: dal.Field("userId")})
+	assert.Equal(t, []string{"ann", "bob"}, usersWhere(t, db, ctx, dal.InSubquery(dal.Field("id"), buyers)))
+
+	banned := dal.From(dal.NewRootCollectionRef("bans", "")).NewQuery().
+		SelectColumns(dal.Column{Expression: dal.Field("userId")})
+	assert.Equal(t, []string{"ann", "cid"}, usersWhere(t, db, ctx, dal.Not(dal.InSubquery(dal.Field("id"), banned))))
+
+	assert.Equal(t, []string{"ann"}, usersWhere(t, db, ctx, dal.NewGroupCondition(dal.And,
+		dal.InSubquery(dal.Field("id"), buyers),
+		dal.Not(dal.InSubquery(dal.Field("id"), banned)),
+	)))
+
+	// Correlated and aggregated: users whose largest order is 30.
+	maxAmount := dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("o", "userId"), dal.Equal, dal.NewFieldRef("u", "id"))).
+		SelectColumns(dal.MaxAs(dal.Field("amount"), "max"))
+	assert.Equal(t, []string{"ann"}, usersWhere(t, db, ctx, dal.InSubquery(dal.NewConstant(30), maxAmount)))
+}
+
+// As SQL NOT IN, a negated IN subquery matches no row whose value is NULL or
+// missing, nor, once the subquery returned a NULL, any row it does not list.
+func TestSubquery_NotInWithNulls(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "4"), &map[string]any{"name": "dan"})))
+	banned := dal.From(dal.NewRootCollectionRef("bans", "")).NewQuery().
+		SelectColumns(dal.Column{Expression: dal.Field("userId")})
+	notBanned := dal.Not(dal.InSubquery(dal.Field("id"), banned))
+
+	assert.Equal(t, []string{"ann", "cid"}, usersWhere(t, db, ctx, notBanned), "a NULL outer value is unknown")
+	assert.Equal(t, []string{"bob"}, usersWhere(t, db, ctx, dal.InSubquery(dal.Field("id"), banned)))
+
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("bans", "y"), &map[string]any{"userId": nil})))
+	assert.Empty(t, usersWhere(t, db, ctx, notBanned), "a miss against a NULL is unknown")
+	assert.Equal(t, []string{"bob"}, usersWhere(t, db, ctx, dal.InSubquery(dal.Field("id"), banned)), "a match is still true")
+
+	none := dal.From(dal.NewRootCollectionRef("bans", "")).NewQuery().
+		WhereField("userId", dal.Equal, 9).
+		SelectColumns(dal.Column{Expression: dal.Field("userId")})
+	assert.Equal(t, []string{"ann", "bob", "cid", "dan"}, usersWhere(t, db, ctx, dal.Not(dal.InSubquery(dal.Field("id"), none))),
+		"NULL NOT IN no rows is true")
+}
+
+func TestSubquery_Nested(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	// Users with an order whose user is not banned: the innermost query refers
+	// to the outermost user.
+	notBanned := dal.From(dal.NewRootCollectionRef("bans", "b")).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("b", "userId"), dal.Equal, dal.NewFieldRef("u", "id"))).
+		SelectKeysOnly(reflect.String)
+	orders := dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(
+			dal.NewComparison(dal.NewFieldRef("o", "userId"), dal.Equal, dal.NewFieldRef("u", "id")),
+			dal.Not(dal.Exists(notBanned)),
+		).
+		SelectKeysOnly(reflect.String)
+	assert.Equal(t, []string{"ann"}, usersWhere(t, db, ctx, dal.Exists(orders)))
+}
+
+func TestSubquery_InJoin(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	banned := dal.From(dal.NewRootCollectionRef("bans", "b")).NewQuery().
+		Where(dal.NewComparison(dal.NewFieldRef("b", "userId"), dal.Equal, dal.NewFieldRef("u", "id"))).
+		SelectKeysOnly(reflect.String)
+	q := dal.From(dal.NewRootCollectionRef("users", "u")).
+		Join(dal.NewJoinedSource(dal.NewRootCollectionRef("orders", "o"), dal.JoinInner,
+			dal.NewComparison(dal.NewFieldRef("u", "id"), dal.Equal, dal.NewFieldRef("o", "userId")))).
+		NewQuery().
+		Where(dal.Exists(banned)).
+		SelectColumns(dal.Column{Expression: dal.NewFieldRef("o", "amount")})
+	rows := runProjection(t, db, ctx, q)
+	require.Len(t, rows, 1)
+	assert.EqualValues(t, 20, rows[0]["amount"])
+}
+
+func TestSubquery_Errors(t *testing.T) {
+	db, ctx := seedCustomers(t)
+	for name, where := range map[string]dal.Condition{
+		"no_columns":  dal.InSubquery(dal.Field("id"), dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().SelectKeysOnly(reflect.String)),
+		"two_columns": dal.InSubquery(dal.Field("id"), dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().SelectColumns(dal.Column{Expression: dal.Field("userId")}, dal.Column{Expression: dal.Field("status")})),
+		"nil_query":   dal.Exists(nil),
+		"bad_subquery": dal.Exists(dal.From(dal.NewRootCollectionRef("orders", "")).NewQuery().
+			SelectColumns(dal.Column{Expression: dal.NewFieldRef("zz", "userId")})),
+	} {
+		t.Run(name, func(t *testing.T) {
+			q := dal.From(dal.NewRootCollectionRef("users", "u")).NewQuery().Where(where).SelectKeysOnly(reflect.String)
+			_, err := db.ExecuteQueryToRecordsReader(ctx, q)
+			assert.Error(t, err)
+		})
+	}
+}
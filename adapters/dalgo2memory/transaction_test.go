@@ -0,0 +1,148 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/require"
+)
+
+var errWorkerFailed = errors.New("worker failed")
+
+// seedTxThings stores two records, one and two, in the "Things" collection.
+func seedTxThings(t *testing.T, db *database) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "one"), &thing{Name: "first", Count: 1})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "two"), &thing{Name: "second", Count: 2})))
+}
+
+// mutateThings performs every kind of write against the seeded records.
+func mutateThings(ctx context.Context, tx dal.ReadwriteTransaction) error {
+	if err := tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "one"), &thing{Name: "overwritten", Count: 10})); err != nil {
+		return err
+	}
+	if err := tx.Insert(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "three"), &thing{Name: "third", Count: 3})); err != nil {
+		return err
+	}
+	if err := tx.Update(ctx, record.NewKeyWithID("Things", "two"), []update.Update{update.ByFieldName("Count", 20)}); err != nil {
+		return err
+	}
+	if err := tx.Delete(ctx, record.NewKeyWithID("Things", "one")); err != nil {
+		return err
+	}
+	return tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("Others", "x"), &thing{Name: "other"}))
+}
+
+// requireSeededThings asserts the database holds exactly the seeded state.
+func requireSeededThings(t *testing.T, db dal.DB) {
+	t.Helper()
+	ctx := context.Background()
+	var one, two thing
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "one"), &one)))
+	require.Equal(t, thing{Name: "first", Count: 1}, one)
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "two"), &two)))
+	require.Equal(t, thing{Name: "second", Count: 2}, two)
+	for _, key := range []*record.Key{record.NewKeyWithID("Things", "three"), record.NewKeyWithID("Others", "x")} {
+		exists, err := db.Exists(ctx, key)
+		require.NoError(t, err)
+		require.False(t, exists, key.String())
+	}
+	q := dal.From(dal.NewRootCollectionRef("Things", "")).NewQuery().SelectKeysOnly(reflect.String)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, recordIDs(readAll(t, reader)))
+}
+
+func TestReadwriteTransaction_RollsBackOnError(t *testing.T) {
+	t.Parallel()
+	for name, db := range map[string]*database{
+		"serialized": NewDB().(*database),
+		"columnar": NewDB(WithSchema(true,
+			WithCollection[thing]("Things", nil, WithColumnarStorage()),
+		)).(*database),
+	} {
+		t.Run(name, func(t *testing.T) {
+			seedTxThings(t, db)
+			err := db.RunReadwriteTransaction(context.Background(), func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+				if err := mutateThings(ctx, tx); err != nil {
+					return err
+				}
+				return errWorkerFailed
+			})
+			require.ErrorIs(t, err, errWorkerFailed)
+			requireSeededThings(t, db)
+		})
+	}
+}
+
+func TestReadwriteTransaction_RollsBackOnPanic(t *testing.T) {
+	t.Parallel()
+	db := NewDB().(*database)
+	seedTxThings(t, db)
+	require.PanicsWithValue(t, "boom", func() {
+		_ = db.RunReadwriteTransaction(context.Background(), func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+			require.NoError(t, mutateThings(ctx, tx))
+			panic("boom")
+		})
+	})
+	requireSeededThings(t, db)
+}
+
+func TestReadwriteTransaction_CommitsOnSuccess(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB().(*database)
+	seedTxThings(t, db)
+	require.NoError(t, db.RunReadwriteTransaction(ctx, mutateThings))
+
+	var two thing
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("Things", "two"), &two)))
+	require.Equal(t, 20, two.Count)
+	exists, err := db.Exists(ctx, record.NewKeyWithID("Things", "one"))
+	require.NoError(t, err)
+	require.False(t, exists)
+	exists, err = db.Exists(ctx, record.NewKeyWithID("Things", "three"))
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+// TestReadwriteTransaction_RollbackResyncsExternalStrategy verifies a rolled
+// back columnar collection leaves an explicit ColumnStrategy describing the
+// restored slots, not the discarded ones.
+func TestReadwriteTransaction_RollbackResyncsExternalStrategy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := NewDB(WithSchema(false,
+		WithCollection[user]("users", nil, WithColumnarStorage()),
+	)).(*database)
+	eng := db.engine("users").(*columnarEngine)
+	stgy := newRecordingStrategy(true, func(slot int) bool { return slot < len(eng.live) && eng.live[slot] })
+	eng.byName["Role"].strategy = stgy
+	eng.byName["Role"].defaultStgy = nil
+
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "u1"), &user{Name: "Alice", Role: "admin"})))
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		if err := tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "u2"), &user{Name: "Bob", Role: "member"})); err != nil {
+			return err
+		}
+		if err := tx.Update(ctx, record.NewKeyWithID("users", "u1"), []update.Update{update.ByFieldName("Role", "member")}); err != nil {
+			return err
+		}
+		return errWorkerFailed
+	})
+	require.ErrorIs(t, err, errWorkerFailed)
+	require.Equal(t, map[int]any{0: "admin"}, stgy.values)
+
+	q := dal.From(dal.NewRootCollectionRef("users", "")).NewQuery().
+		WhereField("Role", dal.Equal, "admin").
+		SelectKeysOnly(reflect.String)
+	reader, err := db.ExecuteQueryToRecordsReader(ctx, q)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1"}, recordIDs(readAll(t, reader)))
+}
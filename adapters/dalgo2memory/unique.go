@@ -0,0 +1,183 @@
+package dalgo2memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// WithUniqueIndex declares a unique index on a collection: no two of its
+// records may have the same values of the fields, compared the way queries
+// compare them (1 and 1.0 are the same number). A record that lacks one of the
+// fields, or holds null in it, is not indexed, as in SQL. An Insert, Set or
+// Update that would duplicate a key stores nothing and fails with a
+// *dal.UniqueViolationError naming the index, the key and the record holding
+// it.
+//
+// A unique index applies to any storage engine and spans every record of the
+// collection, whatever its parent. A composite index is a single index on
+// several fields, e.g. WithUniqueIndex("byTeamAndNumber", "team", "number");
+// declaring the same name again replaces the earlier declaration.
+// WithUniqueIndex panics when no field is given.
+func WithUniqueIndex(name string, fields ...string) CollectionOption {
+	if len(fields) == 0 {
+		panic(fmt.Sprintf("dalgo2memory: unique index %q has no fields", name))
+	}
+	spec := indexSpec{name: name, fields: slices.Clone(fields)}
+	return func(def *collectionDef) {
+		for i, existing := range def.uniques {
+			if existing.name == name {
+				def.uniques[i] = spec
+				return
+			}
+		}
+		def.uniques = append(def.uniques, spec)
+	}
+}
+
+// uniqueHolder is the record holding a key of a unique index.
+type uniqueHolder struct {
+	id  string
+	key *record.Key
+}
+
+// uniqueIndex maps the keys of a unique index to the records holding them.
+type uniqueIndex struct {
+	indexSpec
+	byKey map[string]uniqueHolder // by valueKey of the key
+	byID  map[string]string       // valueKey of each indexed record's key
+}
+
+// keyOf returns the key a record's field view has in the index, with ok=false
+// when a field is absent or null.
+func (ix *uniqueIndex) keyOf(data map[string]any) ([]any, bool) {
+	key := make([]any, len(ix.fields))
+	for i, field := range ix.fields {
+		if key[i] = data[field]; key[i] == nil {
+			return nil, false
+		}
+	}
+	return key, true
+}
+
+// put sets the key of a record from its field view.
+func (ix *uniqueIndex) put(id string, key *record.Key, data map[string]any) {
+	ix.remove(id)
+	if values, ok := ix.keyOf(data); ok {
+		k := valueKey(values)
+		ix.byKey[k] = uniqueHolder{id: id, key: key}
+		ix.byID[id] = k
+	}
+}
+
+// remove drops the key of a record, if it has one.
+func (ix *uniqueIndex) remove(id string) {
+	if k, ok := ix.byID[id]; ok {
+		delete(ix.byKey, k)
+		delete(ix.byID, id)
+	}
+}
+
+// check returns a *dal.UniqueViolationError when another record than id
+// holds the key the field view has in the index.
+func (ix *uniqueIndex) check(collection, id string, data map[string]any) error {
+	values, ok := ix.keyOf(data)
+	if !ok {
+		return nil
+	}
+	holder, ok := ix.byKey[valueKey(values)]
+	if !ok || holder.id == id {
+		return nil
+	}
+	fields := make([]dal.FieldName, len(ix.fields))
+	for i, field := range ix.fields {
+		fields[i] = dal.FieldName(field)
+	}
+	return &dal.UniqueViolationError{Collection: collection, Index: ix.name, Fields: fields, Values: values, Existing: holder.key}
+}
+
+// uniqueIndexes returns the unique indexes of a collection, building them from
+// its stored rows on first use. They are dropped, to be built again, when a
+// transaction that wrote to the collection rolls back.
+func (db *database) uniqueIndexes(collection string) ([]*uniqueIndex, error) {
+	if db.schema == nil || len(db.schema.uniques[collection]) == 0 {
+		return nil, nil
+	}
+	if indexes, ok := db.uniques[collection]; ok {
+		return indexes, nil
+	}
+	rows, err := db.engine(collection).rows()
+	if err != nil {
+		return nil, err
+	}
+	specs := db.schema.uniques[collection]
+	indexes := make([]*uniqueIndex, len(specs))
+	for i, spec := range specs {
+		indexes[i] = &uniqueIndex{indexSpec: spec, byKey: make(map[string]uniqueHolder), byID: make(map[string]string)}
+		for _, row := range rows {
+			indexes[i].put(row.id, row.key, row.data)
+		}
+	}
+	if db.uniques == nil {
+		db.uniques = make(map[string][]*uniqueIndex)
+	}
+	db.uniques[collection] = indexes
+	return indexes, nil
+}
+
+// putUnique sets the key of a record just written in the unique indexes from
+// its field view, or drops the indexes, to be built again from the rows, when
+// the view is not known.
+func (db *database) putUnique(collection string, indexes []*uniqueIndex, id string, key *record.Key, data map[string]any) {
+	if len(indexes) > 0 && data == nil {
+		delete(db.uniques, collection)
+		return
+	}
+	for _, ix := range indexes {
+		ix.put(id, key, data)
+	}
+}
+
+// checkUnique returns the violation of the first unique index that the field
+// view of the record stored under id would duplicate a key of.
+func checkUnique(indexes []*uniqueIndex, collection, id string, data map[string]any) error {
+	for _, ix := range indexes {
+		if err := ix.check(collection, id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldView returns the field view queries see of record data, with ok=false
+// when it does not serialize to a JSON object, which the engine rejects.
+func fieldView(data any) (map[string]any, bool) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, false
+	}
+	var view map[string]any
+	if err := json.Unmarshal(b, &view); err != nil || view == nil {
+		return nil, false
+	}
+	return view, true
+}
+
+// updatedView returns the field view the record stored under key would have
+// after updates, with ok=false when the engine would reject them.
+func updatedView(eng storageEngine, key *record.Key, updates []update.Update) (map[string]any, bool) {
+	var data map[string]any
+	current := record.NewRecordWithData(key, &data)
+	current.SetError(nil)
+	if err := eng.load(keyID(key), current); err != nil || data == nil {
+		return nil, false
+	}
+	if err := applyUpdatesToMap(data, updates); err != nil {
+		return nil, false
+	}
+	return fieldView(data)
+}
@@ -0,0 +1,132 @@
+package dalgo2memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type account struct {
+	Email  string  `json:"email"`
+	Team   string  `json:"team,omitempty"`
+	Number float64 `json:"number,omitempty"`
+}
+
+func accountKey(id string) *record.Key {
+	return record.NewKeyWithID("accounts", id)
+}
+
+func putAccount(db *database, id string, a account) error {
+	return db.Set(context.Background(), record.NewRecordWithData(accountKey(id), &a))
+}
+
+func TestUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	for _, storage := range []struct {
+		name string
+		opt  CollectionOption
+	}{
+		{"serialized", WithSerializedStorage()},
+		{"columnar", WithColumnarStorage()},
+	} {
+		t.Run(storage.name, func(t *testing.T) {
+			db := NewDB(WithSchema(false, WithCollection[account]("accounts", nil, storage.opt,
+				WithUniqueIndex("byEmail", "email"),
+				WithUniqueIndex("byTeamNumber", "team", "number"),
+			))).(*database)
+			require.NoError(t, putAccount(db, "a1", account{Email: "ann@example.com", Team: "red", Number: 7}))
+			require.NoError(t, putAccount(db, "a2", account{Email: "bob@example.com", Team: "red", Number: 8}))
+			require.NoError(t, putAccount(db, "a1", account{Email: "ann@example.com", Team: "red", Number: 7}), "a record does not conflict with itself")
+
+			err := db.Insert(ctx, record.NewRecordWithData(accountKey("a3"), &account{Email: "ann@example.com"}))
+			var violation *dal.UniqueViolationError
+			require.ErrorAs(t, err, &violation)
+			assert.True(t, errors.Is(err, dal.ErrUniqueViolation))
+			assert.Equal(t, &dal.UniqueViolationError{Collection: "accounts", Index: "byEmail", Fields: []dal.FieldName{"email"},
+				Values: []any{"ann@example.com"}, Existing: accountKey("a1")}, violation)
+			exists, err := db.Exists(ctx, accountKey("a3"))
+			require.NoError(t, err)
+			assert.False(t, exists, "a rejected write stores nothing")
+
+			err = putAccount(db, "a3", account{Email: "cid@example.com", Team: "red", Number: 8})
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, "byTeamNumber", violation.Index)
+			assert.Equal(t, []any{"red", 8.0}, violation.Values)
+			require.NoError(t, putAccount(db, "a3", account{Email: "cid@example.com", Team: "blue", Number: 8}), "composite keys differ")
+			require.NoError(t, putAccount(db, "a4", account{Email: "dan@example.com", Number: 7}), "a record without a team is not indexed")
+			require.NoError(t, putAccount(db, "a5", account{Email: "eve@example.com", Number: 7}))
+
+			err = db.Update(ctx, accountKey("a2"), []update.Update{update.ByFieldName("email", "ann@example.com")})
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, accountKey("a1"), violation.Existing)
+			var a2 account
+			require.NoError(t, db.Get(ctx, record.NewRecordWithData(accountKey("a2"), &a2)))
+			assert.Equal(t, "bob@example.com", a2.Email, "a rejected update changes nothing")
+
+			require.NoError(t, db.Update(ctx, accountKey("a1"), []update.Update{update.ByFieldName("email", "ann@example.org")}))
+			require.NoError(t, db.Update(ctx, accountKey("a2"), []update.Update{update.ByFieldName("email", "ann@example.com")}), "the old key is released")
+			require.NoError(t, db.Delete(ctx, accountKey("a2")))
+			require.NoError(t, putAccount(db, "a6", account{Email: "ann@example.com"}), "a deleted record releases its key")
+		})
+	}
+}
+
+func TestUniqueIndex_Transactions(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(true, WithCollection[map[string]any]("accounts", nil, WithUniqueIndex("byEmail", "email")))).(*database)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(accountKey("a1"), map[string]any{"email": "ann@example.com", "n": 1})))
+
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		if err := tx.Delete(ctx, accountKey("a1")); err != nil {
+			return err
+		}
+		if err := tx.Set(ctx, record.NewRecordWithData(accountKey("a2"), map[string]any{"email": "ann@example.com"})); err != nil {
+			return err
+		}
+		return tx.Set(ctx, record.NewRecordWithData(accountKey("a3"), map[string]any{"email": "ann@example.com"}))
+	})
+	assert.ErrorIs(t, err, dal.ErrUniqueViolation)
+
+	err = db.Set(ctx, record.NewRecordWithData(accountKey("a2"), map[string]any{"email": "ann@example.com"}))
+	var violation *dal.UniqueViolationError
+	require.ErrorAs(t, err, &violation, "the rollback restores a1 and its key")
+	assert.Equal(t, accountKey("a1"), violation.Existing)
+
+	err = db.Set(ctx, record.NewRecordWithData(accountKey("a2"), map[string]any{"email": "bob@example.com", "n": 1.0}))
+	require.NoError(t, err)
+	err = db.Update(ctx, accountKey("a2"), []update.Update{update.ByFieldName("email", "ann@example.com")})
+	assert.ErrorIs(t, err, dal.ErrUniqueViolation)
+}
+
+func TestUniqueIndex_NumbersAndBranches(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(WithSchema(true, WithCollection[map[string]any]("accounts", nil, WithUniqueIndex("byNumber", "number")))).(*database)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(accountKey("a1"), map[string]any{"number": 1})))
+	err := db.Set(ctx, record.NewRecordWithData(accountKey("a2"), map[string]any{"number": 1.0}))
+	assert.ErrorIs(t, err, dal.ErrUniqueViolation, "1 and 1.0 are the same number")
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(accountKey("a2"), map[string]any{"number": "1"})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(accountKey("a3"), map[string]any{"number": nil})))
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(accountKey("a4"), map[string]any{"number": nil})), "nulls are not indexed")
+
+	checkpoint, err := NewBranchingProvider().Capture(ctx, db)
+	require.NoError(t, err)
+	branch, err := checkpoint.Branch(ctx)
+	require.NoError(t, err)
+	err = branch.DB().(*database).Set(ctx, record.NewRecordWithData(accountKey("a5"), map[string]any{"number": 1}))
+	assert.ErrorIs(t, err, dal.ErrUniqueViolation, "a branch enforces the index over the captured records")
+}
+
+func TestWithUniqueIndex(t *testing.T) {
+	assert.Panics(t, func() { WithUniqueIndex("empty") })
+	var def collectionDef
+	WithUniqueIndex("i", "a")(&def)
+	WithUniqueIndex("j", "b")(&def)
+	WithUniqueIndex("i", "c", "d")(&def)
+	assert.Equal(t, []indexSpec{{name: "i", fields: []string{"c", "d"}}, {name: "j", fields: []string{"b"}}}, def.uniques)
+}
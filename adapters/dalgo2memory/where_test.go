@@ -82,6 +82,36 @@ func TestMatchesWhere(t *testing.T) {
 		{"group_or_unsupported", dal.NewGroupCondition(dal.Or,
 			cmp(fieldRef("Name"), dal.Equal, dal.Constant{Value: "Alice"}),
 		), false},
+
+		// extended operators
+		{"not_equal_true", cmp(fieldRef("Name"), dal.NotEqual, dal.Constant{Value: "Bob"}), true},
+		{"not_equal_false", cmp(fieldRef("Age"), dal.NotEqual, dal.Constant{Value: 42}), false},
+		{"not_equal_missing_field", cmp(fieldRef("Missing"), dal.NotEqual, dal.Constant{Value: "Bob"}), false},
+		{"is_null_missing_field", cmp(fieldRef("Missing"), dal.IsNull, nil), true},
+		{"is_null_present_field", cmp(fieldRef("Name"), dal.IsNull, nil), false},
+		{"is_not_null_present_field", cmp(fieldRef("Name"), dal.IsNotNull, nil), true},
+		{"is_not_null_missing_field", cmp(fieldRef("Missing"), dal.IsNotNull, nil), false},
+		{"not_in_true", cmp(fieldRef("Name"), dal.NotIn, dal.Array{Value: []string{"Bob", "Eve"}}), true},
+		{"not_in_false", cmp(fieldRef("Age"), dal.NotIn, dal.Array{Value: []int{41, 42}}), false},
+		{"not_in_missing_field", cmp(fieldRef("Missing"), dal.NotIn, dal.Array{Value: []string{"Bob"}}), false},
+		{"between_inclusive_lower", cmp(fieldRef("Age"), dal.Between, dal.Array{Value: []int{42, 50}}), true},
+		{"between_inclusive_upper", cmp(fieldRef("Age"), dal.Between, dal.Array{Value: []int{30, 42}}), true},
+		{"between_outside", cmp(fieldRef("Age"), dal.Between, dal.Array{Value: []int{43, 50}}), false},
+		{"between_wrong_bounds", cmp(fieldRef("Age"), dal.Between, dal.Array{Value: []int{40}}), false},
+		{"starts_with_true", cmp(fieldRef("Name"), dal.StartsWith, dal.Constant{Value: "Al"}), true},
+		{"starts_with_false", cmp(fieldRef("Name"), dal.StartsWith, dal.Constant{Value: "al"}), false},
+		{"starts_with_non_string_field", cmp(fieldRef("Age"), dal.StartsWith, dal.Constant{Value: "4"}), false},
+		{"like_true", cmp(fieldRef("Name"), dal.Like, dal.Constant{Value: "A_i%"}), true},
+		{"like_false", cmp(fieldRef("Name"), dal.Like, dal.Constant{Value: "%x%"}), false},
+		{"array_contains_operator", cmp(fieldRef("Tags"), dal.ArrayContains, dal.Constant{Value: "b"}), true},
+		{"array_contains_operator_no_match", cmp(fieldRef("AnyTag"), dal.ArrayContains, dal.Constant{Value: 8}), false},
+		{"array_contains_any_operator", cmp(fieldRef("AnyTag"), dal.ArrayContainsAny, dal.Array{Value: []int{1, 7}}), true},
+		{"not_comparison", dal.Not(cmp(fieldRef("Name"), dal.Equal, dal.Constant{Value: "Bob"})), true},
+		{"not_group", dal.Not(dal.NewGroupCondition(dal.And,
+			cmp(fieldRef("Name"), dal.Equal, dal.Constant{Value: "Alice"}),
+			cmp(fieldRef("Age"), dal.GreaterOrEqual, dal.Constant{Value: 42}),
+		)), false},
+		{"not_not", dal.Not(dal.Not(cmp(fieldRef("Tags"), dal.ArrayContains, dal.Constant{Value: "a"}))), true},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			require.Equal(t, tt.want, matchesWhere(data, tt.condition))
@@ -313,3 +343,105 @@ func TestQueryWhereMultipleConditionsEndToEnd(t *testing.T) {
 	})
 	require.Equal(t, []string{"t2"}, ids)
 }
+
+// TestQueryWhereExtendedOperatorsEndToEnd runs the extended operators and NOT
+// through the serialized engine, whose rows decode numbers as float64 and
+// arrays as []any.
+func TestQueryWhereExtendedOperatorsEndToEnd(t *testing.T) {
+	db := NewDB().(*database)
+	insertTaggedThings(t, db)
+
+	for _, tt := range []struct {
+		name  string
+		where dal.Condition
+		want  []string
+	}{
+		{"not_equal", dal.WhereField("Rank", dal.NotEqual, 2), []string{"t1", "t3"}},
+		{"not_in", dal.WhereField("Name", dal.NotIn, []string{"first", "third"}), []string{"t2"}},
+		{"between", dal.WhereField("Rank", dal.Between, []int{2, 3}), []string{"t2", "t3"}},
+		{"is_null", dal.WhereField("Tags", dal.IsNull, nil), []string{"t3"}},
+		{"starts_with", dal.WhereField("Name", dal.StartsWith, "th"), []string{"t3"}},
+		{"like", dal.WhereField("Name", dal.Like, "%ir%"), []string{"t1", "t3"}},
+		{"array_contains", dal.WhereField("Tags", dal.ArrayContains, "green"), []string{"t2"}},
+		{"array_contains_any", dal.WhereField("Tags", dal.ArrayContainsAny, []string{"red", "green"}), []string{"t1", "t2"}},
+		{"not", dal.Not(dal.WhereField("Tags", dal.ArrayContains, "green")), []string{"t1"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := queryTaggedThings(t, db, func(qb *dal.QueryBuilder) dal.IQueryBuilder {
+				return qb.Where(tt.where)
+			})
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+// TestQueryWhereNullOperands verifies that a null or missing field is unknown
+// to every comparison but IsNull and an equality with nil, and that negating
+// an unknown comparison does not match either, so Not(Equal) agrees with
+// NotEqual and NotIn.
+func TestQueryWhereNullOperands(t *testing.T) {
+	db := NewDB().(*database)
+	ctx := context.Background()
+	for id, data := range map[string]map[string]any{
+		"five":    {"x": 5},
+		"six":     {"x": 6},
+		"null":    {"x": nil},
+		"missing": {},
+	} {
+		require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("tagged", id), &data)))
+	}
+	for _, tt := range []struct {
+		name  string
+		where dal.Condition
+		want  []string
+	}{
+		{"greater", dal.WhereField("x", dal.GreaterThen, 3), []string{"five", "six"}},
+		{"less_or_equal", dal.WhereField("x", dal.LessOrEqual, 6), []string{"five", "six"}},
+		{"equal_nil", dal.WhereField("x", dal.Equal, nil), []string{"missing", "null"}},
+		{"not_equal", dal.WhereField("x", dal.NotEqual, 5), []string{"six"}},
+		{"not_in", dal.WhereField("x", dal.NotIn, []int{5}), []string{"six"}},
+		{"not_of_equal", dal.Not(dal.WhereField("x", dal.Equal, 5)), []string{"six"}},
+		{"not_of_greater", dal.Not(dal.WhereField("x", dal.GreaterThen, 5)), []string{"five"}},
+		{"not_of_not", dal.Not(dal.Not(dal.WhereField("x", dal.Equal, 5))), []string{"five"}},
+		{"not_of_is_null", dal.Not(dal.WhereField("x", dal.IsNull, nil)), []string{"five", "six"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := queryTaggedThings(t, db, func(qb *dal.QueryBuilder) dal.IQueryBuilder {
+				return qb.Where(tt.where)
+			})
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestLikeMatch(t *testing.T) {
+	for _, tt := range []struct {
+		s, pattern string
+		want       bool
+	}{
+		{"Alice", "Alice", true},
+		{"Alice", "alice", false},
+		{"Alice", "A%", true},
+		{"Alice", "%ce", true},
+		{"Alice", "%li%", true},
+		{"Alice", "A_ice", true},
+		{"Alice", "A_ce", false},
+		{"Alice", "%", true},
+		{"", "%", true},
+		{"", "_", false},
+		{"aXbXc", "a%b%c", true},
+		{"abcbd", "a%bd", true},
+		{"abc", "a%bd", false},
+		{"50%", `50\%`, true},
+		{"500", `50\%`, false},
+		{"a_b", `a\_b`, true},
+		{"axb", `a\_b`, false},
+		{`a\b`, `a\\b`, true},
+		{`a\`, `a\`, true},
+		{"żółw", "ż_łw", true},
+	} {
+		t.Run(tt.s+"~"+tt.pattern, func(t *testing.T) {
+			require.Equal(t, tt.want, likeMatch(tt.s, tt.pattern))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"reflect"
 
 	"github.com/dal-go/record"
@@ -150,6 +151,19 @@ type ManyInserter[K comparable, T any] interface {
 	InsertMany(ctx context.Context, s WriteSession, items ...Item[K, T]) (keys []*record.Key, err error)
 }
 
+// CollectionIterator is the opt-in streaming counterpart of All, kept out of
+// Collection[K, T] so existing implementations of that interface still
+// compile. The concrete Collection[K, T] value satisfies it (obtain it via a
+// type assertion: c.(dal.CollectionIterator[K, T])).
+type CollectionIterator[K comparable, T any] interface {
+	// Iterate streams every record in the collection, each decoded into a
+	// freshly allocated *T. The query runs when the sequence is ranged over and
+	// its reader is closed when iteration ends, including on an early break. A
+	// failure (including ErrNotSupported from an incapable backend) is yielded
+	// as the last item.
+	Iterate(ctx context.Context, s ReadSession) iter.Seq2[record.DataWithID[K, *T], error]
+}
+
 // CollectionOption configures a Collection at construction time.
 type CollectionOption func(*collectionOptions)
 
@@ -272,21 +286,47 @@ func (c collection[K, T]) Get(ctx context.Context, s ReadSession, id K) (T, erro
 	return c.GetData(ctx, s, id)
 }
 
-func (c collection[K, T]) All(ctx context.Context, s ReadSession) ([]T, error) {
-	query := NewQueryBuilder(From(c.ref)).SelectIntoRecord(func() record.Record {
+// allQuery selects every record of the collection decoded into a fresh *T.
+func (c collection[K, T]) allQuery() Query {
+	return NewQueryBuilder(From(c.ref)).SelectIntoRecord(func() record.Record {
 		return record.NewRecordWithIncompleteKey(c.ref.Name(), reflect.String, new(T))
 	})
-	records, err := ExecuteQueryAndReadAllToRecords(ctx, query, s)
-	if err != nil {
-		return nil, err
-	}
-	values := make([]T, len(records))
-	for i, r := range records {
-		values[i] = *r.Data().(*T)
+}
+
+func (c collection[K, T]) All(ctx context.Context, s ReadSession) ([]T, error) {
+	values := []T{}
+	for r, err := range ExecuteQueryAndIterateRecords(ctx, c.allQuery(), s) {
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *r.Data().(*T))
 	}
 	return values, nil
 }
 
+func (c collection[K, T]) Iterate(ctx context.Context, s ReadSession) iter.Seq2[record.DataWithID[K, *T], error] {
+	return func(yield func(record.DataWithID[K, *T], error) bool) {
+		for r, err := range ExecuteQueryAndIterateRecords(ctx, c.allQuery(), s) {
+			if err != nil {
+				yield(record.DataWithID[K, *T]{}, err)
+				return
+			}
+			id, ok := r.Key().ID.(K)
+			if !ok {
+				yield(record.DataWithID[K, *T]{}, fmt.Errorf("dal: collection %q: record id %v is %T, not %T", c.ref.Name(), r.Key().ID, r.Key().ID, id))
+				return
+			}
+			item := record.DataWithID[K, *T]{
+				WithID: record.WithID[K]{ID: id, Key: r.Key(), Record: r},
+				Data:   r.Data().(*T),
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
 func (c collection[K, T]) Insert(ctx context.Context, s WriteSession, value T, opts ...InsertOption) (*record.Key, error) {
 	key := record.NewIncompleteKey(c.ref.Name(), reflect.String, c.ref.Parent())
 	rec := record.NewRecordWithData(key, &value)
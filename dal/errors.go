@@ -21,6 +21,49 @@ var ErrNoMoreRecords = fmt.Errorf("%w: no more errors", io.EOF)
 
 var ErrLimitReached = fmt.Errorf("%w: limit reached", ErrNoMoreRecords)
 
+// ErrPreconditionFailed indicates a write was rejected because one of its
+// preconditions (see WithExistsPrecondition and WithLastUpdateTimePrecondition)
+// did not hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrUniqueViolation indicates a write was rejected because it would store two
+// records with the same values of the fields of a unique index.
+var ErrUniqueViolation = errors.New("unique index violation")
+
+// UniqueViolationError is the typed error of a write rejected by a unique
+// index. Its Unwrap returns ErrUniqueViolation, so callers can check
+// errors.Is(err, dal.ErrUniqueViolation) or extract the detail with errors.As.
+type UniqueViolationError struct {
+	// Collection is the collection of the unique index.
+	Collection string
+	// Index is the name of the unique index.
+	Index string
+	// Fields are the fields the index covers.
+	Fields []FieldName
+	// Values are the values of Fields the rejected write would have duplicated.
+	Values []any
+	// Existing is the key of the record that already holds Values, when known.
+	Existing *record.Key
+}
+
+// Error returns a readable single-line message.
+func (e *UniqueViolationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = string(f)
+	}
+	msg := fmt.Sprintf("%v: index %q on %s(%s) already has key %v", ErrUniqueViolation, e.Index, e.Collection, strings.Join(fields, ", "), e.Values)
+	if e.Existing != nil {
+		msg += fmt.Sprintf(" for record %v", e.Existing)
+	}
+	return msg
+}
+
+// Unwrap returns the ErrUniqueViolation sentinel.
+func (e *UniqueViolationError) Unwrap() error {
+	return ErrUniqueViolation
+}
+
 // ErrDuplicateUser indicates there is a duplicate user // TODO: move to strongo/app?
 type ErrDuplicateUser struct {
 	// TODO: Should it be moved out of this package to strongo/app/user?
@@ -64,6 +64,29 @@ func TestErrNotSupported(t *testing.T) {
 	assert.True(t, errors.Is(err, ErrNotSupported))
 }
 
+func TestErrPreconditionFailed(t *testing.T) {
+	assert.Equal(t, "precondition failed", ErrPreconditionFailed.Error())
+	err := fmt.Errorf("%w: stale last update time", ErrPreconditionFailed)
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+}
+
+func TestUniqueViolationError(t *testing.T) {
+	err := fmt.Errorf("insert failed: %w", &UniqueViolationError{
+		Collection: "users",
+		Index:      "byEmail",
+		Fields:     []FieldName{"email"},
+		Values:     []any{"a@example.com"},
+		Existing:   record.NewKeyWithID("users", "u1"),
+	})
+	assert.True(t, errors.Is(err, ErrUniqueViolation))
+	var violation *UniqueViolationError
+	assert.True(t, errors.As(err, &violation))
+	assert.Equal(t, "byEmail", violation.Index)
+	assert.Equal(t, `insert failed: unique index violation: index "byEmail" on users(email) already has key [a@example.com] for record users/u1`, err.Error())
+	assert.Equal(t, `unique index violation: index "i" on c(a, b) already has key [1 x]`,
+		(&UniqueViolationError{Collection: "c", Index: "i", Fields: []FieldName{"a", "b"}, Values: []any{1, "x"}}).Error())
+}
+
 func TestNewRollbackError(t *testing.T) {
 	err := NewRollbackError(errors.New("some rollback error"), errors.New("some original error"))
 	//if !errors.Is(err, rollbackError{}) {
@@ -0,0 +1,176 @@
+package dal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PlanOperation names the step a PlanNode performs
+type PlanOperation string
+
+const (
+	// PlanScan reads every record of a collection
+	PlanScan PlanOperation = "SCAN"
+	// PlanIndexLookup reads only the records an index selects for Condition
+	PlanIndexLookup PlanOperation = "INDEX_LOOKUP"
+	// PlanFilter keeps the input rows that satisfy Condition
+	PlanFilter PlanOperation = "FILTER"
+	// PlanHashJoin joins its two inputs by hashing the rows of the second one on the ON equalities
+	PlanHashJoin PlanOperation = "HASH_JOIN"
+	// PlanNestedLoopJoin joins its two inputs by evaluating ON for every pair of rows
+	PlanNestedLoopJoin PlanOperation = "NESTED_LOOP_JOIN"
+	// PlanAggregate groups the input rows by GroupBy and keeps the groups satisfying Condition (HAVING)
+	PlanAggregate PlanOperation = "AGGREGATE"
+	// PlanSort orders the input rows by OrderBy
+	PlanSort PlanOperation = "SORT"
+	// PlanDistinct drops duplicate rows
+	PlanDistinct PlanOperation = "DISTINCT"
+	// PlanLimit skips Offset rows and keeps at most Limit of the rest
+	PlanLimit PlanOperation = "LIMIT"
+)
+
+// UnknownRows is the EstimatedRows of a plan step whose output size the adapter cannot estimate
+const UnknownRows = -1
+
+// PlanNode is one step of a QueryPlan: it consumes the rows produced by its
+// Inputs (none for a read, two for a join, one otherwise) and produces about
+// EstimatedRows rows. Fields that do not apply to the Operation are zero.
+//
+// An adapter that pushes a limit down to a read reports it in the read's
+// Limit and Offset rather than as a separate PlanLimit step.
+type PlanNode struct {
+	Operation  PlanOperation `json:"operation"`
+	Collection string        `json:"collection,omitempty"` // read by a SCAN or INDEX_LOOKUP
+	Alias      string        `json:"alias,omitempty"`
+	Index      string        `json:"index,omitempty"` // field or index used by an INDEX_LOOKUP
+
+	// Condition is the lookup predicate, filter, join ON or HAVING condition
+	Condition Condition         `json:"condition,omitempty"`
+	JoinType  JoinType          `json:"joinType,omitempty"`
+	GroupBy   []Expression      `json:"groupBy,omitempty"`
+	OrderBy   []OrderExpression `json:"orderBy,omitempty"`
+	Limit     int               `json:"limit,omitempty"`
+	Offset    int               `json:"offset,omitempty"`
+
+	EstimatedRows int         `json:"estimatedRows"`
+	Inputs        []*PlanNode `json:"inputs,omitempty"`
+}
+
+// String returns a one-line description of the step, without its inputs
+func (n *PlanNode) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(n.Operation))
+	if n.JoinType != "" {
+		sb.WriteString(" " + string(n.JoinType))
+	}
+	if n.Collection != "" {
+		sb.WriteString(" " + n.Collection)
+		if n.Alias != "" {
+			sb.WriteString(" AS " + n.Alias)
+		}
+	}
+	if n.Index != "" {
+		sb.WriteString(" USING " + n.Index)
+	}
+	if len(n.GroupBy) > 0 {
+		sb.WriteString(" BY " + joinStrings(n.GroupBy))
+	}
+	if n.Condition != nil {
+		switch n.Operation {
+		case PlanAggregate:
+			sb.WriteString(" HAVING ")
+		case PlanHashJoin, PlanNestedLoopJoin:
+			sb.WriteString(" ON ")
+		default:
+			sb.WriteString(" WHERE ")
+		}
+		sb.WriteString(n.Condition.String())
+	}
+	if len(n.OrderBy) > 0 {
+		sb.WriteString(" BY " + joinStrings(n.OrderBy))
+	}
+	switch {
+	case n.Operation == PlanLimit && n.Limit <= 0:
+		sb.WriteString(" ALL")
+	case n.Operation == PlanLimit:
+		fmt.Fprintf(&sb, " %d", n.Limit)
+	case n.Limit > 0:
+		fmt.Fprintf(&sb, " LIMIT %d", n.Limit)
+	}
+	if n.Offset > 0 {
+		fmt.Fprintf(&sb, " OFFSET %d", n.Offset)
+	}
+	if n.EstimatedRows != UnknownRows {
+		fmt.Fprintf(&sb, " (rows: %d)", n.EstimatedRows)
+	}
+	return sb.String()
+}
+
+func joinStrings[T fmt.Stringer](values []T) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = v.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// QueryPlan describes how an adapter would execute a query: a tree of steps
+// whose Root produces the query result.
+type QueryPlan struct {
+	Root *PlanNode `json:"root"`
+}
+
+// String returns the plan as an indented tree, one step per line
+func (p QueryPlan) String() string {
+	var sb strings.Builder
+	var write func(n *PlanNode, depth int)
+	write = func(n *PlanNode, depth int) {
+		sb.WriteString(strings.Repeat("  ", depth))
+		sb.WriteString(n.String())
+		sb.WriteString("\n")
+		for _, input := range n.Inputs {
+			write(input, depth+1)
+		}
+	}
+	if p.Root != nil {
+		write(p.Root, 0)
+	}
+	return sb.String()
+}
+
+// Find returns the steps of the plan that perform the given operation, in
+// depth-first order, e.g. to assert that a query reads through an index.
+func (p QueryPlan) Find(operation PlanOperation) (nodes []*PlanNode) {
+	var walk func(n *PlanNode)
+	walk = func(n *PlanNode) {
+		if n.Operation == operation {
+			nodes = append(nodes, n)
+		}
+		for _, input := range n.Inputs {
+			walk(input)
+		}
+	}
+	if p.Root != nil {
+		walk(p.Root)
+	}
+	return nodes
+}
+
+// Explainer is the optional capability interface a DB, session or
+// transaction implements to describe how it would execute a query without
+// executing it. Consumers SHOULD use the ExplainQuery helper rather than
+// type-asserting themselves.
+type Explainer interface {
+	ExplainQuery(ctx context.Context, query Query) (QueryPlan, error)
+}
+
+// ExplainQuery returns the plan the executor would follow for the query. It
+// returns ErrNotSupported when the executor does not implement Explainer.
+func ExplainQuery(ctx context.Context, executor QueryExecutor, query Query) (QueryPlan, error) {
+	explainer, ok := executor.(Explainer)
+	if !ok {
+		return QueryPlan{}, fmt.Errorf("%w: %T does not explain queries", ErrNotSupported, executor)
+	}
+	return explainer.ExplainQuery(ctx, query)
+}
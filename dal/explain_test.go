@@ -0,0 +1,67 @@
+package dal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func samplePlan() QueryPlan {
+	lookup := &PlanNode{Operation: PlanIndexLookup, Collection: "users", Alias: "u", Index: "status",
+		Condition: WhereField("status", Equal, "active"), EstimatedRows: 3}
+	scan := &PlanNode{Operation: PlanScan, Collection: "orders", EstimatedRows: 10}
+	join := &PlanNode{Operation: PlanHashJoin, JoinType: JoinLeft,
+		Condition:     NewComparison(NewFieldRef("u", "id"), Equal, NewFieldRef("orders", "userId")),
+		EstimatedRows: UnknownRows, Inputs: []*PlanNode{lookup, scan}}
+	sort := &PlanNode{Operation: PlanSort, OrderBy: []OrderExpression{DescendingField("total")}, EstimatedRows: UnknownRows, Inputs: []*PlanNode{join}}
+	return QueryPlan{Root: &PlanNode{Operation: PlanLimit, Limit: 5, Offset: 10, EstimatedRows: 5, Inputs: []*PlanNode{sort}}}
+}
+
+func TestQueryPlan_String(t *testing.T) {
+	assert.Equal(t, `LIMIT 5 OFFSET 10 (rows: 5)
+  SORT BY total DESC
+    HASH_JOIN LEFT ON u.id = orders.userId
+      INDEX_LOOKUP users AS u USING status WHERE status = 'active' (rows: 3)
+      SCAN orders (rows: 10)
+`, samplePlan().String())
+	assert.Equal(t, "", QueryPlan{}.String())
+
+	aggregate := &PlanNode{Operation: PlanAggregate, GroupBy: []Expression{Field("team")},
+		Condition: NewComparison(Field("n"), GreaterThen, Constant{Value: 1}), EstimatedRows: UnknownRows}
+	assert.Equal(t, "AGGREGATE BY team HAVING n > 1", aggregate.String())
+	scan := &PlanNode{Operation: PlanScan, Collection: "users", Limit: 10, EstimatedRows: 10}
+	assert.Equal(t, "SCAN users LIMIT 10 (rows: 10)", scan.String(), "a limit pushed down to a read")
+}
+
+func TestQueryPlan_Find(t *testing.T) {
+	plan := samplePlan()
+	lookups := plan.Find(PlanIndexLookup)
+	require.Len(t, lookups, 1)
+	assert.Equal(t, "status", lookups[0].Index)
+	assert.Len(t, plan.Find(PlanScan), 1)
+	assert.Empty(t, plan.Find(PlanNestedLoopJoin))
+	assert.Empty(t, QueryPlan{}.Find(PlanScan))
+}
+
+type explainingExecutor struct {
+	mockQueryExecutor
+	plan QueryPlan
+}
+
+func (e explainingExecutor) ExplainQuery(_ context.Context, _ Query) (QueryPlan, error) {
+	return e.plan, nil
+}
+
+func TestExplainQuery(t *testing.T) {
+	ctx := context.Background()
+	q := From(NewRootCollectionRef("users", "")).NewQuery().SelectKeysOnly(0)
+
+	_, err := ExplainQuery(ctx, mockQueryExecutor{}, q)
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	plan, err := ExplainQuery(ctx, explainingExecutor{plan: samplePlan()}, q)
+	require.NoError(t, err)
+	assert.Equal(t, samplePlan(), plan)
+}
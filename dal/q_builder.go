@@ -34,6 +34,18 @@ type IQueryBuilder interface {
 
 var _ IQueryBuilder = (*QueryBuilder)(nil)
 
+// DistinctQueryBuilder is the optional interface of a query builder that can
+// build DISTINCT queries, kept out of IQueryBuilder so that existing
+// implementations still compile. *QueryBuilder implements it, so Distinct can
+// be called on the builder NewQuery returns before chaining IQueryBuilder
+// methods, or on any IQueryBuilder after a type assertion:
+// qb.(dal.DistinctQueryBuilder).Distinct().
+type DistinctQueryBuilder interface {
+	Distinct() IQueryBuilder
+}
+
+var _ DistinctQueryBuilder = (*QueryBuilder)(nil)
+
 // NewQueryBuilder creates a new IQueryBuilder - it's an entry point to build a query.
 // We can use From() directly but this is easier to remember.
 func NewQueryBuilder(from FromSource) *QueryBuilder {
@@ -86,6 +98,14 @@ func (s *QueryBuilder) OrderBy(expressions ...OrderExpression) IQueryBuilder {
 	return s
 }
 
+// Distinct removes duplicate rows from the result (see SQL SELECT DISTINCT).
+// Rows are compared by their selected columns, or by all their fields when no
+// columns are selected.
+func (s *QueryBuilder) Distinct() IQueryBuilder {
+	s.q.distinct = true
+	return s
+}
+
 func (s *QueryBuilder) Where(conditions ...Condition) IQueryBuilder {
 	s.conditions = append(s.conditions, conditions...)
 	return s
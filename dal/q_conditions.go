@@ -25,12 +25,41 @@ func IsGroupOperator(o Operator) bool {
 	return o == In
 }
 
+// IsArrayOperator says if an operator takes an Array as its right operand
+func IsArrayOperator(o Operator) bool {
+	switch o {
+	case In, NotIn, Between, ArrayContainsAny:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsUnaryOperator says if an operator takes no right operand
+func IsUnaryOperator(o Operator) bool {
+	return o == IsNull || o == IsNotNull
+}
+
 // String returns string representation of a comparison
 func (v Comparison) String() string {
 	o := v.Operator
 	switch o {
 	case Equal:
 		o = "="
+	case NotEqual:
+		o = "<>"
+	case NotIn:
+		o = "NOT IN"
+	case IsNull:
+		return fmt.Sprintf("%v IS NULL", v.Left)
+	case IsNotNull:
+		return fmt.Sprintf("%v IS NOT NULL", v.Left)
+	case Between:
+		if bounds, ok := v.Right.(Array); ok {
+			if rv := reflect.ValueOf(bounds.Value); rv.Kind() == reflect.Slice && rv.Len() == 2 {
+				return fmt.Sprintf("%v BETWEEN %v AND %v", v.Left, Constant{Value: rv.Index(0).Interface()}, Constant{Value: rv.Index(1).Interface()})
+			}
+		}
 	case "":
 		o = "{NO_OPERATOR}"
 	}
@@ -45,6 +45,31 @@ func testComparisonString(t *testing.T) {
 			comparison: Comparison{},
 			want:       "<nil> {NO_OPERATOR} <nil>",
 		},
+		{
+			name:       "not_equal",
+			comparison: NewComparison(Field("a"), NotEqual, String("x")),
+			want:       "a <> 'x'",
+		},
+		{
+			name:       "not_in",
+			comparison: NewComparison(Field("a"), NotIn, NewArray([]int{1, 2})),
+			want:       "a NOT IN (1,2)",
+		},
+		{
+			name:       "is_null",
+			comparison: Comparison{Left: Field("a"), Operator: IsNull},
+			want:       "a IS NULL",
+		},
+		{
+			name:       "is_not_null",
+			comparison: Comparison{Left: Field("a"), Operator: IsNotNull},
+			want:       "a IS NOT NULL",
+		},
+		{
+			name:       "between",
+			comparison: NewComparison(Field("a"), Between, NewArray([]int{1, 9})),
+			want:       "a BETWEEN 1 AND 9",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -79,6 +104,21 @@ func TestIsGroupOperator(t *testing.T) {
 	}
 }
 
+func TestIsArrayOperator(t *testing.T) {
+	for _, o := range []Operator{In, NotIn, Between, ArrayContainsAny} {
+		assert.True(t, IsArrayOperator(o), o)
+	}
+	for _, o := range []Operator{Equal, NotEqual, IsNull, StartsWith, Like, ArrayContains} {
+		assert.False(t, IsArrayOperator(o), o)
+	}
+}
+
+func TestIsUnaryOperator(t *testing.T) {
+	assert.True(t, IsUnaryOperator(IsNull))
+	assert.True(t, IsUnaryOperator(IsNotNull))
+	assert.False(t, IsUnaryOperator(Equal))
+}
+
 func TestNewComparison(t *testing.T) {
 	type args struct {
 		left  Expression
@@ -55,6 +55,12 @@ func (f FieldRef) EqualTo(v any) Condition {
 }
 
 func WhereField(name string, operator Operator, v any) Condition {
+	if IsUnaryOperator(operator) {
+		if v != nil {
+			panic(fmt.Sprintf("the `%v` operator takes no value", operator))
+		}
+		return Comparison{Operator: operator, Left: Field(name)}
+	}
 	var val Expression
 	switch v := v.(type) {
 	case
@@ -67,8 +73,8 @@ func WhereField(name string, operator Operator, v any) Condition {
 		time.Time:
 		val = Constant{Value: v}
 	case []string, []int, []int8, []int16, []int32, []int64, []uint, []uint8, []uint16, []uint32, []uint64, []float32, []float64:
-		if operator != In {
-			panic("arrays must use with `In` operator")
+		if !IsArrayOperator(operator) {
+			panic(fmt.Sprintf("arrays can not be used with the `%v` operator", operator))
 		}
 		val = Array{Value: v}
 	case Constant:
@@ -76,8 +82,8 @@ func WhereField(name string, operator Operator, v any) Condition {
 	case FieldRef:
 		val = v
 	case Array:
-		if operator != In {
-			panic("arrays must use with `In` operator")
+		if !IsArrayOperator(operator) {
+			panic(fmt.Sprintf("arrays can not be used with the `%v` operator", operator))
 		}
 		val = v
 	default:
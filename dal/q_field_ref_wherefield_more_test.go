@@ -1,6 +1,7 @@
 package dal
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -43,3 +44,23 @@ func TestWhereField_panics_on_unsupported_type(t *testing.T) {
 	}()
 	_ = WhereField("x", Equal, unsupported{})
 }
+
+func TestWhereField_extendedOperators(t *testing.T) {
+	if got := WhereField("a", IsNull, nil); got != (Comparison{Left: Field("a"), Operator: IsNull}) {
+		t.Errorf("IsNull comparison must have no right operand, got %#v", got)
+	}
+	for _, o := range []Operator{NotIn, Between, ArrayContainsAny} {
+		if got := WhereField("a", o, []int{1, 2}).(Comparison); !reflect.DeepEqual(got.Right, Array{Value: []int{1, 2}}) {
+			t.Errorf("%v: expected an Array right operand, got %#v", o, got.Right)
+		}
+	}
+}
+
+func TestWhereField_panics_on_unary_operator_with_value(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for IsNull with a value")
+		}
+	}()
+	_ = WhereField("a", IsNull, 1)
+}
@@ -11,25 +11,57 @@ const (
 	MIN     = "MIN"
 	MAX     = "MAX"
 	AVERAGE = "AVG"
+
+	MEDIAN     = "MEDIAN"
+	PERCENTILE = "PERCENTILE"
+	STRING_AGG = "STRING_AGG"
+	ARRAY_AGG  = "ARRAY_AGG"
+	STDDEV     = "STDDEV"
+	STDDEV_POP = "STDDEV_POP"
 )
 
 type function struct {
-	Name string       `json:"name"`
-	Args []Expression `json:"args"`
+	Name     string       `json:"name"`
+	Args     []Expression `json:"args"`
+	Distinct bool         `json:"distinct,omitempty"`
 }
 
 var _ Expression = (*function)(nil)
 
 // AggregateFunc is implemented by aggregate function expressions (SUM, COUNT,
-// MIN, MAX, AVG) so adapters can introspect the function name and its arguments
+// MIN, MAX, AVG, MEDIAN, PERCENTILE, STRING_AGG, ARRAY_AGG, STDDEV,
+// STDDEV_POP) so adapters can introspect the function name and its arguments
 // without depending on the unexported concrete type.
+//
+// The first argument is the aggregated expression. PERCENTILE has the
+// percentile as a Constant float64 second argument, and STRING_AGG the
+// separator as a Constant string.
 type AggregateFunc interface {
 	Expression
 	FuncName() string
 	FuncArgs() []Expression
 }
 
-var _ AggregateFunc = function{}
+// DistinctAggregateFunc is the optional interface of an AggregateFunc that
+// can aggregate distinct values only, kept out of AggregateFunc so that
+// existing implementations still compile. Consumers SHOULD use the
+// IsDistinctAggregate helper rather than type-asserting themselves.
+type DistinctAggregateFunc interface {
+	AggregateFunc
+
+	// FuncDistinct reports whether only distinct non-NULL values of the
+	// aggregated expression are aggregated, e.g. COUNT(DISTINCT x).
+	FuncDistinct() bool
+}
+
+// IsDistinctAggregate reports whether the aggregate is over distinct values,
+// false for one that does not implement DistinctAggregateFunc.
+func IsDistinctAggregate(f AggregateFunc) bool {
+	distinct, ok := f.(DistinctAggregateFunc)
+	return ok && distinct.FuncDistinct()
+}
+
+var _ DistinctAggregateFunc = function{}
 
 // FuncName returns the aggregate function name (e.g. SUM, COUNT).
 func (v function) FuncName() string { return v.Name }
@@ -37,13 +69,20 @@ func (v function) FuncName() string { return v.Name }
 // FuncArgs returns the aggregate function arguments.
 func (v function) FuncArgs() []Expression { return v.Args }
 
+// FuncDistinct reports whether the aggregate is over distinct values.
+func (v function) FuncDistinct() bool { return v.Distinct }
+
 // String returns a text representation of a function
 func (v function) String() string {
 	args := make([]string, len(v.Args))
 	for i, arg := range v.Args {
 		args[i] = arg.String()
 	}
-	return fmt.Sprintf("%v(%v)", v.Name, strings.Join(args, ", "))
+	var distinct string
+	if v.Distinct {
+		distinct = "DISTINCT "
+	}
+	return fmt.Sprintf("%v(%v%v)", v.Name, distinct, strings.Join(args, ", "))
 }
 
 // star is the `*` argument of COUNT(*); it is not a field reference, which is
@@ -97,3 +136,57 @@ func MaxAs(expression Expression, alias string) Column {
 func AverageAs(expression Expression, alias string) Column {
 	return singleArgFunctionAs(AVERAGE, alias, expression)
 }
+
+// CountDistinctAs counts the distinct non-NULL values of an expression (see
+// SQL COUNT(DISTINCT ...))
+func CountDistinctAs(expression Expression, alias string) Column {
+	return Column{
+		Expression: function{Name: COUNT, Args: []Expression{expression}, Distinct: true},
+		Alias:      alias,
+	}
+}
+
+// MedianAs returns the median of the numeric values of an expression,
+// interpolated between the two middle values for an even count
+func MedianAs(expression Expression, alias string) Column {
+	return singleArgFunctionAs(MEDIAN, alias, expression)
+}
+
+// PercentileAs returns the continuous percentile (see SQL PERCENTILE_CONT) of
+// the numeric values of an expression; percentile must be between 0 and 1.
+func PercentileAs(expression Expression, percentile float64, alias string) Column {
+	if percentile < 0 || percentile > 1 {
+		panic(fmt.Sprintf("percentile must be between 0 and 1, got %v", percentile))
+	}
+	return Column{
+		Expression: function{Name: PERCENTILE, Args: []Expression{expression, Constant{Value: percentile}}},
+		Alias:      alias,
+	}
+}
+
+// StringAggAs concatenates the non-NULL values of an expression, separated by
+// separator (see SQL STRING_AGG())
+func StringAggAs(expression Expression, separator string, alias string) Column {
+	return Column{
+		Expression: function{Name: STRING_AGG, Args: []Expression{expression, Constant{Value: separator}}},
+		Alias:      alias,
+	}
+}
+
+// ArrayAggAs collects the values of an expression, NULLs included, into an
+// array (see SQL ARRAY_AGG())
+func ArrayAggAs(expression Expression, alias string) Column {
+	return singleArgFunctionAs(ARRAY_AGG, alias, expression)
+}
+
+// StdDevAs returns the sample standard deviation of the numeric values of an
+// expression (see SQL STDDEV_SAMP())
+func StdDevAs(expression Expression, alias string) Column {
+	return singleArgFunctionAs(STDDEV, alias, expression)
+}
+
+// StdDevPopAs returns the population standard deviation of the numeric values
+// of an expression (see SQL STDDEV_POP())
+func StdDevPopAs(expression Expression, alias string) Column {
+	return singleArgFunctionAs(STDDEV_POP, alias, expression)
+}
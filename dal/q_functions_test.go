@@ -45,3 +45,38 @@ func TestAverageAs(t *testing.T) {
 	assert.Equal(t, alias, averageAs.Alias)
 	assert.Equal(t, "AVG(id) AS c1", averageAs.String())
 }
+
+func TestExtendedAggregates(t *testing.T) {
+	for _, tt := range []struct {
+		column Column
+		want   string
+	}{
+		{CountDistinctAs(Field("id"), "c1"), "COUNT(DISTINCT id) AS c1"},
+		{MedianAs(Field("price"), "c1"), "MEDIAN(price) AS c1"},
+		{PercentileAs(Field("price"), 0.9, "c1"), "PERCENTILE(price, 0.9) AS c1"},
+		{StringAggAs(Field("name"), ", ", "c1"), "STRING_AGG(name, ', ') AS c1"},
+		{ArrayAggAs(Field("name"), "c1"), "ARRAY_AGG(name) AS c1"},
+		{StdDevAs(Field("price"), "c1"), "STDDEV(price) AS c1"},
+		{StdDevPopAs(Field("price"), "c1"), "STDDEV_POP(price) AS c1"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, "c1", tt.column.Alias)
+			assert.Equal(t, tt.want, tt.column.String())
+			_, isAggregate := tt.column.Expression.(AggregateFunc)
+			assert.True(t, isAggregate)
+		})
+	}
+	assert.True(t, IsDistinctAggregate(CountDistinctAs(Field("id"), "").Expression.(AggregateFunc)))
+	assert.False(t, IsDistinctAggregate(CountAs(Field("id"), "").Expression.(AggregateFunc)))
+	assert.False(t, IsDistinctAggregate(plainAggregate{}), "an AggregateFunc without FuncDistinct")
+	assert.Panics(t, func() { PercentileAs(Field("price"), 1.5, "p") })
+	assert.Panics(t, func() { PercentileAs(Field("price"), -0.1, "p") })
+}
+
+// plainAggregate is an AggregateFunc implemented outside this package before
+// DistinctAggregateFunc existed.
+type plainAggregate struct{}
+
+func (plainAggregate) String() string         { return "CUSTOM()" }
+func (plainAggregate) FuncName() string       { return "CUSTOM" }
+func (plainAggregate) FuncArgs() []Expression { return nil }
@@ -74,3 +74,22 @@ func TestCount_Star(t *testing.T) {
 	assert.Equal(t, "COUNT(amount) AS n", field.String())
 	assert.NotEqual(t, c.String(), field.String())
 }
+
+// Distinct is recorded on the query and rendered after SELECT.
+func TestQueryBuilder_Distinct(t *testing.T) {
+	q := From(NewRootCollectionRef("sales", "")).NewQuery().
+		Distinct().
+		SelectColumns(Column{Expression: Field("category")})
+	assert.True(t, IsDistinct(q))
+	assert.Equal(t, "SELECT DISTINCT category FROM [sales]", q.String())
+
+	plain := From(NewRootCollectionRef("sales", "")).NewQuery().
+		Where(WhereField("category", Equal, "a")).
+		SelectColumns(Column{Expression: Field("category")})
+	assert.False(t, IsDistinct(plain))
+
+	var qb IQueryBuilder = From(NewRootCollectionRef("sales", "")).NewQuery().Limit(1)
+	chained := qb.(DistinctQueryBuilder).Distinct().SelectColumns(Column{Expression: Field("category")})
+	assert.True(t, IsDistinct(chained))
+	assert.False(t, IsDistinct(NewTextQuery("SELECT 1", nil)), "a query without Distinct")
+}
@@ -0,0 +1,25 @@
+package dal
+
+import "fmt"
+
+// NotCondition negates the wrapped condition.
+type NotCondition struct {
+	condition Condition
+}
+
+// Not creates a condition that matches when the given condition does not.
+func Not(condition Condition) NotCondition {
+	return NotCondition{condition: condition}
+}
+
+// Condition returns the negated condition.
+func (v NotCondition) Condition() Condition {
+	return v.condition
+}
+
+func (v NotCondition) String() string {
+	if _, isGroup := v.condition.(GroupCondition); isGroup {
+		return fmt.Sprintf("NOT %v", v.condition) // a group is already parenthesized
+	}
+	return fmt.Sprintf("NOT (%v)", v.condition)
+}
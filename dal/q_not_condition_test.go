@@ -0,0 +1,17 @@
+package dal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNot(t *testing.T) {
+	comparison := WhereField("a", Equal, 1)
+	not := Not(comparison)
+	assert.Equal(t, comparison, not.Condition())
+	assert.Equal(t, "NOT (a = 1)", not.String())
+
+	group := NewGroupCondition(Or, comparison, WhereField("b", IsNull, nil))
+	assert.Equal(t, "NOT (a = 1 OR b IS NULL)", Not(group).String())
+}
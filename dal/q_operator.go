@@ -22,6 +22,41 @@ const (
 	// LessOrEqual is a Comparison operator
 	LessOrEqual Operator = "<="
 
+	// NotEqual is a Comparison operator. A missing or NULL left operand does
+	// not match.
+	NotEqual Operator = "!="
+
+	// NotIn is a Comparison operator; the right operand is an Array.
+	NotIn Operator = "NotIn"
+
+	// IsNull is a unary Comparison operator matching a missing or NULL left
+	// operand; the right operand is nil.
+	IsNull Operator = "IsNull"
+
+	// IsNotNull is a unary Comparison operator, the negation of IsNull.
+	IsNotNull Operator = "IsNotNull"
+
+	// Between is a Comparison operator matching lower <= left <= upper (both
+	// bounds inclusive); the right operand is an Array of exactly two values.
+	Between Operator = "Between"
+
+	// StartsWith is a Comparison operator matching a string left operand that
+	// begins with the right operand.
+	StartsWith Operator = "StartsWith"
+
+	// Like is a Comparison operator matching a string left operand against a
+	// SQL LIKE pattern: % matches any run of characters, _ a single character
+	// and a backslash escapes the next pattern character.
+	Like Operator = "Like"
+
+	// ArrayContains is a Comparison operator matching an array field (left)
+	// that contains the right operand, e.g. Firestore's "array-contains".
+	ArrayContains Operator = "array-contains"
+
+	// ArrayContainsAny is a Comparison operator matching an array field (left)
+	// that contains at least one element of the right Array operand.
+	ArrayContainsAny Operator = "array-contains-any"
+
 	// And is a Comparison operator // TODO: Is it an operator?
 	And = "AND"
 
@@ -52,9 +52,9 @@ func (f *from) Join(joint JoinedSource) FromSource {
 	return f
 }
 
-// JoinType enumerates the kinds of join. JoinInner and JoinLeft are
-// supported by executors; JoinRight, JoinFull and JoinCross are reserved
-// for future support and rejected at execution time until implemented.
+// JoinType enumerates the kinds of join. JoinInner, JoinLeft, JoinRight and
+// JoinFull are equi-joins on the ON conditions; JoinCross pairs every row and
+// takes none. Executors that lack a kind reject it at execution time.
 type JoinType string
 
 const (
@@ -0,0 +1,134 @@
+package dal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scalar function names
+const (
+	LOWER      = "LOWER"
+	UPPER      = "UPPER"
+	LENGTH     = "LENGTH"
+	COALESCE   = "COALESCE"
+	CONCAT     = "CONCAT"
+	DATE_TRUNC = "DATE_TRUNC"
+	EXTRACT    = "EXTRACT"
+)
+
+// DatePart names a calendar unit for DateTrunc and Extract
+type DatePart string
+
+const (
+	DatePartYear    DatePart = "year"
+	DatePartQuarter DatePart = "quarter"
+	DatePartMonth   DatePart = "month"
+	DatePartWeek    DatePart = "week" // ISO week, starting on Monday
+	DatePartDay     DatePart = "day"
+	DatePartHour    DatePart = "hour"
+	DatePartMinute  DatePart = "minute"
+	DatePartSecond  DatePart = "second"
+)
+
+// ScalarFunction is a per-row function call, e.g. LOWER(name). Unlike an
+// AggregateFunc it is evaluated for each row and can be used anywhere an
+// Expression is accepted: in conditions, columns, ordering and grouping.
+//
+// Use the constructors (Lower, Upper, Length, Coalesce, Concat, DateTrunc,
+// Extract); adapters switch on Name to translate the call. The unit argument
+// of DATE_TRUNC and EXTRACT is a Constant holding a DatePart string.
+type ScalarFunction struct {
+	Name string       `json:"name"`
+	Args []Expression `json:"args"`
+}
+
+var _ Expression = ScalarFunction{}
+
+// String returns a text representation of a scalar function call
+func (v ScalarFunction) String() string {
+	args := make([]string, len(v.Args))
+	for i, arg := range v.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("%v(%v)", v.Name, strings.Join(args, ", "))
+}
+
+// Lower converts a string to lower case
+func Lower(expression Expression) ScalarFunction {
+	return ScalarFunction{Name: LOWER, Args: []Expression{expression}}
+}
+
+// Upper converts a string to upper case
+func Upper(expression Expression) ScalarFunction {
+	return ScalarFunction{Name: UPPER, Args: []Expression{expression}}
+}
+
+// Length returns the number of characters in a string
+func Length(expression Expression) ScalarFunction {
+	return ScalarFunction{Name: LENGTH, Args: []Expression{expression}}
+}
+
+// Coalesce returns the first of its arguments that is not NULL
+func Coalesce(expressions ...Expression) ScalarFunction {
+	return ScalarFunction{Name: COALESCE, Args: expressions}
+}
+
+// Concat joins its arguments as strings; it is NULL if any argument is NULL
+func Concat(expressions ...Expression) ScalarFunction {
+	return ScalarFunction{Name: CONCAT, Args: expressions}
+}
+
+// DateTrunc truncates a timestamp to the start of the given unit
+func DateTrunc(unit DatePart, expression Expression) ScalarFunction {
+	return ScalarFunction{Name: DATE_TRUNC, Args: []Expression{Constant{Value: string(unit)}, expression}}
+}
+
+// Extract returns the given part of a timestamp as an integer, e.g. its year
+func Extract(part DatePart, expression Expression) ScalarFunction {
+	return ScalarFunction{Name: EXTRACT, Args: []Expression{Constant{Value: string(part)}, expression}}
+}
+
+// ArithmeticOperator defines an Arithmetic operator
+type ArithmeticOperator string
+
+const (
+	Addition       ArithmeticOperator = "+"
+	Subtraction    ArithmeticOperator = "-"
+	Multiplication ArithmeticOperator = "*"
+	Division       ArithmeticOperator = "/"
+)
+
+// Arithmetic is a binary arithmetic expression over two numeric operands, e.g.
+// price * quantity. It is NULL when either operand is NULL.
+type Arithmetic struct {
+	Operator ArithmeticOperator `json:"operator"`
+	Left     Expression         `json:"left"`
+	Right    Expression         `json:"right"`
+}
+
+var _ Expression = Arithmetic{}
+
+// String returns a parenthesized text representation of the expression
+func (v Arithmetic) String() string {
+	return fmt.Sprintf("(%v %v %v)", v.Left, v.Operator, v.Right)
+}
+
+// Add creates a left + right expression
+func Add(left, right Expression) Arithmetic {
+	return Arithmetic{Operator: Addition, Left: left, Right: right}
+}
+
+// Subtract creates a left - right expression
+func Subtract(left, right Expression) Arithmetic {
+	return Arithmetic{Operator: Subtraction, Left: left, Right: right}
+}
+
+// Multiply creates a left * right expression
+func Multiply(left, right Expression) Arithmetic {
+	return Arithmetic{Operator: Multiplication, Left: left, Right: right}
+}
+
+// Divide creates a left / right expression; division by zero yields NULL
+func Divide(left, right Expression) Arithmetic {
+	return Arithmetic{Operator: Division, Left: left, Right: right}
+}
@@ -0,0 +1,36 @@
+package dal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalarFunction_String(t *testing.T) {
+	for _, tt := range []struct {
+		expression Expression
+		want       string
+	}{
+		{Lower(Field("name")), "LOWER(name)"},
+		{Upper(Field("name")), "UPPER(name)"},
+		{Length(Field("name")), "LENGTH(name)"},
+		{Coalesce(Field("nick"), Field("name"), String("?")), "COALESCE(nick, name, '?')"},
+		{Concat(Field("first"), String(" "), Field("last")), "CONCAT(first, ' ', last)"},
+		{DateTrunc(DatePartDay, Field("ts")), "DATE_TRUNC('day', ts)"},
+		{Extract(DatePartYear, Field("ts")), "EXTRACT('year', ts)"},
+		{Add(Field("a"), NewConstant(1)), "(a + 1)"},
+		{Subtract(Field("a"), Field("b")), "(a - b)"},
+		{Multiply(Field("price"), Field("qty")), "(price * qty)"},
+		{Divide(Multiply(Field("a"), Field("b")), NewConstant(2)), "((a * b) / 2)"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.expression.String())
+		})
+	}
+}
+
+func TestScalarFunction_IsNotAggregate(t *testing.T) {
+	var e Expression = Lower(Field("name"))
+	_, isAggregate := e.(AggregateFunc)
+	assert.False(t, isAggregate)
+}
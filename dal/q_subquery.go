@@ -0,0 +1,81 @@
+package dal
+
+import "fmt"
+
+// InSubqueryCondition matches when an expression equals any value of the
+// single column a subquery selects (see SQL `x IN (SELECT ...)`).
+//
+// The subquery may be correlated: a FieldRef in it whose Source() names a
+// recordset of the enclosing query (and none of its own) refers to the
+// enclosing query's current row.
+type InSubqueryCondition struct {
+	expression Expression
+	query      StructuredQuery
+}
+
+// InSubquery creates a condition that matches when expression equals any value
+// selected by query, which must select exactly one column.
+func InSubquery(expression Expression, query StructuredQuery) InSubqueryCondition {
+	return InSubqueryCondition{expression: expression, query: query}
+}
+
+// Expression returns the expression looked up in the subquery results.
+func (v InSubqueryCondition) Expression() Expression {
+	return v.expression
+}
+
+// Query returns the subquery.
+func (v InSubqueryCondition) Query() StructuredQuery {
+	return v.query
+}
+
+func (v InSubqueryCondition) String() string {
+	return fmt.Sprintf("%v IN (%v)", v.expression, v.query)
+}
+
+// ExistsCondition matches when a subquery returns at least one row (see SQL
+// `EXISTS (SELECT ...)`). Like InSubqueryCondition, it is usually correlated
+// to the enclosing query through qualified FieldRefs.
+type ExistsCondition struct {
+	query StructuredQuery
+}
+
+// Exists creates a condition that matches when query returns any row, e.g.
+// users having an open order, correlated on a field of the users' data (a
+// record's key is not a field adapters can compare):
+//
+//	dal.Exists(dal.From(dal.NewRootCollectionRef("orders", "o")).NewQuery().
+//		Where(dal.NewComparison(dal.NewFieldRef("o", "userEmail"), dal.Equal, dal.NewFieldRef("u", "email")),
+//			dal.WhereField("status", dal.Equal, "open")).
+//		SelectKeysOnly(reflect.String))
+func Exists(query StructuredQuery) ExistsCondition {
+	return ExistsCondition{query: query}
+}
+
+// Query returns the subquery.
+func (v ExistsCondition) Query() StructuredQuery {
+	return v.query
+}
+
+func (v ExistsCondition) String() string {
+	return fmt.Sprintf("EXISTS (%v)", v.query)
+}
+
+// HasSubquery reports whether a condition tree contains an InSubqueryCondition
+// or an ExistsCondition, so adapters that cannot execute subqueries can reject
+// such a query with ErrNotSupported instead of misreading it.
+func HasSubquery(condition Condition) bool {
+	switch c := condition.(type) {
+	case InSubqueryCondition, ExistsCondition:
+		return true
+	case GroupCondition:
+		for _, sub := range c.Conditions() {
+			if HasSubquery(sub) {
+				return true
+			}
+		}
+	case NotCondition:
+		return HasSubquery(c.Condition())
+	}
+	return false
+}
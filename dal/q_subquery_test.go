@@ -0,0 +1,43 @@
+package dal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openOrdersOf(userSource string) StructuredQuery {
+	return From(NewRootCollectionRef("orders", "o")).NewQuery().
+		Where(
+			NewComparison(NewFieldRef("o", "userID"), Equal, NewFieldRef(userSource, "id")),
+			WhereField("status", Equal, "open"),
+		).
+		SelectKeysOnly(reflect.String)
+}
+
+func TestExists(t *testing.T) {
+	subquery := openOrdersOf("u")
+	exists := Exists(subquery)
+	assert.Equal(t, subquery, exists.Query())
+	assert.Equal(t, "EXISTS (SELECT *\nFROM [orders]\nWHERE (o.userID = u.id AND status = 'open'))", exists.String())
+}
+
+func TestInSubquery(t *testing.T) {
+	subquery := From(NewRootCollectionRef("orders", "")).NewQuery().
+		WhereField("status", Equal, "open").
+		SelectColumns(Column{Expression: Field("userID")})
+	in := InSubquery(Field("id"), subquery)
+	assert.Equal(t, Field("id"), in.Expression())
+	assert.Equal(t, subquery, in.Query())
+	assert.Equal(t, "id IN (SELECT userID FROM [orders] WHERE status = 'open')", in.String())
+}
+
+func TestHasSubquery(t *testing.T) {
+	exists := Exists(openOrdersOf("u"))
+	assert.True(t, HasSubquery(exists))
+	assert.True(t, HasSubquery(InSubquery(Field("id"), openOrdersOf("u"))))
+	assert.True(t, HasSubquery(NewGroupCondition(And, WhereField("a", Equal, 1), Not(exists))))
+	assert.False(t, HasSubquery(NewGroupCondition(Or, WhereField("a", Equal, 1), Not(WhereField("b", Equal, 2)))))
+	assert.False(t, HasSubquery(nil))
+}
@@ -58,3 +58,20 @@ type StructuredQuery interface {
 	// StartFrom specifies the startCursor/point to start from
 	StartFrom() Cursor
 }
+
+// DistinctQuery is the optional interface a StructuredQuery implements when
+// it can remove duplicate result rows; the queries built by QueryBuilder do.
+// It is kept out of StructuredQuery so that existing implementations still
+// compile. Consumers SHOULD use the IsDistinct helper rather than
+// type-asserting themselves.
+type DistinctQuery interface {
+	// Distinct reports whether duplicate result rows are removed
+	Distinct() bool
+}
+
+// IsDistinct reports whether duplicate result rows of the query are removed,
+// false for a query that does not implement DistinctQuery.
+func IsDistinct(q Query) bool {
+	distinct, ok := q.(DistinctQuery)
+	return ok && distinct.Distinct()
+}
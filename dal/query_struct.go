@@ -34,6 +34,9 @@ type structuredQuery struct {
 	// Columns define what columns to return
 	columns []Column
 
+	// Distinct removes duplicate result rows
+	distinct bool
+
 	intoRecord       func() record.Record
 	recordsetOptions []recordset.Option
 
@@ -85,6 +88,12 @@ func (q structuredQuery) Columns() []Column {
 	return q.columns[:]
 }
 
+var _ DistinctQuery = structuredQuery{}
+
+func (q structuredQuery) Distinct() bool {
+	return q.distinct
+}
+
 func (q structuredQuery) IntoRecord() record.Record {
 	if q.intoRecord == nil {
 		return nil
@@ -111,6 +120,9 @@ func (q structuredQuery) Limit() int {
 func (q structuredQuery) String() string {
 	writer := bytes.NewBuffer(make([]byte, 0, 1024))
 	_, _ = writer.WriteString("SELECT")
+	if q.distinct {
+		_, _ = writer.WriteString(" DISTINCT")
+	}
 	if q.limit > 0 {
 		_, _ = writer.WriteString(" TOP " + strconv.Itoa(q.limit))
 	}
@@ -17,79 +17,44 @@ import (
 // - If WithLimit <= 0, reads until RecordsReader.Next() returns ErrNoMoreRecords.
 // - Ensures reader.Close() is called; if Close returns an error and no prior error occurred, that error is returned.
 // - Any panic inside getItem will propagate to the caller.
+//
+// It is a thin consumer of Records; use Records directly to stream records without collecting them.
 func SelectAll(ctx context.Context, reader RecordsReader, addItem func(r record.Record), options ...ReaderOption) (err error) {
 	if reader == nil {
 		panic("reader is a required parameter, got nil")
 	}
-	// Ensure Close is called and its error is propagated if no earlier error.
-	defer func() {
-		closeErr := reader.Close()
-		if err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close reader: %w", closeErr)
-		}
-	}()
-
 	ro := newReaderOptions(options...)
 
-	// Skip offset records if requested.
-	offset := ro.offset
-	for offset > 0 {
-		if ctx != nil {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+	// The context is checked before each read, so a done context stops reading immediately.
+	ctxErr := func() error {
+		if ctx == nil {
+			return nil
 		}
-		if _, e := reader.Next(); e != nil {
-			if errors.Is(e, ErrNoMoreRecords) {
-				// Nothing to read after skipping: return empty slice and nil error.
-				return nil
-			}
+		return ctx.Err()
+	}
+	if err = ctxErr(); err != nil {
+		_ = reader.Close()
+		return err
+	}
+	var closeErr error
+	skipped, added := 0, 0
+	for r, e := range readRecords(reader, &closeErr) {
+		if e != nil {
 			return e
 		}
-		offset--
-	}
-
-	if ro.limit <= 0 {
-		// Unlimited read until no more records.
-		for {
-			if ctx != nil {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-				}
-			}
-			r, e := reader.Next()
-			if e != nil {
-				if errors.Is(e, io.EOF) {
-					return nil
-				}
-				return e
-			}
+		if skipped < ro.offset {
+			skipped++
+		} else {
 			addItem(r)
-		}
-	} else {
-		for i := 0; i < ro.limit; i++ {
-			if ctx != nil {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-				}
-			}
-			r, e := reader.Next()
-			if e != nil {
-				if errors.Is(e, ErrNoMoreRecords) {
-					return nil
-				}
-				return e
+			if added++; ro.limit > 0 && added >= ro.limit {
+				break
 			}
-			addItem(r)
 		}
-		return nil
+		if err = ctxErr(); err != nil {
+			return err
+		}
 	}
+	return closeErr
 }
 
 // SelectAllIDs is a helper method that for a given reader returns all IDs as a strongly typed slice.
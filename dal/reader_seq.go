@@ -0,0 +1,160 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/dal-go/dalgo/recordset"
+	"github.com/dal-go/record"
+)
+
+// Records returns a range-over-func view of reader:
+//
+//	for rec, err := range dal.Records(reader) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Iteration ends when the reader reports ErrNoMoreRecords (or io.EOF). Any
+// other Next error is yielded once as (nil, err) and ends the iteration. The
+// reader is closed exactly once when iteration ends for any reason, including
+// the consumer breaking out of the loop early; a Close error is yielded as the
+// last item when the reader was drained, and is discarded after an early break.
+//
+// The returned sequence is single-use: it consumes reader.
+func Records(reader RecordsReader) iter.Seq2[record.Record, error] {
+	return readRecords(reader, nil)
+}
+
+// readRecords implements Records. When breakCloseErr is not nil, a Close error
+// after an early break or a yielded error is stored there instead of being
+// discarded.
+func readRecords(reader RecordsReader, breakCloseErr *error) iter.Seq2[record.Record, error] {
+	return func(yield func(record.Record, error) bool) {
+		closed := false
+		defer func() {
+			if !closed { // a panic unwinding through Next or the loop body
+				_ = reader.Close()
+			}
+		}()
+		drained := true
+		for {
+			r, err := reader.Next()
+			if err != nil {
+				if !errors.Is(err, ErrNoMoreRecords) && !errors.Is(err, io.EOF) {
+					yield(nil, err)
+					drained = false
+				}
+				break
+			}
+			if !yield(r, nil) {
+				drained = false
+				break
+			}
+		}
+		closed = true
+		if err := reader.Close(); err != nil {
+			err = fmt.Errorf("failed to close reader: %w", err)
+			if drained {
+				yield(nil, err)
+			} else if breakCloseErr != nil {
+				*breakCloseErr = err
+			}
+		}
+	}
+}
+
+// Rows returns a range-over-func view of a recordset reader, with the same
+// termination and Close semantics as Records. Each yielded row belongs to
+// reader.Recordset().
+func Rows(reader RecordsetReader) iter.Seq2[recordset.Row, error] {
+	return func(yield func(recordset.Row, error) bool) {
+		closed := false
+		defer func() {
+			if !closed {
+				_ = reader.Close()
+			}
+		}()
+		drained := true
+		for {
+			row, _, err := reader.Next()
+			if err != nil {
+				if !errors.Is(err, ErrNoMoreRecords) && !errors.Is(err, io.EOF) {
+					yield(nil, err)
+					drained = false
+				}
+				break
+			}
+			if !yield(row, nil) {
+				drained = false
+				break
+			}
+		}
+		closed = true
+		if err := reader.Close(); err != nil && drained {
+			yield(nil, fmt.Errorf("failed to close reader: %w", err))
+		}
+	}
+}
+
+// ExecuteQueryAndIterateRecords executes query when the returned sequence is
+// ranged over and yields its records as Records does. A failure to execute
+// the query is yielded as the only item. Once ctx is done, ctx.Err() is yielded
+// in place of the next record and iteration ends.
+func ExecuteQueryAndIterateRecords(ctx context.Context, query Query, qe QueryExecutor) iter.Seq2[record.Record, error] {
+	return func(yield func(record.Record, error) bool) {
+		reader, err := qe.ExecuteQueryToRecordsReader(ctx, query)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for r, err := range Records(reader) {
+			if err == nil {
+				if err = ctx.Err(); err != nil {
+					r = nil
+				}
+			}
+			if !yield(r, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// RecordsetRow is a row yielded by ExecuteQueryAndIterateRows together with
+// the recordset needed to read its values.
+type RecordsetRow struct {
+	Row       recordset.Row
+	Recordset recordset.Recordset
+}
+
+// ExecuteQueryAndIterateRows executes query when the returned sequence is
+// ranged over and yields its rows as Rows does, together with the recordset
+// they belong to. A failure to execute the query is yielded as the only item.
+// Once ctx is done, ctx.Err() is yielded in place of the next row and
+// iteration ends.
+func ExecuteQueryAndIterateRows(ctx context.Context, query Query, qe QueryExecutor) iter.Seq2[RecordsetRow, error] {
+	return func(yield func(RecordsetRow, error) bool) {
+		reader, err := query.GetRecordsetReader(ctx, qe)
+		if err != nil {
+			yield(RecordsetRow{}, fmt.Errorf("failed to get the recordset reader: %w", err))
+			return
+		}
+		rs := reader.Recordset()
+		for row, err := range Rows(reader) {
+			if err == nil {
+				if err = ctx.Err(); err != nil {
+					row = nil
+				}
+			}
+			if !yield(RecordsetRow{Row: row, Recordset: rs}, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package dal_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader counts Close calls and can fail Next after n records or
+// fail Close.
+type countingReader struct {
+	dal.RecordsReader
+	closed   int
+	nextErr  error
+	failAt   int
+	read     int
+	closeErr error
+}
+
+func (r *countingReader) Next() (record.Record, error) {
+	if r.nextErr != nil && r.read == r.failAt {
+		return nil, r.nextErr
+	}
+	r.read++
+	return r.RecordsReader.Next()
+}
+
+func (r *countingReader) Close() error {
+	r.closed++
+	return r.closeErr
+}
+
+func newCountingReader(ids ...string) *countingReader {
+	records := make([]record.Record, len(ids))
+	for i, id := range ids {
+		records[i] = record.NewRecord(record.NewKeyWithID("users", id))
+	}
+	return &countingReader{RecordsReader: dal.NewRecordsReader(records)}
+}
+
+func TestRecords_ClosesOnceOnEveryPath(t *testing.T) {
+	drained := newCountingReader("a", "b", "c")
+	var ids []any
+	for r, err := range dal.Records(drained) {
+		require.NoError(t, err)
+		ids = append(ids, r.Key().ID)
+	}
+	require.Equal(t, []any{"a", "b", "c"}, ids)
+	require.Equal(t, 1, drained.closed)
+
+	broken := newCountingReader("a", "b", "c")
+	for range dal.Records(broken) {
+		break
+	}
+	require.Equal(t, 1, broken.closed)
+	require.Equal(t, 1, broken.read, "no record is read after the consumer stops")
+
+	panicking := newCountingReader("a", "b")
+	require.Panics(t, func() {
+		for range dal.Records(panicking) {
+			panic("boom")
+		}
+	})
+	require.Equal(t, 1, panicking.closed)
+}
+
+func TestRecords_YieldsErrors(t *testing.T) {
+	errNext := errors.New("next failed")
+	failing := newCountingReader("a", "b")
+	failing.nextErr, failing.failAt = errNext, 1
+	var errs []error
+	count := 0
+	for _, err := range dal.Records(failing) {
+		count++
+		errs = append(errs, err)
+	}
+	require.Equal(t, 2, count)
+	require.Equal(t, []error{nil, errNext}, errs)
+	require.Equal(t, 1, failing.closed)
+
+	errClose := errors.New("close failed")
+	closing := newCountingReader("a")
+	closing.closeErr = errClose
+	errs = nil
+	for _, err := range dal.Records(closing) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 2)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], errClose)
+}
+
+func TestExecuteQueryAndIterateRecords(t *testing.T) {
+	ctx := context.Background()
+	db := newMemoryDB(t)
+	users := dal.CollectionOf[string, User]()
+	write(t, db, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		for _, name := range []string{"Ada", "Bob", "Eve"} {
+			if _, err := users.InsertWithID(ctx, tx, name, User{Name: name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	query := dal.From(dal.NewRootCollectionRef("users", "")).NewQuery().
+		OrderBy(dal.AscendingField("name")).
+		SelectIntoRecord(func() record.Record {
+			return record.NewRecordWithIncompleteKey("users", reflect.String, &User{})
+		})
+
+	var names []string
+	for r, err := range dal.ExecuteQueryAndIterateRecords(ctx, query, db) {
+		require.NoError(t, err)
+		names = append(names, r.Data().(*User).Name)
+	}
+	require.Equal(t, []string{"Ada", "Bob", "Eve"}, names)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var got []error
+	for _, err := range dal.ExecuteQueryAndIterateRecords(cancelled, query, db) {
+		got = append(got, err)
+		cancel()
+	}
+	require.Len(t, got, 2)
+	require.NoError(t, got[0])
+	require.ErrorIs(t, got[1], context.Canceled)
+
+	var rows []string
+	for row, err := range dal.ExecuteQueryAndIterateRows(ctx, query, db) {
+		require.NoError(t, err)
+		name, err := row.Row.GetValueByName("name", row.Recordset)
+		require.NoError(t, err)
+		rows = append(rows, name.(string))
+	}
+	require.Equal(t, []string{"Ada", "Bob", "Eve"}, rows)
+
+	var items []string
+	iterator, ok := users.(dal.CollectionIterator[string, User])
+	require.True(t, ok)
+	for item, err := range iterator.Iterate(ctx, db) {
+		require.NoError(t, err)
+		require.Equal(t, item.ID, item.Data.Name)
+		items = append(items, item.ID)
+	}
+	require.ElementsMatch(t, []string{"Ada", "Bob", "Eve"}, items)
+
+	for _, err := range dal.CollectionAt[int, User]("users").(dal.CollectionIterator[int, User]).Iterate(ctx, db) {
+		require.ErrorContains(t, err, "not int")
+	}
+}
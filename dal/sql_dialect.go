@@ -0,0 +1,234 @@
+package dal
+
+import "strings"
+
+// PlaceholderStyle selects how RenderSQL writes query parameters.
+type PlaceholderStyle int
+
+const (
+	// PlaceholderQuestion writes positional `?` placeholders.
+	PlaceholderQuestion PlaceholderStyle = iota
+
+	// PlaceholderDollar writes numbered positional `$1`, `$2`, ... placeholders.
+	PlaceholderDollar
+
+	// PlaceholderAtP writes numbered positional `@p1`, `@p2`, ... placeholders.
+	PlaceholderAtP
+
+	// PlaceholderNamedColon writes named `:p1`, `:p2`, ... placeholders; the
+	// returned QueryArg values carry the names.
+	PlaceholderNamedColon
+
+	// PlaceholderNamedAt writes named `@p1`, `@p2`, ... placeholders; the
+	// returned QueryArg values carry the names.
+	PlaceholderNamedAt
+)
+
+// LimitStyle selects how RenderSQL restricts the returned rows.
+type LimitStyle int
+
+const (
+	// LimitOffsetClause appends `LIMIT n` and `OFFSET m` after ORDER BY.
+	LimitOffsetClause LimitStyle = iota
+
+	// TopOrOffsetFetch writes `SELECT TOP n` when there is no offset and
+	// `OFFSET m ROWS FETCH NEXT n ROWS ONLY` otherwise (T-SQL).
+	TopOrOffsetFetch
+)
+
+// DateFunctionStyle selects how RenderSQL writes DATE_TRUNC and EXTRACT.
+type DateFunctionStyle int
+
+const (
+	// DateFunctionsUnsupported fails DATE_TRUNC and EXTRACT with
+	// ErrNotSupported.
+	DateFunctionsUnsupported DateFunctionStyle = iota
+
+	// DateFunctionsExtract writes the standard `EXTRACT(YEAR FROM ts)` for the
+	// year, month, day, hour, minute and second parts; DATE_TRUNC, and the
+	// quarter and week parts are not supported.
+	DateFunctionsExtract
+
+	// DateFunctionsPostgreSQL writes `DATE_TRUNC('day', ts)` and
+	// `EXTRACT(DAY FROM ts)` for every DatePart.
+	DateFunctionsPostgreSQL
+
+	// DateFunctionsTSQL writes `DATETRUNC(day, ts)` and `DATEPART(day, ts)`,
+	// with iso_week for the week part.
+	DateFunctionsTSQL
+)
+
+// StringAggStyle selects how RenderSQL writes STRING_AGG.
+type StringAggStyle int
+
+const (
+	// StringAggUnsupported fails STRING_AGG with ErrNotSupported.
+	StringAggUnsupported StringAggStyle = iota
+
+	// StringAggListAgg writes the standard `LISTAGG(x, sep)`.
+	StringAggListAgg
+
+	// StringAggFunction writes `STRING_AGG(x, sep)` (PostgreSQL).
+	StringAggFunction
+
+	// StringAggFunctionNoDistinct writes `STRING_AGG(x, sep)` and does not
+	// support DISTINCT (T-SQL).
+	StringAggFunctionNoDistinct
+
+	// StringAggGroupConcat writes `GROUP_CONCAT(x, sep)` and does not support
+	// DISTINCT (SQLite).
+	StringAggGroupConcat
+
+	// StringAggGroupConcatSeparator writes `GROUP_CONCAT(x SEPARATOR 'sep')`
+	// with the separator as a string literal, since MySQL does not accept a
+	// parameter there.
+	StringAggGroupConcatSeparator
+)
+
+// SQLDialect describes the SQL flavor RenderSQL emits. Use one of the
+// predefined dialects, optionally adjusting a copy (e.g. its Placeholders).
+type SQLDialect struct {
+	// Name identifies the dialect in error messages.
+	Name string
+
+	// IdentifierQuotes are the opening and closing characters wrapped around
+	// table, column and alias names. An embedded closing character is doubled.
+	IdentifierQuotes [2]string
+
+	// TrueLiteral and FalseLiteral render boolean constants.
+	TrueLiteral, FalseLiteral string
+
+	// Placeholders selects the parameter placeholder syntax.
+	Placeholders PlaceholderStyle
+
+	// Limit selects the row-limiting syntax.
+	Limit LimitStyle
+
+	// UnboundedLimit is the LIMIT written for a query with an offset but no
+	// limit, for dialects whose OFFSET requires a LIMIT. Empty omits LIMIT.
+	UnboundedLimit string
+
+	// LikeEscape is appended to LIKE predicates to make backslash their escape
+	// character. Empty for dialects where backslash already is the default.
+	LikeEscape string
+
+	// LengthFunction is the name LENGTH is written as, e.g. CHAR_LENGTH.
+	LengthFunction string
+
+	// ConcatOperator joins CONCAT arguments when set (e.g. `||`); otherwise
+	// CONCAT is written as a CONCAT(...) call.
+	ConcatOperator string
+
+	// DateFunctions selects how DATE_TRUNC and EXTRACT are written.
+	DateFunctions DateFunctionStyle
+
+	// StringAgg selects how STRING_AGG is written.
+	StringAgg StringAggStyle
+
+	// PercentileCont enables MEDIAN and PERCENTILE, written as
+	// `PERCENTILE_CONT(p) WITHIN GROUP (ORDER BY x)`.
+	PercentileCont bool
+
+	// ArrayAgg enables ARRAY_AGG.
+	ArrayAgg bool
+
+	// StdDevFunctions are the names STDDEV and STDDEV_POP are written as;
+	// empty names are not supported.
+	StdDevFunctions [2]string
+}
+
+var (
+	// SQLDialectANSI renders standard SQL.
+	SQLDialectANSI = SQLDialect{
+		Name:             "ANSI",
+		IdentifierQuotes: [2]string{`"`, `"`},
+		TrueLiteral:      "TRUE",
+		FalseLiteral:     "FALSE",
+		Placeholders:     PlaceholderQuestion,
+		Limit:            LimitOffsetClause,
+		LikeEscape:       ` ESCAPE '\'`,
+		LengthFunction:   "CHAR_LENGTH",
+		ConcatOperator:   "||",
+		DateFunctions:    DateFunctionsExtract,
+		StringAgg:        StringAggListAgg,
+		PercentileCont:   true,
+		ArrayAgg:         true,
+		StdDevFunctions:  [2]string{"STDDEV_SAMP", "STDDEV_POP"},
+	}
+
+	// SQLDialectPostgreSQL renders PostgreSQL.
+	SQLDialectPostgreSQL = SQLDialect{
+		Name:             "PostgreSQL",
+		IdentifierQuotes: [2]string{`"`, `"`},
+		TrueLiteral:      "TRUE",
+		FalseLiteral:     "FALSE",
+		Placeholders:     PlaceholderDollar,
+		Limit:            LimitOffsetClause,
+		LengthFunction:   "CHAR_LENGTH",
+		ConcatOperator:   "||",
+		DateFunctions:    DateFunctionsPostgreSQL,
+		StringAgg:        StringAggFunction,
+		PercentileCont:   true,
+		ArrayAgg:         true,
+		StdDevFunctions:  [2]string{"STDDEV_SAMP", "STDDEV_POP"},
+	}
+
+	// SQLDialectSQLite renders SQLite.
+	SQLDialectSQLite = SQLDialect{
+		Name:             "SQLite",
+		IdentifierQuotes: [2]string{`"`, `"`},
+		TrueLiteral:      "1",
+		FalseLiteral:     "0",
+		Placeholders:     PlaceholderQuestion,
+		Limit:            LimitOffsetClause,
+		UnboundedLimit:   "-1",
+		LikeEscape:       ` ESCAPE '\'`,
+		LengthFunction:   "LENGTH",
+		ConcatOperator:   "||",
+		StringAgg:        StringAggGroupConcat,
+	}
+
+	// SQLDialectMySQL renders MySQL and MariaDB.
+	SQLDialectMySQL = SQLDialect{
+		Name:             "MySQL",
+		IdentifierQuotes: [2]string{"`", "`"},
+		TrueLiteral:      "TRUE",
+		FalseLiteral:     "FALSE",
+		Placeholders:     PlaceholderQuestion,
+		Limit:            LimitOffsetClause,
+		UnboundedLimit:   "18446744073709551615",
+		LengthFunction:   "CHAR_LENGTH",
+		DateFunctions:    DateFunctionsExtract,
+		StringAgg:        StringAggGroupConcatSeparator,
+		StdDevFunctions:  [2]string{"STDDEV_SAMP", "STDDEV_POP"},
+	}
+
+	// SQLDialectTSQL renders Microsoft SQL Server T-SQL.
+	SQLDialectTSQL = SQLDialect{
+		Name:             "T-SQL",
+		IdentifierQuotes: [2]string{"[", "]"},
+		TrueLiteral:      "1",
+		FalseLiteral:     "0",
+		Placeholders:     PlaceholderAtP,
+		Limit:            TopOrOffsetFetch,
+		LikeEscape:       ` ESCAPE '\'`,
+		LengthFunction:   "LEN",
+		DateFunctions:    DateFunctionsTSQL,
+		StringAgg:        StringAggFunctionNoDistinct,
+		StdDevFunctions:  [2]string{"STDEV", "STDEVP"},
+	}
+)
+
+// QuoteIdentifier wraps name in the dialect's identifier quotes.
+func (d SQLDialect) QuoteIdentifier(name string) string {
+	open, closing := d.IdentifierQuotes[0], d.IdentifierQuotes[1]
+	return open + strings.ReplaceAll(name, closing, closing+closing) + closing
+}
+
+// BoolLiteral renders a boolean constant.
+func (d SQLDialect) BoolLiteral(v bool) string {
+	if v {
+		return d.TrueLiteral
+	}
+	return d.FalseLiteral
+}
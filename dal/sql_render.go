@@ -0,0 +1,697 @@
+package dal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RenderSQL renders a structured query as a SELECT statement in the given
+// dialect. Constants are not inlined: each becomes a placeholder and its value
+// is returned in args, in placeholder order (named for the named placeholder
+// styles). Booleans and NULL are written as literals, and the elements of an
+// IN array each get their own placeholder. The PERCENTILE fraction and MySQL's
+// GROUP_CONCAT separator, which cannot be parameters, are written as literals.
+//
+// Unlike StructuredQuery.String, which is meant for humans, the result is
+// meant to be executed, so anything the renderer cannot express faithfully —
+// a StartFrom cursor, a collection scoped under a parent key, the
+// ArrayContains operators, a function or aggregate the dialect has no
+// equivalent for, an unknown expression or condition type — fails with
+// ErrNotSupported. StartsWith is written as a LIKE with the prefix's
+// wildcards escaped.
+func RenderSQL(q StructuredQuery, dialect SQLDialect) (text string, args []QueryArg, err error) {
+	r := sqlRenderer{dialect: dialect}
+	if err = r.renderQuery(q); err != nil {
+		return "", nil, err
+	}
+	return r.buf.String(), r.args, nil
+}
+
+type sqlRenderer struct {
+	dialect SQLDialect
+	buf     strings.Builder
+	args    []QueryArg
+}
+
+func (r *sqlRenderer) write(s ...string) {
+	for _, v := range s {
+		r.buf.WriteString(v)
+	}
+}
+
+func (r *sqlRenderer) notSupported(format string, a ...any) error {
+	return fmt.Errorf("%w: %s SQL rendering: %s", ErrNotSupported, r.dialect.Name, fmt.Sprintf(format, a...))
+}
+
+func (r *sqlRenderer) renderQuery(q StructuredQuery) error {
+	if q.StartFrom() != "" {
+		return r.notSupported("StartFrom cursor")
+	}
+	limit, offset := q.Limit(), q.Offset()
+	r.write("SELECT ")
+	if IsDistinct(q) {
+		r.write("DISTINCT ")
+	}
+	if r.dialect.Limit == TopOrOffsetFetch && limit > 0 && offset <= 0 {
+		r.write("TOP ", strconv.Itoa(limit), " ")
+	}
+	if err := r.renderColumns(q.Columns()); err != nil {
+		return err
+	}
+	if from := q.From(); from != nil {
+		if err := r.renderFrom(from); err != nil {
+			return err
+		}
+	}
+	if where := q.Where(); where != nil {
+		r.write("\nWHERE ")
+		if err := r.renderCondition(where); err != nil {
+			return err
+		}
+	}
+	if groupBy := q.GroupBy(); len(groupBy) > 0 {
+		r.write("\nGROUP BY ")
+		for i, expr := range groupBy {
+			if i > 0 {
+				r.write(", ")
+			}
+			if err := r.renderExpression(expr); err != nil {
+				return err
+			}
+		}
+	}
+	if having := q.Having(); having != nil {
+		r.write("\nHAVING ")
+		if err := r.renderCondition(having); err != nil {
+			return err
+		}
+	}
+	orderBy := q.OrderBy()
+	if len(orderBy) > 0 {
+		r.write("\nORDER BY ")
+		for i, o := range orderBy {
+			if i > 0 {
+				r.write(", ")
+			}
+			if err := r.renderExpression(o.Expression()); err != nil {
+				return err
+			}
+			if o.Descending() {
+				r.write(" DESC")
+			}
+		}
+	}
+	r.renderLimit(limit, offset, len(orderBy) > 0)
+	return nil
+}
+
+func (r *sqlRenderer) renderLimit(limit, offset int, ordered bool) {
+	switch r.dialect.Limit {
+	case TopOrOffsetFetch:
+		if offset <= 0 {
+			return // a limit alone was written as TOP
+		}
+		if !ordered {
+			// OFFSET ... FETCH is only allowed after an ORDER BY.
+			r.write("\nORDER BY (SELECT NULL)")
+		}
+		r.write("\nOFFSET ", strconv.Itoa(offset), " ROWS")
+		if limit > 0 {
+			r.write(" FETCH NEXT ", strconv.Itoa(limit), " ROWS ONLY")
+		}
+	default:
+		if limit > 0 {
+			r.write("\nLIMIT ", strconv.Itoa(limit))
+		} else if offset > 0 && r.dialect.UnboundedLimit != "" {
+			r.write("\nLIMIT ", r.dialect.UnboundedLimit)
+		}
+		if offset > 0 {
+			r.write("\nOFFSET ", strconv.Itoa(offset))
+		}
+	}
+}
+
+func (r *sqlRenderer) renderColumns(columns []Column) error {
+	if len(columns) == 0 {
+		r.write("*")
+		return nil
+	}
+	for i, col := range columns {
+		if i > 0 {
+			r.write(", ")
+		}
+		if col.Expression == nil {
+			r.write("NULL")
+		} else if err := r.renderExpression(col.Expression); err != nil {
+			return err
+		}
+		if col.Alias != "" {
+			r.write(" AS ", r.dialect.QuoteIdentifier(col.Alias))
+		}
+	}
+	return nil
+}
+
+func (r *sqlRenderer) renderFrom(from FromSource) error {
+	r.write("\nFROM ")
+	if err := r.renderSource(from.Base()); err != nil {
+		return err
+	}
+	for _, join := range from.Joins() {
+		joinType := join.JoinType()
+		if joinType == "" {
+			joinType = JoinInner
+		}
+		r.write("\n", string(joinType), " JOIN ")
+		if err := r.renderSource(join.RecordsetSource); err != nil {
+			return err
+		}
+		on := join.On()
+		if joinType == JoinCross {
+			if len(on) > 0 {
+				return r.notSupported("CROSS JOIN with ON conditions")
+			}
+			continue
+		}
+		if len(on) == 0 {
+			return r.notSupported("%s JOIN without ON conditions", joinType)
+		}
+		r.write(" ON ")
+		for i, condition := range on {
+			if i > 0 {
+				r.write(" AND ")
+			}
+			if err := r.renderCondition(condition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *sqlRenderer) renderSource(source RecordsetSource) error {
+	switch src := source.(type) {
+	case CollectionRef:
+		if src.Parent() != nil {
+			return r.notSupported("collection %q scoped under parent key %v", src.Name(), src.Parent())
+		}
+	case *CollectionRef:
+		if src.Parent() != nil {
+			return r.notSupported("collection %q scoped under parent key %v", src.Name(), src.Parent())
+		}
+	case CollectionGroupRef, *CollectionGroupRef:
+	default:
+		return r.notSupported("recordset source %T", source)
+	}
+	r.write(r.dialect.QuoteIdentifier(source.Name()))
+	if alias := source.Alias(); alias != "" {
+		r.write(" AS ", r.dialect.QuoteIdentifier(alias))
+	}
+	return nil
+}
+
+func (r *sqlRenderer) renderCondition(condition Condition) error {
+	switch c := condition.(type) {
+	case Comparison:
+		return r.renderComparison(c)
+	case *Comparison:
+		return r.renderComparison(*c)
+	case GroupCondition:
+		return r.renderGroupCondition(c)
+	case *GroupCondition:
+		return r.renderGroupCondition(*c)
+	case NotCondition:
+		return r.renderNotCondition(c)
+	case *NotCondition:
+		return r.renderNotCondition(*c)
+	case InSubqueryCondition:
+		if err := r.renderExpression(c.Expression()); err != nil {
+			return err
+		}
+		r.write(" IN ")
+		return r.renderSubquery(c.Query())
+	case ExistsCondition:
+		r.write("EXISTS ")
+		return r.renderSubquery(c.Query())
+	default:
+		return r.notSupported("condition %T", condition)
+	}
+}
+
+// renderSubquery writes a parenthesized subquery, continuing the enclosing
+// query's placeholder numbering.
+func (r *sqlRenderer) renderSubquery(q StructuredQuery) error {
+	if q == nil {
+		return r.notSupported("subquery without a query")
+	}
+	r.write("(")
+	if err := r.renderQuery(q); err != nil {
+		return err
+	}
+	r.write(")")
+	return nil
+}
+
+func (r *sqlRenderer) renderGroupCondition(g GroupCondition) error {
+	conditions := g.Conditions()
+	if len(conditions) == 0 {
+		return r.notSupported("empty %s group", g.Operator())
+	}
+	r.write("(")
+	for i, condition := range conditions {
+		if i > 0 {
+			r.write(" ", string(g.Operator()), " ")
+		}
+		if err := r.renderCondition(condition); err != nil {
+			return err
+		}
+	}
+	r.write(")")
+	return nil
+}
+
+func (r *sqlRenderer) renderNotCondition(n NotCondition) error {
+	if n.Condition() == nil {
+		return r.notSupported("NOT without a condition")
+	}
+	r.write("NOT ")
+	switch n.Condition().(type) {
+	case GroupCondition, *GroupCondition:
+		return r.renderCondition(n.Condition()) // a group is already parenthesized
+	}
+	r.write("(")
+	if err := r.renderCondition(n.Condition()); err != nil {
+		return err
+	}
+	r.write(")")
+	return nil
+}
+
+func (r *sqlRenderer) renderComparison(c Comparison) error {
+	if err := r.renderExpression(c.Left); err != nil {
+		return err
+	}
+	switch c.Operator {
+	case Equal:
+		if isNullConstant(c.Right) {
+			r.write(" IS NULL")
+			return nil
+		}
+		r.write(" = ")
+	case NotEqual:
+		if isNullConstant(c.Right) {
+			r.write(" IS NOT NULL")
+			return nil
+		}
+		r.write(" <> ")
+	case IsNull:
+		r.write(" IS NULL")
+		return nil
+	case IsNotNull:
+		r.write(" IS NOT NULL")
+		return nil
+	case In:
+		r.write(" IN ")
+		return r.renderInList(c.Right)
+	case NotIn:
+		r.write(" NOT IN ")
+		return r.renderInList(c.Right)
+	case Between:
+		return r.renderBetween(c.Right)
+	case StartsWith:
+		prefix, ok := stringConstant(c.Right)
+		if !ok {
+			return r.notSupported("StartsWith with %T operand", c.Right)
+		}
+		r.write(" LIKE ")
+		r.renderValue(escapeLikePattern(prefix) + "%")
+		r.write(r.dialect.LikeEscape)
+		return nil
+	case Like:
+		r.write(" LIKE ")
+		if err := r.renderExpression(c.Right); err != nil {
+			return err
+		}
+		r.write(r.dialect.LikeEscape)
+		return nil
+	case GreaterThen, GreaterOrEqual, LessThen, LessOrEqual:
+		r.write(" ", string(c.Operator), " ")
+	default:
+		return r.notSupported("operator %q", c.Operator)
+	}
+	return r.renderExpression(c.Right)
+}
+
+// renderBetween writes `BETWEEN lower AND upper` from a two-element Array.
+func (r *sqlRenderer) renderBetween(expr Expression) error {
+	bounds, ok := arrayValues(expr)
+	if !ok || bounds.Len() != 2 {
+		return r.notSupported("BETWEEN with an operand other than a two-element array")
+	}
+	r.write(" BETWEEN ")
+	r.renderValue(bounds.Index(0).Interface())
+	r.write(" AND ")
+	r.renderValue(bounds.Index(1).Interface())
+	return nil
+}
+
+func stringConstant(expr Expression) (string, bool) {
+	switch v := expr.(type) {
+	case Constant:
+		s, ok := v.Value.(string)
+		return s, ok
+	case *Constant:
+		s, ok := v.Value.(string)
+		return s, ok
+	}
+	return "", false
+}
+
+// escapeLikePattern escapes the LIKE wildcards and the backslash escape
+// character so s matches literally.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func isNullConstant(expr Expression) bool {
+	switch v := expr.(type) {
+	case Constant:
+		return v.Value == nil
+	case *Constant:
+		return v.Value == nil
+	}
+	return false
+}
+
+// renderInList writes an IN list with one placeholder per array element.
+func (r *sqlRenderer) renderInList(expr Expression) error {
+	list, ok := arrayValues(expr)
+	if !ok {
+		return r.notSupported("IN with %T operand", expr)
+	}
+	if list.Len() == 0 {
+		return r.notSupported("IN with an empty or non-slice array")
+	}
+	r.write("(")
+	for i := 0; i < list.Len(); i++ {
+		if i > 0 {
+			r.write(", ")
+		}
+		r.renderValue(list.Index(i).Interface())
+	}
+	r.write(")")
+	return nil
+}
+
+// arrayValues returns the slice held by an Array expression.
+func arrayValues(expr Expression) (reflect.Value, bool) {
+	var values any
+	switch v := expr.(type) {
+	case Array:
+		values = v.Value
+	case *Array:
+		values = v.Value
+	default:
+		return reflect.Value{}, false
+	}
+	list := reflect.ValueOf(values)
+	return list, list.Kind() == reflect.Slice
+}
+
+func (r *sqlRenderer) renderExpression(expr Expression) error {
+	switch e := expr.(type) {
+	case FieldRef:
+		r.renderFieldRef(e)
+	case *FieldRef:
+		r.renderFieldRef(*e)
+	case FieldName:
+		r.write(r.dialect.QuoteIdentifier(string(e)))
+	case Constant:
+		r.renderValue(e.Value)
+	case *Constant:
+		r.renderValue(e.Value)
+	case star:
+		r.write("*")
+	case AggregateFunc:
+		return r.renderAggregate(e)
+	case ScalarFunction:
+		return r.renderScalarFunction(e)
+	case Arithmetic:
+		switch e.Operator {
+		case Addition, Subtraction, Multiplication, Division:
+		default:
+			return r.notSupported("arithmetic operator %q", e.Operator)
+		}
+		r.write("(")
+		if err := r.renderExpression(e.Left); err != nil {
+			return err
+		}
+		r.write(" ", string(e.Operator), " ")
+		if err := r.renderExpression(e.Right); err != nil {
+			return err
+		}
+		r.write(")")
+	default:
+		return r.notSupported("expression %T", expr)
+	}
+	return nil
+}
+
+// renderAggregate writes an aggregate call in the dialect's syntax, failing
+// with ErrNotSupported for an aggregate (or its DISTINCT form) the dialect
+// has no equivalent for.
+func (r *sqlRenderer) renderAggregate(f AggregateFunc) error {
+	name, args, distinct := f.FuncName(), f.FuncArgs(), IsDistinctAggregate(f)
+	if len(args) == 0 {
+		return r.notSupported("%s without arguments", name)
+	}
+	switch name {
+	case SUM, COUNT, MIN, MAX, AVERAGE:
+	case MEDIAN, PERCENTILE:
+		if !r.dialect.PercentileCont || distinct {
+			return r.notSupported("%s", f.String())
+		}
+		p := 0.5
+		if name == PERCENTILE {
+			c, _ := constantValue(args, 1)
+			var ok bool
+			if p, ok = c.(float64); !ok {
+				return r.notSupported("%s with percentile %v", name, c)
+			}
+		}
+		r.write("PERCENTILE_CONT(", strconv.FormatFloat(p, 'g', -1, 64), ") WITHIN GROUP (ORDER BY ")
+		if err := r.renderExpression(args[0]); err != nil {
+			return err
+		}
+		r.write(")")
+		return nil
+	case STRING_AGG:
+		return r.renderStringAgg(f)
+	case ARRAY_AGG:
+		if !r.dialect.ArrayAgg {
+			return r.notSupported("%s", name)
+		}
+	case STDDEV, STDDEV_POP:
+		i := 0
+		if name == STDDEV_POP {
+			i = 1
+		}
+		if name = r.dialect.StdDevFunctions[i]; name == "" {
+			return r.notSupported("%s", f.FuncName())
+		}
+	default:
+		return r.notSupported("aggregate %q", name)
+	}
+	r.write(name, "(")
+	if distinct {
+		r.write("DISTINCT ")
+	}
+	for i, arg := range args {
+		if i > 0 {
+			r.write(", ")
+		}
+		if err := r.renderExpression(arg); err != nil {
+			return err
+		}
+	}
+	r.write(")")
+	return nil
+}
+
+// constantValue returns the value of the i-th argument when it is a Constant.
+func constantValue(args []Expression, i int) (any, bool) {
+	if i >= len(args) {
+		return nil, false
+	}
+	c, ok := args[i].(Constant)
+	return c.Value, ok
+}
+
+func (r *sqlRenderer) renderStringAgg(f AggregateFunc) error {
+	args, distinct := f.FuncArgs(), IsDistinctAggregate(f)
+	c, _ := constantValue(args, 1)
+	separator, ok := c.(string)
+	if !ok {
+		return r.notSupported("%s with separator %v", STRING_AGG, c)
+	}
+	var name string
+	switch r.dialect.StringAgg {
+	case StringAggListAgg:
+		name = "LISTAGG"
+	case StringAggFunction, StringAggFunctionNoDistinct:
+		name = STRING_AGG
+	case StringAggGroupConcat, StringAggGroupConcatSeparator:
+		name = "GROUP_CONCAT"
+	default:
+		return r.notSupported("%s", STRING_AGG)
+	}
+	if distinct && (r.dialect.StringAgg == StringAggFunctionNoDistinct || r.dialect.StringAgg == StringAggGroupConcat) {
+		return r.notSupported("%s", f.String())
+	}
+	r.write(name, "(")
+	if distinct {
+		r.write("DISTINCT ")
+	}
+	if err := r.renderExpression(args[0]); err != nil {
+		return err
+	}
+	if r.dialect.StringAgg == StringAggGroupConcatSeparator {
+		r.write(" SEPARATOR '", strings.NewReplacer(`\`, `\\`, "'", "''").Replace(separator), "')")
+		return nil
+	}
+	r.write(", ")
+	r.renderValue(separator)
+	r.write(")")
+	return nil
+}
+
+func (r *sqlRenderer) renderScalarFunction(f ScalarFunction) error {
+	switch f.Name {
+	case LOWER, UPPER, COALESCE:
+		return r.renderCall(f.Name, f.Args)
+	case LENGTH:
+		name := r.dialect.LengthFunction
+		if name == "" {
+			name = "CHAR_LENGTH"
+		}
+		return r.renderCall(name, f.Args)
+	case CONCAT:
+		if r.dialect.ConcatOperator == "" {
+			return r.renderCall(CONCAT, f.Args)
+		}
+		r.write("(")
+		for i, arg := range f.Args {
+			if i > 0 {
+				r.write(" ", r.dialect.ConcatOperator, " ")
+			}
+			if err := r.renderExpression(arg); err != nil {
+				return err
+			}
+		}
+		r.write(")")
+		return nil
+	case DATE_TRUNC, EXTRACT:
+		return r.renderDateFunction(f)
+	default:
+		return r.notSupported("scalar function %q", f.Name)
+	}
+}
+
+func (r *sqlRenderer) renderCall(name string, args []Expression) error {
+	r.write(name, "(")
+	for i, arg := range args {
+		if i > 0 {
+			r.write(", ")
+		}
+		if err := r.renderExpression(arg); err != nil {
+			return err
+		}
+	}
+	r.write(")")
+	return nil
+}
+
+// renderDateFunction writes DATE_TRUNC or EXTRACT in the dialect's style. The
+// date part is written as a keyword (or, for PostgreSQL's DATE_TRUNC, a
+// string literal), never as a placeholder.
+func (r *sqlRenderer) renderDateFunction(f ScalarFunction) error {
+	if len(f.Args) != 2 {
+		return r.notSupported("%s with %d arguments", f.Name, len(f.Args))
+	}
+	unit, _ := f.Args[0].(Constant)
+	part, _ := unit.Value.(string)
+	switch DatePart(part) {
+	case DatePartYear, DatePartMonth, DatePartDay, DatePartHour, DatePartMinute, DatePartSecond:
+	case DatePartQuarter, DatePartWeek:
+		if r.dialect.DateFunctions == DateFunctionsExtract {
+			return r.notSupported("%s of %s", f.Name, part)
+		}
+	default:
+		return r.notSupported("%s with date part %v", f.Name, f.Args[0])
+	}
+	switch r.dialect.DateFunctions {
+	case DateFunctionsExtract, DateFunctionsPostgreSQL:
+		if f.Name == EXTRACT {
+			r.write("EXTRACT(", strings.ToUpper(part), " FROM ")
+		} else if r.dialect.DateFunctions == DateFunctionsPostgreSQL {
+			r.write("DATE_TRUNC('", part, "', ")
+		} else {
+			return r.notSupported("%s", DATE_TRUNC)
+		}
+	case DateFunctionsTSQL:
+		if part == string(DatePartWeek) {
+			part = "iso_week"
+		}
+		if f.Name == EXTRACT {
+			r.write("DATEPART(", part, ", ")
+		} else {
+			r.write("DATETRUNC(", part, ", ")
+		}
+	default:
+		return r.notSupported("%s", f.Name)
+	}
+	if err := r.renderExpression(f.Args[1]); err != nil {
+		return err
+	}
+	r.write(")")
+	return nil
+}
+
+func (r *sqlRenderer) renderFieldRef(f FieldRef) {
+	if source := f.Source(); source != "" {
+		r.write(r.dialect.QuoteIdentifier(source), ".")
+	}
+	r.write(r.dialect.QuoteIdentifier(f.Name()))
+}
+
+// renderValue writes a constant: booleans and nil as literals, anything else as
+// a placeholder bound to a new argument.
+func (r *sqlRenderer) renderValue(v any) {
+	switch v := v.(type) {
+	case nil:
+		r.write("NULL")
+		return
+	case bool:
+		r.write(r.dialect.BoolLiteral(v))
+		return
+	}
+	n := strconv.Itoa(len(r.args) + 1)
+	arg := QueryArg{Value: v}
+	switch r.dialect.Placeholders {
+	case PlaceholderDollar:
+		r.write("$", n)
+	case PlaceholderAtP:
+		r.write("@p", n)
+	case PlaceholderNamedColon:
+		arg.Name = "p" + n
+		r.write(":", arg.Name)
+	case PlaceholderNamedAt:
+		arg.Name = "p" + n
+		r.write("@", arg.Name)
+	default:
+		r.write("?")
+	}
+	r.args = append(r.args, arg)
+}
@@ -0,0 +1,334 @@
+package dal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSQL_Dialects(t *testing.T) {
+	query := From(NewRootCollectionRef("users", "u")).NewQuery().
+		Where(
+			WhereField("age", GreaterOrEqual, 18),
+			WhereField("active", Equal, true),
+			WhereField("role", In, []string{"admin", "owner"}),
+		).
+		OrderBy(DescendingField("age")).
+		Offset(20).
+		Limit(10).
+		SelectColumns(Column{Expression: Field("name"), Alias: "n"})
+
+	tests := []struct {
+		dialect SQLDialect
+		want    string
+	}{
+		{
+			dialect: SQLDialectANSI,
+			want: `SELECT "name" AS "n"
+FROM "users" AS "u"
+WHERE ("age" >= ? AND "active" = TRUE AND "role" IN (?, ?))
+ORDER BY "age" DESC
+LIMIT 10
+OFFSET 20`,
+		},
+		{
+			dialect: SQLDialectPostgreSQL,
+			want: `SELECT "name" AS "n"
+FROM "users" AS "u"
+WHERE ("age" >= $1 AND "active" = TRUE AND "role" IN ($2, $3))
+ORDER BY "age" DESC
+LIMIT 10
+OFFSET 20`,
+		},
+		{
+			dialect: SQLDialectSQLite,
+			want: `SELECT "name" AS "n"
+FROM "users" AS "u"
+WHERE ("age" >= ? AND "active" = 1 AND "role" IN (?, ?))
+ORDER BY "age" DESC
+LIMIT 10
+OFFSET 20`,
+		},
+		{
+			dialect: SQLDialectMySQL,
+			want: "SELECT `name` AS `n`\n" +
+				"FROM `users` AS `u`\n" +
+				"WHERE (`age` >= ? AND `active` = TRUE AND `role` IN (?, ?))\n" +
+				"ORDER BY `age` DESC\n" +
+				"LIMIT 10\n" +
+				"OFFSET 20",
+		},
+		{
+			dialect: SQLDialectTSQL,
+			want: `SELECT [name] AS [n]
+FROM [users] AS [u]
+WHERE ([age] >= @p1 AND [active] = 1 AND [role] IN (@p2, @p3))
+ORDER BY [age] DESC
+OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name, func(t *testing.T) {
+			text, args, err := RenderSQL(query, tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, text)
+			assert.Equal(t, []QueryArg{{Value: 18}, {Value: "admin"}, {Value: "owner"}}, args)
+		})
+	}
+}
+
+func TestRenderSQL_LimitOffsetVariants(t *testing.T) {
+	build := func(offset, limit int) StructuredQuery {
+		return From(NewRootCollectionRef("t", "")).NewQuery().Offset(offset).Limit(limit).SelectColumns()
+	}
+	tests := []struct {
+		name          string
+		dialect       SQLDialect
+		offset, limit int
+		want          string
+	}{
+		{"ansi_none", SQLDialectANSI, 0, 0, "SELECT *\nFROM \"t\""},
+		{"ansi_offset_only", SQLDialectANSI, 5, 0, "SELECT *\nFROM \"t\"\nOFFSET 5"},
+		{"sqlite_offset_only", SQLDialectSQLite, 5, 0, "SELECT *\nFROM \"t\"\nLIMIT -1\nOFFSET 5"},
+		{"mysql_offset_only", SQLDialectMySQL, 5, 0, "SELECT *\nFROM `t`\nLIMIT 18446744073709551615\nOFFSET 5"},
+		{"tsql_limit_only", SQLDialectTSQL, 0, 3, "SELECT TOP 3 *\nFROM [t]"},
+		{"tsql_offset_unordered", SQLDialectTSQL, 5, 0, "SELECT *\nFROM [t]\nORDER BY (SELECT NULL)\nOFFSET 5 ROWS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, args, err := RenderSQL(build(tt.offset, tt.limit), tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, text)
+			assert.Empty(t, args)
+		})
+	}
+}
+
+func TestRenderSQL_JoinsGroupByHaving(t *testing.T) {
+	orders := NewRootCollectionRef("orders", "o")
+	join := NewJoinedSource(orders, JoinLeft, NewComparison(NewFieldRef("u", "id"), Equal, NewFieldRef("o", "user id")))
+	query := From(NewRootCollectionRef("users", "u")).Join(join).NewQuery().
+		Where(WhereField("deleted_at", Equal, nil)).
+		GroupBy(NewFieldRef("u", "id")).
+		Having(NewComparison(function{Name: SUM, Args: []Expression{NewFieldRef("o", "total")}}, GreaterThen, NewConstant(100))).
+		SelectColumns(
+			Column{Expression: NewFieldRef("u", "id")},
+			SumAs(NewFieldRef("o", "total"), "total"),
+			Count(),
+		)
+
+	named := SQLDialectPostgreSQL
+	named.Placeholders = PlaceholderNamedColon
+	text, args, err := RenderSQL(query, named)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "u"."id", SUM("o"."total") AS "total", COUNT(*)
+FROM "users" AS "u"
+LEFT JOIN "orders" AS "o" ON "u"."id" = "o"."user id"
+WHERE "deleted_at" IS NULL
+GROUP BY "u"."id"
+HAVING SUM("o"."total") > :p1`, text)
+	assert.Equal(t, []QueryArg{{Name: "p1", Value: 100}}, args)
+
+	cross := From(NewRootCollectionRef("a", "")).Join(NewJoinedSource(NewRootCollectionRef("b", ""), JoinCross)).NewQuery().SelectColumns()
+	text, _, err = RenderSQL(cross, SQLDialectTSQL)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT *\nFROM [a]\nCROSS JOIN [b]", text)
+}
+
+func TestRenderSQL_ExtendedOperators(t *testing.T) {
+	query := From(NewRootCollectionRef("users", "")).NewQuery().
+		Where(
+			WhereField("role", NotEqual, "guest"),
+			WhereField("status", NotIn, []string{"banned", "deleted"}),
+			WhereField("age", Between, []int{18, 65}),
+			WhereField("deleted_at", IsNull, nil),
+			WhereField("email", IsNotNull, nil),
+			WhereField("name", StartsWith, "50%_"),
+			WhereField("city", Like, "Lon%"),
+			WhereField("nick", NotEqual, nil),
+			Not(WhereField("flag", Equal, true)),
+			Not(NewGroupCondition(Or, WhereField("a", Equal, 1), WhereField("b", Equal, 2))),
+		).
+		SelectColumns()
+
+	text, args, err := RenderSQL(query, SQLDialectPostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT *
+FROM "users"
+WHERE ("role" <> $1 AND "status" NOT IN ($2, $3) AND "age" BETWEEN $4 AND $5 AND "deleted_at" IS NULL AND "email" IS NOT NULL AND "name" LIKE $6 AND "city" LIKE $7 AND "nick" IS NOT NULL AND NOT ("flag" = TRUE) AND NOT ("a" = $8 OR "b" = $9))`, text)
+	assert.Equal(t, []QueryArg{
+		{Value: "guest"}, {Value: "banned"}, {Value: "deleted"}, {Value: 18}, {Value: 65},
+		{Value: `50\%\_%`}, {Value: "Lon%"}, {Value: 1}, {Value: 2},
+	}, args)
+
+	like := From(NewRootCollectionRef("t", "")).NewQuery().Where(WhereField("a", Like, "x%")).SelectColumns()
+	text, _, err = RenderSQL(like, SQLDialectSQLite)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT *\nFROM \"t\"\nWHERE \"a\" LIKE ? ESCAPE '\\'", text)
+}
+
+func TestRenderSQL_ScalarFunctions(t *testing.T) {
+	query := From(NewRootCollectionRef("orders", "")).NewQuery().
+		Where(WhereField("x", Equal, 1), NewComparison(Lower(Field("email")), Equal, String("a@b.c"))).
+		OrderBy(Descending(Multiply(Field("price"), Field("qty")))).
+		SelectColumns(
+			Column{Expression: Upper(Field("name"))},
+			Column{Expression: Length(Field("name")), Alias: "len"},
+			Column{Expression: Coalesce(Field("nick"), Field("name"))},
+			Column{Expression: Concat(Field("first"), Field("last"))},
+			Column{Expression: Subtract(Field("total"), NewConstant(5))},
+			Column{Expression: Extract(DatePartYear, Field("created"))},
+		)
+	tests := []struct {
+		dialect SQLDialect
+		want    string
+	}{
+		{SQLDialectPostgreSQL, `SELECT UPPER("name"), CHAR_LENGTH("name") AS "len", COALESCE("nick", "name"), ("first" || "last"), ("total" - $1), EXTRACT(YEAR FROM "created")
+FROM "orders"
+WHERE ("x" = $2 AND LOWER("email") = $3)
+ORDER BY ("price" * "qty") DESC`},
+		{SQLDialectMySQL, "SELECT UPPER(`name`), CHAR_LENGTH(`name`) AS `len`, COALESCE(`nick`, `name`), CONCAT(`first`, `last`), (`total` - ?), EXTRACT(YEAR FROM `created`)\n" +
+			"FROM `orders`\n" +
+			"WHERE (`x` = ? AND LOWER(`email`) = ?)\n" +
+			"ORDER BY (`price` * `qty`) DESC"},
+		{SQLDialectTSQL, `SELECT UPPER([name]), LEN([name]) AS [len], COALESCE([nick], [name]), CONCAT([first], [last]), ([total] - @p1), DATEPART(year, [created])
+FROM [orders]
+WHERE ([x] = @p2 AND LOWER([email]) = @p3)
+ORDER BY ([price] * [qty]) DESC`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name, func(t *testing.T) {
+			text, args, err := RenderSQL(query, tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, text)
+			assert.Equal(t, []QueryArg{{Value: 5}, {Value: 1}, {Value: "a@b.c"}}, args)
+		})
+	}
+
+	trunc := func(part DatePart) StructuredQuery {
+		return From(NewRootCollectionRef("t", "")).NewQuery().GroupBy(DateTrunc(part, Field("ts"))).SelectColumns(Column{Expression: DateTrunc(part, Field("ts"))})
+	}
+	text, _, err := RenderSQL(trunc(DatePartWeek), SQLDialectPostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DATE_TRUNC('week', \"ts\")\nFROM \"t\"\nGROUP BY DATE_TRUNC('week', \"ts\")", text)
+	text, _, err = RenderSQL(trunc(DatePartWeek), SQLDialectTSQL)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DATETRUNC(iso_week, [ts])\nFROM [t]\nGROUP BY DATETRUNC(iso_week, [ts])", text)
+	for _, dialect := range []SQLDialect{SQLDialectANSI, SQLDialectSQLite} {
+		_, _, err = RenderSQL(trunc(DatePartDay), dialect)
+		assert.ErrorIs(t, err, ErrNotSupported, dialect.Name)
+	}
+}
+
+func TestRenderSQL_Aggregates(t *testing.T) {
+	query := func(columns ...Column) StructuredQuery {
+		return From(NewRootCollectionRef("sales", "")).NewQuery().GroupBy(Field("region")).SelectColumns(columns...)
+	}
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		column  Column
+		want    string
+		args    []QueryArg
+	}{
+		{"count_distinct", SQLDialectMySQL, CountDistinctAs(Field("customer"), "n"), "COUNT(DISTINCT `customer`) AS `n`", nil},
+		{"median", SQLDialectPostgreSQL, MedianAs(Field("amount"), "m"), `PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY "amount") AS "m"`, nil},
+		{"percentile", SQLDialectANSI, PercentileAs(Field("amount"), 0.95, "p"), `PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY "amount") AS "p"`, nil},
+		{"string_agg_ansi", SQLDialectANSI, StringAggAs(Field("name"), ", ", "s"), `LISTAGG("name", ?) AS "s"`, []QueryArg{{Value: ", "}}},
+		{"string_agg_postgres", SQLDialectPostgreSQL, StringAggAs(Field("name"), ", ", "s"), `STRING_AGG("name", $1) AS "s"`, []QueryArg{{Value: ", "}}},
+		{"string_agg_sqlite", SQLDialectSQLite, StringAggAs(Field("name"), ", ", "s"), `GROUP_CONCAT("name", ?) AS "s"`, []QueryArg{{Value: ", "}}},
+		{"string_agg_mysql", SQLDialectMySQL, StringAggAs(Field("name"), `'\`, "s"), "GROUP_CONCAT(`name` SEPARATOR '''\\\\') AS `s`", nil},
+		{"string_agg_tsql", SQLDialectTSQL, StringAggAs(Field("name"), ", ", "s"), "STRING_AGG([name], @p1) AS [s]", []QueryArg{{Value: ", "}}},
+		{"array_agg", SQLDialectPostgreSQL, ArrayAggAs(Field("name"), "a"), `ARRAY_AGG("name") AS "a"`, nil},
+		{"stddev", SQLDialectMySQL, StdDevAs(Field("amount"), "sd"), "STDDEV_SAMP(`amount`) AS `sd`", nil},
+		{"stddev_pop_tsql", SQLDialectTSQL, StdDevPopAs(Field("amount"), "sd"), "STDEVP([amount]) AS [sd]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, args, err := RenderSQL(query(tt.column), tt.dialect)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(text, "SELECT "+tt.want+"\n"), text)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+
+	text, _, err := RenderSQL(From(NewRootCollectionRef("sales", "")).NewQuery().Distinct().Limit(5).
+		SelectColumns(Column{Expression: Field("region")}), SQLDialectTSQL)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DISTINCT TOP 5 [region]\nFROM [sales]", text)
+
+	for name, tt := range map[string]struct {
+		dialect SQLDialect
+		column  Column
+	}{
+		"median_mysql":           {SQLDialectMySQL, MedianAs(Field("amount"), "m")},
+		"percentile_tsql":        {SQLDialectTSQL, PercentileAs(Field("amount"), 0.5, "p")},
+		"array_agg_sqlite":       {SQLDialectSQLite, ArrayAggAs(Field("name"), "a")},
+		"stddev_sqlite":          {SQLDialectSQLite, StdDevAs(Field("amount"), "sd")},
+		"string_agg_distinct":    {SQLDialectSQLite, Column{Expression: function{Name: STRING_AGG, Args: []Expression{Field("name"), String(",")}, Distinct: true}}},
+		"string_agg_no_sep":      {SQLDialectPostgreSQL, Column{Expression: function{Name: STRING_AGG, Args: []Expression{Field("name")}}}},
+		"unknown_aggregate":      {SQLDialectPostgreSQL, Column{Expression: function{Name: "MODE", Args: []Expression{Field("name")}}}},
+		"aggregate_without_args": {SQLDialectPostgreSQL, Column{Expression: function{Name: SUM}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := RenderSQL(query(tt.column), tt.dialect)
+			assert.ErrorIs(t, err, ErrNotSupported)
+		})
+	}
+}
+
+func TestRenderSQL_Subqueries(t *testing.T) {
+	users := From(NewRootCollectionRef("users", "u")).NewQuery()
+	query := users.
+		Where(
+			WhereField("active", Equal, true),
+			Exists(openOrdersOf("u")),
+			Not(InSubquery(Field("id"), From(NewRootCollectionRef("bans", "")).NewQuery().
+				WhereField("year", Equal, 2024).
+				SelectColumns(Column{Expression: Field("userID")}))),
+		).
+		Limit(10).
+		SelectColumns(Column{Expression: NewFieldRef("u", "name")})
+	text, args, err := RenderSQL(query, SQLDialectPostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "u"."name"
+FROM "users" AS "u"
+WHERE ("active" = TRUE AND EXISTS (SELECT *
+FROM "orders" AS "o"
+WHERE ("o"."userID" = "u"."id" AND "status" = $1)) AND NOT ("id" IN (SELECT "userID"
+FROM "bans"
+WHERE "year" = $2)))
+LIMIT 10`, text)
+	assert.Equal(t, []QueryArg{{Value: "open"}, {Value: 2024}}, args)
+
+	_, _, err = RenderSQL(From(NewRootCollectionRef("users", "")).NewQuery().Where(Exists(nil)).SelectColumns(), SQLDialectANSI)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, _, err = RenderSQL(From(NewRootCollectionRef("users", "")).NewQuery().
+		Where(Exists(From(NewRootCollectionRef("orders", "")).NewQuery().StartFrom("c").SelectColumns())).SelectColumns(), SQLDialectANSI)
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestRenderSQL_QuotesEmbeddedQuotes(t *testing.T) {
+	assert.Equal(t, `"a""b"`, SQLDialectANSI.QuoteIdentifier(`a"b`))
+	assert.Equal(t, "[a]]b]", SQLDialectTSQL.QuoteIdentifier("a]b"))
+	assert.Equal(t, "`a``b`", SQLDialectMySQL.QuoteIdentifier("a`b"))
+}
+
+func TestRenderSQL_NotSupported(t *testing.T) {
+	parent := record.NewKeyWithID("users", "u1")
+	for name, query := range map[string]StructuredQuery{
+		"cursor":       From(NewRootCollectionRef("t", "")).NewQuery().StartFrom("abc").SelectColumns(),
+		"parent":       From(NewCollectionRef("orders", "", parent)).NewQuery().SelectColumns(),
+		"operator":     From(NewRootCollectionRef("t", "")).NewQuery().Where(Comparison{Operator: "~", Left: Field("a"), Right: NewConstant(1)}).SelectColumns(),
+		"empty_in":     From(NewRootCollectionRef("t", "")).NewQuery().Where(WhereField("a", In, []int{})).SelectColumns(),
+		"join_without": From(NewRootCollectionRef("a", "")).Join(NewJoinedSource(NewRootCollectionRef("b", ""), JoinInner)).NewQuery().SelectColumns(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := RenderSQL(query, SQLDialectANSI)
+			assert.ErrorIs(t, err, ErrNotSupported)
+		})
+	}
+}
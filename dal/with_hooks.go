@@ -1,209 +1,274 @@
 package dal
 
-//import (
-//	"context"
-//)
-
-//type KeyOperation = func(ctx context.Context, key record.Key) (err error)
-//type GetFunc = func(ctx context.Context, record record.Record) (err error)
-//type SetFunc = func(ctx context.Context, record record.Record) (err error)
-//type DelFunc = func(ctx context.Context, key record.Key) (err error)
-//type UpdateFunc = func(ctx context.Context, key *record.Key, updates []update, preconditions ...Precondition) error
-
-//type HookContext struct {
-//	context.Context
-//	IsInTransaction bool
-//}
-//
-//type BeforeKeyOperationHook = func(ctx HookContext, key *record.Key) error
-//type BeforeKeysOperationHook = func(ctx HookContext, keys []*record.Key) error
-//type BeforeRecordOperationHook = func(ctx HookContext, record record.Record) error
-//type BeforeRecordsOperationHook = func(ctx HookContext, records []record.Record) error
-//
-//type AfterKeyOperationHook = func(ctx HookContext, key *record.Key, err error) error
-//type AfterKeysOperationHook = func(ctx HookContext, keys []*record.Key, err error) error
-//type AfterRecordOperationHook = func(ctx HookContext, record record.Record, err error) error
-//type AfterRecordsOperationHook = func(ctx HookContext, records []record.Record, err error) error
-//
-//type BeforeUpdateHook = func(ctx HookContext, key *record.Key, updates []update, preconditions ...Precondition) error
-//
-//type Hooks struct {
-//	beforeGet BeforeRecordOperationHook
-//	afterGet  AfterRecordOperationHook
-//
-//	beforeGetMulti BeforeRecordsOperationHook
-//	afterGetMulti  AfterRecordsOperationHook
-//
-//	beforeSet BeforeRecordOperationHook
-//	afterSet  AfterRecordOperationHook
-//
-//	beforeSetMulti BeforeRecordsOperationHook
-//	afterSetMulti  AfterRecordsOperationHook
-//
-//	beforeInsert BeforeRecordOperationHook
-//	afterInsert  AfterRecordOperationHook
-//
-//	beforeInsertMulti BeforeRecordsOperationHook
-//	afterInsertMulti  AfterRecordsOperationHook
-//
-//	beforeUpdate BeforeUpdateHook
-//	afterUpdate  AfterKeyOperationHook
-//
-//	beforeDelete BeforeKeyOperationHook
-//	afterDelete  AfterKeyOperationHook
-//
-//	beforeDeleteMulti BeforeKeysOperationHook
-//	afterDeleteMulti  AfterKeysOperationHook
-//}
-//
-//func (v *Hooks) BeforeGet() BeforeRecordOperationHook {
-//	return v.beforeGet
-//}
-//
-//func (v *Hooks) BeforeGetMulti() BeforeRecordsOperationHook {
-//	return v.beforeGetMulti
-//}
-//
-//func (v *Hooks) AfterGet() AfterRecordOperationHook {
-//	return v.afterGet
-//}
-//
-//func (v *Hooks) AfterGetMulti() AfterRecordsOperationHook {
-//	return v.afterGetMulti
-//}
-//
-//func (v *Hooks) BeforeInsert() BeforeRecordOperationHook {
-//	return v.beforeInsert
-//}
-//
-//func (v *Hooks) AfterInsert() AfterRecordOperationHook {
-//	return v.afterInsert
-//}
-//
-//func (v *Hooks) BeforeInsertMulti() BeforeRecordsOperationHook {
-//	return v.beforeInsertMulti
-//}
-//
-//func (v *Hooks) AfterInsertMulti() AfterRecordsOperationHook {
-//	return v.afterInsertMulti
-//}
-//
-//func (v *Hooks) BeforeSet() BeforeRecordOperationHook {
-//	return v.beforeSet
-//}
-//
-//func (v *Hooks) BeforeSetMulti() BeforeRecordsOperationHook {
-//	return v.beforeSetMulti
-//}
-//
-//func (v *Hooks) AfterSet() AfterRecordOperationHook {
-//	return v.afterSet
-//}
-//
-//func (v *Hooks) AfterSetMulti() AfterRecordsOperationHook {
-//	return v.afterSetMulti
-//}
-//
-//func (v *Hooks) BeforeUpdate() BeforeUpdateHook {
-//	return v.beforeUpdate
-//}
-//
-//func (v *Hooks) AfterUpdate() AfterKeyOperationHook {
-//	return v.afterUpdate
-//}
-//
-//func (v *Hooks) BeforeDelete() BeforeKeyOperationHook {
-//	return v.beforeDelete
-//}
-//
-//func (v *Hooks) BeforeDeleteMulti() BeforeKeysOperationHook {
-//	return v.beforeDeleteMulti
-//}
-//
-//func (v *Hooks) AfterDelete() AfterKeyOperationHook {
-//	return v.afterDelete
-//}
-//
-//func (v *Hooks) AfterDeleteMulti() AfterKeysOperationHook {
-//	return v.afterDeleteMulti
-//}
-//
-//func WithHooks(options ...func(bag *Hooks)) (hooks *Hooks) {
-//	hooks = new(Hooks)
-//	for _, o := range options {
-//		o(hooks)
-//	}
-//	return
-//}
-//
-//func BeforeGet(hook BeforeRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeGet = hook
-//	}
-//}
-//
-//func AfterGet(hook AfterRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterGet = hook
-//	}
-//}
-//
-//func BeforeGetMulti(hook BeforeRecordsOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeGetMulti = hook
-//	}
-//}
-//
-//func AfterGetMulti(hook AfterRecordsOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterGetMulti = hook
-//	}
-//}
-
-//func BeforeSet(hook BeforeRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeSet = hook
-//	}
-//}
-//
-//func AfterSet(hook AfterRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterSet = hook
-//	}
-//}
-//
-//func BeforeInsert(hook BeforeRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeInsert = hook
-//	}
-//}
-//
-//func AfterInsert(hook AfterRecordOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterInsert = hook
-//	}
-//}
-//
-//func BeforeUpdate(hook BeforeUpdateHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeUpdate = hook
-//	}
-//}
-//
-//func AfterUpdate(hook AfterKeyOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterUpdate = hook
-//	}
-//}
-//
-//func BeforeDelete(hook BeforeKeyOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.beforeDelete = hook
-//	}
-//}
-//
-//func AfterDelete(hook AfterKeyOperationHook) func(*Hooks) {
-//	return func(hooks *Hooks) {
-//		hooks.afterDelete = hook
-//	}
-//}
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// BeforeKeyOperationHook is called before an operation on a single key.
+type BeforeKeyOperationHook = func(ctx context.Context, key *record.Key) error
+
+// BeforeKeysOperationHook is called before an operation on multiple keys.
+type BeforeKeysOperationHook = func(ctx context.Context, keys []*record.Key) error
+
+// BeforeRecordOperationHook is called before an operation on a single record.
+type BeforeRecordOperationHook = func(ctx context.Context, record record.Record) error
+
+// BeforeRecordsOperationHook is called before an operation on multiple records.
+type BeforeRecordsOperationHook = func(ctx context.Context, records []record.Record) error
+
+// BeforeUpdateHook is called before a single record is updated.
+type BeforeUpdateHook = func(ctx context.Context, key *record.Key, updates []update.Update, preconditions ...Precondition) error
+
+// BeforeUpdateMultiHook is called before multiple records are updated.
+type BeforeUpdateMultiHook = func(ctx context.Context, keys []*record.Key, updates []update.Update, preconditions ...Precondition) error
+
+// AfterKeyOperationHook is called after an operation on a single key with the
+// error the operation returned. The hook's result replaces that error.
+type AfterKeyOperationHook = func(ctx context.Context, key *record.Key, err error) error
+
+// AfterKeysOperationHook is called after an operation on multiple keys with the
+// error the operation returned. The hook's result replaces that error.
+type AfterKeysOperationHook = func(ctx context.Context, keys []*record.Key, err error) error
+
+// AfterRecordOperationHook is called after an operation on a single record with
+// the error the operation returned. The hook's result replaces that error.
+type AfterRecordOperationHook = func(ctx context.Context, record record.Record, err error) error
+
+// AfterRecordsOperationHook is called after an operation on multiple records
+// with the error the operation returned. The hook's result replaces that error.
+type AfterRecordsOperationHook = func(ctx context.Context, records []record.Record, err error) error
+
+// HooksOption registers hooks with a Hooks pipeline.
+type HooksOption = func(hooks *Hooks)
+
+// Hooks is a pipeline of operation hooks that WithHooks runs around the
+// operations of a DB and of the transactions it starts. Hooks registered for
+// the same event run in registration order.
+//
+// A before hook that returns an error aborts the operation; the error is
+// returned wrapped in ErrHookFailed and no after hooks are called. After hooks
+// receive the operation's error and return the error to propagate: returning
+// it unchanged (or wrapped) keeps it, returning nil swallows it, and any other
+// error is wrapped in ErrHookFailed.
+type Hooks struct {
+	beforeGet []BeforeRecordOperationHook
+	afterGet  []AfterRecordOperationHook
+
+	beforeGetMulti []BeforeRecordsOperationHook
+	afterGetMulti  []AfterRecordsOperationHook
+
+	beforeSet []BeforeRecordOperationHook
+	afterSet  []AfterRecordOperationHook
+
+	beforeSetMulti []BeforeRecordsOperationHook
+	afterSetMulti  []AfterRecordsOperationHook
+
+	beforeInsert []BeforeRecordOperationHook
+	afterInsert  []AfterRecordOperationHook
+
+	beforeInsertMulti []BeforeRecordsOperationHook
+	afterInsertMulti  []AfterRecordsOperationHook
+
+	beforeUpdate []BeforeUpdateHook
+	afterUpdate  []AfterKeyOperationHook
+
+	beforeUpdateMulti []BeforeUpdateMultiHook
+	afterUpdateMulti  []AfterKeysOperationHook
+
+	beforeDelete []BeforeKeyOperationHook
+	afterDelete  []AfterKeyOperationHook
+
+	beforeDeleteMulti []BeforeKeysOperationHook
+	afterDeleteMulti  []AfterKeysOperationHook
+}
+
+// NewHooks builds a hook pipeline from options.
+func NewHooks(options ...HooksOption) *Hooks {
+	hooks := new(Hooks)
+	for _, o := range options {
+		o(hooks)
+	}
+	return hooks
+}
+
+// BeforeGet registers a hook called before Get.
+func BeforeGet(hook BeforeRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeGet = append(hooks.beforeGet, hook)
+	}
+}
+
+// AfterGet registers a hook called after Get.
+func AfterGet(hook AfterRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterGet = append(hooks.afterGet, hook)
+	}
+}
+
+// BeforeGetMulti registers a hook called before GetMulti.
+func BeforeGetMulti(hook BeforeRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeGetMulti = append(hooks.beforeGetMulti, hook)
+	}
+}
+
+// AfterGetMulti registers a hook called after GetMulti.
+func AfterGetMulti(hook AfterRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterGetMulti = append(hooks.afterGetMulti, hook)
+	}
+}
+
+// BeforeSet registers a hook called before Set.
+func BeforeSet(hook BeforeRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeSet = append(hooks.beforeSet, hook)
+	}
+}
+
+// AfterSet registers a hook called after Set.
+func AfterSet(hook AfterRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterSet = append(hooks.afterSet, hook)
+	}
+}
+
+// BeforeSetMulti registers a hook called before SetMulti.
+func BeforeSetMulti(hook BeforeRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeSetMulti = append(hooks.beforeSetMulti, hook)
+	}
+}
+
+// AfterSetMulti registers a hook called after SetMulti.
+func AfterSetMulti(hook AfterRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterSetMulti = append(hooks.afterSetMulti, hook)
+	}
+}
+
+// BeforeInsert registers a hook called before Insert.
+func BeforeInsert(hook BeforeRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeInsert = append(hooks.beforeInsert, hook)
+	}
+}
+
+// AfterInsert registers a hook called after Insert.
+func AfterInsert(hook AfterRecordOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterInsert = append(hooks.afterInsert, hook)
+	}
+}
+
+// BeforeInsertMulti registers a hook called before InsertMulti.
+func BeforeInsertMulti(hook BeforeRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeInsertMulti = append(hooks.beforeInsertMulti, hook)
+	}
+}
+
+// AfterInsertMulti registers a hook called after InsertMulti.
+func AfterInsertMulti(hook AfterRecordsOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterInsertMulti = append(hooks.afterInsertMulti, hook)
+	}
+}
+
+// BeforeUpdate registers a hook called before Update and UpdateRecord.
+func BeforeUpdate(hook BeforeUpdateHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeUpdate = append(hooks.beforeUpdate, hook)
+	}
+}
+
+// AfterUpdate registers a hook called after Update and UpdateRecord.
+func AfterUpdate(hook AfterKeyOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterUpdate = append(hooks.afterUpdate, hook)
+	}
+}
+
+// BeforeUpdateMulti registers a hook called before UpdateMulti.
+func BeforeUpdateMulti(hook BeforeUpdateMultiHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeUpdateMulti = append(hooks.beforeUpdateMulti, hook)
+	}
+}
+
+// AfterUpdateMulti registers a hook called after UpdateMulti.
+func AfterUpdateMulti(hook AfterKeysOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterUpdateMulti = append(hooks.afterUpdateMulti, hook)
+	}
+}
+
+// BeforeDelete registers a hook called before Delete.
+func BeforeDelete(hook BeforeKeyOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeDelete = append(hooks.beforeDelete, hook)
+	}
+}
+
+// AfterDelete registers a hook called after Delete.
+func AfterDelete(hook AfterKeyOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterDelete = append(hooks.afterDelete, hook)
+	}
+}
+
+// BeforeDeleteMulti registers a hook called before DeleteMulti.
+func BeforeDeleteMulti(hook BeforeKeysOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.beforeDeleteMulti = append(hooks.beforeDeleteMulti, hook)
+	}
+}
+
+// AfterDeleteMulti registers a hook called after DeleteMulti.
+func AfterDeleteMulti(hook AfterKeysOperationHook) HooksOption {
+	return func(hooks *Hooks) {
+		hooks.afterDeleteMulti = append(hooks.afterDeleteMulti, hook)
+	}
+}
+
+// callBeforeHooks runs before hooks in order and stops at the first failure.
+func callBeforeHooks[T any](ctx context.Context, hooks []func(context.Context, T) error, arg T) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, arg); err != nil {
+			return fmt.Errorf("%w: %w", ErrHookFailed, err)
+		}
+	}
+	return nil
+}
+
+// callAfterHooks threads the operation error through after hooks in order.
+func callAfterHooks[T any](ctx context.Context, hooks []func(context.Context, T, error) error, arg T, err error) error {
+	for _, hook := range hooks {
+		result := hook(ctx, arg, err)
+		if result != nil && !errors.Is(result, err) {
+			result = fmt.Errorf("%w: %w", ErrHookFailed, result)
+		}
+		err = result
+	}
+	return err
+}
+
+// callBeforeUpdateHooks runs update hooks in order and stops at the first
+// failure.
+func callBeforeUpdateHooks[K any](
+	ctx context.Context,
+	hooks []func(context.Context, K, []update.Update, ...Precondition) error,
+	keys K, updates []update.Update, preconditions []Precondition,
+) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, keys, updates, preconditions...); err != nil {
+			return fmt.Errorf("%w: %w", ErrHookFailed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package dal
+
+import (
+	"context"
+
+	"github.com/dal-go/dalgo/recordset"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// WithHooks wraps db so that the hooks registered by options run around its
+// operations, including those performed inside RunReadonlyTransaction and
+// RunReadwriteTransaction workers. Exists and queries pass through unhooked,
+// and so does ExplainQuery (see Explainer). When db itself implements
+// WriteSession, so does the returned DB.
+func WithHooks(db DB, options ...HooksOption) DB {
+	hooks := NewHooks(options...)
+	hooked := hookedDB{
+		hookedReadSession: hookedReadSession{session: db, hooks: hooks},
+		db:                db,
+	}
+	if ws, ok := db.(WriteSession); ok {
+		return &hookedReadwriteDB{
+			hookedDB:           hooked,
+			hookedWriteSession: hookedWriteSession{session: ws, hooks: hooks},
+		}
+	}
+	return &hooked
+}
+
+type hookedDB struct {
+	hookedReadSession
+	db DB
+}
+
+func (db *hookedDB) ID() string { return db.db.ID() }
+
+func (db *hookedDB) Adapter() Adapter { return db.db.Adapter() }
+
+func (db *hookedDB) Schema() Schema { return db.db.Schema() }
+
+func (db *hookedDB) SupportsConcurrentConnections() bool {
+	return db.db.SupportsConcurrentConnections()
+}
+
+func (db *hookedDB) RunReadonlyTransaction(ctx context.Context, worker ROTxWorker, options ...TransactionOption) error {
+	return db.db.RunReadonlyTransaction(ctx, func(workerCtx context.Context, tx ReadTransaction) error {
+		hookedTx := &hookedReadTransaction{
+			hookedReadSession: hookedReadSession{session: tx, hooks: db.hooks},
+			tx:                tx,
+		}
+		return worker(NewContextWithTransaction(workerCtx, hookedTx), hookedTx)
+	}, options...)
+}
+
+func (db *hookedDB) RunReadwriteTransaction(ctx context.Context, worker RWTxWorker, options ...TransactionOption) error {
+	return db.db.RunReadwriteTransaction(ctx, func(workerCtx context.Context, tx ReadwriteTransaction) error {
+		hookedTx := &hookedReadwriteTransaction{
+			hookedReadSession:  hookedReadSession{session: tx, hooks: db.hooks},
+			hookedWriteSession: hookedWriteSession{session: tx, hooks: db.hooks},
+			tx:                 tx,
+		}
+		return worker(NewContextWithTransaction(workerCtx, hookedTx), hookedTx)
+	}, options...)
+}
+
+// hookedReadwriteDB is returned by WithHooks for a DB that can also write
+// outside of a transaction.
+type hookedReadwriteDB struct {
+	hookedDB
+	hookedWriteSession
+}
+
+type hookedReadTransaction struct {
+	hookedReadSession
+	tx ReadTransaction
+}
+
+func (tx *hookedReadTransaction) Options() TransactionOptions { return tx.tx.Options() }
+
+type hookedReadwriteTransaction struct {
+	hookedReadSession
+	hookedWriteSession
+	tx ReadwriteTransaction
+}
+
+func (tx *hookedReadwriteTransaction) ID() string { return tx.tx.ID() }
+
+func (tx *hookedReadwriteTransaction) Options() TransactionOptions { return tx.tx.Options() }
+
+var (
+	_ DB                   = (*hookedDB)(nil)
+	_ DB                   = (*hookedReadwriteDB)(nil)
+	_ WriteSession         = (*hookedReadwriteDB)(nil)
+	_ ReadTransaction      = (*hookedReadTransaction)(nil)
+	_ ReadwriteTransaction = (*hookedReadwriteTransaction)(nil)
+)
+
+type hookedReadSession struct {
+	session ReadSession
+	hooks   *Hooks
+}
+
+// ExplainQuery forwards to the wrapped database or transaction, failing with
+// ErrNotSupported when it does not implement Explainer.
+func (s hookedReadSession) ExplainQuery(ctx context.Context, query Query) (QueryPlan, error) {
+	return ExplainQuery(ctx, s.session, query)
+}
+
+func (s hookedReadSession) Exists(ctx context.Context, key *record.Key) (bool, error) {
+	return s.session.Exists(ctx, key)
+}
+
+func (s hookedReadSession) Get(ctx context.Context, record record.Record) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeGet, record); err != nil {
+		return err
+	}
+	err := s.session.Get(ctx, record)
+	return callAfterHooks(ctx, s.hooks.afterGet, record, err)
+}
+
+func (s hookedReadSession) GetMulti(ctx context.Context, records []record.Record) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeGetMulti, records); err != nil {
+		return err
+	}
+	err := s.session.GetMulti(ctx, records)
+	return callAfterHooks(ctx, s.hooks.afterGetMulti, records, err)
+}
+
+func (s hookedReadSession) ExecuteQueryToRecordsReader(ctx context.Context, query Query) (RecordsReader, error) {
+	return s.session.ExecuteQueryToRecordsReader(ctx, query)
+}
+
+func (s hookedReadSession) ExecuteQueryToRecordsetReader(ctx context.Context, query Query, options ...recordset.Option) (RecordsetReader, error) {
+	return s.session.ExecuteQueryToRecordsetReader(ctx, query, options...)
+}
+
+type hookedWriteSession struct {
+	session WriteSession
+	hooks   *Hooks
+}
+
+func (s hookedWriteSession) Set(ctx context.Context, record record.Record) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeSet, record); err != nil {
+		return err
+	}
+	err := s.session.Set(ctx, record)
+	return callAfterHooks(ctx, s.hooks.afterSet, record, err)
+}
+
+func (s hookedWriteSession) SetMulti(ctx context.Context, records []record.Record) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeSetMulti, records); err != nil {
+		return err
+	}
+	err := s.session.SetMulti(ctx, records)
+	return callAfterHooks(ctx, s.hooks.afterSetMulti, records, err)
+}
+
+func (s hookedWriteSession) Insert(ctx context.Context, record record.Record, opts ...InsertOption) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeInsert, record); err != nil {
+		return err
+	}
+	err := s.session.Insert(ctx, record, opts...)
+	return callAfterHooks(ctx, s.hooks.afterInsert, record, err)
+}
+
+func (s hookedWriteSession) InsertMulti(ctx context.Context, records []record.Record, opts ...InsertOption) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeInsertMulti, records); err != nil {
+		return err
+	}
+	err := s.session.InsertMulti(ctx, records, opts...)
+	return callAfterHooks(ctx, s.hooks.afterInsertMulti, records, err)
+}
+
+func (s hookedWriteSession) Update(ctx context.Context, key *record.Key, updates []update.Update, preconditions ...Precondition) error {
+	if err := callBeforeUpdateHooks(ctx, s.hooks.beforeUpdate, key, updates, preconditions); err != nil {
+		return err
+	}
+	err := s.session.Update(ctx, key, updates, preconditions...)
+	return callAfterHooks(ctx, s.hooks.afterUpdate, key, err)
+}
+
+func (s hookedWriteSession) UpdateRecord(ctx context.Context, record record.Record, updates []update.Update, preconditions ...Precondition) error {
+	key := record.Key()
+	if err := callBeforeUpdateHooks(ctx, s.hooks.beforeUpdate, key, updates, preconditions); err != nil {
+		return err
+	}
+	err := s.session.UpdateRecord(ctx, record, updates, preconditions...)
+	return callAfterHooks(ctx, s.hooks.afterUpdate, key, err)
+}
+
+func (s hookedWriteSession) UpdateMulti(ctx context.Context, keys []*record.Key, updates []update.Update, preconditions ...Precondition) error {
+	if err := callBeforeUpdateHooks(ctx, s.hooks.beforeUpdateMulti, keys, updates, preconditions); err != nil {
+		return err
+	}
+	err := s.session.UpdateMulti(ctx, keys, updates, preconditions...)
+	return callAfterHooks(ctx, s.hooks.afterUpdateMulti, keys, err)
+}
+
+func (s hookedWriteSession) Delete(ctx context.Context, key *record.Key) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeDelete, key); err != nil {
+		return err
+	}
+	err := s.session.Delete(ctx, key)
+	return callAfterHooks(ctx, s.hooks.afterDelete, key, err)
+}
+
+func (s hookedWriteSession) DeleteMulti(ctx context.Context, keys []*record.Key) error {
+	if err := callBeforeHooks(ctx, s.hooks.beforeDeleteMulti, keys); err != nil {
+		return err
+	}
+	err := s.session.DeleteMulti(ctx, keys)
+	return callAfterHooks(ctx, s.hooks.afterDeleteMulti, keys, err)
+}
@@ -1,200 +1,208 @@
-package dal
+package dal_test
 
-//
-//import (
-//	"context"
-//	"testing"
-//)
-//
-//func TestWithHooks2(t *testing.T) {
-//	type args struct {
-//		options []func(bag *Hooks)
-//	}
-//	tests := []struct {
-//		name        string
-//		args        args
-//		assertHooks func(tt *testing.T, hooks *Hooks)
-//	}{
-//		{
-//			name: "nil",
-//			args: args{
-//				options: nil,
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeGet != nil {
-//					tt.Error("beforeGet is not nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeGet",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeGet(func(ctx context.Context, record record.Record) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeGet == nil {
-//					tt.Error("beforeGet is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterGet",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterGet(func(ctx context.Context, record record.Record, err error) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterGet == nil {
-//					tt.Error("afterGet is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeGetMulti",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeGetMulti(func(ctx context.Context, records []record.Record) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeGetMulti == nil {
-//					tt.Error("beforeGetMulti is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterGetMulti",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterGetMulti(func(ctx context.Context, records []record.Record, err error) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterGetMulti == nil {
-//					tt.Error("afterGetMulti is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeInsert",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeInsert(func(ctx context.Context, record record.Record) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeInsert == nil {
-//					tt.Error("beforeInsert is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterInsert",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterInsert(func(ctx context.Context, record record.Record, err error) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterInsert == nil {
-//					tt.Error("afterInsert is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeSet",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeSet(func(ctx context.Context, record record.Record) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeSet == nil {
-//					tt.Error("beforeSet is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterSet",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterSet(func(ctx context.Context, record record.Record, err error) error { return nil }),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterSet == nil {
-//					tt.Error("afterSet is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeUpdate",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeUpdate(func(ctx context.Context, key *record.Key, updates []update, preconditions ...Precondition) error {
-//						return nil
-//					}),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeUpdate == nil {
-//					tt.Error("beforeUpdate is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterUpdate",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterUpdate(func(ctx context.Context, key *record.Key, err error) error {
-//						return nil
-//					}),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterUpdate == nil {
-//					tt.Error("afterUpdate is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "beforeDelete",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					BeforeDelete(func(ctx context.Context, key *record.Key) error {
-//						return nil
-//					}),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.beforeDelete == nil {
-//					tt.Error("beforeDelete is nil")
-//				}
-//			},
-//		},
-//		{
-//			name: "afterDelete",
-//			args: args{
-//				options: []func(bag *Hooks){
-//					AfterDelete(func(ctx context.Context, key *record.Key, err error) error {
-//						return nil
-//					}),
-//				},
-//			},
-//			assertHooks: func(tt *testing.T, hooks *Hooks) {
-//				if hooks.afterDelete == nil {
-//					tt.Error("afterDelete is nil")
-//				}
-//			},
-//		},
-//	}
-//	for _, tt := range tests {
-//		t.Run(tt.name, func(t *testing.T) {
-//			hooks := WithHooks(tt.args.options...)
-//			tt.assertHooks(t, hooks)
-//		})
-//	}
-//}
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/require"
+)
+
+var errRejected = errors.New("rejected")
+
+func TestWithHooks_RunsHooksInRegistrationOrder(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	db := dal.WithHooks(newMemoryDB(t),
+		dal.BeforeSet(func(ctx context.Context, r record.Record) error {
+			calls = append(calls, "before1:"+r.Key().String())
+			return nil
+		}),
+		dal.BeforeSet(func(ctx context.Context, r record.Record) error {
+			calls = append(calls, "before2")
+			return nil
+		}),
+		dal.AfterSet(func(ctx context.Context, r record.Record, err error) error {
+			calls = append(calls, "after")
+			return err
+		}),
+		dal.BeforeGet(func(ctx context.Context, r record.Record) error {
+			calls = append(calls, "beforeGet")
+			return nil
+		}),
+		dal.AfterGet(func(ctx context.Context, r record.Record, err error) error {
+			calls = append(calls, "afterGet")
+			return err
+		}),
+	)
+	ws, ok := db.(dal.WriteSession)
+	require.True(t, ok, "a hooked writable DB stays writable")
+
+	key := record.NewKeyWithID("users", "u1")
+	require.NoError(t, ws.Set(ctx, record.NewRecordWithData(key, &User{Name: "Ada"})))
+	var user User
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(key, &user)))
+	require.Equal(t, "Ada", user.Name)
+	require.Equal(t, []string{"before1:users/u1", "before2", "after", "beforeGet", "afterGet"}, calls)
+}
+
+func TestWithHooks_BeforeHookAbortsOperation(t *testing.T) {
+	ctx := context.Background()
+	afterCalled := false
+	db := dal.WithHooks(newMemoryDB(t),
+		dal.BeforeInsert(func(ctx context.Context, r record.Record) error {
+			return errRejected
+		}),
+		dal.AfterInsert(func(ctx context.Context, r record.Record, err error) error {
+			afterCalled = true
+			return err
+		}),
+	)
+	key := record.NewKeyWithID("users", "u1")
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Insert(ctx, record.NewRecordWithData(key, &User{Name: "Ada"}))
+	})
+	require.ErrorIs(t, err, dal.ErrHookFailed)
+	require.ErrorIs(t, err, errRejected)
+	require.False(t, afterCalled)
+
+	exists, err := db.Exists(ctx, key)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestWithHooks_AfterHookSeesAndReplacesError(t *testing.T) {
+	ctx := context.Background()
+	missing := record.NewKeyWithID("users", "missing")
+	var seen error
+	swallow := dal.WithHooks(newMemoryDB(t),
+		dal.AfterGet(func(ctx context.Context, r record.Record, err error) error {
+			seen = err
+			return nil
+		}),
+	)
+	require.NoError(t, swallow.Get(ctx, record.NewRecordWithData(missing, &User{})))
+	require.True(t, record.IsNotFound(seen))
+
+	replace := dal.WithHooks(newMemoryDB(t),
+		dal.AfterGet(func(ctx context.Context, r record.Record, err error) error {
+			return errRejected
+		}),
+		dal.AfterGet(func(ctx context.Context, r record.Record, err error) error {
+			seen = err
+			return err
+		}),
+	)
+	err := replace.Get(ctx, record.NewRecordWithData(missing, &User{}))
+	require.ErrorIs(t, err, dal.ErrHookFailed)
+	require.ErrorIs(t, err, errRejected)
+	require.ErrorIs(t, seen, errRejected, "later hooks see the replaced error")
+
+	keep := dal.WithHooks(newMemoryDB(t),
+		dal.AfterGet(func(ctx context.Context, r record.Record, err error) error {
+			return err
+		}),
+	)
+	err = keep.Get(ctx, record.NewRecordWithData(missing, &User{}))
+	require.True(t, record.IsNotFound(err))
+	require.NotErrorIs(t, err, dal.ErrHookFailed)
+}
+
+func TestWithHooks_MultiAndUpdateHooksInTransaction(t *testing.T) {
+	ctx := context.Background()
+	keys := []*record.Key{record.NewKeyWithID("users", "u1"), record.NewKeyWithID("users", "u2")}
+	var calls []string
+	var inTx bool
+	db := dal.WithHooks(newMemoryDB(t),
+		dal.BeforeSetMulti(func(ctx context.Context, records []record.Record) error {
+			calls = append(calls, "setMulti")
+			inTx = dal.GetTransaction(ctx) != nil
+			return nil
+		}),
+		dal.BeforeGetMulti(func(ctx context.Context, records []record.Record) error {
+			calls = append(calls, "getMulti")
+			return nil
+		}),
+		dal.BeforeUpdate(func(ctx context.Context, key *record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+			calls = append(calls, "update:"+key.String())
+			return nil
+		}),
+		dal.BeforeUpdateMulti(func(ctx context.Context, keys []*record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+			calls = append(calls, "updateMulti")
+			return nil
+		}),
+		dal.AfterDeleteMulti(func(ctx context.Context, keys []*record.Key, err error) error {
+			calls = append(calls, "deleteMulti")
+			return err
+		}),
+		dal.BeforeDelete(func(ctx context.Context, key *record.Key) error {
+			return errRejected
+		}),
+	)
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		if err := tx.SetMulti(ctx, []record.Record{
+			record.NewRecordWithData(keys[0], &User{Name: "Ada"}),
+			record.NewRecordWithData(keys[1], &User{Name: "Bob"}),
+		}); err != nil {
+			return err
+		}
+		if err := tx.UpdateRecord(ctx, record.NewRecordWithData(keys[0], &User{}), []update.Update{update.ByFieldName("name", "Eve")}); err != nil {
+			return err
+		}
+		if err := tx.UpdateMulti(ctx, keys, []update.Update{update.ByFieldName("name", "Joe")}); err != nil {
+			return err
+		}
+		if err := tx.GetMulti(ctx, []record.Record{record.NewRecordWithData(keys[0], &User{})}); err != nil {
+			return err
+		}
+		require.ErrorIs(t, tx.Delete(ctx, keys[0]), dal.ErrHookFailed)
+		return tx.DeleteMulti(ctx, keys)
+	})
+	require.NoError(t, err)
+	require.True(t, inTx, "hooks inside a worker receive the transactional context")
+	require.Equal(t, []string{"setMulti", "update:users/u1", "updateMulti", "getMulti", "deleteMulti"}, calls)
+}
+
+// readonlyDB is a dal.DB without write methods of its own.
+type readonlyDB struct{ dal.DB }
+
+func TestWithHooks_ReadonlyDB(t *testing.T) {
+	ctx := context.Background()
+	var got []string
+	db := dal.WithHooks(readonlyDB{newMemoryDB(t)},
+		dal.BeforeGet(func(ctx context.Context, r record.Record) error {
+			got = append(got, "get")
+			return nil
+		}),
+	)
+	_, ok := db.(dal.WriteSession)
+	require.False(t, ok)
+	err := db.RunReadonlyTransaction(ctx, func(ctx context.Context, tx dal.ReadTransaction) error {
+		err := tx.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "u1"), &User{}))
+		require.True(t, record.IsNotFound(err))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"get"}, got)
+}
+
+func TestWithHooks_ExplainQuery(t *testing.T) {
+	ctx := context.Background()
+	q := dal.From(dal.NewRootCollectionRef("users", "")).NewQuery().SelectKeysOnly(reflect.String)
+	db := dal.WithHooks(newMemoryDB(t))
+	plan, err := dal.ExplainQuery(ctx, db, q)
+	require.NoError(t, err)
+	require.Equal(t, dal.PlanScan, plan.Root.Operation)
+	err = db.RunReadonlyTransaction(ctx, func(ctx context.Context, tx dal.ReadTransaction) error {
+		_, err := dal.ExplainQuery(ctx, tx, q)
+		return err
+	})
+	require.NoError(t, err, "hooked transactions forward ExplainQuery")
+
+	_, err = dal.ExplainQuery(ctx, dal.WithHooks(readonlyDB{newMemoryDB(t)}), q)
+	require.ErrorIs(t, err, dal.ErrNotSupported)
+}
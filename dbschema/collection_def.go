@@ -32,4 +32,8 @@ type CollectionDef struct {
 	// Indexes lists the secondary indexes declared inline with this
 	// collection definition.
 	Indexes []IndexDef
+	// Constraints lists the constraints declared inline with this
+	// collection definition, as ListConstraints reports them. The ddl
+	// package neither creates nor diffs them.
+	Constraints []ConstraintDef
 }
@@ -0,0 +1,145 @@
+package dbschema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// IncompatibleChangeError is the typed error returned by [CheckChange] when
+// values stored under one field definition may not be valid under another.
+type IncompatibleChangeError struct {
+	// Field is the name of the changed field.
+	Field dal.FieldName
+	// From and To render the types of the definitions (see
+	// FieldDef.TypeString).
+	From, To string
+	// Reasons lists why the change is not a safe widening, e.g.
+	// "length shrinks from 10 to 5".
+	Reasons []string
+}
+
+// Error returns a readable single-line message.
+func (e *IncompatibleChangeError) Error() string {
+	return fmt.Sprintf("dbschema: field %s: changing %s to %s is not a safe widening: %s",
+		e.Field, e.From, e.To, strings.Join(e.Reasons, "; "))
+}
+
+// CheckChange reports whether changing a field definition from `from` to
+// `to` is safe: every value valid under from is valid under to, so existing
+// records need no conversion. It returns nil for an identical definition or
+// a safe widening, and an *IncompatibleChangeError otherwise.
+//
+// The safe type changes are:
+//
+//   - Int to Float or Decimal;
+//   - UUID or Enum to String;
+//   - any type to JSON;
+//   - Object to Map, when every field of the object fits the map values;
+//   - Enum to an Enum with a superset of its values;
+//   - Array and Map to ones with widened (or untyped) elements;
+//   - Object to an Object whose fields widen the existing ones and whose
+//     added fields are nullable or have a default;
+//   - Reference to a Reference to the same collection.
+//
+// Besides the type, a change narrows when a nullable field becomes not
+// null, a length is set or shrinks, or a precision loses integer digits or
+// scale. Defaults and auto-increment do not affect stored values and are
+// not checked.
+func CheckChange(from, to FieldDef) error {
+	reasons := changeReasons(from, to)
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &IncompatibleChangeError{Field: to.Name, From: from.TypeString(), To: to.TypeString(), Reasons: reasons}
+}
+
+func changeReasons(from, to FieldDef) (reasons []string) {
+	if from.Nullable && !to.Nullable {
+		reasons = append(reasons, "becomes not null")
+	}
+	if to.Type == JSON {
+		return reasons
+	}
+	if from.Type != to.Type {
+		switch {
+		case from.Type == Int && (to.Type == Float || to.Type == Decimal):
+		case (from.Type == UUID || from.Type == Enum) && to.Type == String:
+		case from.Type == Object && to.Type == Map:
+			if to.Elem != nil {
+				for _, f := range from.Fields {
+					elem := *to.Elem
+					elem.Nullable = elem.Nullable || f.Nullable // a missing key is fine
+					reasons = append(reasons, prefixed("field "+string(f.Name), changeReasons(f, elem))...)
+				}
+			}
+			return reasons
+		default:
+			return append(reasons, fmt.Sprintf("type changes from %s to %s", from.TypeString(), to.TypeString()))
+		}
+	}
+	switch {
+	case to.Length == nil:
+	case from.Length == nil:
+		reasons = append(reasons, fmt.Sprintf("length becomes limited to %d", *to.Length))
+	case *to.Length < *from.Length:
+		reasons = append(reasons, fmt.Sprintf("length shrinks from %d to %d", *from.Length, *to.Length))
+	}
+	switch {
+	case to.Precision == nil:
+	case from.Precision == nil:
+		reasons = append(reasons, fmt.Sprintf("precision becomes limited to (%d,%d)", to.Precision.Total, to.Precision.Scale))
+	case to.Precision.Total-to.Precision.Scale < from.Precision.Total-from.Precision.Scale:
+		reasons = append(reasons, fmt.Sprintf("precision loses integer digits from (%d,%d) to (%d,%d)",
+			from.Precision.Total, from.Precision.Scale, to.Precision.Total, to.Precision.Scale))
+	case to.Precision.Scale < from.Precision.Scale:
+		reasons = append(reasons, fmt.Sprintf("scale shrinks from %d to %d", from.Precision.Scale, to.Precision.Scale))
+	}
+	if from.Type != to.Type {
+		return reasons
+	}
+	switch to.Type {
+	case Array, Map:
+		switch {
+		case to.Elem == nil:
+		case from.Elem == nil:
+			reasons = append(reasons, "elements become typed as "+to.Elem.TypeString())
+		default:
+			reasons = append(reasons, prefixed("elements", changeReasons(*from.Elem, *to.Elem))...)
+		}
+	case Object:
+		for _, f := range from.Fields {
+			i := slices.IndexFunc(to.Fields, func(t FieldDef) bool { return t.Name == f.Name })
+			if i < 0 {
+				reasons = append(reasons, "drops field "+string(f.Name))
+				continue
+			}
+			reasons = append(reasons, prefixed("field "+string(f.Name), changeReasons(f, to.Fields[i]))...)
+		}
+		for _, t := range to.Fields {
+			if !t.Nullable && t.Default == nil && !slices.ContainsFunc(from.Fields, func(f FieldDef) bool { return f.Name == t.Name }) {
+				reasons = append(reasons, "adds required field "+string(t.Name))
+			}
+		}
+	case Reference:
+		if from.RefCollection != to.RefCollection {
+			reasons = append(reasons, fmt.Sprintf("refers to %s instead of %s", to.RefCollection, from.RefCollection))
+		}
+	case Enum:
+		for _, v := range from.Values {
+			if !slices.Contains(to.Values, v) {
+				reasons = append(reasons, fmt.Sprintf("drops enum value '%s'", v))
+			}
+		}
+	}
+	return reasons
+}
+
+func prefixed(prefix string, reasons []string) []string {
+	for i, r := range reasons {
+		reasons[i] = prefix + ": " + r
+	}
+	return reasons
+}
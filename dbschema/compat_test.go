@@ -0,0 +1,77 @@
+package dbschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldDef_TypeString(t *testing.T) {
+	for want, f := range map[string]FieldDef{
+		"string(255)":                       {Type: String, Length: intPtr(255)},
+		"decimal(12,2)":                     {Type: Decimal, Precision: &Precision{Total: 12, Scale: 2}},
+		"array":                             {Type: Array},
+		"array<map<int>>":                   {Type: Array, Elem: &FieldDef{Type: Map, Elem: &FieldDef{Type: Int}}},
+		"object{city string, geo geopoint}": {Type: Object, Fields: []FieldDef{{Name: "city", Type: String}, {Name: "geo", Type: GeoPoint}}},
+		"reference(users)":                  {Type: Reference, RefCollection: "users"},
+		"enum('draft','it''s')":             {Type: Enum, Values: []string{"draft", "it's"}},
+		"uuid":                              {Type: UUID},
+	} {
+		assert.Equal(t, want, f.TypeString())
+	}
+}
+
+func TestCheckChange(t *testing.T) {
+	address := FieldDef{Type: Object, Fields: []FieldDef{{Name: "city", Type: String}, {Name: "zip", Type: String, Nullable: true}}}
+	safe := []struct{ from, to FieldDef }{
+		{FieldDef{Type: Int}, FieldDef{Type: Int}},
+		{FieldDef{Type: Int}, FieldDef{Type: Float, Nullable: true}},
+		{FieldDef{Type: Int}, FieldDef{Type: Decimal}},
+		{FieldDef{Type: UUID}, FieldDef{Type: String}},
+		{FieldDef{Type: Enum, Values: []string{"a"}}, FieldDef{Type: String}},
+		{FieldDef{Type: GeoPoint}, FieldDef{Type: JSON}},
+		{FieldDef{Type: String, Length: intPtr(10)}, FieldDef{Type: String, Length: intPtr(20)}},
+		{FieldDef{Type: String, Length: intPtr(10)}, FieldDef{Type: String}},
+		{FieldDef{Type: Decimal, Precision: &Precision{Total: 10, Scale: 2}}, FieldDef{Type: Decimal, Precision: &Precision{Total: 12, Scale: 3}}},
+		{FieldDef{Type: Enum, Values: []string{"a"}}, FieldDef{Type: Enum, Values: []string{"a", "b"}}},
+		{FieldDef{Type: Array, Elem: &FieldDef{Type: Int}}, FieldDef{Type: Array, Elem: &FieldDef{Type: Float}}},
+		{FieldDef{Type: Array, Elem: &FieldDef{Type: Int}}, FieldDef{Type: Array}},
+		{address, FieldDef{Type: Object, Fields: append(address.Fields, FieldDef{Name: "country", Type: String, Default: DefaultLiteral{Value: "NL"}})}},
+		{address, FieldDef{Type: Map, Elem: &FieldDef{Type: String}}},
+		{FieldDef{Type: Reference, RefCollection: "users"}, FieldDef{Type: Reference, RefCollection: "users"}},
+	}
+	for _, c := range safe {
+		assert.NoError(t, CheckChange(c.from, c.to), "%s -> %s", c.from.TypeString(), c.to.TypeString())
+	}
+
+	unsafe := []struct {
+		from, to FieldDef
+		reason   string
+	}{
+		{FieldDef{Type: Float}, FieldDef{Type: Int}, "type changes from float to int"},
+		{FieldDef{Type: String}, FieldDef{Type: UUID}, "type changes from string to uuid"},
+		{FieldDef{Type: Int, Nullable: true}, FieldDef{Type: Int}, "becomes not null"},
+		{FieldDef{Type: String}, FieldDef{Type: String, Length: intPtr(5)}, "length becomes limited to 5"},
+		{FieldDef{Type: String, Length: intPtr(10)}, FieldDef{Type: String, Length: intPtr(5)}, "length shrinks from 10 to 5"},
+		{FieldDef{Type: Decimal, Precision: &Precision{Total: 10, Scale: 2}}, FieldDef{Type: Decimal, Precision: &Precision{Total: 10, Scale: 4}}, "precision loses integer digits"},
+		{FieldDef{Type: Decimal, Precision: &Precision{Total: 10, Scale: 2}}, FieldDef{Type: Decimal, Precision: &Precision{Total: 10, Scale: 1}}, "scale shrinks from 2 to 1"},
+		{FieldDef{Type: Enum, Values: []string{"a", "b"}}, FieldDef{Type: Enum, Values: []string{"a"}}, "drops enum value 'b'"},
+		{FieldDef{Type: Array}, FieldDef{Type: Array, Elem: &FieldDef{Type: Int}}, "elements become typed as int"},
+		{FieldDef{Type: Map, Elem: &FieldDef{Type: Float}}, FieldDef{Type: Map, Elem: &FieldDef{Type: Int}}, "elements: type changes from float to int"},
+		{address, FieldDef{Type: Object, Fields: address.Fields[:1]}, "drops field zip"},
+		{address, FieldDef{Type: Object, Fields: append(address.Fields, FieldDef{Name: "country", Type: String})}, "adds required field country"},
+		{address, FieldDef{Type: Map, Elem: &FieldDef{Type: Int}}, "field city: type changes from string to int"},
+		{FieldDef{Type: Reference, RefCollection: "users"}, FieldDef{Type: Reference, RefCollection: "teams"}, "refers to teams instead of users"},
+	}
+	for _, c := range unsafe {
+		err := CheckChange(c.from, c.to)
+		var changeErr *IncompatibleChangeError
+		require.True(t, errors.As(err, &changeErr), "%s -> %s", c.from.TypeString(), c.to.TypeString())
+		assert.Contains(t, changeErr.Error(), c.reason)
+	}
+
+	err := CheckChange(FieldDef{Name: "age", Type: Float, Nullable: true}, FieldDef{Name: "age", Type: Int})
+	assert.EqualError(t, err, "dbschema: field age: changing float to int is not a safe widening: becomes not null; type changes from float to int")
+}
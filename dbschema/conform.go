@@ -0,0 +1,390 @@
+package dbschema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dal-go/record"
+)
+
+// UUIDPattern matches a UUID in its canonical hyphenated form, in either
+// case.
+var UUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ViolationKind classifies a FieldViolation.
+type ViolationKind string
+
+const (
+	// ViolationType is a value that does not conform to the field type,
+	// including an Enum value outside its values or a malformed UUID.
+	ViolationType ViolationKind = "type"
+	// ViolationNull is a missing or null value of a not-null field.
+	ViolationNull ViolationKind = "null"
+	// ViolationLength is a string or bytes value longer than the field
+	// Length.
+	ViolationLength ViolationKind = "length"
+	// ViolationPrecision is a decimal with more integer digits or scale
+	// than the field Precision.
+	ViolationPrecision ViolationKind = "precision"
+	// ViolationUnknown is a field the definition does not declare,
+	// reported by a strict Checker only.
+	ViolationUnknown ViolationKind = "unknown"
+)
+
+// FieldViolation describes why the value of one field does not conform to
+// its definition.
+type FieldViolation struct {
+	// Field is the path of the field, with nested fields joined by dots
+	// and array elements indexed, e.g. "address.city" or "tags[2]".
+	Field string
+	Kind  ViolationKind
+	// Message explains the violation, e.g. "length 12 exceeds 10".
+	Message string
+}
+
+// String returns the field and its message.
+func (v FieldViolation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// NormalizeValue returns v as encoding/json decodes its encoding, with
+// numbers as json.Number, so that struct and map data look alike to a
+// Checker.
+func NormalizeValue(v any) (any, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("value cannot be encoded: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded any
+	if err = decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("value cannot be decoded: %w", err)
+	}
+	return decoded, nil
+}
+
+// WrittenData returns the data of a record that is being written. The record
+// may not have been read, so its error is cleared first to make its data
+// readable.
+func WrittenData(r record.Record) any {
+	r.SetError(nil)
+	return r.Data()
+}
+
+// Checker checks decoded JSON values against field definitions and collects
+// their violations. Values are those NormalizeValue returns, or decoded with
+// numbers as float64. Bytes are base64 strings and Times RFC 3339 strings,
+// as encoding/json writes them; UUIDs, Enums and References are strings;
+// Decimals are numbers or numeric strings in plain decimal notation, whose
+// leading and trailing fraction zeros do not count towards Precision;
+// GeoPoints are objects with numeric latitude and longitude. Every value
+// conforms to Null and JSON.
+type Checker struct {
+	// Strict reports the fields of objects their definition does not
+	// declare.
+	Strict bool
+	// Violations are the violations found, in the order they were found.
+	Violations []FieldViolation
+}
+
+// Violate records a violation of the field at path.
+func (c *Checker) Violate(path string, kind ViolationKind, message string) {
+	c.Violations = append(c.Violations, FieldViolation{Field: path, Kind: kind, Message: message})
+}
+
+// Record checks the data of a record of collection def: an object whose
+// primary key and auto-increment fields may be missing, as adapters keep the
+// former in the record key and generate the latter.
+func (c *Checker) Record(def CollectionDef, data any) {
+	fields, ok := data.(map[string]any)
+	if !ok {
+		c.Violate("", ViolationType, fmt.Sprintf("record data is %s, not an object", kindOfValue(data)))
+		return
+	}
+	c.Fields("", def.Fields, fields, func(f FieldDef) bool {
+		return f.AutoIncrement || slices.Contains(def.PrimaryKey, f.Name)
+	})
+}
+
+// Fields checks the values of an object, whose fields are under prefix,
+// against the definitions of its fields. A field for which mayBeMissing
+// reports true may be absent.
+func (c *Checker) Fields(prefix string, fields []FieldDef, data map[string]any, mayBeMissing func(FieldDef) bool) {
+	for _, f := range fields {
+		v, ok := data[string(f.Name)]
+		if !ok && mayBeMissing != nil && mayBeMissing(f) {
+			continue
+		}
+		c.Value(joinPath(prefix, string(f.Name)), f, v)
+	}
+	if !c.Strict {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(data)) {
+		if !slices.ContainsFunc(fields, func(f FieldDef) bool { return string(f.Name) == name }) {
+			c.Violate(joinPath(prefix, name), ViolationUnknown, "field is not declared")
+		}
+	}
+}
+
+// Path checks the value v set at a field path of an object with the given
+// fields, as an update does. A path through a Map or Array checks v against
+// the definition of their elements; a path through a JSON field or an
+// undeclared one is not checked, unless the Checker is strict.
+func (c *Checker) Path(fields []FieldDef, path []string, v any) {
+	var f FieldDef
+	var at string
+	for i, segment := range path {
+		at = joinPath(at, segment)
+		if i > 0 {
+			switch f.Type {
+			case Object:
+				fields = f.Fields
+			case Map, Array:
+				if f.Elem == nil {
+					return
+				}
+				f = *f.Elem
+				continue
+			case JSON, Null:
+				return
+			default:
+				c.Violate(at, ViolationType, fmt.Sprintf("%s field has no nested fields", f.TypeString()))
+				return
+			}
+		}
+		j := slices.IndexFunc(fields, func(f FieldDef) bool { return string(f.Name) == segment })
+		if j < 0 {
+			if c.Strict {
+				c.Violate(at, ViolationUnknown, "field is not declared")
+			}
+			return
+		}
+		f = fields[j]
+	}
+	c.Value(at, f, v)
+}
+
+// Value checks the value v of field f at path.
+func (c *Checker) Value(path string, f FieldDef, v any) {
+	if v == nil {
+		if !f.Nullable {
+			c.Violate(path, ViolationNull, "value is required")
+		}
+		return
+	}
+	mismatch := func() {
+		c.Violate(path, ViolationType, fmt.Sprintf("expected %s, got %s", f.TypeString(), kindOfValue(v)))
+	}
+	switch f.Type {
+	case Bool:
+		if _, ok := v.(bool); !ok {
+			mismatch()
+		}
+	case Int:
+		if s, ok := numberText(v); !ok {
+			mismatch()
+		} else if !isInteger(v) {
+			c.Violate(path, ViolationType, fmt.Sprintf("expected int, got %s", s))
+		}
+	case Float:
+		if _, ok := numberText(v); !ok {
+			mismatch()
+		}
+	case Decimal:
+		s, ok := decimalText(v)
+		if !ok {
+			mismatch()
+			return
+		}
+		c.precision(path, f, s)
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			mismatch()
+			return
+		}
+		c.length(path, f, utf8.RuneCountInString(s))
+	case Bytes:
+		s, ok := v.(string)
+		if !ok {
+			mismatch()
+			return
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			c.Violate(path, ViolationType, "expected base64 encoded bytes")
+			return
+		}
+		c.length(path, f, len(b))
+	case Time:
+		if s, ok := v.(string); !ok {
+			mismatch()
+		} else if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			c.Violate(path, ViolationType, fmt.Sprintf("expected an RFC 3339 time, got %q", s))
+		}
+	case UUID:
+		if s, ok := v.(string); !ok {
+			mismatch()
+		} else if !UUIDPattern.MatchString(s) {
+			c.Violate(path, ViolationType, fmt.Sprintf("expected a UUID, got %q", s))
+		}
+	case Enum:
+		if s, ok := v.(string); !ok {
+			mismatch()
+		} else if !slices.Contains(f.Values, s) {
+			c.Violate(path, ViolationType, fmt.Sprintf("%q is not one of %s", s, f.TypeString()))
+		}
+	case Reference:
+		if _, ok := v.(string); !ok {
+			mismatch()
+		}
+	case GeoPoint:
+		m, ok := v.(map[string]any)
+		if !ok {
+			mismatch()
+			return
+		}
+		_, lat := numberText(m["latitude"])
+		_, lng := numberText(m["longitude"])
+		if !lat || !lng {
+			c.Violate(path, ViolationType, "expected numeric latitude and longitude")
+		}
+	case Array:
+		elems, ok := v.([]any)
+		if !ok {
+			mismatch()
+			return
+		}
+		if f.Elem != nil {
+			for i, e := range elems {
+				c.Value(fmt.Sprintf("%s[%d]", path, i), *f.Elem, e)
+			}
+		}
+	case Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			mismatch()
+			return
+		}
+		if f.Elem != nil {
+			for _, key := range slices.Sorted(maps.Keys(m)) {
+				c.Value(joinPath(path, key), *f.Elem, m[key])
+			}
+		}
+	case Object:
+		m, ok := v.(map[string]any)
+		if !ok {
+			mismatch()
+			return
+		}
+		c.Fields(path, f.Fields, m, nil)
+	}
+}
+
+func (c *Checker) length(path string, f FieldDef, n int) {
+	if f.Length != nil && n > *f.Length {
+		c.Violate(path, ViolationLength, fmt.Sprintf("length %d exceeds %d", n, *f.Length))
+	}
+}
+
+func (c *Checker) precision(path string, f FieldDef, s string) {
+	if f.Precision == nil {
+		return
+	}
+	digits, _ := strings.CutPrefix(s, "-")
+	integer, fraction, _ := strings.Cut(digits, ".")
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > f.Precision.Scale {
+		c.Violate(path, ViolationPrecision, fmt.Sprintf("%s has %d decimal places, more than %d", s, len(fraction), f.Precision.Scale))
+	}
+	if maxInteger := f.Precision.Total - f.Precision.Scale; len(integer) > maxInteger {
+		c.Violate(path, ViolationPrecision, fmt.Sprintf("%s has %d integer digits, more than %d", s, len(integer), maxInteger))
+	}
+}
+
+// numberText returns the text of a number, a json.Number or a float64.
+func numberText(v any) (string, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// isInteger reports whether a number is an integer that fits an int64.
+func isInteger(v any) bool {
+	switch v := v.(type) {
+	case json.Number:
+		_, err := v.Int64()
+		return err == nil
+	case float64:
+		return v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64
+	default:
+		return false
+	}
+}
+
+// decimalText returns a number, or a numeric string already in plain decimal
+// notation, in plain decimal notation.
+func decimalText(v any) (string, bool) {
+	s, ok := numberText(v)
+	if !ok {
+		if s, ok = v.(string); !ok {
+			return "", false
+		}
+		return s, plainDecimalPattern.MatchString(s)
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return s, true
+}
+
+var plainDecimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// kindOfValue names the JSON kind of a decoded value.
+func kindOfValue(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
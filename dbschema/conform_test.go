@@ -0,0 +1,82 @@
+package dbschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Types(t *testing.T) {
+	for _, tt := range []struct {
+		f    FieldDef
+		ok   []any
+		fail []any
+	}{
+		{FieldDef{Type: Bool}, []any{true}, []any{"true"}},
+		{FieldDef{Type: Float}, []any{1, 1.5}, []any{"1"}},
+		{FieldDef{Type: Decimal}, []any{"12.50", 3, 1e21}, []any{"NaN", "1,5", true}},
+		{FieldDef{Type: Bytes, Length: intPtr(2)}, []any{[]byte("ab")}, []any{[]byte("abc"), "not base64!", 1}},
+		{FieldDef{Type: UUID}, []any{"123e4567-e89b-12d3-a456-426614174000"}, []any{"123", 1}},
+		{FieldDef{Type: Reference, RefCollection: "users"}, []any{"ann"}, []any{1}},
+		{FieldDef{Type: GeoPoint}, []any{map[string]float64{"latitude": 1, "longitude": 2}}, []any{map[string]any{"latitude": 1}, "x"}},
+		{FieldDef{Type: Map, Elem: &FieldDef{Type: Int}}, []any{map[string]int{"a": 1}}, []any{map[string]string{"a": "b"}, []int{}}},
+		{FieldDef{Type: JSON}, []any{[]any{1, "a"}, "x"}, nil},
+		{FieldDef{Type: Time}, []any{time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC)}, []any{1}},
+	} {
+		check := func(v any) []FieldViolation {
+			n, err := NormalizeValue(v)
+			require.NoError(t, err)
+			var c Checker
+			c.Value("f", tt.f, n)
+			return c.Violations
+		}
+		for _, v := range tt.ok {
+			assert.Empty(t, check(v), "%s accepts %v", tt.f.TypeString(), v)
+		}
+		for _, v := range tt.fail {
+			assert.NotEmpty(t, check(v), "%s rejects %v", tt.f.TypeString(), v)
+		}
+	}
+}
+
+func TestChecker_DecodedFloats(t *testing.T) {
+	var decoded any
+	require.NoError(t, json.Unmarshal([]byte(`{"n": 2, "x": 2.5, "at": {"latitude": 1, "longitude": 2.5}}`), &decoded))
+	def := CollectionDef{Fields: []FieldDef{
+		{Name: "n", Type: Int},
+		{Name: "x", Type: Decimal, Precision: &Precision{Total: 3, Scale: 1}},
+		{Name: "at", Type: GeoPoint},
+	}}
+	var c Checker
+	c.Record(def, decoded)
+	assert.Empty(t, c.Violations, "numbers decoded as float64 are checked like json.Number")
+
+	def.Fields[0].Name, def.Fields[1].Precision = "x", &Precision{Total: 2}
+	c.Record(def, decoded)
+	assert.Equal(t, []FieldViolation{
+		{Field: "x", Kind: ViolationType, Message: "expected int, got 2.5"},
+		{Field: "x", Kind: ViolationPrecision, Message: "2.5 has 1 decimal places, more than 0"},
+	}, c.Violations)
+}
+
+func TestChecker_Record(t *testing.T) {
+	def := CollectionDef{PrimaryKey: []dal.FieldName{"id"}, Fields: []FieldDef{{Name: "id", Type: Int}, {Name: "name", Type: String}}}
+	c := Checker{Strict: true}
+	c.Record(def, map[string]any{"extra": true})
+	assert.Equal(t, []FieldViolation{
+		{Field: "name", Kind: ViolationNull, Message: "value is required"},
+		{Field: "extra", Kind: ViolationUnknown, Message: "field is not declared"},
+	}, c.Violations, "the primary key may be missing")
+
+	c = Checker{}
+	c.Record(def, []any{})
+	assert.Equal(t, []FieldViolation{{Kind: ViolationType, Message: "record data is array, not an object"}}, c.Violations)
+
+	c = Checker{}
+	c.Path(def.Fields, []string{"name", "first"}, "Ann")
+	assert.Equal(t, []FieldViolation{{Field: "name.first", Kind: ViolationType, Message: "string field has no nested fields"}}, c.Violations)
+}
@@ -12,6 +12,17 @@
 // and the shared NotSupportedError typed error used by both the read
 // and write sides.
 //
+// FromStruct and FromType derive a CollectionDef from a Go record type,
+// honoring json tags and the overrides of the "dbschema" struct tag.
+//
+// A Schema — named collection definitions — is serialized as a versioned
+// dalgo.io/schema/v1 Document through the YAMLCodec or JSONCodec (see
+// DecodeSchema and EncodeSchema), and ToJSONSchema exports the JSON Schema
+// of a collection's records.
+//
+// ProfileCollection infers a CollectionDef, with per-field statistics, from
+// a sample of the records of a schemaless collection.
+//
 // dbschema does NOT contain operations. CREATE / DROP / ALTER live in
 // the sibling [ddl] sub-package, which imports dbschema for the types
 // it operates on.
@@ -0,0 +1,565 @@
+package dbschema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DocumentAPIVersion is the first stable portable schema document
+	// version.
+	DocumentAPIVersion = "dalgo.io/schema/v1"
+	SchemaKind         = "Schema"
+)
+
+// Schema is a named set of collection definitions, the content of a schema
+// document.
+type Schema struct {
+	Name        string
+	Collections []CollectionDef
+}
+
+// Document is the storage-neutral representation of a Schema shared by
+// YAML, JSON, and third-party codecs. YAML is the canonical human-authored
+// encoding.
+type Document struct {
+	APIVersion  string               `json:"apiVersion" yaml:"apiVersion"`
+	Kind        string               `json:"kind" yaml:"kind"`
+	Metadata    DocumentMetadata     `json:"metadata" yaml:"metadata"`
+	Collections []DocumentCollection `json:"collections" yaml:"collections"`
+}
+
+type DocumentMetadata struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+type DocumentCollection struct {
+	Name        string               `json:"name" yaml:"name"`
+	PrimaryKey  []string             `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	Fields      []DocumentField      `json:"fields" yaml:"fields"`
+	Indexes     []DocumentIndex      `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+	Constraints []DocumentConstraint `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+}
+
+// DocumentField describes a field. Type is the name Type.String returns;
+// the parameters that do not apply to it must be left out.
+type DocumentField struct {
+	Name          string             `json:"name,omitempty" yaml:"name,omitempty"`
+	Type          string             `json:"type" yaml:"type"`
+	Length        *int               `json:"length,omitempty" yaml:"length,omitempty"`
+	Precision     *DocumentPrecision `json:"precision,omitempty" yaml:"precision,omitempty"`
+	Nullable      bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Default       *DocumentDefault   `json:"default,omitempty" yaml:"default,omitempty"`
+	AutoIncrement bool               `json:"autoIncrement,omitempty" yaml:"autoIncrement,omitempty"`
+	Elem          *DocumentField     `json:"elem,omitempty" yaml:"elem,omitempty"`
+	Fields        []DocumentField    `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Ref           string             `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Values        []string           `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+type DocumentPrecision struct {
+	Total int `json:"total" yaml:"total"`
+	Scale int `json:"scale" yaml:"scale"`
+}
+
+// DocumentDefault is either the current timestamp or a literal value, null
+// when omitted. Time literals are RFC 3339 strings and bytes literals
+// base64 strings.
+type DocumentDefault struct {
+	CurrentTimestamp bool `json:"currentTimestamp,omitempty" yaml:"currentTimestamp,omitempty"`
+	Value            any  `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type DocumentIndex struct {
+	Name   string   `json:"name" yaml:"name"`
+	Fields []string `json:"fields" yaml:"fields"`
+	Unique bool     `json:"unique,omitempty" yaml:"unique,omitempty"`
+}
+
+type DocumentConstraint struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// Codec decouples schema loading from both its syntax and its storage. A
+// caller may implement this interface for HCL or another representation.
+type Codec interface {
+	Decode(io.Reader, *Document) error
+	Encode(io.Writer, Document) error
+}
+
+type YAMLCodec struct{}
+
+func (YAMLCodec) Decode(reader io.Reader, document *Document) error {
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(document); err != nil {
+		return fmt.Errorf("dbschema: decode YAML schema: %w", err)
+	}
+	return ensureSingleDocument(func(value any) error { return decoder.Decode(value) })
+}
+
+func (YAMLCodec) Encode(writer io.Writer, document Document) (err error) {
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+	defer func() {
+		err = errors.Join(err, encoder.Close())
+	}()
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("dbschema: encode YAML schema: %w", err)
+	}
+	return nil
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Decode(reader io.Reader, document *Document) error {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber() // keeps int64 default literals exact
+	if err := decoder.Decode(document); err != nil {
+		return fmt.Errorf("dbschema: decode JSON schema: %w", err)
+	}
+	return ensureSingleDocument(func(value any) error { return decoder.Decode(value) })
+}
+
+func (JSONCodec) Encode(writer io.Writer, document Document) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("dbschema: encode JSON schema: %w", err)
+	}
+	return nil
+}
+
+func ensureSingleDocument(next func(any) error) error {
+	var extra any
+	err := next(&extra)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("dbschema: a schema stream must contain exactly one document")
+}
+
+// DecodeSchema decodes and validates one Schema from any reader. Besides the
+// shape the codec enforces, it checks that names are present and unique,
+// that types are known and carry only their own parameters, and that
+// primary keys and indexes name top-level fields.
+func DecodeSchema(reader io.Reader, codec Codec) (*Schema, error) {
+	if reader == nil || codec == nil {
+		return nil, fmt.Errorf("dbschema: schema reader and codec are required")
+	}
+	var document Document
+	if err := codec.Decode(reader, &document); err != nil {
+		return nil, err
+	}
+	if document.APIVersion != DocumentAPIVersion {
+		return nil, fmt.Errorf("dbschema: unsupported apiVersion %q", document.APIVersion)
+	}
+	if document.Kind != SchemaKind {
+		return nil, fmt.Errorf("dbschema: expected kind %s, got %q", SchemaKind, document.Kind)
+	}
+	if strings.TrimSpace(document.Metadata.Name) == "" {
+		return nil, fmt.Errorf("dbschema: metadata.name is required")
+	}
+	schema := &Schema{Name: document.Metadata.Name}
+	for i, c := range document.Collections {
+		def, err := collectionFromDocument(c)
+		if err != nil {
+			return nil, fmt.Errorf("dbschema: collections[%d]: %w", i, err)
+		}
+		if slices.ContainsFunc(schema.Collections, func(other CollectionDef) bool { return other.Name == def.Name }) {
+			return nil, fmt.Errorf("dbschema: collections[%d]: collection %q is defined twice", i, def.Name)
+		}
+		schema.Collections = append(schema.Collections, def)
+	}
+	return schema, nil
+}
+
+// EncodeSchema validates and writes one Schema through codec. Decoding the
+// output returns an equal Schema, except that Int, Float and JSON-like
+// default literals come back as int64, float64 and encoding/json values,
+// and that indexes come back with their Collection set.
+func EncodeSchema(writer io.Writer, codec Codec, schema *Schema) error {
+	if schema == nil {
+		return fmt.Errorf("dbschema: schema is required")
+	}
+	if writer == nil || codec == nil {
+		return fmt.Errorf("dbschema: schema writer and codec are required")
+	}
+	document := Document{
+		APIVersion: DocumentAPIVersion,
+		Kind:       SchemaKind,
+		Metadata:   DocumentMetadata{Name: schema.Name},
+	}
+	for i, c := range schema.Collections {
+		collection, err := documentCollection(c)
+		if err == nil {
+			_, err = collectionFromDocument(collection)
+		}
+		if err != nil {
+			return fmt.Errorf("dbschema: collections[%d]: %w", i, err)
+		}
+		document.Collections = append(document.Collections, collection)
+	}
+	return codec.Encode(writer, document)
+}
+
+func MarshalSchemaYAML(schema *Schema) ([]byte, error) {
+	return marshalSchema(schema, YAMLCodec{})
+}
+
+func MarshalSchemaJSON(schema *Schema) ([]byte, error) {
+	return marshalSchema(schema, JSONCodec{})
+}
+
+func marshalSchema(schema *Schema, codec Codec) ([]byte, error) {
+	var data bytes.Buffer
+	if err := EncodeSchema(&data, codec, schema); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func UnmarshalSchemaYAML(data []byte) (*Schema, error) {
+	return DecodeSchema(bytes.NewReader(data), YAMLCodec{})
+}
+
+func UnmarshalSchemaJSON(data []byte) (*Schema, error) {
+	return DecodeSchema(bytes.NewReader(data), JSONCodec{})
+}
+
+func collectionFromDocument(c DocumentCollection) (CollectionDef, error) {
+	if strings.TrimSpace(c.Name) == "" {
+		return CollectionDef{}, fmt.Errorf("collection name is required")
+	}
+	def := CollectionDef{Name: c.Name}
+	var err error
+	if def.Fields, err = fieldsFromDocument(c.Fields); err != nil {
+		return CollectionDef{}, fmt.Errorf("collection %q: %w", c.Name, err)
+	}
+	if def.PrimaryKey, err = fieldNames(def.Fields, c.PrimaryKey); err != nil {
+		return CollectionDef{}, fmt.Errorf("collection %q: primaryKey: %w", c.Name, err)
+	}
+	for i, index := range c.Indexes {
+		if strings.TrimSpace(index.Name) == "" {
+			return CollectionDef{}, fmt.Errorf("collection %q: indexes[%d]: index name is required", c.Name, i)
+		}
+		if len(index.Fields) == 0 {
+			return CollectionDef{}, fmt.Errorf("collection %q: index %q has no fields", c.Name, index.Name)
+		}
+		fields, err := fieldNames(def.Fields, index.Fields)
+		if err != nil {
+			return CollectionDef{}, fmt.Errorf("collection %q: index %q: %w", c.Name, index.Name, err)
+		}
+		def.Indexes = append(def.Indexes, IndexDef{Name: index.Name, Collection: c.Name, Fields: fields, Unique: index.Unique})
+	}
+	for i, constraint := range c.Constraints {
+		if strings.TrimSpace(constraint.Name) == "" || strings.TrimSpace(constraint.Type) == "" {
+			return CollectionDef{}, fmt.Errorf("collection %q: constraints[%d]: constraint name and type are required", c.Name, i)
+		}
+		def.Constraints = append(def.Constraints, ConstraintDef{Name: constraint.Name, Type: constraint.Type})
+	}
+	return def, nil
+}
+
+// fieldNames checks that names are those of fields.
+func fieldNames(fields []FieldDef, names []string) ([]dal.FieldName, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	result := make([]dal.FieldName, len(names))
+	for i, name := range names {
+		if !slices.ContainsFunc(fields, func(f FieldDef) bool { return string(f.Name) == name }) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		result[i] = dal.FieldName(name)
+	}
+	return result, nil
+}
+
+func fieldsFromDocument(fields []DocumentField) ([]FieldDef, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	defs := make([]FieldDef, 0, len(fields))
+	for i, field := range fields {
+		if strings.TrimSpace(field.Name) == "" {
+			return nil, fmt.Errorf("fields[%d]: field name is required", i)
+		}
+		if slices.ContainsFunc(defs, func(f FieldDef) bool { return string(f.Name) == field.Name }) {
+			return nil, fmt.Errorf("duplicate field name %q", field.Name)
+		}
+		def, err := fieldFromDocument(field)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func fieldFromDocument(field DocumentField) (FieldDef, error) {
+	t, ok := parseType(field.Type)
+	if !ok {
+		return FieldDef{}, fmt.Errorf("unknown type %q", field.Type)
+	}
+	def := FieldDef{
+		Name:          dal.FieldName(field.Name),
+		Type:          t,
+		Length:        field.Length,
+		Nullable:      field.Nullable,
+		AutoIncrement: field.AutoIncrement,
+		RefCollection: field.Ref,
+		Values:        field.Values,
+	}
+	misplaced := func(parameter string) error {
+		return fmt.Errorf("%s does not apply to type %s", parameter, t)
+	}
+	if field.Length != nil && t != String && t != Bytes {
+		return FieldDef{}, misplaced("length")
+	}
+	if field.Precision != nil {
+		if t != Decimal {
+			return FieldDef{}, misplaced("precision")
+		}
+		def.Precision = &Precision{Total: field.Precision.Total, Scale: field.Precision.Scale}
+	}
+	if field.Elem != nil {
+		if t != Array && t != Map {
+			return FieldDef{}, misplaced("elem")
+		}
+		elem, err := fieldFromDocument(*field.Elem)
+		if err != nil {
+			return FieldDef{}, fmt.Errorf("elem: %w", err)
+		}
+		def.Elem = &elem
+	}
+	if len(field.Fields) > 0 && t != Object {
+		return FieldDef{}, misplaced("fields")
+	}
+	if t == Object {
+		var err error
+		if def.Fields, err = fieldsFromDocument(field.Fields); err != nil {
+			return FieldDef{}, err
+		}
+	}
+	if (field.Ref != "") != (t == Reference) {
+		if t == Reference {
+			return FieldDef{}, fmt.Errorf("reference requires ref")
+		}
+		return FieldDef{}, misplaced("ref")
+	}
+	if (len(field.Values) > 0) != (t == Enum) {
+		if t == Enum {
+			return FieldDef{}, fmt.Errorf("enum requires values")
+		}
+		return FieldDef{}, misplaced("values")
+	}
+	if field.Default != nil {
+		if field.Default.CurrentTimestamp {
+			if field.Default.Value != nil {
+				return FieldDef{}, fmt.Errorf("default has both a value and currentTimestamp")
+			}
+			def.Default = DefaultCurrentTimestamp{}
+		} else {
+			v, err := literalFromDocument(t, field.Default.Value)
+			if err != nil {
+				return FieldDef{}, fmt.Errorf("default: %w", err)
+			}
+			def.Default = DefaultLiteral{Value: v}
+		}
+	}
+	return def, nil
+}
+
+// literalFromDocument converts a decoded default value to the Go type the
+// field type calls for, the same ones FromStruct uses.
+func literalFromDocument(t Type, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	mismatch := fmt.Errorf("%v is not a valid %s value", v, t)
+	switch t {
+	case Bool:
+		if _, ok := v.(bool); !ok {
+			return nil, mismatch
+		}
+		return v, nil
+	case Int:
+		switch n := v.(type) {
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case uint64:
+			if n <= math.MaxInt64 {
+				return int64(n), nil
+			}
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) <= 1<<53 {
+				return int64(n), nil
+			}
+		case json.Number:
+			if i, err := n.Int64(); err == nil {
+				return i, nil
+			}
+		}
+		return nil, mismatch
+	case Float:
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case uint64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		case json.Number:
+			if f, err := n.Float64(); err == nil {
+				return f, nil
+			}
+		}
+		return nil, mismatch
+	case Time:
+		switch tm := v.(type) {
+		case time.Time:
+			return tm, nil
+		case string:
+			if parsed, err := time.Parse(time.RFC3339Nano, tm); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, mismatch
+	case Bytes:
+		s, ok := v.(string)
+		if !ok {
+			return nil, mismatch
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, mismatch
+		}
+		return b, nil
+	case String, Decimal, UUID, Enum, Reference:
+		if _, ok := v.(string); !ok {
+			return nil, mismatch
+		}
+		return v, nil
+	default:
+		// composite and JSON values decode the same from every codec
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, mismatch
+		}
+		var decoded any
+		if err = json.Unmarshal(encoded, &decoded); err != nil {
+			return nil, mismatch
+		}
+		return decoded, nil
+	}
+}
+
+func documentCollection(c CollectionDef) (DocumentCollection, error) {
+	collection := DocumentCollection{Name: c.Name}
+	var err error
+	if collection.Fields, err = documentFields(c.Fields); err != nil {
+		return DocumentCollection{}, fmt.Errorf("collection %q: %w", c.Name, err)
+	}
+	for _, name := range c.PrimaryKey {
+		collection.PrimaryKey = append(collection.PrimaryKey, string(name))
+	}
+	for _, index := range c.Indexes {
+		documentIndex := DocumentIndex{Name: index.Name, Unique: index.Unique}
+		for _, name := range index.Fields {
+			documentIndex.Fields = append(documentIndex.Fields, string(name))
+		}
+		collection.Indexes = append(collection.Indexes, documentIndex)
+	}
+	for _, constraint := range c.Constraints {
+		collection.Constraints = append(collection.Constraints, DocumentConstraint{Name: constraint.Name, Type: constraint.Type})
+	}
+	return collection, nil
+}
+
+func documentFields(fields []FieldDef) ([]DocumentField, error) {
+	var result []DocumentField
+	for _, f := range fields {
+		field, err := documentField(f)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		result = append(result, field)
+	}
+	return result, nil
+}
+
+func documentField(f FieldDef) (DocumentField, error) {
+	field := DocumentField{
+		Name:          string(f.Name),
+		Type:          f.Type.String(),
+		Length:        f.Length,
+		Nullable:      f.Nullable,
+		AutoIncrement: f.AutoIncrement,
+	}
+	if f.Precision != nil {
+		field.Precision = &DocumentPrecision{Total: f.Precision.Total, Scale: f.Precision.Scale}
+	}
+	switch f.Type {
+	case Array, Map:
+		if f.Elem != nil {
+			elem, err := documentField(*f.Elem)
+			if err != nil {
+				return DocumentField{}, fmt.Errorf("elem: %w", err)
+			}
+			elem.Name = ""
+			field.Elem = &elem
+		}
+	case Object:
+		var err error
+		if field.Fields, err = documentFields(f.Fields); err != nil {
+			return DocumentField{}, err
+		}
+	case Reference:
+		field.Ref = f.RefCollection
+	case Enum:
+		field.Values = f.Values
+	}
+	switch d := f.Default.(type) {
+	case nil:
+	case DefaultCurrentTimestamp:
+		field.Default = &DocumentDefault{CurrentTimestamp: true}
+	case DefaultLiteral:
+		field.Default = &DocumentDefault{Value: documentLiteral(d.Value)}
+	default:
+		return DocumentField{}, fmt.Errorf("unsupported default %T", d)
+	}
+	return field, nil
+}
+
+// documentLiteral returns a default value as the document stores it.
+func documentLiteral(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return v
+	}
+}
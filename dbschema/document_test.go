@@ -0,0 +1,174 @@
+package dbschema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shopSchema() *Schema {
+	length := func(n int) *int { return &n }
+	return &Schema{
+		Name: "shop",
+		Collections: []CollectionDef{
+			{
+				Name: "users",
+				Fields: []FieldDef{
+					{Name: "id", Type: Int, AutoIncrement: true},
+					{Name: "email", Type: String, Length: length(255)},
+					{Name: "avatar", Type: Bytes, Nullable: true, Default: DefaultLiteral{Value: []byte("png")}},
+					{Name: "visits", Type: Int, Default: DefaultLiteral{Value: int64(1) << 60}},
+					{Name: "score", Type: Float, Default: DefaultLiteral{Value: 0.5}},
+					{Name: "since", Type: Time, Default: DefaultLiteral{Value: time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC)}},
+					{Name: "seen", Type: Time, Nullable: true, Default: DefaultCurrentTimestamp{}},
+					{Name: "nick", Type: String, Nullable: true, Default: DefaultLiteral{}},
+					{Name: "prefs", Type: JSON, Default: DefaultLiteral{Value: map[string]any{"theme": "dark", "size": 2.0}}},
+				},
+				PrimaryKey: []dal.FieldName{"id"},
+				Indexes:    []IndexDef{{Name: "users_email", Collection: "users", Fields: []dal.FieldName{"email"}, Unique: true}},
+				Constraints: []ConstraintDef{
+					{Name: "users_email_check", Type: "check"},
+				},
+			},
+			{
+				Name: "orders",
+				Fields: []FieldDef{
+					{Name: "id", Type: UUID},
+					{Name: "user", Type: Reference, RefCollection: "users"},
+					{Name: "status", Type: Enum, Values: []string{"new", "paid"}, Default: DefaultLiteral{Value: "new"}},
+					{Name: "total", Type: Decimal, Precision: &Precision{Total: 12, Scale: 2}},
+					{Name: "tags", Type: Array, Elem: &FieldDef{Type: String, Length: length(20)}},
+					{Name: "attrs", Type: Map, Nullable: true, Elem: &FieldDef{Type: Int}},
+					{Name: "address", Type: Object, Fields: []FieldDef{
+						{Name: "city", Type: String},
+						{Name: "at", Type: GeoPoint, Nullable: true},
+					}},
+					{Name: "raw", Type: Array},
+				},
+				PrimaryKey: []dal.FieldName{"id"},
+			},
+		},
+	}
+}
+
+func TestSchemaDocument_RoundTrips(t *testing.T) {
+	for name, codec := range map[string]Codec{"yaml": YAMLCodec{}, "json": JSONCodec{}} {
+		t.Run(name, func(t *testing.T) {
+			var data bytes.Buffer
+			require.NoError(t, EncodeSchema(&data, codec, shopSchema()))
+			got, err := DecodeSchema(bytes.NewReader(data.Bytes()), codec)
+			require.NoError(t, err, data.String())
+			assert.Equal(t, shopSchema(), got)
+		})
+	}
+}
+
+func TestSchemaDocument_YAML(t *testing.T) {
+	data, err := MarshalSchemaYAML(&Schema{Name: "blog", Collections: []CollectionDef{{
+		Name: "posts",
+		Fields: []FieldDef{
+			{Name: "id", Type: String},
+			{Name: "tags", Type: Array, Elem: &FieldDef{Type: String}},
+			{Name: "created", Type: Time, Default: DefaultCurrentTimestamp{}},
+		},
+		PrimaryKey: []dal.FieldName{"id"},
+	}}})
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: dalgo.io/schema/v1
+kind: Schema
+metadata:
+  name: blog
+collections:
+  - name: posts
+    primaryKey:
+      - id
+    fields:
+      - name: id
+        type: string
+      - name: tags
+        type: array
+        elem:
+          type: string
+      - name: created
+        type: time
+        default:
+          currentTimestamp: true
+`, string(data))
+
+	schema, err := UnmarshalSchemaYAML(data)
+	require.NoError(t, err)
+	data, err = MarshalSchemaJSON(schema)
+	require.NoError(t, err)
+	schema, err = UnmarshalSchemaJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, "posts", schema.Collections[0].Name)
+}
+
+func TestDecodeSchema_Validation(t *testing.T) {
+	const header = "apiVersion: dalgo.io/schema/v1\nkind: Schema\nmetadata:\n  name: shop\n"
+	collection := func(fields string) string {
+		return header + "collections:\n  - name: users\n    fields:\n" + fields
+	}
+	for _, tt := range []struct {
+		name, yaml, err string
+	}{
+		{"api version", "apiVersion: dalgo.io/schema/v2\nkind: Schema\nmetadata:\n  name: shop\n", `unsupported apiVersion "dalgo.io/schema/v2"`},
+		{"kind", "apiVersion: dalgo.io/schema/v1\nkind: AccessPolicy\nmetadata:\n  name: shop\n", "expected kind Schema"},
+		{"name", "apiVersion: dalgo.io/schema/v1\nkind: Schema\nmetadata:\n  name: ' '\n", "metadata.name is required"},
+		{"unknown key", header + "owner: me\n", "field owner not found"},
+		{"two documents", header + "---\n" + header, "exactly one document"},
+		{"collection name", header + "collections:\n  - fields: []\n", "collection name is required"},
+		{"duplicate collection", header + "collections:\n  - name: a\n    fields: []\n  - name: a\n    fields: []\n", `collection "a" is defined twice`},
+		{"field name", collection("      - type: int\n"), "fields[0]: field name is required"},
+		{"duplicate field", collection("      - {name: a, type: int}\n      - {name: a, type: int}\n"), `duplicate field name "a"`},
+		{"type", collection("      - {name: a, type: varchar}\n"), `field a: unknown type "varchar"`},
+		{"length", collection("      - {name: a, type: int, length: 3}\n"), "length does not apply to type int"},
+		{"precision", collection("      - {name: a, type: float, precision: {total: 3, scale: 1}}\n"), "precision does not apply"},
+		{"elem", collection("      - {name: a, type: object, elem: {type: int}}\n"), "elem does not apply"},
+		{"nested elem", collection("      - {name: a, type: array, elem: {type: array, elem: {type: nope}}}\n"), `field a: elem: elem: unknown type "nope"`},
+		{"fields", collection("      - {name: a, type: map, fields: [{name: b, type: int}]}\n"), "fields does not apply"},
+		{"nested field", collection("      - {name: a, type: object, fields: [{name: b, type: nope}]}\n"), `field a: field b: unknown type "nope"`},
+		{"ref", collection("      - {name: a, type: reference}\n"), "reference requires ref"},
+		{"misplaced ref", collection("      - {name: a, type: string, ref: users}\n"), "ref does not apply"},
+		{"values", collection("      - {name: a, type: enum}\n"), "enum requires values"},
+		{"misplaced values", collection("      - {name: a, type: string, values: [x]}\n"), "values does not apply"},
+		{"default kind", collection("      - {name: a, type: time, default: {currentTimestamp: true, value: x}}\n"), "both a value and currentTimestamp"},
+		{"default int", collection("      - {name: a, type: int, default: {value: 1.5}}\n"), "default: 1.5 is not a valid int value"},
+		{"default time", collection("      - {name: a, type: time, default: {value: yesterday}}\n"), "yesterday is not a valid time value"},
+		{"default bytes", collection("      - {name: a, type: bytes, default: {value: '%%'}}\n"), "is not a valid bytes value"},
+		{"default string", collection("      - {name: a, type: string, default: {value: 1}}\n"), "1 is not a valid string value"},
+		{"primary key", collection("      - {name: a, type: int}\n    primaryKey: [id]\n"), `primaryKey: unknown field "id"`},
+		{"index name", collection("      - {name: a, type: int}\n    indexes: [{fields: [a]}]\n"), "index name is required"},
+		{"index fields", collection("      - {name: a, type: int}\n    indexes: [{name: i, fields: []}]\n"), `index "i" has no fields`},
+		{"index field", collection("      - {name: a, type: int}\n    indexes: [{name: i, fields: [b]}]\n"), `index "i": unknown field "b"`},
+		{"constraint", collection("      - {name: a, type: int}\n    constraints: [{name: c}]\n"), "constraint name and type are required"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalSchemaYAML([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := UnmarshalSchemaJSON([]byte(`{"apiVersion": "dalgo.io/schema/v1", "kind": "Schema", "metadata": {"name": "x"}, "extra": 1}`))
+	assert.ErrorContains(t, err, `unknown field "extra"`)
+	_, err = DecodeSchema(nil, YAMLCodec{})
+	assert.Error(t, err)
+}
+
+func TestEncodeSchema_Errors(t *testing.T) {
+	assert.Error(t, EncodeSchema(&bytes.Buffer{}, YAMLCodec{}, nil))
+	assert.Error(t, EncodeSchema(nil, YAMLCodec{}, shopSchema()))
+	_, err := MarshalSchemaJSON(&Schema{Name: "x", Collections: []CollectionDef{{
+		Name:   "users",
+		Fields: []FieldDef{{Name: "age", Type: Int, Length: new(int)}},
+	}}})
+	assert.ErrorContains(t, err, "collections[0]: collection \"users\": field age: length does not apply to type int",
+		"encoding refuses what decoding would reject")
+	assert.True(t, strings.HasPrefix(err.Error(), "dbschema: "))
+}
@@ -1,6 +1,11 @@
 package dbschema
 
-import "github.com/dal-go/dalgo/dal"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dal-go/dalgo/dal"
+)
 
 // FieldDef is the portable description of one field (a.k.a. column)
 // of a [CollectionDef].
@@ -12,6 +17,12 @@ import "github.com/dal-go/dalgo/dal"
 // for query and runtime concerns; FieldDef exists to describe a
 // column's structure in a portable way.
 //
+// Elem, Fields, RefCollection and Values parameterize the composite
+// types: Array and Map carry their element definition in Elem, Object its
+// nested fields in Fields, Reference the referred collection in
+// RefCollection, and Enum its allowed values in Values. They are ignored
+// for other types.
+//
 // AutoIncrement is advisory: drivers MAY restrict it to integer types
 // in the primary key and return *NotSupportedError if a caller passes
 // AutoIncrement on a non-integer field or a field not in the primary
@@ -36,4 +47,52 @@ type FieldDef struct {
 	// AutoIncrement is true if the field should auto-generate values.
 	// Typically restricted by drivers to integer primary-key fields.
 	AutoIncrement bool
+	// Elem describes the elements of an Array or the values of a Map.
+	// Its Name is ignored. nil means "untyped elements."
+	Elem *FieldDef
+	// Fields lists the nested fields of an Object in declared order.
+	Fields []FieldDef
+	// RefCollection is the name of the collection a Reference refers to.
+	RefCollection string
+	// Values lists the allowed values of an Enum.
+	Values []string
+}
+
+// TypeString renders the type of the field with its parameters, e.g.
+// "string(255)", "decimal(12,2)", "array<string>", "map<int>",
+// "object{city string, zip string}", "reference(users)" or
+// "enum('draft','published')". Nullability and defaults are not included.
+func (f FieldDef) TypeString() string {
+	var b strings.Builder
+	b.WriteString(f.Type.String())
+	switch f.Type {
+	case Array, Map:
+		if f.Elem != nil {
+			b.WriteString("<" + f.Elem.TypeString() + ">")
+		}
+	case Object:
+		b.WriteString("{")
+		for i, field := range f.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s %s", field.Name, field.TypeString())
+		}
+		b.WriteString("}")
+	case Reference:
+		fmt.Fprintf(&b, "(%s)", f.RefCollection)
+	case Enum:
+		values := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			values[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+		b.WriteString("(" + strings.Join(values, ",") + ")")
+	}
+	switch {
+	case f.Precision != nil:
+		fmt.Fprintf(&b, "(%d,%d)", f.Precision.Total, f.Precision.Scale)
+	case f.Length != nil:
+		fmt.Fprintf(&b, "(%d)", *f.Length)
+	}
+	return b.String()
 }
@@ -0,0 +1,195 @@
+package dbschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema version ToJSONSchema generates.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema, limited to the keywords ToJSONSchema uses.
+// Marshal it with encoding/json.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is a type name or, for nullable fields, a list of them.
+	Type                 any                    `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MultipleOf           json.Number            `json:"multipleOf,omitempty"`
+	Minimum              json.Number            `json:"minimum,omitempty"`
+	Maximum              json.Number            `json:"maximum,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+}
+
+// ToJSONSchema returns a JSON Schema validating the JSON payloads of the
+// records of a collection, as they are written through the enforce
+// package:
+//
+//   - not-null fields are required unless they have a default, are part of
+//     the primary key or auto-increment, as those may be left out on
+//     insert; nullable fields also accept null;
+//   - strings and bytes (base64) honor Length, and enums their values;
+//   - decimals are numbers or numeric strings that honor Precision, the
+//     former through multipleOf, minimum and maximum and the latter
+//     through a pattern;
+//   - times are RFC 3339 strings, UUIDs canonical strings, references
+//     record ids and geopoints latitude/longitude objects;
+//   - arrays, maps and objects are described recursively, and JSON fields
+//     accept any value.
+//
+// Fields that are not declared are allowed.
+func ToJSONSchema(def CollectionDef) *JSONSchema {
+	s := objectSchema(def.Fields, func(f FieldDef) bool {
+		return f.Default != nil || f.AutoIncrement || slices.Contains(def.PrimaryKey, f.Name)
+	})
+	s.Schema = JSONSchemaDialect
+	s.Title = def.Name
+	return s
+}
+
+// ToJSONSchemas returns the JSON Schema of every collection of a schema by
+// collection name.
+func ToJSONSchemas(schema *Schema) map[string]*JSONSchema {
+	schemas := make(map[string]*JSONSchema, len(schema.Collections))
+	for _, c := range schema.Collections {
+		schemas[c.Name] = ToJSONSchema(c)
+	}
+	return schemas
+}
+
+func objectSchema(fields []FieldDef, mayBeMissing func(FieldDef) bool) *JSONSchema {
+	s := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema, len(fields))}
+	for _, f := range fields {
+		s.Properties[string(f.Name)] = fieldSchema(f)
+		if !f.Nullable && !mayBeMissing(f) {
+			s.Required = append(s.Required, string(f.Name))
+		}
+	}
+	return s
+}
+
+func fieldSchema(f FieldDef) *JSONSchema {
+	s := new(JSONSchema)
+	switch f.Type {
+	case Null:
+		s.Type = "null"
+	case Bool:
+		s.Type = "boolean"
+	case Int:
+		s.Type = "integer"
+	case Float:
+		s.Type = "number"
+	case Decimal:
+		s.Type = []string{"number", "string"}
+		s.Pattern = `^-?[0-9]+(\.[0-9]+)?$`
+		if f.Precision != nil {
+			s.Pattern = decimalPattern(*f.Precision)
+			s.MultipleOf, s.Minimum, s.Maximum = decimalBounds(*f.Precision)
+		}
+	case String:
+		s.Type = "string"
+		s.MaxLength = f.Length
+	case Bytes:
+		s.Type = "string"
+		s.ContentEncoding = "base64"
+		if f.Length != nil {
+			encoded := (*f.Length + 2) / 3 * 4
+			s.MaxLength = &encoded
+		}
+	case Time:
+		s.Type = "string"
+		s.Format = "date-time"
+	case UUID:
+		s.Type = "string"
+		s.Format = "uuid"
+	case Reference:
+		s.Type = "string"
+		s.Description = fmt.Sprintf("The id of a %s record.", f.RefCollection)
+	case Enum:
+		s.Type = "string"
+		for _, v := range f.Values {
+			s.Enum = append(s.Enum, v)
+		}
+	case Array:
+		s.Type = "array"
+		if f.Elem != nil {
+			s.Items = fieldSchema(*f.Elem)
+		}
+	case Map:
+		s.Type = "object"
+		if f.Elem != nil {
+			s.AdditionalProperties = fieldSchema(*f.Elem)
+		}
+	case Object:
+		s = objectSchema(f.Fields, func(FieldDef) bool { return false })
+	case GeoPoint:
+		s = &JSONSchema{
+			Type: "object",
+			Properties: map[string]*JSONSchema{
+				"latitude":  {Type: "number"},
+				"longitude": {Type: "number"},
+			},
+			Required: []string{"latitude", "longitude"},
+		}
+	}
+	if f.Nullable {
+		switch t := s.Type.(type) {
+		case string:
+			if t != "null" {
+				s.Type = []string{t, "null"}
+			}
+		case []string:
+			s.Type = append(t, "null")
+		}
+		if s.Enum != nil {
+			s.Enum = append(s.Enum, nil)
+		}
+	}
+	if d, ok := f.Default.(DefaultLiteral); ok {
+		s.Default = documentLiteral(d.Value)
+	}
+	return s
+}
+
+// decimalPattern matches the numeric strings that fit p. Leading zeros and
+// trailing fraction zeros do not count towards it, as for a Checker.
+func decimalPattern(p Precision) string {
+	integer := "0+"
+	if digits := p.Total - p.Scale; digits > 0 {
+		integer = fmt.Sprintf("0*[0-9]{1,%d}", digits)
+	}
+	fraction := `(\.0+)?`
+	if p.Scale > 0 {
+		fraction = fmt.Sprintf(`(\.[0-9]{1,%d}0*)?`, p.Scale)
+	}
+	return "^-?" + integer + fraction + "$"
+}
+
+// decimalBounds returns the step and the range of the numbers that fit p.
+func decimalBounds(p Precision) (multipleOf, minimum, maximum json.Number) {
+	multipleOf = "1"
+	if p.Scale > 0 {
+		multipleOf = json.Number("0." + strings.Repeat("0", p.Scale-1) + "1")
+	}
+	integer := "0"
+	if digits := p.Total - p.Scale; digits > 0 {
+		integer = strings.Repeat("9", digits)
+	}
+	maximum = json.Number(integer)
+	if p.Scale > 0 {
+		maximum += json.Number("." + strings.Repeat("9", p.Scale))
+	}
+	return multipleOf, "-" + maximum, maximum
+}
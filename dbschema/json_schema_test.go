@@ -0,0 +1,88 @@
+package dbschema
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToJSONSchema(t *testing.T) {
+	data, err := json.MarshalIndent(ToJSONSchema(shopSchema().Collections[1]), "", "  ")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "orders",
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"user": {"type": "string", "description": "The id of a users record."},
+			"status": {"type": "string", "enum": ["new", "paid"], "default": "new"},
+			"total": {"type": ["number", "string"], "pattern": "^-?0*[0-9]{1,10}(\\.[0-9]{1,2}0*)?$",
+				"multipleOf": 0.01, "minimum": -9999999999.99, "maximum": 9999999999.99},
+			"tags": {"type": "array", "items": {"type": "string", "maxLength": 20}},
+			"attrs": {"type": ["object", "null"], "additionalProperties": {"type": "integer"}},
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": {"type": "string"},
+					"at": {
+						"type": ["object", "null"],
+						"properties": {"latitude": {"type": "number"}, "longitude": {"type": "number"}},
+						"required": ["latitude", "longitude"]
+					}
+				},
+				"required": ["city"]
+			},
+			"raw": {"type": "array"}
+		},
+		"required": ["user", "total", "tags", "address", "raw"]
+	}`, string(data))
+}
+
+func TestToJSONSchema_Fields(t *testing.T) {
+	users := ToJSONSchemas(shopSchema())["users"]
+	require.NotNil(t, users)
+	assert.Equal(t, []string{"email"}, users.Required, "defaults, auto-increment and nullable fields may be missing")
+	avatar := users.Properties["avatar"]
+	assert.Equal(t, []string{"string", "null"}, avatar.Type)
+	assert.Equal(t, "base64", avatar.ContentEncoding)
+	assert.Equal(t, "cG5n", avatar.Default)
+	assert.Equal(t, "2026-01-18T09:30:00Z", users.Properties["since"].Default)
+	assert.Equal(t, "date-time", users.Properties["seen"].Format)
+	assert.Nil(t, users.Properties["prefs"].Type, "JSON accepts any value")
+
+	for _, tt := range []struct {
+		f    FieldDef
+		want JSONSchema
+	}{
+		{FieldDef{Type: Null}, JSONSchema{Type: "null"}},
+		{FieldDef{Type: Null, Nullable: true}, JSONSchema{Type: "null"}},
+		{FieldDef{Type: Bool}, JSONSchema{Type: "boolean"}},
+		{FieldDef{Type: Float}, JSONSchema{Type: "number"}},
+		{FieldDef{Type: Bytes, Length: func() *int { n := 4; return &n }()}, JSONSchema{Type: "string", ContentEncoding: "base64", MaxLength: func() *int { n := 8; return &n }()}},
+		{FieldDef{Type: Decimal}, JSONSchema{Type: []string{"number", "string"}, Pattern: `^-?[0-9]+(\.[0-9]+)?$`}},
+		{FieldDef{Type: Decimal, Nullable: true, Precision: &Precision{Total: 3}}, JSONSchema{Type: []string{"number", "string", "null"}, Pattern: `^-?0*[0-9]{1,3}(\.0+)?$`, MultipleOf: "1", Minimum: "-999", Maximum: "999"}},
+		{FieldDef{Type: Decimal, Precision: &Precision{Total: 2, Scale: 2}}, JSONSchema{Type: []string{"number", "string"}, Pattern: `^-?0+(\.[0-9]{1,2}0*)?$`, MultipleOf: "0.01", Minimum: "-0.99", Maximum: "0.99"}},
+		{FieldDef{Type: Enum, Nullable: true, Values: []string{"a"}}, JSONSchema{Type: []string{"string", "null"}, Enum: []any{"a", nil}}},
+		{FieldDef{Type: Map}, JSONSchema{Type: "object"}},
+	} {
+		assert.Equal(t, &tt.want, fieldSchema(tt.f), tt.f.TypeString())
+	}
+}
+
+// The pattern of a decimal string accepts exactly what a Checker accepts.
+func TestToJSONSchema_DecimalPatternAgreesWithChecker(t *testing.T) {
+	values := []string{"001.50", "1.500", "-12.3", "0", "000", "0.00", "123", "1234", "1.234", "12.0", ".5", "1.", "1e2", "-", "0123.4"}
+	for _, p := range []Precision{{Total: 3, Scale: 2}, {Total: 3}, {Total: 2, Scale: 2}, {Total: 5, Scale: 1}} {
+		f := FieldDef{Name: "d", Type: Decimal, Precision: &p}
+		pattern := regexp.MustCompile(fieldSchema(f).Pattern)
+		for _, v := range values {
+			var c Checker
+			c.Value("d", f, v)
+			assert.Equal(t, len(c.Violations) == 0, pattern.MatchString(v), "%q for decimal(%d,%d): %v", v, p.Total, p.Scale, c.Violations)
+		}
+	}
+}
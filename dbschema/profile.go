@@ -0,0 +1,320 @@
+package dbschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+)
+
+const (
+	// DefaultSampleLimit is the number of records ProfileCollection reads
+	// unless WithSampleLimit says otherwise.
+	DefaultSampleLimit = 1000
+	// DistinctLimit is the number of distinct values a FieldStats counts
+	// before it stops tracking them.
+	DistinctLimit = 1000
+)
+
+// Profile is what a Profiler inferred from a sample of records.
+type Profile struct {
+	// Collection is the inferred definition, with its fields in name
+	// order and no primary key or indexes.
+	Collection CollectionDef
+	// Records is the number of records sampled.
+	Records int
+	// Fields holds the statistics of Collection.Fields, in the same order.
+	Fields []FieldStats
+}
+
+// FieldStats describes the values a field took in a sample.
+type FieldStats struct {
+	Name dal.FieldName
+	// Present is the number of values, nulls included, and Nulls the
+	// number of nulls among them. The field is missing from the other
+	// records (or, for an element, objects) of the sample.
+	Present int
+	Nulls   int
+	// Types counts the non-null values by the type they were recognized
+	// as: Bool, Int, Float, String, Time (RFC 3339 strings), UUID,
+	// Array or Object.
+	Types map[Type]int
+	// MaxLength is the length of the longest string, in runes, or of the
+	// longest array.
+	MaxLength int
+	// Distinct is the number of distinct scalar values, counted up to
+	// DistinctLimit; DistinctExceeded reports there were more.
+	Distinct         int
+	DistinctExceeded bool
+	// Elem describes the elements of arrays.
+	Elem *FieldStats
+	// Fields describes the fields of objects, in name order.
+	Fields []FieldStats
+
+	distinct map[string]bool
+	fields   map[string]*FieldStats
+	objects  int
+}
+
+// Unique hints that no two non-null values were equal, so the field may be
+// a key: it took at least two scalar values, all distinct.
+func (s FieldStats) Unique() bool {
+	return !s.DistinctExceeded && s.Distinct > 1 && s.Distinct == s.Present-s.Nulls
+}
+
+// Profiler infers a CollectionDef from records added to it one by one, for
+// adapters and tools that read records their own way. ProfileCollection
+// samples a collection through any dal.QueryExecutor.
+type Profiler struct {
+	name    string
+	records int
+	root    FieldStats
+}
+
+// NewProfiler returns a Profiler for the named collection.
+func NewProfiler(name string) *Profiler {
+	return &Profiler{name: name}
+}
+
+// Add adds the data of a record, anything encoding/json encodes as an
+// object.
+func (p *Profiler) Add(data any) error {
+	normalized, err := NormalizeValue(data)
+	if err != nil {
+		return fmt.Errorf("dbschema: record data: %w", err)
+	}
+	fields, ok := normalized.(map[string]any)
+	if !ok && normalized != nil {
+		return fmt.Errorf("dbschema: record data is %s, not an object", kindOfValue(normalized))
+	}
+	p.records++
+	p.root.addObject(fields)
+	return nil
+}
+
+// Profile returns the definition and statistics inferred so far. A field's
+// type is the one its non-null values share. Int and Float mix into Float,
+// and String, Time and UUID into String; other mixes are JSON, and a field
+// with only nulls is Null. Arrays get an Elem inferred from their elements
+// and objects their nested Fields. A field is nullable when it is null or
+// missing in at least one record (or object).
+func (p *Profiler) Profile() *Profile {
+	profile := &Profile{Collection: CollectionDef{Name: p.name}, Records: p.records}
+	for _, s := range p.root.sortedFields() {
+		profile.Collection.Fields = append(profile.Collection.Fields, s.fieldDef(p.records))
+		profile.Fields = append(profile.Fields, s.export())
+	}
+	return profile
+}
+
+type profileOptions struct {
+	sampleLimit int
+	query       dal.Query
+}
+
+// ProfileOption configures ProfileCollection.
+type ProfileOption func(*profileOptions)
+
+// WithSampleLimit sets the number of records to read; 0 reads them all.
+func WithSampleLimit(limit int) ProfileOption {
+	return func(o *profileOptions) {
+		o.sampleLimit = limit
+	}
+}
+
+// WithQuery profiles the records a query returns instead of scanning the
+// collection, e.g. to sample recent records only. The query must select
+// records with their data.
+func WithQuery(query dal.Query) ProfileOption {
+	return func(o *profileOptions) {
+		o.query = query
+	}
+}
+
+// ProfileCollection samples the records of a root collection, by default
+// the first DefaultSampleLimit ones, and infers their definition and
+// statistics (see Profiler.Profile).
+func ProfileCollection(ctx context.Context, qe dal.QueryExecutor, name string, options ...ProfileOption) (*Profile, error) {
+	o := profileOptions{sampleLimit: DefaultSampleLimit}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.sampleLimit < 0 {
+		return nil, fmt.Errorf("dbschema: negative sample limit %d", o.sampleLimit)
+	}
+	q := o.query
+	if q == nil {
+		q = dal.From(dal.NewRootCollectionRef(name, "")).NewQuery().
+			Limit(o.sampleLimit).
+			SelectIntoRecord(func() record.Record {
+				return record.NewRecordWithIncompleteKey(name, reflect.String, new(map[string]any))
+			})
+	}
+	p := NewProfiler(name)
+	for r, err := range dal.ExecuteQueryAndIterateRecords(ctx, q, qe) {
+		if err != nil {
+			return nil, fmt.Errorf("dbschema: failed to sample %q: %w", name, err)
+		}
+		if err = p.Add(r.Data()); err != nil {
+			return nil, fmt.Errorf("%w (record %v)", err, r.Key())
+		}
+		if p.records == o.sampleLimit {
+			break
+		}
+	}
+	return p.Profile(), nil
+}
+
+// add adds one value of the field.
+func (s *FieldStats) add(v any) {
+	s.Present++
+	if v == nil {
+		s.Nulls++
+		return
+	}
+	if s.Types == nil {
+		s.Types = make(map[Type]int)
+	}
+	switch v := v.(type) {
+	case bool:
+		s.Types[Bool]++
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			s.Types[Int]++
+		} else {
+			s.Types[Float]++
+		}
+	case string:
+		switch {
+		case isTime(v):
+			s.Types[Time]++
+		case UUIDPattern.MatchString(v):
+			s.Types[UUID]++
+		default:
+			s.Types[String]++
+		}
+		s.MaxLength = max(s.MaxLength, utf8.RuneCountInString(v))
+	case []any:
+		s.Types[Array]++
+		s.MaxLength = max(s.MaxLength, len(v))
+		if s.Elem == nil {
+			s.Elem = new(FieldStats)
+		}
+		for _, e := range v {
+			s.Elem.add(e)
+		}
+		return
+	case map[string]any:
+		s.Types[Object]++
+		s.addObject(v)
+		return
+	}
+	s.countDistinct(v)
+}
+
+func isTime(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+func (s *FieldStats) addObject(fields map[string]any) {
+	s.objects++
+	if s.fields == nil {
+		s.fields = make(map[string]*FieldStats)
+	}
+	for name, v := range fields {
+		field, ok := s.fields[name]
+		if !ok {
+			field = &FieldStats{Name: dal.FieldName(name)}
+			s.fields[name] = field
+		}
+		field.add(v)
+	}
+}
+
+func (s *FieldStats) countDistinct(v any) {
+	if s.DistinctExceeded {
+		return
+	}
+	key := fmt.Sprintf("%T:%v", v, v)
+	if s.distinct[key] {
+		return
+	}
+	if s.Distinct == DistinctLimit {
+		s.DistinctExceeded = true
+		s.distinct = nil
+		return
+	}
+	if s.distinct == nil {
+		s.distinct = make(map[string]bool)
+	}
+	s.distinct[key] = true
+	s.Distinct++
+}
+
+func (s *FieldStats) sortedFields() []*FieldStats {
+	fields := make([]*FieldStats, 0, len(s.fields))
+	for _, name := range slices.Sorted(maps.Keys(s.fields)) {
+		fields = append(fields, s.fields[name])
+	}
+	return fields
+}
+
+// fieldDef returns the definition of a field that occurs in some of the
+// given number of records or objects.
+func (s *FieldStats) fieldDef(of int) FieldDef {
+	f := FieldDef{Name: s.Name, Type: s.inferredType(), Nullable: s.Nulls > 0 || s.Present < of}
+	switch f.Type {
+	case Array:
+		if s.Elem != nil && s.Elem.Present > 0 {
+			elem := s.Elem.fieldDef(s.Elem.Present)
+			f.Elem = &elem
+		}
+	case Object:
+		for _, field := range s.sortedFields() {
+			f.Fields = append(f.Fields, field.fieldDef(s.objects))
+		}
+	}
+	return f
+}
+
+func (s *FieldStats) inferredType() Type {
+	switch types := slices.Collect(maps.Keys(s.Types)); len(types) {
+	case 0:
+		return Null
+	case 1:
+		return types[0]
+	default:
+		if !slices.ContainsFunc(types, func(t Type) bool { return t != Int && t != Float }) {
+			return Float
+		}
+		if !slices.ContainsFunc(types, func(t Type) bool { return t != String && t != Time && t != UUID }) {
+			return String
+		}
+		return JSON
+	}
+}
+
+// export returns a copy of s without its tracking state.
+func (s *FieldStats) export() FieldStats {
+	out := *s
+	out.Types = maps.Clone(s.Types)
+	out.distinct, out.fields, out.objects = nil, nil, 0
+	if s.Elem != nil {
+		elem := s.Elem.export()
+		out.Elem = &elem
+	}
+	out.Fields = nil
+	for _, field := range s.sortedFields() {
+		out.Fields = append(out.Fields, field.export())
+	}
+	return out
+}
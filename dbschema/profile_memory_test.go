@@ -0,0 +1,62 @@
+package dbschema_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/adapters/dalgo2memory"
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileCollection_Memory(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	ws := db.(dal.WriteSession)
+	for i := range 5 {
+		data := map[string]any{"n": i, "name": fmt.Sprintf("user %d", i)}
+		if i%2 == 0 {
+			data["status"] = "even"
+		}
+		require.NoError(t, ws.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", fmt.Sprintf("u%d", i)), data)))
+	}
+
+	profile, err := dbschema.ProfileCollection(ctx, db, "users")
+	require.NoError(t, err)
+	assert.Equal(t, 5, profile.Records)
+	assert.Equal(t, []dbschema.FieldDef{
+		{Name: "n", Type: dbschema.Int},
+		{Name: "name", Type: dbschema.String},
+		{Name: "status", Type: dbschema.String, Nullable: true},
+	}, profile.Collection.Fields)
+	assert.Equal(t, 6, profile.Fields[1].MaxLength)
+	assert.True(t, profile.Fields[0].Unique())
+	assert.Equal(t, 1, profile.Fields[2].Distinct)
+
+	profile, err = dbschema.ProfileCollection(ctx, db, "users", dbschema.WithSampleLimit(2))
+	require.NoError(t, err)
+	assert.Equal(t, 2, profile.Records)
+
+	q := dal.From(dal.NewRootCollectionRef("users", "")).NewQuery().
+		WhereField("status", dal.Equal, "even").
+		SelectIntoRecord(func() record.Record {
+			return record.NewRecordWithIncompleteKey("users", reflect.String, new(map[string]any))
+		})
+	profile, err = dbschema.ProfileCollection(ctx, db, "users", dbschema.WithQuery(q), dbschema.WithSampleLimit(0))
+	require.NoError(t, err)
+	assert.Equal(t, 3, profile.Records)
+	assert.False(t, profile.Collection.Fields[2].Nullable, "every sampled record has a status")
+
+	profile, err = dbschema.ProfileCollection(ctx, db, "missing")
+	require.NoError(t, err)
+	assert.Zero(t, profile.Records)
+	assert.Empty(t, profile.Collection.Fields)
+
+	_, err = dbschema.ProfileCollection(ctx, db, "users", dbschema.WithSampleLimit(-1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package dbschema
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type user struct {
+		ID      string    `json:"id"`
+		Age     int       `json:"age"`
+		Score   float64   `json:"score"`
+		Active  bool      `json:"active"`
+		Created time.Time `json:"created"`
+		Tags    []string  `json:"tags"`
+		Address *address  `json:"address"`
+	}
+	p := NewProfiler("users")
+	require.NoError(t, p.Add(user{ID: "123e4567-e89b-12d3-a456-426614174000", Age: 30, Score: 1, Active: true,
+		Created: time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC), Tags: []string{"go", "sql"}, Address: &address{City: "Amsterdam", Zip: "1011"}}))
+	require.NoError(t, p.Add(user{ID: "ann", Age: 41, Score: 2.5, Tags: []string{"go"}, Address: &address{City: "Utrecht"}}))
+	require.NoError(t, p.Add(map[string]any{"id": "bob", "age": 30, "nick": "bobby", "mixed": 1, "tags": []any{"x", nil}}))
+	require.NoError(t, p.Add(map[string]any{"id": "cat", "age": 30, "mixed": "one", "none": nil}))
+
+	profile := p.Profile()
+	assert.Equal(t, 4, profile.Records)
+	assert.Equal(t, CollectionDef{Name: "users", Fields: []FieldDef{
+		{Name: "active", Type: Bool, Nullable: true},
+		{Name: "address", Type: Object, Nullable: true, Fields: []FieldDef{
+			{Name: "city", Type: String},
+			{Name: "zip", Type: String, Nullable: true},
+		}},
+		{Name: "age", Type: Int},
+		{Name: "created", Type: Time, Nullable: true},
+		{Name: "id", Type: String},
+		{Name: "mixed", Type: JSON, Nullable: true},
+		{Name: "nick", Type: String, Nullable: true},
+		{Name: "none", Type: Null, Nullable: true},
+		{Name: "score", Type: Float, Nullable: true},
+		{Name: "tags", Type: Array, Nullable: true, Elem: &FieldDef{Type: String, Nullable: true}},
+	}}, profile.Collection)
+
+	stats := make(map[string]FieldStats, len(profile.Fields))
+	for _, s := range profile.Fields {
+		stats[string(s.Name)] = s
+	}
+	id := stats["id"]
+	assert.Equal(t, map[Type]int{UUID: 1, String: 3}, id.Types)
+	assert.Equal(t, 36, id.MaxLength)
+	assert.Equal(t, 4, id.Distinct)
+	assert.True(t, id.Unique())
+	age := stats["age"]
+	assert.Equal(t, 2, age.Distinct)
+	assert.False(t, age.Unique())
+	assert.Equal(t, FieldStats{Name: "none", Present: 1, Nulls: 1}, stats["none"])
+	tags := stats["tags"]
+	assert.Equal(t, 3, tags.Present)
+	assert.Equal(t, 2, tags.MaxLength, "the longest array")
+	assert.Equal(t, &FieldStats{Present: 5, Nulls: 1, Types: map[Type]int{String: 4}, MaxLength: 3, Distinct: 3}, tags.Elem)
+	require.Len(t, stats["address"].Fields, 2)
+	assert.Equal(t, FieldStats{Name: "zip", Present: 1, Types: map[Type]int{String: 1}, MaxLength: 4, Distinct: 1}, stats["address"].Fields[1])
+
+	assert.Error(t, p.Add("not an object"))
+	assert.Error(t, p.Add(func() {}))
+}
+
+func TestFieldStats_DistinctLimit(t *testing.T) {
+	var s FieldStats
+	for i := 0; i <= DistinctLimit; i++ {
+		s.add(strconv.Itoa(i))
+	}
+	assert.True(t, s.DistinctExceeded)
+	assert.Equal(t, DistinctLimit, s.Distinct)
+	assert.False(t, s.Unique())
+}
+
+func TestFieldStats_InferredType(t *testing.T) {
+	for _, tt := range []struct {
+		types map[Type]int
+		want  Type
+	}{
+		{nil, Null},
+		{map[Type]int{Int: 2, Float: 1}, Float},
+		{map[Type]int{String: 2, Time: 1, UUID: 1}, String},
+		{map[Type]int{Array: 1, Object: 1}, JSON},
+		{map[Type]int{Bool: 1, Int: 1}, JSON},
+	} {
+		assert.Equal(t, tt.want, (&FieldStats{Types: tt.types}).inferredType(), "%v", tt.types)
+	}
+}
@@ -0,0 +1,456 @@
+package dbschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+)
+
+// StructTag is the struct-tag key FromStruct reads schema overrides from.
+//
+// Its value is a comma-separated list of options:
+//
+//	pk                 the field is part of the primary key, in field order
+//	type=decimal       the field type, by its Type.String name
+//	length=64          the Length hint
+//	precision=10.2     the Precision hint, as total or total.scale
+//	default=0          a DefaultLiteral parsed for the field type, or
+//	default=now        DefaultCurrentTimestamp
+//	nullable, notnull  override the derived nullability
+//	autoincrement      the field auto-generates values
+//	index=byEmail      the field is part of the named index, in field order
+//	unique=byEmail     the field is part of the named unique index
+//	enum=draft|live    the field is an Enum of the given values
+//	ref=users          the field is a Reference to the given collection
+//	-                  the field is left out of the definition
+//
+// A default value may not contain a comma. The index and unique options can
+// be repeated to put a field in several indexes; the pk, index and unique
+// options apply to top-level fields only.
+const StructTag = "dbschema"
+
+// FromStruct returns the definition of a collection named name whose records
+// are the Go struct T (or a pointer to it). See FromType.
+func FromStruct[T any](name string) (CollectionDef, error) {
+	return FromType(name, reflect.TypeFor[T]())
+}
+
+// FromType returns the definition of a collection named name whose records
+// are of struct type t (or a pointer to it), for the same types to feed DDL,
+// validation and documentation.
+//
+// Fields are the exported fields serialized to JSON, named and skipped by
+// their json tags; the fields of embedded structs without a json name are
+// promoted, and a promoted field sharing its name with another is shadowed
+// or dropped, as encoding/json does. Types map as follows:
+//
+//   - bool to Bool, integer kinds to Int, float kinds to Float, string to
+//     String, byte slices, named or not, to Bytes and time.Time to Time;
+//   - a number with the json "string" option to String;
+//   - a pointer to the type it points to;
+//   - slices and arrays to Array, and maps with string keys to Map, with
+//     the definition of their elements in Elem;
+//   - nested structs to Object, with their fields in Fields;
+//   - types with their own encoding: a json.Marshaler to JSON, otherwise an
+//     encoding.TextMarshaler to String, as encoding/json prefers them;
+//   - interfaces, json.RawMessage, other maps and recursive struct types to
+//     JSON;
+//   - other types — channels, functions — to Null, having no portable
+//     counterpart.
+//
+// A field (or element) is nullable when its type has a nil value (a
+// pointer, map, slice or interface) or its json tag has omitempty. The StructTag overrides the
+// derived definition and declares the primary key and indexes.
+func FromType(name string, t reflect.Type) (CollectionDef, error) {
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return CollectionDef{}, fmt.Errorf("dbschema: collection %q: %v is not a struct type", name, t)
+	}
+	c := CollectionDef{Name: name}
+	b := structDefBuilder{def: &c, indexes: make(map[string]int), visiting: map[reflect.Type]bool{t: true}}
+	if err := b.addFields(t, false); err != nil {
+		return CollectionDef{}, fmt.Errorf("dbschema: collection %q: %w", name, err)
+	}
+	return c, nil
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// implements reports whether values of type t, or pointers to them,
+// implement the interface type iface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// structDefBuilder accumulates the definition of a struct type.
+type structDefBuilder struct {
+	def *CollectionDef
+	// indexes maps an index name to its position in def.Indexes.
+	indexes map[string]int
+	// nested is true for the fields of an Object, which cannot be in the
+	// primary key or indexes.
+	nested bool
+	// visiting holds the struct types being defined, to tell a recursive
+	// type.
+	visiting map[reflect.Type]bool
+}
+
+// structField is a field of a struct type serialized to JSON, possibly
+// promoted from an embedded struct.
+type structField struct {
+	field    reflect.StructField
+	name     dal.FieldName
+	jsonOpts string
+	// nullable is true for a field promoted through an embedded pointer.
+	nullable bool
+	// depth is the number of embedded structs the field is promoted through,
+	// and tagged whether its json tag names it.
+	depth  int
+	tagged bool
+}
+
+// addFields adds the fields of struct type t, resolving the fields sharing a
+// name as encoding/json does: the shallowest one wins, then the one whose json
+// tag names it, and when that leaves several, none is added.
+func (b *structDefBuilder) addFields(t reflect.Type, nullable bool) error {
+	fields := jsonFields(t, 0, nullable, map[reflect.Type]bool{t: true})
+	for i, sf := range fields {
+		if !dominates(fields, i) {
+			continue
+		}
+		if err := b.addField(sf.field, sf.name, sf.jsonOpts, sf.nullable); err != nil {
+			return fmt.Errorf("field %s: %w", sf.field.Name, err)
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the fields of struct type t at depth, in order, with the
+// fields of its embedded structs without a json name in place of them. The
+// fields of an embedded pointer are nullable. embedding holds the struct types
+// being embedded, which are not embedded again.
+func jsonFields(t reflect.Type, depth int, nullable bool, embedding map[reflect.Type]bool) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, jsonOpts, hasJSONName, skip := jsonTag(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && !hasJSONName {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if !embedding[ft] {
+					embedding[ft] = true
+					fields = append(fields, jsonFields(ft, depth+1, nullable || field.Type.Kind() == reflect.Pointer, embedding)...)
+					delete(embedding, ft)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if hasJSONName {
+			name = jsonName
+		}
+		fields = append(fields, structField{field: field, name: dal.FieldName(name), jsonOpts: jsonOpts, nullable: nullable, depth: depth, tagged: hasJSONName})
+	}
+	return fields
+}
+
+// dominates reports whether fields[i] is the field of its name that
+// encoding/json serializes.
+func dominates(fields []structField, i int) bool {
+	for j, other := range fields {
+		if j == i || other.name != fields[i].name {
+			continue
+		}
+		if other.depth < fields[i].depth || other.depth == fields[i].depth && (other.tagged || !fields[i].tagged) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *structDefBuilder) addField(field reflect.StructField, name dal.FieldName, jsonOpts string, nullable bool) error {
+	tag, hasTag := field.Tag.Lookup(StructTag)
+	if hasTag && tag == "-" {
+		return nil
+	}
+	opts := strings.Split(jsonOpts, ",")
+	f, err := b.elemDef(field.Type)
+	if err != nil {
+		return err
+	}
+	f.Name = name
+	f.Nullable = f.Nullable || nullable || slices.Contains(opts, "omitempty")
+	if slices.Contains(opts, "string") && (f.Type == Int || f.Type == Float || f.Type == Bool) {
+		f.Type = String
+	}
+	if hasTag && tag != "" {
+		if f, err = b.applyTag(f, tag); err != nil {
+			return err
+		}
+	}
+	b.def.Fields = append(b.def.Fields, f)
+	return nil
+}
+
+// structTagOptions maps the StructTag options to whether they take a value.
+var structTagOptions = map[string]bool{
+	"pk": false, "type": true, "length": true, "precision": true, "default": true,
+	"nullable": false, "notnull": false, "autoincrement": false, "index": true, "unique": true,
+	"enum": true, "ref": true,
+}
+
+// applyTag applies the options of a StructTag value to f, recording primary
+// key and index membership in the definition.
+func (b *structDefBuilder) applyTag(f FieldDef, tag string) (FieldDef, error) {
+	var defaultValue *string
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(opt), "=")
+		if valued, known := structTagOptions[key]; known && hasValue != valued {
+			if hasValue {
+				return f, fmt.Errorf("%s tag option %q takes no value", StructTag, key)
+			}
+			return f, fmt.Errorf("%s tag option %q needs a value", StructTag, key)
+		}
+		if b.nested && (key == "pk" || key == "index" || key == "unique") {
+			return f, fmt.Errorf("%s tag option %q is not supported on a nested field", StructTag, key)
+		}
+		switch key {
+		case "pk":
+			b.def.PrimaryKey = append(b.def.PrimaryKey, f.Name)
+		case "type":
+			t, ok := parseType(value)
+			if !ok {
+				return f, fmt.Errorf("unknown type %q", value)
+			}
+			f.Type = t
+		case "length":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return f, fmt.Errorf("invalid length %q", value)
+			}
+			f.Length = &n
+		case "precision":
+			p, err := parsePrecision(value)
+			if err != nil {
+				return f, err
+			}
+			f.Precision = &p
+		case "default":
+			defaultValue = &value
+		case "nullable":
+			f.Nullable = true
+		case "notnull":
+			f.Nullable = false
+		case "autoincrement":
+			f.AutoIncrement = true
+		case "enum":
+			f.Type, f.Values = Enum, strings.Split(value, "|")
+		case "ref":
+			f.Type, f.RefCollection = Reference, value
+		case "index", "unique":
+			if err := b.addToIndex(value, key == "unique", f.Name); err != nil {
+				return f, err
+			}
+		default:
+			return f, fmt.Errorf("unknown %s tag option %q", StructTag, key)
+		}
+	}
+	if defaultValue != nil { // parsed last, once the type is final
+		d, err := parseDefault(*defaultValue, f.Type)
+		if err != nil {
+			return f, err
+		}
+		f.Default = d
+	}
+	return f, nil
+}
+
+func (b *structDefBuilder) addToIndex(name string, unique bool, field dal.FieldName) error {
+	if name == "" {
+		return fmt.Errorf("empty index name")
+	}
+	i, ok := b.indexes[name]
+	if !ok {
+		b.indexes[name] = len(b.def.Indexes)
+		b.def.Indexes = append(b.def.Indexes, IndexDef{Name: name, Collection: b.def.Name, Fields: []dal.FieldName{field}, Unique: unique})
+		return nil
+	}
+	if b.def.Indexes[i].Unique != unique {
+		return fmt.Errorf("index %q is declared both unique and not unique", name)
+	}
+	b.def.Indexes[i].Fields = append(b.def.Indexes[i].Fields, field)
+	return nil
+}
+
+// jsonTag returns the name and options of a field's json tag, whether the
+// tag names the field, and whether it leaves the field out.
+func jsonTag(field reflect.StructField) (name, opts string, named, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", "", false, true
+	}
+	name, opts, _ = strings.Cut(tag, ",")
+	return name, opts, name != "", false
+}
+
+// elemDef returns the definition of a value of Go type t, nullable when t
+// has a nil value.
+func (b *structDefBuilder) elemDef(t reflect.Type) (FieldDef, error) {
+	var nullable bool
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		nullable = true
+	}
+	f, err := b.typeDef(t)
+	f.Nullable = nullable
+	return f, err
+}
+
+// typeDef returns the definition of the type of a value of Go type t.
+func (b *structDefBuilder) typeDef(t reflect.Type) (FieldDef, error) {
+	if t.Kind() == reflect.Pointer {
+		return b.typeDef(t.Elem())
+	}
+	switch t {
+	case timeType:
+		return FieldDef{Type: Time}, nil
+	case rawMessageType:
+		return FieldDef{Type: JSON}, nil
+	}
+	if implements(t, jsonMarshalerType) {
+		return FieldDef{Type: JSON}, nil
+	}
+	if implements(t, textMarshalerType) {
+		return FieldDef{Type: String}, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return FieldDef{Type: Bool}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return FieldDef{Type: Int}, nil
+	case reflect.Float32, reflect.Float64:
+		return FieldDef{Type: Float}, nil
+	case reflect.String:
+		return FieldDef{Type: String}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && isByteElem(t.Elem()) {
+			return FieldDef{Type: Bytes}, nil // base64, as encoding/json writes it
+		}
+		return b.containerDef(Array, t.Elem())
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return FieldDef{Type: JSON}, nil
+		}
+		return b.containerDef(Map, t.Elem())
+	case reflect.Struct:
+		if b.visiting[t] {
+			return FieldDef{Type: JSON}, nil
+		}
+		b.visiting[t] = true
+		defer delete(b.visiting, t)
+		var object CollectionDef
+		nested := structDefBuilder{def: &object, indexes: make(map[string]int), nested: true, visiting: b.visiting}
+		if err := nested.addFields(t, false); err != nil {
+			return FieldDef{}, err
+		}
+		return FieldDef{Type: Object, Fields: object.Fields}, nil
+	case reflect.Interface:
+		return FieldDef{Type: JSON}, nil
+	default:
+		return FieldDef{Type: Null}, nil
+	}
+}
+
+// isByteElem reports whether encoding/json writes a slice of elements of type
+// t as a base64 string: t is a byte type that does not marshal itself.
+func isByteElem(t reflect.Type) bool {
+	return t.Kind() == reflect.Uint8 && !implements(t, jsonMarshalerType) && !implements(t, textMarshalerType)
+}
+
+func (b *structDefBuilder) containerDef(t Type, elemType reflect.Type) (FieldDef, error) {
+	elem, err := b.elemDef(elemType)
+	if err != nil {
+		return FieldDef{}, err
+	}
+	return FieldDef{Type: t, Elem: &elem}, nil
+}
+
+func parseType(s string) (Type, bool) {
+	for t := Null; t <= GeoPoint; t++ {
+		if t.String() == s {
+			return t, true
+		}
+	}
+	return Null, false
+}
+
+func parsePrecision(s string) (Precision, error) {
+	total, scale, hasScale := strings.Cut(s, ".")
+	var p Precision
+	var err error
+	if p.Total, err = strconv.Atoi(total); err != nil || p.Total <= 0 {
+		return p, fmt.Errorf("invalid precision %q", s)
+	}
+	if hasScale {
+		if p.Scale, err = strconv.Atoi(scale); err != nil || p.Scale < 0 {
+			return p, fmt.Errorf("invalid precision %q", s)
+		}
+	}
+	return p, nil
+}
+
+// parseDefault parses a default value for a field of type t: "now" or
+// "current_timestamp" for a Time field, a literal of the field type
+// otherwise. A Decimal default stays a string to keep its digits exact.
+func parseDefault(s string, t Type) (DefaultExpr, error) {
+	var (
+		v   any
+		err error
+	)
+	switch t {
+	case Time:
+		if s == "now" || s == "current_timestamp" {
+			return DefaultCurrentTimestamp{}, nil
+		}
+		v, err = time.Parse(time.RFC3339, s)
+	case Bool:
+		v, err = strconv.ParseBool(s)
+	case Int:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case Float:
+		v, err = strconv.ParseFloat(s, 64)
+	case Bytes:
+		v = []byte(s)
+	default:
+		v = s
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s default %q", t, s)
+	}
+	return DefaultLiteral{Value: v}, nil
+}
@@ -0,0 +1,221 @@
+package dbschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type audited struct {
+	CreatedAt time.Time  `json:"createdAt" dbschema:"default=now"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type user struct {
+	audited
+	TenantID string            `json:"tenantID" dbschema:"pk,length=36,unique=byTenantEmail"`
+	ID       int64             `json:"id" dbschema:"pk,autoincrement"`
+	Email    string            `json:"email" dbschema:"length=255,unique=byTenantEmail,index=byEmail"`
+	Nick     string            `json:"nick,omitempty" dbschema:"notnull,default=anon"`
+	Age      *int              `json:"age"`
+	Balance  string            `json:"balance" dbschema:"type=decimal,precision=12.2,default=0.00"`
+	Active   bool              `json:"active" dbschema:"default=true"`
+	Score    float64           `json:"score,string"`
+	Avatar   []byte            `json:"avatar"`
+	Address  address           `json:"address"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Status   string            `json:"status" dbschema:"enum=draft|live,default=draft"`
+	Manager  string            `json:"manager,omitempty" dbschema:"ref=users"`
+	Extra    json.RawMessage   `json:"extra"`
+	Any      any               `json:"any"`
+	Parent   *user             `json:"parent"`
+	Scores   map[int]int       `json:"scores"`
+	Homes    []*address        `json:"homes"`
+	Secret   string            `json:"-"`
+	Cache    string            `json:"cache" dbschema:"-"`
+	Plain    int
+	internal int
+}
+
+func TestFromStruct(t *testing.T) {
+	c, err := FromStruct[*user]("users")
+	require.NoError(t, err)
+	assert.Equal(t, "users", c.Name)
+	assert.Equal(t, []FieldDef{
+		{Name: "createdAt", Type: Time, Default: DefaultCurrentTimestamp{}},
+		{Name: "deletedAt", Type: Time, Nullable: true},
+		{Name: "tenantID", Type: String, Length: intPtr(36)},
+		{Name: "id", Type: Int, AutoIncrement: true},
+		{Name: "email", Type: String, Length: intPtr(255)},
+		{Name: "nick", Type: String, Default: DefaultLiteral{Value: "anon"}},
+		{Name: "age", Type: Int, Nullable: true},
+		{Name: "balance", Type: Decimal, Precision: &Precision{Total: 12, Scale: 2}, Default: DefaultLiteral{Value: "0.00"}},
+		{Name: "active", Type: Bool, Default: DefaultLiteral{Value: true}},
+		{Name: "score", Type: String},
+		{Name: "avatar", Type: Bytes, Nullable: true},
+		{Name: "address", Type: Object, Fields: []FieldDef{{Name: "city", Type: String}}},
+		{Name: "tags", Type: Array, Nullable: true, Elem: &FieldDef{Type: String}},
+		{Name: "labels", Type: Map, Nullable: true, Elem: &FieldDef{Type: String}},
+		{Name: "status", Type: Enum, Values: []string{"draft", "live"}, Default: DefaultLiteral{Value: "draft"}},
+		{Name: "manager", Type: Reference, RefCollection: "users", Nullable: true},
+		{Name: "extra", Type: JSON, Nullable: true},
+		{Name: "any", Type: JSON, Nullable: true},
+		{Name: "parent", Type: JSON, Nullable: true},
+		{Name: "scores", Type: JSON, Nullable: true},
+		{Name: "homes", Type: Array, Nullable: true, Elem: &FieldDef{Type: Object, Nullable: true, Fields: []FieldDef{{Name: "city", Type: String}}}},
+		{Name: "Plain", Type: Int},
+	}, c.Fields)
+	assert.Equal(t, []dal.FieldName{"tenantID", "id"}, c.PrimaryKey)
+	assert.Equal(t, []IndexDef{
+		{Name: "byTenantEmail", Collection: "users", Fields: []dal.FieldName{"tenantID", "email"}, Unique: true},
+		{Name: "byEmail", Collection: "users", Fields: []dal.FieldName{"email"}},
+	}, c.Indexes)
+}
+
+func TestFromType_EmbeddedPointer(t *testing.T) {
+	type named struct {
+		Name string `json:"name"`
+	}
+	type withPointer struct {
+		*named
+		Address *address `json:"address"`
+	}
+	c, err := FromType("things", reflect.TypeOf(withPointer{}))
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{
+		{Name: "name", Type: String, Nullable: true},
+		{Name: "address", Type: Object, Nullable: true, Fields: []FieldDef{{Name: "city", Type: String}}},
+	}, c.Fields)
+}
+
+type celsius float64
+
+func (c celsius) MarshalText() ([]byte, error) { return []byte(fmt.Sprintf("%gC", float64(c))), nil }
+
+type point struct{ X, Y int }
+
+func (p *point) MarshalJSON() ([]byte, error) { return json.Marshal([]int{p.X, p.Y}) }
+
+func TestFromType_Marshalers(t *testing.T) {
+	type reading struct {
+		Temp     celsius  `json:"temp"`
+		Location point    `json:"location"`
+		Previous *celsius `json:"previous"`
+	}
+	c, err := FromStruct[reading]("readings")
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{
+		{Name: "temp", Type: String},
+		{Name: "location", Type: JSON},
+		{Name: "previous", Type: String, Nullable: true},
+	}, c.Fields)
+}
+
+type hash []byte
+
+type level byte
+
+func (l level) MarshalText() ([]byte, error) { return []byte(strconv.Itoa(int(l))), nil }
+
+func TestFromType_ByteSlices(t *testing.T) {
+	type blob struct {
+		H      hash    `json:"h"`
+		Array  [2]byte `json:"array"`
+		Levels []level `json:"levels"`
+	}
+	c, err := FromStruct[blob]("blobs")
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{
+		{Name: "h", Type: Bytes, Nullable: true},
+		{Name: "array", Type: Array, Elem: &FieldDef{Type: Int}},
+		{Name: "levels", Type: Array, Nullable: true, Elem: &FieldDef{Type: String}},
+	}, c.Fields)
+
+	data, err := NormalizeValue(blob{H: hash("abc"), Levels: []level{1}})
+	require.NoError(t, err)
+	var checker Checker
+	checker.Record(c, data)
+	assert.Empty(t, checker.Violations, "the definition accepts the encoding/json encoding %v", data)
+}
+
+type shadowedName struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Code  string
+}
+
+type shadowedCode struct {
+	Code int
+}
+
+func TestFromType_Shadowing(t *testing.T) {
+	type record struct {
+		shadowedName
+		shadowedCode
+		Name int `json:"name"`
+	}
+	c, err := FromStruct[record]("records")
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{
+		{Name: "label", Type: String},
+		{Name: "name", Type: Int},
+	}, c.Fields, "the shallower name wins, the equally deep Codes are dropped")
+	data, err := json.Marshal(record{Name: 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label": "", "name": 1}`, string(data), "as encoding/json serializes it")
+
+	duplicate := reflect.StructOf([]reflect.StructField{
+		{Name: "A", Type: reflect.TypeOf(""), Tag: `json:"a"`},
+		{Name: "B", Type: reflect.TypeOf(""), Tag: `json:"a"`},
+		{Name: "C", Type: reflect.TypeOf(0)},
+	})
+	c, err = FromType("x", duplicate)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldDef{{Name: "C", Type: Int}}, c.Fields)
+}
+
+func TestFromType_Errors(t *testing.T) {
+	_, err := FromType("x", reflect.TypeOf(1))
+	assert.ErrorContains(t, err, "not a struct type")
+	_, err = FromType("x", nil)
+	assert.Error(t, err)
+
+	for tag, want := range map[string]string{
+		`dbschema:"length=0"`:               "invalid length",
+		`dbschema:"length"`:                 "needs a value",
+		`dbschema:"pk=1"`:                   "takes no value",
+		`dbschema:"size=1"`:                 "unknown dbschema tag option",
+		`dbschema:"type=varchar"`:           "unknown type",
+		`dbschema:"precision=a.1"`:          "invalid precision",
+		`dbschema:"default=abc"`:            "invalid int default",
+		`dbschema:"index=a,unique=a"`:       "both unique and not unique",
+		`dbschema:"index="`:                 "empty index name",
+		`dbschema:"type=time,default=then"`: "invalid time default",
+		`dbschema:"ref"`:                    "needs a value",
+	} {
+		st := reflect.StructOf([]reflect.StructField{{Name: "N", Type: reflect.TypeOf(0), Tag: reflect.StructTag(tag)}})
+		_, err := FromType("x", st)
+		assert.ErrorContains(t, err, want, tag)
+	}
+
+	type inner struct {
+		ID string `json:"id" dbschema:"pk"`
+	}
+	type outer struct {
+		Inner inner `json:"inner"`
+	}
+	_, err = FromStruct[outer]("x")
+	assert.ErrorContains(t, err, `option "pk" is not supported on a nested field`)
+}
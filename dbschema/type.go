@@ -27,6 +27,27 @@ const (
 	// Decimal is a fixed-point numeric column. Drivers honor optional
 	// Precision hints (e.g. NUMERIC(total, scale) on PostgreSQL).
 	Decimal
+	// Array is an ordered list of values described by FieldDef.Elem
+	// (untyped when nil).
+	Array
+	// Map is a set of string-keyed values described by FieldDef.Elem
+	// (untyped when nil).
+	Map
+	// Object is a nested record with the fields listed in
+	// FieldDef.Fields.
+	Object
+	// Reference is a reference to a record of the collection named by
+	// FieldDef.RefCollection.
+	Reference
+	// UUID is a universally unique identifier, stored as its canonical
+	// string by engines without a native type.
+	UUID
+	// Enum is a string restricted to FieldDef.Values.
+	Enum
+	// JSON is an arbitrary JSON value.
+	JSON
+	// GeoPoint is a latitude/longitude pair.
+	GeoPoint
 )
 
 // String returns a non-empty lowercase identifier suitable for
@@ -49,6 +70,22 @@ func (t Type) String() string {
 		return "time"
 	case Decimal:
 		return "decimal"
+	case Array:
+		return "array"
+	case Map:
+		return "map"
+	case Object:
+		return "object"
+	case Reference:
+		return "reference"
+	case UUID:
+		return "uuid"
+	case Enum:
+		return "enum"
+	case JSON:
+		return "json"
+	case GeoPoint:
+		return "geopoint"
 	default:
 		return "unknown"
 	}
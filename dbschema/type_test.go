@@ -50,3 +50,22 @@ func TestPrecision_Structure(t *testing.T) {
 	assert.Equal(t, 18, p.Total)
 	assert.Equal(t, 4, p.Scale)
 }
+
+func TestType_Composite(t *testing.T) {
+	cases := map[Type]string{
+		Array:     "array",
+		Map:       "map",
+		Object:    "object",
+		Reference: "reference",
+		UUID:      "uuid",
+		Enum:      "enum",
+		JSON:      "json",
+		GeoPoint:  "geopoint",
+	}
+	for ty, want := range cases {
+		assert.Equal(t, want, ty.String())
+		parsed, ok := parseType(want)
+		assert.True(t, ok)
+		assert.Equal(t, ty, parsed)
+	}
+}
@@ -0,0 +1,207 @@
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+)
+
+// DiffOption configures [Diff] and [DiffCollections].
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	dropUnlisted bool
+	renames      map[string]map[dal.FieldName]dal.FieldName // collection -> new name -> old name
+}
+
+// DropUnlistedCollections makes the plan drop the existing collections that
+// are not among the desired ones. Without it they are left alone, so that a
+// plan for some collections does not drop the others.
+func DropUnlistedCollections() DiffOption {
+	return func(o *diffOptions) { o.dropUnlisted = true }
+}
+
+// RenamedField tells the diff that field from of a collection is now named
+// to. A diff cannot tell a rename from a dropped field and an added one, so
+// without this hint it plans a destructive DropField and an AddField.
+func RenamedField(collection string, from, to dal.FieldName) DiffOption {
+	return func(o *diffOptions) {
+		if o.renames == nil {
+			o.renames = make(map[string]map[dal.FieldName]dal.FieldName)
+		}
+		if o.renames[collection] == nil {
+			o.renames[collection] = make(map[dal.FieldName]dal.FieldName)
+		}
+		o.renames[collection][to] = from
+	}
+}
+
+// Diff compares the desired collection definitions to the ones db reports
+// through [dbschema.SchemaReader] and returns the plan that migrates db to
+// them (see [DiffCollections]). It returns *dbschema.NotSupportedError when
+// db does not implement SchemaReader.
+func Diff(ctx context.Context, db dal.DB, desired []dbschema.CollectionDef, opts ...DiffOption) (*Plan, error) {
+	var o diffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	refs, err := dbschema.ListCollections(ctx, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]dbschema.CollectionDef, 0, len(refs))
+	for _, ref := range refs {
+		if !o.dropUnlisted && !slices.ContainsFunc(desired, func(d dbschema.CollectionDef) bool { return d.Name == ref.Name() }) {
+			current = append(current, dbschema.CollectionDef{Name: ref.Name()})
+			continue
+		}
+		def, err := dbschema.DescribeCollection(ctx, db, &ref)
+		if err != nil {
+			return nil, err
+		}
+		indexes, err := dbschema.ListIndexes(ctx, db, &ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range indexes {
+			if !slices.ContainsFunc(def.Indexes, func(i dbschema.IndexDef) bool { return i.Name == idx.Name }) {
+				def.Indexes = append(def.Indexes, idx)
+			}
+		}
+		current = append(current, *def)
+	}
+	return DiffCollections(current, desired, opts...), nil
+}
+
+// DiffCollections compares the current collection definitions to the
+// desired ones and returns the plan that migrates the former to the latter:
+//
+//  1. a StepCreateCollection for each desired collection that does not
+//     exist, in desired order;
+//  2. for each desired collection that exists, StepAlterCollection steps
+//     dropping the indexes that are gone or changed, renaming the fields
+//     named by RenamedField, modifying the changed fields, dropping the
+//     fields that are gone, adding the new fields in desired order and
+//     adding the new or changed indexes;
+//  3. with DropUnlistedCollections, a StepDropCollection for each current
+//     collection that is not desired.
+//
+// Fields and indexes are matched by name; an index whose fields or
+// uniqueness change is dropped and added again. A changed primary key, which
+// no AlterOp can apply, is reported among the plan's warnings.
+func DiffCollections(current, desired []dbschema.CollectionDef, opts ...DiffOption) *Plan {
+	var o diffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	plan := &Plan{}
+	byName := make(map[string]dbschema.CollectionDef, len(current))
+	for _, c := range current {
+		byName[c.Name] = c
+	}
+	for _, d := range desired {
+		if _, ok := byName[d.Name]; !ok {
+			create := d
+			plan.Steps = append(plan.Steps, Step{Kind: StepCreateCollection, Collection: d.Name, Create: &create})
+		}
+	}
+	for _, d := range desired {
+		if c, ok := byName[d.Name]; ok {
+			diffCollection(plan, c, d, o.renames[d.Name])
+		}
+	}
+	if o.dropUnlisted {
+		for _, c := range current {
+			if !slices.ContainsFunc(desired, func(d dbschema.CollectionDef) bool { return d.Name == c.Name }) {
+				plan.Steps = append(plan.Steps, Step{Kind: StepDropCollection, Collection: c.Name, Destructive: true})
+			}
+		}
+	}
+	return plan
+}
+
+// diffCollection appends the steps altering collection c into d.
+func diffCollection(plan *Plan, c, d dbschema.CollectionDef, renames map[dal.FieldName]dal.FieldName) {
+	alter := func(op AlterOp, destructive bool) {
+		plan.Steps = append(plan.Steps, Step{Kind: StepAlterCollection, Collection: d.Name, Op: op, Destructive: destructive})
+	}
+	if len(c.PrimaryKey) > 0 && !slices.Equal(c.PrimaryKey, d.PrimaryKey) {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: primary key %s cannot be changed to %s",
+			d.Name, fieldList(c.PrimaryKey), fieldList(d.PrimaryKey)))
+	}
+
+	desiredIndex := func(name string) (dbschema.IndexDef, bool) {
+		i := slices.IndexFunc(d.Indexes, func(idx dbschema.IndexDef) bool { return idx.Name == name })
+		if i < 0 {
+			return dbschema.IndexDef{}, false
+		}
+		return d.Indexes[i], true
+	}
+	kept := make(map[string]bool)
+	for _, idx := range c.Indexes {
+		if want, ok := desiredIndex(idx.Name); ok && sameIndex(idx, want) {
+			kept[idx.Name] = true
+			continue
+		}
+		alter(DropIndex(idx.Name), false)
+	}
+
+	names := make([]dal.FieldName, len(c.Fields)) // current field names, renamed
+	fields := make(map[dal.FieldName]dbschema.FieldDef, len(c.Fields))
+	for i, f := range c.Fields {
+		names[i] = f.Name
+		fields[f.Name] = f
+	}
+	for _, f := range d.Fields {
+		from, ok := renames[f.Name]
+		if _, exists := fields[f.Name]; exists || !ok {
+			continue
+		}
+		if old, ok := fields[from]; ok {
+			alter(RenameField(from, f.Name), false)
+			names[slices.Index(names, from)] = f.Name
+			delete(fields, from)
+			old.Name = f.Name
+			fields[f.Name] = old
+		}
+	}
+	for _, f := range d.Fields {
+		if old, ok := fields[f.Name]; ok && !sameField(old, f) {
+			alter(ModifyField(f.Name, f), dbschema.CheckChange(old, f) != nil)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(d.Fields, func(want dbschema.FieldDef) bool { return want.Name == name }) {
+			alter(DropField(name), true)
+		}
+	}
+	for _, f := range d.Fields {
+		if _, ok := fields[f.Name]; !ok {
+			alter(AddField(f), false)
+		}
+	}
+
+	for _, idx := range d.Indexes {
+		if !kept[idx.Name] {
+			if idx.Collection == "" {
+				idx.Collection = d.Name
+			}
+			alter(AddIndex(idx), false)
+		}
+	}
+}
+
+func sameIndex(a, b dbschema.IndexDef) bool {
+	return a.Unique == b.Unique && slices.Equal(a.Fields, b.Fields)
+}
+
+func sameField(a, b dbschema.FieldDef) bool {
+	return a.Name == b.Name && a.Type == b.Type && a.Nullable == b.Nullable && a.AutoIncrement == b.AutoIncrement &&
+		reflect.DeepEqual(a.Length, b.Length) && reflect.DeepEqual(a.Precision, b.Precision) &&
+		reflect.DeepEqual(a.Default, b.Default) && reflect.DeepEqual(a.Elem, b.Elem) && reflect.DeepEqual(a.Fields, b.Fields) &&
+		a.RefCollection == b.RefCollection && slices.Equal(a.Values, b.Values)
+}
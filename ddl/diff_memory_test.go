@@ -0,0 +1,58 @@
+package ddl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dal-go/dalgo/adapters/dalgo2memory"
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff_AppliedToMemory(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB(dalgo2memory.WithSchema(true))
+	writer := db.(interface {
+		Set(ctx context.Context, r record.Record) error
+	})
+	require.NoError(t, writer.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "u1"),
+		map[string]any{"email": "ann@example.com", "nick": "ann", "legacy": 1})))
+	require.NoError(t, writer.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("logs", "l1"), map[string]any{"msg": "up"})))
+
+	desired := []dbschema.CollectionDef{
+		{
+			Name: "users",
+			Fields: []dbschema.FieldDef{
+				{Name: "email", Type: dbschema.String},
+				{Name: "nickname", Type: dbschema.String},
+				{Name: "active", Type: dbschema.Bool, Default: dbschema.DefaultLiteral{Value: true}},
+			},
+			Indexes: []dbschema.IndexDef{{Name: "byEmail", Fields: []dal.FieldName{"email"}, Unique: true}},
+		},
+		{Name: "orders", Fields: []dbschema.FieldDef{{Name: "total", Type: dbschema.Float}}},
+	}
+	plan, err := ddl.Diff(ctx, db, desired, ddl.RenamedField("users", "nick", "nickname"), ddl.DropUnlistedCollections())
+	require.NoError(t, err)
+	assert.Equal(t, `1. create collection orders (total float not null)
+2. alter users: rename field nick to nickname
+3. alter users: drop field legacy [destructive]
+4. alter users: add field active bool not null default true
+5. alter users: add index byEmail unique(email)
+6. drop collection logs [destructive]`, plan.String())
+	require.NoError(t, plan.Apply(ctx, db))
+
+	var user map[string]any
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "u1"), &user)))
+	assert.Equal(t, map[string]any{"email": "ann@example.com", "nickname": "ann", "active": true}, user)
+
+	plan, err = ddl.Diff(ctx, db, desired, ddl.DropUnlistedCollections())
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+
+	_, err = ddl.Diff(ctx, dal.DB(nil), desired)
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+}
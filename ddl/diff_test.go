@@ -0,0 +1,164 @@
+package ddl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int { return &v }
+
+func usersDef() dbschema.CollectionDef {
+	return dbschema.CollectionDef{
+		Name: "users",
+		Fields: []dbschema.FieldDef{
+			{Name: "email", Type: dbschema.String, Length: intPtr(128)},
+			{Name: "age", Type: dbschema.Int, Nullable: true},
+			{Name: "legacy", Type: dbschema.String, Nullable: true},
+			{Name: "nick", Type: dbschema.String, Nullable: true},
+		},
+		PrimaryKey: []dal.FieldName{"email"},
+		Indexes: []dbschema.IndexDef{
+			{Name: "byEmail", Collection: "users", Fields: []dal.FieldName{"email"}, Unique: true},
+			{Name: "byAge", Collection: "users", Fields: []dal.FieldName{"age"}},
+		},
+	}
+}
+
+func TestDiffCollections(t *testing.T) {
+	current := []dbschema.CollectionDef{usersDef(), {Name: "logs"}}
+	desired := dbschema.CollectionDef{
+		Name: "users",
+		Fields: []dbschema.FieldDef{
+			{Name: "email", Type: dbschema.String, Length: intPtr(64)},
+			{Name: "age", Type: dbschema.Float, Nullable: true},
+			{Name: "nickname", Type: dbschema.String, Nullable: true},
+			{Name: "active", Type: dbschema.Bool, Default: dbschema.DefaultLiteral{Value: true}},
+		},
+		PrimaryKey: []dal.FieldName{"email"},
+		Indexes: []dbschema.IndexDef{
+			{Name: "byEmail", Fields: []dal.FieldName{"email"}, Unique: true},
+			{Name: "byAge", Fields: []dal.FieldName{"age"}, Unique: true},
+		},
+	}
+	orders := dbschema.CollectionDef{Name: "orders", Fields: []dbschema.FieldDef{{Name: "total", Type: dbschema.Decimal,
+		Precision: &dbschema.Precision{Total: 10, Scale: 2}, Default: dbschema.DefaultLiteral{Value: 0}}}}
+
+	plan := DiffCollections(current, []dbschema.CollectionDef{desired, orders},
+		RenamedField("users", "nick", "nickname"), DropUnlistedCollections())
+	assert.Equal(t, `1. create collection orders (total decimal(10,2) not null default 0)
+2. alter users: drop index byAge
+3. alter users: rename field nick to nickname
+4. alter users: modify field email to email string(64) not null [destructive]
+5. alter users: modify field age to age float null
+6. alter users: drop field legacy [destructive]
+7. alter users: add field active bool not null default true
+8. alter users: add index byAge unique(age)
+9. drop collection logs [destructive]`, plan.String())
+	assert.Len(t, plan.Destructive(), 3)
+	assert.Equal(t, StepAlterCollection, plan.Steps[7].Kind)
+	assert.Equal(t, AddIndex(dbschema.IndexDef{Name: "byAge", Collection: "users", Fields: []dal.FieldName{"age"}, Unique: true}), plan.Steps[7].Op)
+	assert.Empty(t, plan.Warnings)
+
+	t.Run("without hints", func(t *testing.T) {
+		plan := DiffCollections(current, []dbschema.CollectionDef{desired})
+		assert.Contains(t, plan.String(), "alter users: drop field nick [destructive]")
+		assert.Contains(t, plan.String(), "alter users: add field nickname string null")
+		assert.NotContains(t, plan.String(), "logs", "unlisted collections are kept")
+	})
+	t.Run("no changes", func(t *testing.T) {
+		plan := DiffCollections(current, []dbschema.CollectionDef{usersDef()})
+		assert.True(t, plan.Empty())
+		assert.Equal(t, "no changes", plan.String())
+	})
+	t.Run("primary key", func(t *testing.T) {
+		changed := usersDef()
+		changed.PrimaryKey = []dal.FieldName{"nick"}
+		plan := DiffCollections(current, []dbschema.CollectionDef{changed})
+		assert.True(t, plan.Empty())
+		assert.Equal(t, []string{"users: primary key (email) cannot be changed to (nick)"}, plan.Warnings)
+		assert.Equal(t, "warning: users: primary key (email) cannot be changed to (nick)", plan.String())
+	})
+}
+
+func TestDiffCollections_DestructiveModify(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		from, to dbschema.FieldDef
+		want     bool
+	}{
+		{"int to float", dbschema.FieldDef{Type: dbschema.Int}, dbschema.FieldDef{Type: dbschema.Float}, false},
+		{"float to int", dbschema.FieldDef{Type: dbschema.Float}, dbschema.FieldDef{Type: dbschema.Int}, true},
+		{"becomes nullable", dbschema.FieldDef{Type: dbschema.Int}, dbschema.FieldDef{Type: dbschema.Int, Nullable: true}, false},
+		{"becomes not null", dbschema.FieldDef{Type: dbschema.Int, Nullable: true}, dbschema.FieldDef{Type: dbschema.Int}, true},
+		{"longer", dbschema.FieldDef{Type: dbschema.String, Length: intPtr(5)}, dbschema.FieldDef{Type: dbschema.String, Length: intPtr(9)}, false},
+		{"length limited", dbschema.FieldDef{Type: dbschema.String}, dbschema.FieldDef{Type: dbschema.String, Length: intPtr(9)}, true},
+		{"smaller scale", dbschema.FieldDef{Type: dbschema.Decimal, Precision: &dbschema.Precision{Total: 9, Scale: 2}},
+			dbschema.FieldDef{Type: dbschema.Decimal, Precision: &dbschema.Precision{Total: 12, Scale: 1}}, true},
+		{"wider enum", dbschema.FieldDef{Type: dbschema.Enum, Values: []string{"a"}}, dbschema.FieldDef{Type: dbschema.Enum, Values: []string{"a", "b"}}, false},
+		{"typed elements", dbschema.FieldDef{Type: dbschema.Array}, dbschema.FieldDef{Type: dbschema.Array, Elem: &dbschema.FieldDef{Type: dbschema.Int}}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.from.Name, tt.to.Name = "f", "f"
+			plan := DiffCollections(
+				[]dbschema.CollectionDef{{Name: "c", Fields: []dbschema.FieldDef{tt.from}}},
+				[]dbschema.CollectionDef{{Name: "c", Fields: []dbschema.FieldDef{tt.to}}},
+			)
+			require.Len(t, plan.Steps, 1)
+			assert.Equal(t, tt.want, plan.Steps[0].Destructive)
+		})
+	}
+}
+
+func TestPlan_Apply(t *testing.T) {
+	ctx := context.Background()
+	plan := &Plan{Steps: []Step{
+		{Kind: StepCreateCollection, Collection: "orders", Create: &dbschema.CollectionDef{Name: "orders"}},
+		{Kind: StepAlterCollection, Collection: "users", Op: DropField("a")},
+		{Kind: StepAlterCollection, Collection: "users", Op: DropField("b")},
+		{Kind: StepAlterCollection, Collection: "orders", Op: DropField("c")},
+		{Kind: StepDropCollection, Collection: "logs"},
+	}}
+	db := newSchemaModifierStub("stub")
+	require.NoError(t, plan.Apply(ctx, db))
+	require.Len(t, db.createCollectionCalls, 1)
+	assert.Equal(t, "orders", db.createCollectionCalls[0].name)
+	require.Len(t, db.alterCollectionCalls, 2, "consecutive alterations of a collection are one call")
+	assert.Equal(t, []AlterOp{DropField("a"), DropField("b")}, db.alterCollectionCalls[0].ops)
+	assert.Equal(t, "orders", db.alterCollectionCalls[1].name)
+	require.Len(t, db.dropCollectionCalls, 1)
+
+	err := plan.Apply(ctx, newMinStubDB("plain"))
+	var notSupported *dbschema.NotSupportedError
+	require.True(t, errors.As(err, &notSupported))
+	assert.Contains(t, err.Error(), "create collection orders")
+	assert.NoError(t, (*Plan)(nil).Apply(ctx, db))
+	assert.Error(t, (&Plan{Steps: []Step{{Collection: "x"}}}).Apply(ctx, db))
+}
+
+func TestStepKind_String(t *testing.T) {
+	assert.Equal(t, "create", StepCreateCollection.String())
+	assert.Equal(t, "alter", StepAlterCollection.String())
+	assert.Equal(t, "drop", StepDropCollection.String())
+	assert.Equal(t, "unknown", StepKind(0).String())
+	assert.Equal(t, "unknown x", Step{Collection: "x"}.String())
+}
+
+func TestDescribeOp(t *testing.T) {
+	assert.Equal(t, "drop index byAge", describeOp(DropIndex("byAge")))
+	assert.Equal(t, "add field at time null default current_timestamp auto increment",
+		describeOp(AddField(dbschema.FieldDef{Name: "at", Type: dbschema.Time, Nullable: true, Default: dbschema.DefaultCurrentTimestamp{}, AutoIncrement: true})))
+	assert.Equal(t, "add field s string not null default 'it''s'",
+		describeOp(AddField(dbschema.FieldDef{Name: "s", Type: dbschema.String, Default: dbschema.DefaultLiteral{Value: "it's"}})))
+	assert.Equal(t, "modify field tags to tags array<enum('a','b')> null",
+		describeOp(ModifyField("tags", dbschema.FieldDef{Name: "tags", Type: dbschema.Array, Nullable: true,
+			Elem: &dbschema.FieldDef{Type: dbschema.Enum, Values: []string{"a", "b"}}})))
+	assert.Equal(t, "create collection c (id int not null, primary key(id), byID index(id))", Step{Kind: StepCreateCollection, Collection: "c",
+		Create: &dbschema.CollectionDef{Name: "c", Fields: []dbschema.FieldDef{{Name: "id", Type: dbschema.Int}}, PrimaryKey: []dal.FieldName{"id"},
+			Indexes: []dbschema.IndexDef{{Name: "byID", Fields: []dal.FieldName{"id"}}}}}.String())
+}
@@ -0,0 +1,228 @@
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+)
+
+// StepKind identifies what a [Step] of a [Plan] does.
+type StepKind int8
+
+const (
+	// StepCreateCollection creates a collection with its inline indexes.
+	StepCreateCollection StepKind = iota + 1
+	// StepAlterCollection applies one AlterOp to an existing collection.
+	StepAlterCollection
+	// StepDropCollection drops a collection.
+	StepDropCollection
+)
+
+// String returns a lowercase identifier for diagnostic output.
+func (k StepKind) String() string {
+	switch k {
+	case StepCreateCollection:
+		return "create"
+	case StepAlterCollection:
+		return "alter"
+	case StepDropCollection:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// Step is one operation of a [Plan].
+type Step struct {
+	// Kind is what the step does.
+	Kind StepKind
+	// Collection is the name of the collection the step applies to.
+	Collection string
+	// Create is the definition of the collection a StepCreateCollection
+	// creates, including its inline indexes.
+	Create *dbschema.CollectionDef
+	// Op is the alteration a StepAlterCollection applies.
+	Op AlterOp
+	// Destructive is true when the step may lose stored data: dropping a
+	// collection or a field, or narrowing a field's definition.
+	Destructive bool
+}
+
+// String renders the step on a single line, e.g.
+// "alter users: add field age int null default 0".
+func (s Step) String() string {
+	var b strings.Builder
+	switch s.Kind {
+	case StepCreateCollection:
+		fmt.Fprintf(&b, "create collection %s", s.Collection)
+		if s.Create != nil {
+			parts := make([]string, 0, len(s.Create.Fields)+len(s.Create.Indexes)+1)
+			for _, f := range s.Create.Fields {
+				parts = append(parts, describeField(f))
+			}
+			if len(s.Create.PrimaryKey) > 0 {
+				parts = append(parts, "primary key"+fieldList(s.Create.PrimaryKey))
+			}
+			for _, idx := range s.Create.Indexes {
+				parts = append(parts, describeIndex(idx))
+			}
+			fmt.Fprintf(&b, " (%s)", strings.Join(parts, ", "))
+		}
+	case StepAlterCollection:
+		fmt.Fprintf(&b, "alter %s: %s", s.Collection, describeOp(s.Op))
+	case StepDropCollection:
+		fmt.Fprintf(&b, "drop collection %s", s.Collection)
+	default:
+		fmt.Fprintf(&b, "%v %s", s.Kind, s.Collection)
+	}
+	if s.Destructive {
+		b.WriteString(" [destructive]")
+	}
+	return b.String()
+}
+
+// Plan is an ordered list of steps that migrates a database's collections
+// to desired definitions, as produced by [Diff] and [DiffCollections].
+type Plan struct {
+	// Steps are the operations to apply, in order.
+	Steps []Step
+	// Warnings describe differences that no step can apply, such as a
+	// changed primary key.
+	Warnings []string
+}
+
+// Empty reports whether the plan has no step.
+func (p *Plan) Empty() bool {
+	return p == nil || len(p.Steps) == 0
+}
+
+// Destructive returns the steps that may lose stored data.
+func (p *Plan) Destructive() []Step {
+	if p == nil {
+		return nil
+	}
+	var steps []Step
+	for _, s := range p.Steps {
+		if s.Destructive {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// String renders the plan for review, one numbered step per line followed
+// by the warnings, or "no changes".
+func (p *Plan) String() string {
+	if p.Empty() && (p == nil || len(p.Warnings) == 0) {
+		return "no changes"
+	}
+	var b strings.Builder
+	for i, s := range p.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, s)
+	}
+	for _, w := range p.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Apply applies the steps of the plan to db in order. Consecutive
+// StepAlterCollection steps on the same collection are applied by a single
+// AlterCollection call, so a driver that supports TransactionalDDL applies
+// them atomically. Apply stops at the first failing call and returns its
+// error; callers that must not lose data check [Plan.Destructive] first.
+func (p *Plan) Apply(ctx context.Context, db dal.DB) error {
+	if p == nil {
+		return nil
+	}
+	for i := 0; i < len(p.Steps); {
+		s := p.Steps[i]
+		var err error
+		switch s.Kind {
+		case StepCreateCollection:
+			err = CreateCollection(ctx, db, *s.Create)
+			i++
+		case StepDropCollection:
+			err = DropCollection(ctx, db, s.Collection)
+			i++
+		case StepAlterCollection:
+			var ops []AlterOp
+			for ; i < len(p.Steps) && p.Steps[i].Kind == StepAlterCollection && p.Steps[i].Collection == s.Collection; i++ {
+				ops = append(ops, p.Steps[i].Op)
+			}
+			err = AlterCollection(ctx, db, s.Collection, ops...)
+		default:
+			return fmt.Errorf("ddl: plan step %q has unknown kind %d", s.Collection, s.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("ddl: %s: %w", s, err)
+		}
+	}
+	return nil
+}
+
+// describeOp renders an AlterOp without the collection name.
+func describeOp(op AlterOp) string {
+	switch o := op.(type) {
+	case addFieldOp:
+		return "add field " + describeField(o.field)
+	case dropFieldOp:
+		return fmt.Sprintf("drop field %s", o.name)
+	case modifyFieldOp:
+		return fmt.Sprintf("modify field %s to %s", o.name, describeField(o.newDef))
+	case renameFieldOp:
+		return fmt.Sprintf("rename field %s to %s", o.oldName, o.newName)
+	case addIndexOp:
+		return "add index " + describeIndex(o.index)
+	case dropIndexOp:
+		return "drop index " + o.name
+	default:
+		return fmt.Sprintf("%T", op)
+	}
+}
+
+// describeField renders a field definition, e.g.
+// "name string(64) null default 'x'".
+func describeField(f dbschema.FieldDef) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", f.Name, f.TypeString())
+	if f.Nullable {
+		b.WriteString(" null")
+	} else {
+		b.WriteString(" not null")
+	}
+	switch d := f.Default.(type) {
+	case dbschema.DefaultLiteral:
+		if s, ok := d.Value.(string); ok {
+			fmt.Fprintf(&b, " default '%s'", strings.ReplaceAll(s, "'", "''"))
+		} else {
+			fmt.Fprintf(&b, " default %v", d.Value)
+		}
+	case dbschema.DefaultCurrentTimestamp:
+		b.WriteString(" default current_timestamp")
+	}
+	if f.AutoIncrement {
+		b.WriteString(" auto increment")
+	}
+	return b.String()
+}
+
+// describeIndex renders an index definition, e.g. "byEmail unique(email)".
+func describeIndex(idx dbschema.IndexDef) string {
+	kind := "index"
+	if idx.Unique {
+		kind = "unique"
+	}
+	return idx.Name + " " + kind + fieldList(idx.Fields)
+}
+
+func fieldList(fields []dal.FieldName) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f)
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
@@ -121,31 +121,35 @@ func condFromYAML(c condYAML) (dal.Condition, error) {
 	isComparison := c.Op != "" || c.Left != nil || c.Right != nil
 	hasAnd := c.And != nil
 	hasOr := c.Or != nil
+	hasNot := c.Not != nil
 
 	forms := 0
-	if isComparison {
-		forms++
-	}
-	if hasAnd {
-		forms++
-	}
-	if hasOr {
-		forms++
+	for _, has := range []bool{isComparison, hasAnd, hasOr, hasNot} {
+		if has {
+			forms++
+		}
 	}
 	switch {
 	case forms == 0:
-		return nil, fmt.Errorf("invalid DTQL: condition must be a comparison (op/left/right) or a group (and/or)")
+		return nil, fmt.Errorf("invalid DTQL: condition must be a comparison (op/left/right), a group (and/or) or a negation (not)")
 	case forms > 1:
-		return nil, fmt.Errorf("invalid DTQL: condition mixes comparison and group forms")
+		return nil, fmt.Errorf("invalid DTQL: condition mixes comparison and group forms (set only one of op/left/right, and, or, not)")
 	}
 
-	if isComparison {
+	switch {
+	case isComparison:
 		return comparisonFromYAML(c)
-	}
-	if hasAnd {
+	case hasAnd:
 		return groupFromYAML(dal.And, c.And)
+	case hasOr:
+		return groupFromYAML(dal.Or, c.Or)
+	default:
+		sub, err := condFromYAML(*c.Not)
+		if err != nil {
+			return nil, fmt.Errorf("not condition: %w", err)
+		}
+		return dal.Not(sub), nil
 	}
-	return groupFromYAML(dal.Or, c.Or)
 }
 
 func comparisonFromYAML(c condYAML) (dal.Condition, error) {
@@ -153,6 +157,16 @@ func comparisonFromYAML(c condYAML) (dal.Condition, error) {
 	if !inScopeComparisonOps[op] {
 		return nil, fmt.Errorf("invalid DTQL: unknown comparison operator %q", c.Op)
 	}
+	if dal.IsUnaryOperator(op) {
+		if c.Left == nil || c.Right != nil {
+			return nil, fmt.Errorf("invalid DTQL: %q comparison requires left and no right", op)
+		}
+		left, err := exprFromYAML(*c.Left)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DTQL: comparison left: %w", err)
+		}
+		return dal.Comparison{Operator: op, Left: left}, nil
+	}
 	if c.Left == nil || c.Right == nil {
 		return nil, fmt.Errorf("invalid DTQL: comparison requires both left and right")
 	}
@@ -164,6 +178,11 @@ func comparisonFromYAML(c condYAML) (dal.Condition, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid DTQL: comparison right: %w", err)
 	}
+	if op == dal.Between {
+		if values, ok := c.Right.Values.([]any); !ok || len(values) != 2 {
+			return nil, fmt.Errorf("invalid DTQL: %q comparison requires right values with exactly two bounds", op)
+		}
+	}
 	return dal.NewComparison(left, op, right), nil
 }
 
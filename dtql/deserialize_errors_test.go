@@ -51,9 +51,34 @@ func TestDeserialize_errorBranches(t *testing.T) {
 		},
 		{
 			name:    "or group with invalid child",
-			yaml:    "from:\n  name: users\nwhere:\n  or:\n    - op: \"!=\"\n      left:\n        field: a\n      right:\n        value: 1\n",
+			yaml:    "from:\n  name: users\nwhere:\n  or:\n    - op: \"~=\"\n      left:\n        field: a\n      right:\n        value: 1\n",
 			wantErr: "group condition #0:",
 		},
+		{
+			name:    "condition mixes group and negation",
+			yaml:    "from:\n  name: users\nwhere:\n  and:\n    - op: IsNull\n      left:\n        field: a\n  not:\n    op: IsNull\n    left:\n      field: b\n",
+			wantErr: "mixes comparison and group forms",
+		},
+		{
+			name:    "not with invalid child",
+			yaml:    "from:\n  name: users\nwhere:\n  not:\n    op: \"~=\"\n    left:\n      field: a\n    right:\n      value: 1\n",
+			wantErr: "not condition:",
+		},
+		{
+			name:    "unary comparison with right",
+			yaml:    "from:\n  name: users\nwhere:\n  op: IsNull\n  left:\n    field: a\n  right:\n    value: 1\n",
+			wantErr: "requires left and no right",
+		},
+		{
+			name:    "unary comparison left invalid expression",
+			yaml:    "from:\n  name: users\nwhere:\n  op: IsNotNull\n  left:\n    field: a\n    value: 1\n",
+			wantErr: "comparison left:",
+		},
+		{
+			name:    "between with one bound",
+			yaml:    "from:\n  name: users\nwhere:\n  op: Between\n  left:\n    field: a\n  right:\n    values: [1]\n",
+			wantErr: "exactly two bounds",
+		},
 		{
 			name:    "orderBy with no expression form",
 			yaml:    "from:\n  name: users\norderBy:\n  - desc: true\n",
@@ -59,7 +59,7 @@ func TestDeserialize_invalidInputRejected(t *testing.T) {
 		},
 		{
 			name:    "unknown operator",
-			yaml:    "from:\n  name: users\nwhere:\n  op: \"!=\"\n  left:\n    field: a\n  right:\n    value: 1\n",
+			yaml:    "from:\n  name: users\nwhere:\n  op: \"~=\"\n  left:\n    field: a\n  right:\n    value: 1\n",
 			wantErr: "unknown comparison operator",
 		},
 		{
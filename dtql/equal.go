@@ -82,6 +82,9 @@ func condEqual(a, b dal.Condition) bool {
 		return comparisonEqual(ac, b)
 	case dal.GroupCondition:
 		return groupEqual(ac, b)
+	case dal.NotCondition:
+		bn, ok := b.(dal.NotCondition)
+		return ok && condEqual(ac.Condition(), bn.Condition())
 	default:
 		return false
 	}
@@ -117,6 +120,8 @@ func groupEqual(a dal.GroupCondition, b dal.Condition) bool {
 
 func exprEqual(a, b dal.Expression) bool {
 	switch ae := a.(type) {
+	case nil:
+		return b == nil
 	case dal.FieldRef:
 		bv, ok := b.(dal.FieldRef)
 		return ok && ae.Equal(bv)
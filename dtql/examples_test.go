@@ -10,7 +10,7 @@ import (
 // TestExamplesValid asserts every example .dtql.yaml document deserializes to a
 // dal.StructuredQuery and validates against the generated schema. The set
 // together exercises source, columns, comparison + And/Or group filters,
-// ordering and limit/offset.
+// negation and the unary comparisons, ordering and limit/offset.
 func TestExamplesValid(t *testing.T) {
 	files, err := filepath.Glob("examples/*.dtql.yaml")
 	if err != nil {
@@ -50,6 +50,8 @@ func TestExamplesValid(t *testing.T) {
 		"where:",   // filters
 		"and:",     // And group
 		"or:",      // Or group
+		"not:",     // negation
+		"IsNull",   // unary comparison
 		"values:",  // In with array
 		"orderBy:", // ordering
 		"limit:",   // limit
@@ -13,6 +13,7 @@ type fakeQuery struct {
 	groupBy   []dal.Expression
 	orderBy   []dal.OrderExpression
 	columns   []dal.Column
+	distinct  bool
 	limit     int
 	offset    int
 	startFrom dal.Cursor
@@ -23,6 +24,7 @@ func (q fakeQuery) Where() dal.Condition           { return q.where }
 func (q fakeQuery) GroupBy() []dal.Expression      { return q.groupBy }
 func (q fakeQuery) OrderBy() []dal.OrderExpression { return q.orderBy }
 func (q fakeQuery) Columns() []dal.Column          { return q.columns }
+func (q fakeQuery) Distinct() bool                 { return q.distinct }
 func (q fakeQuery) Limit() int                     { return q.limit }
 func (q fakeQuery) Offset() int                    { return q.offset }
 func (q fakeQuery) StartFrom() dal.Cursor          { return q.startFrom }
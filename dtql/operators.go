@@ -4,11 +4,21 @@ import "github.com/dal-go/dalgo/dal"
 
 // inScopeComparisonOps is the set of dal comparison operators DTQL represents.
 // Their YAML form is the dal.Operator string itself (e.g. "==", "In", ">").
+// The unary IsNull/IsNotNull comparisons have no right operand.
 var inScopeComparisonOps = map[dal.Operator]bool{
-	dal.Equal:          true, // ==
-	dal.In:             true, // In
-	dal.GreaterThen:    true, // >
-	dal.GreaterOrEqual: true, // >=
-	dal.LessThen:       true, // <
-	dal.LessOrEqual:    true, // <=
+	dal.Equal:            true, // ==
+	dal.NotEqual:         true, // !=
+	dal.In:               true, // In
+	dal.NotIn:            true, // NotIn
+	dal.GreaterThen:      true, // >
+	dal.GreaterOrEqual:   true, // >=
+	dal.LessThen:         true, // <
+	dal.LessOrEqual:      true, // <=
+	dal.Between:          true, // Between
+	dal.IsNull:           true, // IsNull
+	dal.IsNotNull:        true, // IsNotNull
+	dal.StartsWith:       true, // StartsWith
+	dal.Like:             true, // Like
+	dal.ArrayContains:    true, // array-contains
+	dal.ArrayContainsAny: true, // array-contains-any
 }
@@ -30,7 +30,7 @@ func TestSerialize_acceptsInScope(t *testing.T) {
 // single validating build pass via the public Serialize entry point.
 func TestSerialize_rejectsOutOfScopeConstructs(t *testing.T) {
 	parentKey := record.NewKeyWithID("orgs", "org1")
-	badCmp := dal.Comparison{Operator: "!=", Left: dal.Field("a"), Right: dal.Constant{Value: 1}}
+	badCmp := dal.Comparison{Operator: "~=", Left: dal.Field("a"), Right: dal.Constant{Value: 1}}
 	tests := []struct {
 		name    string
 		q       dal.StructuredQuery
@@ -46,6 +46,7 @@ func TestSerialize_rejectsOutOfScopeConstructs(t *testing.T) {
 		{"collection group ref", fakeQuery{from: dal.From(dal.NewCollectionGroupRef("users", ""))}, "unsupported From source"},
 		{"parented collection ref", fakeQuery{from: dal.From(dal.NewCollectionRef("users", "", parentKey))}, "parented collection reference"},
 		{"group by", fakeQuery{from: rootFrom(), groupBy: []dal.Expression{dal.Field("country")}}, "GroupBy is not supported"},
+		{"distinct", fakeQuery{from: rootFrom(), distinct: true}, "DISTINCT is not supported"},
 		{"cursor", fakeQuery{from: rootFrom(), startFrom: dal.Cursor("c1")}, "cursor (StartFrom) is not supported"},
 		{"aggregate function column", fakeQuery{from: rootFrom(), columns: []dal.Column{dal.CountAs(dal.Field("id"), "cnt")}}, "column #0: unsupported expression"},
 		{"unsupported column expression", fakeQuery{from: rootFrom(), columns: []dal.Column{{Expression: unsupportedExpr{}}}}, "column #0: unsupported expression"},
@@ -62,6 +62,27 @@ func roundTripCases() map[string]dal.StructuredQuery {
 				dal.WhereField("d", dal.LessOrEqual, 4),
 			),
 		},
+		"extended operators": fakeQuery{
+			from: rootFrom(),
+			where: dal.NewGroupCondition(dal.And,
+				dal.WhereField("a", dal.NotEqual, "x"),
+				dal.WhereField("b", dal.NotIn, []int{1, 2}),
+				dal.WhereField("c", dal.Between, []int{3, 4}),
+				dal.WhereField("d", dal.IsNull, nil),
+				dal.WhereField("e", dal.IsNotNull, nil),
+				dal.WhereField("f", dal.StartsWith, "pre"),
+				dal.WhereField("g", dal.Like, "%mid%"),
+				dal.WhereField("h", dal.ArrayContains, "tag"),
+				dal.WhereField("i", dal.ArrayContainsAny, []string{"x", "y"}),
+			),
+		},
+		"negation": fakeQuery{
+			from: rootFrom(),
+			where: dal.Not(dal.NewGroupCondition(dal.Or,
+				dal.WhereField("a", dal.Equal, 1),
+				dal.Not(dal.WhereField("b", dal.IsNull, nil)),
+			)),
+		},
 		"nested groups": fullQuery(),
 	}
 }
@@ -93,6 +114,7 @@ func TestEqual_detectsDifferences(t *testing.T) {
 		fakeQuery{from: rootFrom(), where: dal.WhereField("age", dal.GreaterThen, 18)},     // operator
 		fakeQuery{from: rootFrom(), limit: 1, where: dal.WhereField("age", dal.Equal, 18)}, // limit
 		fakeQuery{from: dal.From(dal.NewRootCollectionRef("orders", ""))},                  // from
+		fakeQuery{from: rootFrom(), where: dal.Not(dal.WhereField("age", dal.Equal, 18))},  // negation
 	}
 	for i, d := range diffs {
 		if Equal(base, d) {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"sort"
 
+	"github.com/dal-go/dalgo/dal"
 	"gopkg.in/yaml.v3"
 )
 
@@ -65,11 +66,28 @@ func schemaDocument() map[string]any {
 			"additionalProperties": false,
 			"required":             []any{"op", "left", "right"},
 			"properties": map[string]any{
-				"op":    map[string]any{"enum": comparisonOpEnum()},
+				"op":    map[string]any{"enum": comparisonOpEnum(false)},
 				"left":  map[string]any{"$ref": "#/$defs/expression"},
 				"right": map[string]any{"$ref": "#/$defs/expression"},
 			},
 		},
+		"unaryComparison": map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []any{"op", "left"},
+			"properties": map[string]any{
+				"op":   map[string]any{"enum": comparisonOpEnum(true)},
+				"left": map[string]any{"$ref": "#/$defs/expression"},
+			},
+		},
+		"not": map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []any{"not"},
+			"properties": map[string]any{
+				"not": map[string]any{"$ref": "#/$defs/condition"},
+			},
+		},
 		"group": map[string]any{
 			"type":                 "object",
 			"additionalProperties": false,
@@ -85,7 +103,9 @@ func schemaDocument() map[string]any {
 		"condition": map[string]any{
 			"oneOf": []any{
 				map[string]any{"$ref": "#/$defs/comparison"},
+				map[string]any{"$ref": "#/$defs/unaryComparison"},
 				map[string]any{"$ref": "#/$defs/group"},
+				map[string]any{"$ref": "#/$defs/not"},
 			},
 		},
 	}
@@ -121,12 +141,15 @@ func mergeProps(a, b map[string]any) map[string]any {
 	return out
 }
 
-// comparisonOpEnum returns the in-scope comparison operators (sorted) as the
-// schema enum, derived from inScopeComparisonOps so the schema tracks the code.
-func comparisonOpEnum() []any {
+// comparisonOpEnum returns the in-scope binary or unary comparison operators
+// (sorted) as the schema enum, derived from inScopeComparisonOps so the schema
+// tracks the code.
+func comparisonOpEnum(unary bool) []any {
 	ops := make([]string, 0, len(inScopeComparisonOps))
 	for op := range inScopeComparisonOps {
-		ops = append(ops, string(op))
+		if dal.IsUnaryOperator(op) == unary {
+			ops = append(ops, string(op))
+		}
 	}
 	sort.Strings(ops)
 	enum := make([]any, len(ops))
@@ -43,7 +43,7 @@ func (w *yamlBuffer) Write(p []byte) (int, error) {
 
 // queryToDocument validates and builds in one pass. It is the single enforcement
 // point of the lossless guarantee: it rejects any out-of-scope construct
-// (joins, GroupBy, cursor, a non-root source, an unsupported expression,
+// (joins, GroupBy, DISTINCT, cursor, a non-root source, an unsupported expression,
 // condition or operator) with a descriptive error rather than dropping it.
 func queryToDocument(q dal.StructuredQuery) (document, error) {
 	from := q.From()
@@ -56,6 +56,9 @@ func queryToDocument(q dal.StructuredQuery) (document, error) {
 	if len(q.GroupBy()) > 0 {
 		return document{}, fmt.Errorf("GroupBy is not supported by DTQL")
 	}
+	if dal.IsDistinct(q) {
+		return document{}, fmt.Errorf("DISTINCT is not supported by DTQL")
+	}
 	if q.StartFrom() != "" {
 		return document{}, fmt.Errorf("cursor (StartFrom) is not supported by DTQL")
 	}
@@ -122,6 +125,15 @@ func condToYAML(cond dal.Condition) (*condYAML, error) {
 		return comparisonToYAML(c)
 	case dal.GroupCondition:
 		return groupToYAML(c)
+	case dal.NotCondition:
+		if c.Condition() == nil {
+			return nil, fmt.Errorf("not condition without a condition")
+		}
+		sub, err := condToYAML(c.Condition())
+		if err != nil {
+			return nil, fmt.Errorf("not condition: %w", err)
+		}
+		return &condYAML{Not: sub}, nil
 	default:
 		return nil, fmt.Errorf("unsupported condition %T", cond)
 	}
@@ -135,6 +147,12 @@ func comparisonToYAML(c dal.Comparison) (*condYAML, error) {
 	if err != nil {
 		return nil, fmt.Errorf("comparison left: %w", err)
 	}
+	if dal.IsUnaryOperator(c.Operator) {
+		if c.Right != nil {
+			return nil, fmt.Errorf("comparison operator %q takes no right operand", c.Operator)
+		}
+		return &condYAML{Op: string(c.Operator), Left: &left}, nil
+	}
 	right, err := exprToYAML(c.Right)
 	if err != nil {
 		return nil, fmt.Errorf("comparison right: %w", err)
@@ -39,11 +39,13 @@ type orderYAML struct {
 }
 
 // condYAML is the YAML representation of a dal.Condition.
-// A Comparison sets Op/Left/Right; a GroupCondition sets And or Or.
+// A Comparison sets Op/Left/Right (Right is omitted for the unary IsNull and
+// IsNotNull); a GroupCondition sets And or Or; a NotCondition sets Not.
 type condYAML struct {
 	Op    string     `yaml:"op,omitempty"`    // dal.Comparison.Operator
 	Left  *exprYAML  `yaml:"left,omitempty"`  // dal.Comparison.Left
 	Right *exprYAML  `yaml:"right,omitempty"` // dal.Comparison.Right
 	And   []condYAML `yaml:"and,omitempty"`   // dal.GroupCondition (And)
 	Or    []condYAML `yaml:"or,omitempty"`    // dal.GroupCondition (Or)
+	Not   *condYAML  `yaml:"not,omitempty"`   // dal.NotCondition
 }
@@ -71,7 +71,7 @@ func TestSchemaRejectsInvalid(t *testing.T) {
 	sch := compileSchema(t)
 	invalid := map[string]string{
 		"missing from":     "columns:\n  - field: name\n",
-		"unknown operator": "from:\n  name: users\nwhere:\n  op: \"!=\"\n  left:\n    field: a\n  right:\n    value: 1\n",
+		"unknown operator": "from:\n  name: users\nwhere:\n  op: \"~=\"\n  left:\n    field: a\n  right:\n    value: 1\n",
 		"unknown top key":  "from:\n  name: users\nbogus: 1\n",
 		"empty expression": "from:\n  name: users\ncolumns:\n  - as: x\n",
 	}
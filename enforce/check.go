@@ -0,0 +1,34 @@
+package enforce
+
+import (
+	"strings"
+
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// checker accumulates the violations of one write.
+type checker struct {
+	dbschema.Checker
+}
+
+func (c *checker) err(op string, key *record.Key) error {
+	if len(c.Violations) == 0 {
+		return nil
+	}
+	return &ViolationError{Op: op, Key: key, Fields: c.Violations}
+}
+
+// update checks the value an update sets at path.
+func (c *checker) update(def dbschema.CollectionDef, path update.FieldPath, value any) {
+	if value == update.DeleteField {
+		value = nil
+	}
+	v, err := dbschema.NormalizeValue(value)
+	if err != nil {
+		c.Violate(strings.Join(path, "."), ViolationType, err.Error())
+		return
+	}
+	c.Path(def.Fields, path, v)
+}
@@ -0,0 +1,25 @@
+// Package enforce validates the writes to any DALgo database against
+// declared dbschema.CollectionDef definitions.
+//
+// WithSchema wraps a dal.DB so that every Set, Insert and Update — on the
+// database and inside read-write transactions, single or multi — is checked
+// before it reaches the adapter: values must conform to the field types
+// (including the elements of arrays and maps and the fields of objects),
+// not-null fields must have a value, strings and bytes must fit their
+// Length and decimals their Precision. Updates are checked per field path.
+// Inserts have missing fields filled from their DefaultLiteral or
+// DefaultCurrentTimestamp defaults. With Strict, fields the definitions do
+// not declare are rejected too.
+//
+// Records of collections without a definition pass through unchecked. The
+// fields of the primary key and auto-increment fields may be missing, as
+// adapters keep the former in the record key and generate the latter.
+//
+// A rejected write returns a *ViolationError matching ErrSchemaViolation
+// with one FieldViolation per offending field. The checks are those of a
+// dbschema.Checker.
+//
+// The returned database forwards the query plans, schema reading and DDL of
+// the wrapped one (dal.Explainer, dbschema.SchemaReader, ddl.SchemaModifier
+// and ddl.TransactionalDDL).
+package enforce
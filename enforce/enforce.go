@@ -0,0 +1,264 @@
+package enforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+type schemaOptions struct {
+	strict bool
+	clock  func() time.Time
+}
+
+// Option configures WithSchema.
+type Option func(*schemaOptions)
+
+// Strict rejects fields the definitions do not declare, at the top level
+// and in Object fields.
+func Strict() Option {
+	return func(o *schemaOptions) {
+		o.strict = true
+	}
+}
+
+// WithClock sets the clock that fills DefaultCurrentTimestamp defaults
+// (default time.Now).
+func WithClock(clock func() time.Time) Option {
+	return func(o *schemaOptions) {
+		o.clock = clock
+	}
+}
+
+// WithSchema wraps db so that writes to the given collections are checked
+// against their definitions (see the package documentation). It fails for a
+// nil db, a collection without a name or a collection defined twice.
+func WithSchema(db dal.DB, collections []dbschema.CollectionDef, options ...Option) (dal.DB, error) {
+	if db == nil {
+		return nil, fmt.Errorf("enforce: db is required")
+	}
+	s := &schema{defs: make(map[string]dbschema.CollectionDef, len(collections)), options: schemaOptions{clock: time.Now}}
+	for _, option := range options {
+		option(&s.options)
+	}
+	for _, c := range collections {
+		if c.Name == "" {
+			return nil, fmt.Errorf("enforce: collection without a name")
+		}
+		if _, ok := s.defs[c.Name]; ok {
+			return nil, fmt.Errorf("enforce: collection %q is defined twice", c.Name)
+		}
+		s.defs[c.Name] = c
+	}
+	hooked := dal.WithHooks(db,
+		dal.BeforeSet(func(ctx context.Context, r record.Record) error {
+			return s.checkRecord("set", r)
+		}),
+		dal.BeforeSetMulti(func(ctx context.Context, records []record.Record) error {
+			return s.checkRecords("set", records)
+		}),
+		dal.BeforeInsert(func(ctx context.Context, r record.Record) error {
+			return s.checkRecord("insert", r)
+		}),
+		dal.BeforeInsertMulti(func(ctx context.Context, records []record.Record) error {
+			return s.checkRecords("insert", records)
+		}),
+		dal.BeforeUpdate(func(ctx context.Context, key *record.Key, updates []update.Update, _ ...dal.Precondition) error {
+			return s.checkUpdates(key, updates)
+		}),
+		dal.BeforeUpdateMulti(func(ctx context.Context, keys []*record.Key, updates []update.Update, _ ...dal.Precondition) error {
+			for _, key := range keys {
+				if err := s.checkUpdates(key, updates); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	)
+	enforced := enforcedDB{DB: hooked, db: db}
+	if ws, ok := hooked.(dal.WriteSession); ok {
+		return &enforcedReadwriteDB{enforcedDB: enforced, WriteSession: ws}, nil
+	}
+	return &enforced, nil
+}
+
+// enforcedDB is the database WithSchema returns. Besides the checked
+// operations it forwards the optional capabilities of the wrapped database —
+// dal.Explainer, dbschema.SchemaReader, ddl.SchemaModifier and
+// ddl.TransactionalDDL — through their helpers, which fail with a
+// not-supported error when the wrapped database lacks them. DDL does not
+// change the definitions WithSchema enforces.
+type enforcedDB struct {
+	dal.DB
+	db dal.DB
+}
+
+// enforcedReadwriteDB is the database WithSchema returns for a database that
+// can also write outside of a transaction.
+type enforcedReadwriteDB struct {
+	enforcedDB
+	dal.WriteSession
+}
+
+var (
+	_ dal.Explainer         = (*enforcedDB)(nil)
+	_ dbschema.SchemaReader = (*enforcedDB)(nil)
+	_ ddl.SchemaModifier    = (*enforcedDB)(nil)
+	_ ddl.TransactionalDDL  = (*enforcedDB)(nil)
+	_ dal.WriteSession      = (*enforcedReadwriteDB)(nil)
+)
+
+func (db *enforcedDB) ExplainQuery(ctx context.Context, query dal.Query) (dal.QueryPlan, error) {
+	return dal.ExplainQuery(ctx, db.DB, query)
+}
+
+func (db *enforcedDB) ListCollections(ctx context.Context, parent *record.Key) ([]dal.CollectionRef, error) {
+	return dbschema.ListCollections(ctx, db.db, parent)
+}
+
+func (db *enforcedDB) DescribeCollection(ctx context.Context, ref *dal.CollectionRef) (*dbschema.CollectionDef, error) {
+	return dbschema.DescribeCollection(ctx, db.db, ref)
+}
+
+func (db *enforcedDB) ListIndexes(ctx context.Context, ref *dal.CollectionRef) ([]dbschema.IndexDef, error) {
+	return dbschema.ListIndexes(ctx, db.db, ref)
+}
+
+func (db *enforcedDB) ListConstraints(ctx context.Context, ref *dal.CollectionRef) ([]dbschema.ConstraintDef, error) {
+	return dbschema.ListConstraints(ctx, db.db, ref)
+}
+
+func (db *enforcedDB) ListReferrers(ctx context.Context, ref *dal.CollectionRef) ([]dbschema.Referrer, error) {
+	return dbschema.ListReferrers(ctx, db.db, ref)
+}
+
+func (db *enforcedDB) CreateCollection(ctx context.Context, c dbschema.CollectionDef, opts ...ddl.Option) error {
+	return ddl.CreateCollection(ctx, db.db, c, opts...)
+}
+
+func (db *enforcedDB) DropCollection(ctx context.Context, name string, opts ...ddl.Option) error {
+	return ddl.DropCollection(ctx, db.db, name, opts...)
+}
+
+func (db *enforcedDB) AlterCollection(ctx context.Context, name string, ops ...ddl.AlterOp) error {
+	return ddl.AlterCollection(ctx, db.db, name, ops...)
+}
+
+func (db *enforcedDB) SupportsTransactionalDDL() bool {
+	return ddl.SupportsTransactionalDDL(db.db)
+}
+
+// MustWithSchema is WithSchema that panics on error.
+func MustWithSchema(db dal.DB, collections []dbschema.CollectionDef, options ...Option) dal.DB {
+	enforced, err := WithSchema(db, collections, options...)
+	if err != nil {
+		panic(err)
+	}
+	return enforced
+}
+
+// schema holds the definitions WithSchema enforces.
+type schema struct {
+	defs    map[string]dbschema.CollectionDef
+	options schemaOptions
+}
+
+func (s *schema) checkRecords(op string, records []record.Record) error {
+	for _, r := range records {
+		if err := s.checkRecord(op, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRecord checks the data of a record, first filling the defaults of
+// its missing fields on insert.
+func (s *schema) checkRecord(op string, r record.Record) error {
+	def, ok := s.defs[r.Key().Collection()]
+	if !ok {
+		return nil
+	}
+	data := dbschema.WrittenData(r)
+	if op == "insert" {
+		if err := s.fillDefaults(def, data); err != nil {
+			return fmt.Errorf("enforce: insert %v: %w", r.Key(), err)
+		}
+	}
+	view, err := dbschema.NormalizeValue(data)
+	if err != nil {
+		return fmt.Errorf("enforce: %s %v: %w", op, r.Key(), err)
+	}
+	c := checker{dbschema.Checker{Strict: s.options.strict}}
+	c.Record(def, view)
+	return c.err(op, r.Key())
+}
+
+func (s *schema) checkUpdates(key *record.Key, updates []update.Update) error {
+	def, ok := s.defs[key.Collection()]
+	if !ok {
+		return nil
+	}
+	c := checker{dbschema.Checker{Strict: s.options.strict}}
+	for _, u := range updates {
+		path := u.FieldPath()
+		if len(path) == 0 {
+			path = update.FieldPath{u.FieldName()}
+		}
+		c.update(def, path, u.Value())
+	}
+	return c.err("update", key)
+}
+
+// fillDefaults sets the missing or null top-level fields of data that have
+// a default. Map data is set directly; the data a pointer refers to is
+// patched through encoding/json, so a struct field is filled only when it
+// is serialized as missing or null (a nil pointer, or an empty omitempty
+// field). Other data is left as is.
+func (s *schema) fillDefaults(def dbschema.CollectionDef, data any) error {
+	view, err := dbschema.NormalizeValue(data)
+	if err != nil {
+		return err
+	}
+	current, _ := view.(map[string]any)
+	patch := make(map[string]any)
+	for _, f := range def.Fields {
+		if f.Default == nil || current[string(f.Name)] != nil {
+			continue
+		}
+		switch d := f.Default.(type) {
+		case dbschema.DefaultLiteral:
+			patch[string(f.Name)] = d.Value
+		case dbschema.DefaultCurrentTimestamp:
+			patch[string(f.Name)] = s.options.clock()
+		}
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	if m, ok := data.(map[string]any); ok {
+		for name, v := range patch {
+			m[name] = v
+		}
+		return nil
+	}
+	if data == nil || reflect.TypeOf(data).Kind() != reflect.Pointer {
+		return nil
+	}
+	encoded, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to encode defaults: %w", err)
+	}
+	if err = json.Unmarshal(encoded, data); err != nil {
+		return fmt.Errorf("failed to fill defaults: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package enforce
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/adapters/dalgo2memory"
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int { return &i }
+
+var now = time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC)
+
+func usersDef() dbschema.CollectionDef {
+	return dbschema.CollectionDef{
+		Name: "users",
+		Fields: []dbschema.FieldDef{
+			{Name: "id", Type: dbschema.String},
+			{Name: "email", Type: dbschema.String, Length: intPtr(20)},
+			{Name: "age", Type: dbschema.Int, Nullable: true},
+			{Name: "status", Type: dbschema.Enum, Values: []string{"new", "active"}, Default: dbschema.DefaultLiteral{Value: "new"}},
+			{Name: "created", Type: dbschema.Time, Default: dbschema.DefaultCurrentTimestamp{}},
+			{Name: "balance", Type: dbschema.Decimal, Nullable: true, Precision: &dbschema.Precision{Total: 5, Scale: 2}},
+			{Name: "tags", Type: dbschema.Array, Nullable: true, Elem: &dbschema.FieldDef{Type: dbschema.String, Length: intPtr(5)}},
+			{Name: "address", Type: dbschema.Object, Nullable: true, Fields: []dbschema.FieldDef{
+				{Name: "city", Type: dbschema.String},
+				{Name: "zip", Type: dbschema.String, Nullable: true},
+			}},
+		},
+		PrimaryKey: []dal.FieldName{"id"},
+	}
+}
+
+type user struct {
+	Email   string     `json:"email"`
+	Age     *int       `json:"age,omitempty"`
+	Status  string     `json:"status,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+	Balance string     `json:"balance,omitempty"`
+}
+
+type writableDB interface {
+	dal.DB
+	dal.WriteSession
+}
+
+func newDB(t *testing.T, options ...Option) writableDB {
+	t.Helper()
+	db, err := WithSchema(dalgo2memory.NewDB(), []dbschema.CollectionDef{usersDef()}, append(options, WithClock(func() time.Time { return now }))...)
+	require.NoError(t, err)
+	return db.(writableDB)
+}
+
+func violations(t *testing.T, err error) []FieldViolation {
+	t.Helper()
+	var violation *ViolationError
+	require.True(t, errors.As(err, &violation), "%v", err)
+	assert.ErrorIs(t, err, ErrSchemaViolation)
+	return violation.Fields
+}
+
+func TestWithSchema_InsertFillsDefaults(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	u := &user{Email: "ann@example.com"}
+	require.NoError(t, db.Insert(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "ann"), u)))
+	assert.Equal(t, "new", u.Status)
+	require.NotNil(t, u.Created)
+	assert.True(t, now.Equal(*u.Created))
+
+	data := map[string]any{"email": "bob@example.com", "status": "active"}
+	require.NoError(t, db.Insert(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "bob"), data)))
+	assert.Equal(t, "active", data["status"])
+	assert.Equal(t, now, data["created"])
+
+	err := db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "cat"), &user{Email: "cat@example.com"}))
+	assert.Equal(t, []FieldViolation{
+		{Field: "status", Kind: ViolationNull, Message: "value is required"},
+		{Field: "created", Kind: ViolationNull, Message: "value is required"},
+	}, violations(t, err), "set does not fill defaults")
+}
+
+func TestWithSchema_Violations(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, Strict())
+	err := db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "ann"), map[string]any{
+		"email":   "a-very-long-address@example.com",
+		"age":     1.5,
+		"status":  "gone",
+		"created": "yesterday",
+		"balance": "1234.567",
+		"tags":    []string{"ok", "too long"},
+		"address": map[string]any{"zip": "1011", "street": "Dam"},
+		"nick":    "ann",
+	}))
+	assert.Equal(t, []FieldViolation{
+		{Field: "email", Kind: ViolationLength, Message: "length 31 exceeds 20"},
+		{Field: "age", Kind: ViolationType, Message: "expected int, got 1.5"},
+		{Field: "status", Kind: ViolationType, Message: `"gone" is not one of enum('new','active')`},
+		{Field: "created", Kind: ViolationType, Message: `expected an RFC 3339 time, got "yesterday"`},
+		{Field: "balance", Kind: ViolationPrecision, Message: "1234.567 has 3 decimal places, more than 2"},
+		{Field: "balance", Kind: ViolationPrecision, Message: "1234.567 has 4 integer digits, more than 3"},
+		{Field: "tags[1]", Kind: ViolationLength, Message: "length 8 exceeds 5"},
+		{Field: "address.city", Kind: ViolationNull, Message: "value is required"},
+		{Field: "address.street", Kind: ViolationUnknown, Message: "field is not declared"},
+		{Field: "nick", Kind: ViolationUnknown, Message: "field is not declared"},
+	}, violations(t, err))
+	assert.Contains(t, err.Error(), "enforce: set users/ann violates the schema: email: length 31 exceeds 20; age:")
+
+	exists, err := db.Exists(ctx, record.NewKeyWithID("users", "ann"))
+	require.NoError(t, err)
+	assert.False(t, exists, "a rejected write does not reach the database")
+
+	err = db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "ann"), "not an object"))
+	assert.Equal(t, ViolationType, violations(t, err)[0].Kind)
+}
+
+func TestWithSchema_Updates(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t, Strict())
+	key := record.NewKeyWithID("users", "ann")
+	require.NoError(t, db.Insert(ctx, record.NewRecordWithData(key, map[string]any{"email": "ann@example.com"})))
+
+	require.NoError(t, db.Update(ctx, key, []update.Update{
+		update.ByFieldName("age", 30),
+		update.ByFieldPath(update.FieldPath{"address", "zip"}, "1011"),
+		update.ByFieldName("balance", 12.5),
+		update.DeleteByFieldName("age"),
+	}))
+	err := db.Update(ctx, key, []update.Update{
+		update.ByFieldName("email", nil),
+		update.ByFieldPath(update.FieldPath{"address", "city"}, 1),
+		update.ByFieldPath(update.FieldPath{"tags", "0"}, "toolong"),
+		update.ByFieldPath(update.FieldPath{"email", "x"}, "y"),
+		update.ByFieldName("nick", "ann"),
+		update.DeleteByFieldName("status"),
+	})
+	assert.Equal(t, []FieldViolation{
+		{Field: "email", Kind: ViolationNull, Message: "value is required"},
+		{Field: "address.city", Kind: ViolationType, Message: "expected string, got number"},
+		{Field: "tags.0", Kind: ViolationLength, Message: "length 7 exceeds 5"},
+		{Field: "email.x", Kind: ViolationType, Message: "string(20) field has no nested fields"},
+		{Field: "nick", Kind: ViolationUnknown, Message: "field is not declared"},
+		{Field: "status", Kind: ViolationNull, Message: "value is required"},
+	}, violations(t, err))
+
+	err = db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.UpdateMulti(ctx, []*record.Key{key}, []update.Update{update.ByFieldName("age", "old")})
+	})
+	assert.Equal(t, "age", violations(t, err)[0].Field, "writes in transactions are checked too")
+}
+
+func TestWithSchema_UndefinedCollectionsAndLenientMode(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	require.NoError(t, db.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("logs", "l1"), map[string]any{"anything": 1})))
+	require.NoError(t, db.Insert(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "ann"),
+		map[string]any{"email": "ann@example.com", "nick": "ann"})), "unknown fields pass when not strict")
+	err := db.SetMulti(ctx, []record.Record{
+		record.NewRecordWithData(record.NewKeyWithID("logs", "l2"), map[string]any{}),
+		record.NewRecordWithData(record.NewKeyWithID("users", "bob"), map[string]any{}),
+	})
+	assert.Equal(t, "email", violations(t, err)[0].Field)
+}
+
+func TestWithSchema_Errors(t *testing.T) {
+	_, err := WithSchema(nil, nil)
+	assert.Error(t, err)
+	_, err = WithSchema(dalgo2memory.NewDB(), []dbschema.CollectionDef{{}})
+	assert.Error(t, err)
+	_, err = WithSchema(dalgo2memory.NewDB(), []dbschema.CollectionDef{usersDef(), usersDef()})
+	assert.Error(t, err)
+	assert.Panics(t, func() { MustWithSchema(nil, nil) })
+}
+
+// readonlyDB is a dal.DB without write methods or optional capabilities of
+// its own.
+type readonlyDB struct{ dal.DB }
+
+func TestWithSchema_ForwardsCapabilities(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	require.True(t, ddl.SupportsTransactionalDDL(db))
+	require.NoError(t, ddl.CreateCollection(ctx, db, dbschema.CollectionDef{Name: "tags", Fields: []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}}))
+	tags := dal.NewRootCollectionRef("tags", "")
+	def, err := dbschema.DescribeCollection(ctx, db, &tags)
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}, def.Fields)
+	plan, err := dal.ExplainQuery(ctx, db, dal.From(tags).NewQuery().SelectKeysOnly(reflect.String))
+	require.NoError(t, err)
+	assert.Equal(t, dal.PlanScan, plan.Root.Operation)
+
+	readonly, err := WithSchema(readonlyDB{dalgo2memory.NewDB()}, nil)
+	require.NoError(t, err)
+	_, writable := readonly.(dal.WriteSession)
+	assert.False(t, writable)
+	assert.False(t, ddl.SupportsTransactionalDDL(readonly))
+	_, err = dbschema.DescribeCollection(ctx, readonly, &tags)
+	var notSupported *dbschema.NotSupportedError
+	assert.ErrorAs(t, err, &notSupported)
+}
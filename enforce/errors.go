@@ -0,0 +1,52 @@
+package enforce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/record"
+)
+
+// ErrSchemaViolation is matched by every *ViolationError.
+var ErrSchemaViolation = errors.New("enforce: schema violation")
+
+// ViolationKind classifies a FieldViolation.
+type ViolationKind = dbschema.ViolationKind
+
+const (
+	ViolationType      = dbschema.ViolationType
+	ViolationNull      = dbschema.ViolationNull
+	ViolationLength    = dbschema.ViolationLength
+	ViolationPrecision = dbschema.ViolationPrecision
+	// ViolationUnknown is reported in Strict mode only.
+	ViolationUnknown = dbschema.ViolationUnknown
+)
+
+// FieldViolation describes why the value of one field was rejected.
+type FieldViolation = dbschema.FieldViolation
+
+// ViolationError is returned for a write that violates the schema.
+type ViolationError struct {
+	// Op is the rejected operation: "set", "insert" or "update".
+	Op string
+	// Key is the key of the rejected record.
+	Key *record.Key
+	// Fields lists the violations in field order.
+	Fields []FieldViolation
+}
+
+// Error returns a readable single-line message.
+func (e *ViolationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, v := range e.Fields {
+		fields[i] = v.String()
+	}
+	return fmt.Sprintf("enforce: %s %v violates the schema: %s", e.Op, e.Key, strings.Join(fields, "; "))
+}
+
+// Unwrap returns ErrSchemaViolation.
+func (e *ViolationError) Unwrap() error {
+	return ErrSchemaViolation
+}
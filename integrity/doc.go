@@ -0,0 +1,36 @@
+// Package integrity keeps references between the records of any DALgo
+// database consistent, for backends such as Firestore that have no foreign
+// keys.
+//
+// A Reference declares that a field of one collection holds the id of a
+// record of a target collection, and what happens to the referencing
+// records when that record is deleted: Restrict (the default) rejects the
+// delete, Cascade deletes them too and SetNull clears their field.
+// References can be declared directly, derived from the Reference fields of
+// dbschema.CollectionDef definitions (see References) or built from the
+// dbschema.Referrer values a schema reader reports (see FromReferrers).
+//
+// WithReferences wraps a dal.DB so that:
+//
+//   - Set, Insert and the updates of a reference field fail with a
+//     *DanglingReferenceError when the referenced record does not exist;
+//   - Delete applies the delete actions of the references to the deleted
+//     records, recursively, and fails with a *ReferencedError when a
+//     Restrict reference is in the way of the delete, counting every
+//     record the delete cascades to as deleted;
+//   - ListReferrers reports the declared references as dbschema.Referrer
+//     values.
+//
+// Every check and every cascaded write happens in the read-write
+// transaction of the guarded write, so a rejected write leaves no partial
+// changes. Writes outside of a transaction run in a transaction of their
+// own. A delete reads everything it will change before it writes, as
+// Firestore requires, and records written or deleted earlier in the same
+// transaction are taken into account.
+//
+// Referencing collections are root collections queried by an equality on
+// the reference field, so the backend must be able to run that query (in
+// Firestore, a single-field index, which is created by default). Reference
+// fields hold the id of the referenced record, a string or an integer, or
+// null for no reference.
+package integrity
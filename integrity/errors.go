@@ -0,0 +1,55 @@
+package integrity
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dal-go/record"
+)
+
+var (
+	// ErrDanglingReference is matched by every *DanglingReferenceError.
+	ErrDanglingReference = errors.New("integrity: dangling reference")
+	// ErrReferenced is matched by every *ReferencedError.
+	ErrReferenced = errors.New("integrity: record is referenced")
+)
+
+// DanglingReferenceError is returned for a write that would make a record
+// reference a record that does not exist.
+type DanglingReferenceError struct {
+	Reference Reference
+	// Key is the key of the rejected record.
+	Key *record.Key
+	// Parent is the key of the missing referenced record.
+	Parent *record.Key
+}
+
+// Error returns a readable single-line message.
+func (e *DanglingReferenceError) Error() string {
+	return fmt.Sprintf("integrity: %v references missing %v via %s.%s", e.Key, e.Parent, e.Reference.Collection, e.Reference.Field)
+}
+
+// Unwrap returns ErrDanglingReference.
+func (e *DanglingReferenceError) Unwrap() error {
+	return ErrDanglingReference
+}
+
+// ReferencedError is returned for the delete of a record that a Restrict
+// reference still points at.
+type ReferencedError struct {
+	Reference Reference
+	// Key is the key of the record that cannot be deleted.
+	Key *record.Key
+	// Referrer is the key of a record that references it.
+	Referrer *record.Key
+}
+
+// Error returns a readable single-line message.
+func (e *ReferencedError) Error() string {
+	return fmt.Sprintf("integrity: cannot delete %v: referenced by %v via %s.%s", e.Key, e.Referrer, e.Reference.Collection, e.Reference.Field)
+}
+
+// Unwrap returns ErrReferenced.
+func (e *ReferencedError) Unwrap() error {
+	return ErrReferenced
+}
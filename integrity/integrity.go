@@ -0,0 +1,188 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// WithReferences wraps db so that the given references are kept consistent
+// (see the package documentation). The returned DB also implements
+// dal.WriteSession, running each write in a read-write transaction of its
+// own, dbschema.SchemaReader, dal.Explainer, ddl.SchemaModifier and
+// ddl.TransactionalDDL. It fails for a nil db, an incomplete
+// reference, an unknown delete action or a field declared twice.
+func WithReferences(db dal.DB, refs []Reference) (dal.DB, error) {
+	if db == nil {
+		return nil, fmt.Errorf("integrity: db is required")
+	}
+	for i, ref := range refs {
+		if ref.Collection == "" || ref.Field == "" || ref.Target == "" {
+			return nil, fmt.Errorf("integrity: reference at index %d needs a collection, a field and a target", i)
+		}
+		if ref.OnDelete < Restrict || ref.OnDelete > SetNull {
+			return nil, fmt.Errorf("integrity: reference %v has an unknown delete action", ref)
+		}
+		if slices.ContainsFunc(refs[:i], func(r Reference) bool { return r.Collection == ref.Collection && r.Field == ref.Field }) {
+			return nil, fmt.Errorf("integrity: field %s.%s is declared twice", ref.Collection, ref.Field)
+		}
+	}
+	return &integrityDB{DB: db, refs: slices.Clone(refs)}, nil
+}
+
+// MustWithReferences is WithReferences that panics on error.
+func MustWithReferences(db dal.DB, refs []Reference) dal.DB {
+	guarded, err := WithReferences(db, refs)
+	if err != nil {
+		panic(err)
+	}
+	return guarded
+}
+
+// integrityDB passes reads and read-only transactions through to the
+// wrapped DB and guards the writes of its read-write transactions. It
+// forwards the optional capabilities of the wrapped DB — dal.Explainer,
+// dbschema.SchemaReader, ddl.SchemaModifier and ddl.TransactionalDDL —
+// through their helpers, which fail with a not-supported error when the
+// wrapped DB lacks them, except ListReferrers, which reports the declared
+// references.
+type integrityDB struct {
+	dal.DB
+	refs []Reference
+}
+
+func (db *integrityDB) RunReadwriteTransaction(ctx context.Context, worker dal.RWTxWorker, options ...dal.TransactionOption) error {
+	return db.DB.RunReadwriteTransaction(ctx, func(workerCtx context.Context, tx dal.ReadwriteTransaction) error {
+		guardedTx := &integrityTx{
+			ReadwriteTransaction: tx,
+			refs:                 db.refs,
+			written:              make(map[string]bool),
+			deleted:              make(map[string]bool),
+		}
+		return worker(dal.NewContextWithTransaction(workerCtx, guardedTx), guardedTx)
+	}, options...)
+}
+
+func (db *integrityDB) Set(ctx context.Context, r record.Record) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Set(ctx, r)
+	})
+}
+
+func (db *integrityDB) SetMulti(ctx context.Context, records []record.Record) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.SetMulti(ctx, records)
+	})
+}
+
+func (db *integrityDB) Insert(ctx context.Context, r record.Record, opts ...dal.InsertOption) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Insert(ctx, r, opts...)
+	})
+}
+
+func (db *integrityDB) InsertMulti(ctx context.Context, records []record.Record, opts ...dal.InsertOption) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.InsertMulti(ctx, records, opts...)
+	})
+}
+
+func (db *integrityDB) Update(ctx context.Context, key *record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Update(ctx, key, updates, preconditions...)
+	})
+}
+
+func (db *integrityDB) UpdateRecord(ctx context.Context, r record.Record, updates []update.Update, preconditions ...dal.Precondition) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.UpdateRecord(ctx, r, updates, preconditions...)
+	})
+}
+
+func (db *integrityDB) UpdateMulti(ctx context.Context, keys []*record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.UpdateMulti(ctx, keys, updates, preconditions...)
+	})
+}
+
+func (db *integrityDB) Delete(ctx context.Context, key *record.Key) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Delete(ctx, key)
+	})
+}
+
+func (db *integrityDB) DeleteMulti(ctx context.Context, keys []*record.Key) error {
+	return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.DeleteMulti(ctx, keys)
+	})
+}
+
+func (db *integrityDB) ExplainQuery(ctx context.Context, query dal.Query) (dal.QueryPlan, error) {
+	return dal.ExplainQuery(ctx, db.DB, query)
+}
+
+func (db *integrityDB) ListCollections(ctx context.Context, parent *record.Key) ([]dal.CollectionRef, error) {
+	return dbschema.ListCollections(ctx, db.DB, parent)
+}
+
+func (db *integrityDB) DescribeCollection(ctx context.Context, ref *dal.CollectionRef) (*dbschema.CollectionDef, error) {
+	return dbschema.DescribeCollection(ctx, db.DB, ref)
+}
+
+func (db *integrityDB) ListIndexes(ctx context.Context, ref *dal.CollectionRef) ([]dbschema.IndexDef, error) {
+	return dbschema.ListIndexes(ctx, db.DB, ref)
+}
+
+func (db *integrityDB) ListConstraints(ctx context.Context, ref *dal.CollectionRef) ([]dbschema.ConstraintDef, error) {
+	return dbschema.ListConstraints(ctx, db.DB, ref)
+}
+
+func (db *integrityDB) CreateCollection(ctx context.Context, c dbschema.CollectionDef, opts ...ddl.Option) error {
+	return ddl.CreateCollection(ctx, db.DB, c, opts...)
+}
+
+func (db *integrityDB) DropCollection(ctx context.Context, name string, opts ...ddl.Option) error {
+	return ddl.DropCollection(ctx, db.DB, name, opts...)
+}
+
+func (db *integrityDB) AlterCollection(ctx context.Context, name string, ops ...ddl.AlterOp) error {
+	return ddl.AlterCollection(ctx, db.DB, name, ops...)
+}
+
+func (db *integrityDB) SupportsTransactionalDDL() bool {
+	return ddl.SupportsTransactionalDDL(db.DB)
+}
+
+// ListReferrers reports the declared references to ref, one Referrer per
+// referencing collection in declaration order.
+func (db *integrityDB) ListReferrers(_ context.Context, ref *dal.CollectionRef) ([]dbschema.Referrer, error) {
+	var referrers []dbschema.Referrer
+	for _, r := range db.refs {
+		if r.Target != ref.Name() {
+			continue
+		}
+		i := slices.IndexFunc(referrers, func(referrer dbschema.Referrer) bool { return referrer.Collection.Name() == r.Collection })
+		if i < 0 {
+			referrers = append(referrers, dbschema.Referrer{Collection: dal.NewRootCollectionRef(r.Collection, "")})
+			i = len(referrers) - 1
+		}
+		referrers[i].Fields = append(referrers[i].Fields, r.Field)
+	}
+	return referrers, nil
+}
+
+var (
+	_ dal.DB                   = (*integrityDB)(nil)
+	_ dal.WriteSession         = (*integrityDB)(nil)
+	_ dal.Explainer            = (*integrityDB)(nil)
+	_ dbschema.SchemaReader    = (*integrityDB)(nil)
+	_ ddl.SchemaModifier       = (*integrityDB)(nil)
+	_ ddl.TransactionalDDL     = (*integrityDB)(nil)
+	_ dal.ReadwriteTransaction = (*integrityTx)(nil)
+)
@@ -0,0 +1,267 @@
+package integrity
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dal-go/dalgo/adapters/dalgo2memory"
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/dalgo/migrate"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type writableDB interface {
+	dal.DB
+	dal.WriteSession
+}
+
+var shopRefs = []Reference{
+	{Collection: "orders", Field: "customer", Target: "customers", OnDelete: Cascade},
+	{Collection: "lines", Field: "order", Target: "orders", OnDelete: Cascade},
+	{Collection: "lines", Field: "product", Target: "products"},
+	{Collection: "reviews", Field: "author", Target: "customers", OnDelete: SetNull},
+}
+
+func newDB(t *testing.T) writableDB {
+	t.Helper()
+	db, err := WithReferences(dalgo2memory.NewDB(), shopRefs)
+	require.NoError(t, err)
+	return db.(writableDB)
+}
+
+func key(collection, id string) *record.Key {
+	return record.NewKeyWithID(collection, id)
+}
+
+func set(t *testing.T, db writableDB, collection, id string, data map[string]any) {
+	t.Helper()
+	require.NoError(t, db.Set(context.Background(), record.NewRecordWithData(key(collection, id), data)))
+}
+
+func exists(t *testing.T, db dal.DB, collection, id string) bool {
+	t.Helper()
+	found, err := db.Exists(context.Background(), key(collection, id))
+	require.NoError(t, err)
+	return found
+}
+
+// seed creates a customer with an order of two lines and a review.
+func seed(t *testing.T, db writableDB) {
+	t.Helper()
+	set(t, db, "customers", "ann", map[string]any{"name": "Ann"})
+	set(t, db, "products", "pen", map[string]any{"name": "Pen"})
+	set(t, db, "orders", "o1", map[string]any{"customer": "ann"})
+	set(t, db, "lines", "l1", map[string]any{"order": "o1", "product": "pen"})
+	set(t, db, "lines", "l2", map[string]any{"order": "o1", "product": "pen"})
+	set(t, db, "reviews", "r1", map[string]any{"author": "ann", "text": "nice"})
+}
+
+func TestWithReferences_RejectsDanglingReferences(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	seed(t, db)
+
+	err := db.Insert(ctx, record.NewRecordWithData(key("orders", "o2"), map[string]any{"customer": "bob"}))
+	var dangling *DanglingReferenceError
+	require.True(t, errors.As(err, &dangling), "%v", err)
+	assert.ErrorIs(t, err, ErrDanglingReference)
+	assert.Equal(t, "customers/bob", dangling.Parent.String())
+	assert.Equal(t, "integrity: orders/o2 references missing customers/bob via orders.customer", err.Error())
+	assert.False(t, exists(t, db, "orders", "o2"))
+
+	require.NoError(t, db.Insert(ctx, record.NewRecordWithData(key("reviews", "r2"), map[string]any{"author": nil})), "null is no reference")
+
+	err = db.Update(ctx, key("lines", "l1"), []update.Update{update.ByFieldName("product", "ink")})
+	assert.ErrorIs(t, err, ErrDanglingReference)
+	require.NoError(t, db.Update(ctx, key("lines", "l1"), []update.Update{update.ByFieldPath(update.FieldPath{"product"}, "pen")}))
+	require.NoError(t, db.Update(ctx, key("reviews", "r1"), []update.Update{update.DeleteByFieldName("author")}))
+
+	err = db.SetMulti(ctx, []record.Record{
+		record.NewRecordWithData(key("customers", "bob"), map[string]any{"name": "Bob"}),
+		record.NewRecordWithData(key("orders", "o2"), map[string]any{"customer": "bob"}),
+	})
+	require.NoError(t, err, "parents written in the same batch count")
+
+	err = db.Set(ctx, record.NewRecordWithData(key("orders", "o3"), map[string]any{"customer": 1.5}))
+	assert.EqualError(t, err, "integrity: orders/o3: field customer holds 1.5, not a record id")
+}
+
+func TestWithReferences_DeleteActions(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	seed(t, db)
+
+	err := db.Delete(ctx, key("products", "pen"))
+	var referenced *ReferencedError
+	require.True(t, errors.As(err, &referenced), "%v", err)
+	assert.ErrorIs(t, err, ErrReferenced)
+	assert.Equal(t, "products/pen", referenced.Key.String())
+	assert.Equal(t, "lines", referenced.Referrer.Collection())
+	assert.True(t, exists(t, db, "products", "pen"))
+
+	require.NoError(t, db.Delete(ctx, key("customers", "ann")))
+	for _, k := range []*record.Key{key("customers", "ann"), key("orders", "o1"), key("lines", "l1"), key("lines", "l2")} {
+		assert.False(t, exists(t, db, k.Collection(), k.ID.(string)), "%v is deleted", k)
+	}
+	var review map[string]any
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(key("reviews", "r1"), &review)))
+	assert.Equal(t, map[string]any{"author": nil, "text": "nice"}, review)
+
+	require.NoError(t, db.Delete(ctx, key("products", "pen")), "no lines are left")
+}
+
+func TestWithReferences_RestrictRollsBackCascade(t *testing.T) {
+	ctx := context.Background()
+	db, err := WithReferences(dalgo2memory.NewDB(), []Reference{
+		{Collection: "orders", Field: "customer", Target: "customers", OnDelete: Cascade},
+		{Collection: "invoices", Field: "order", Target: "orders"},
+	})
+	require.NoError(t, err)
+	w := db.(writableDB)
+	set(t, w, "customers", "ann", map[string]any{})
+	set(t, w, "orders", "o1", map[string]any{"customer": "ann"})
+	set(t, w, "invoices", "i1", map[string]any{"order": "o1"})
+
+	assert.ErrorIs(t, w.Delete(ctx, key("customers", "ann")), ErrReferenced)
+	assert.True(t, exists(t, db, "customers", "ann"))
+	assert.True(t, exists(t, db, "orders", "o1"))
+
+	require.NoError(t, w.DeleteMulti(ctx, []*record.Key{key("customers", "ann"), key("invoices", "i1")}),
+		"a referrer deleted along with its target does not restrict")
+	assert.False(t, exists(t, db, "orders", "o1"))
+}
+
+func TestWithReferences_DeleteMultiOrder(t *testing.T) {
+	ctx := context.Background()
+	for _, keys := range [][]*record.Key{
+		{key("customers", "ann"), key("products", "pen")},
+		{key("products", "pen"), key("customers", "ann")},
+	} {
+		db := newDB(t)
+		seed(t, db)
+		require.NoError(t, db.DeleteMulti(ctx, keys),
+			"the lines restricting products/pen are cascaded from customers/ann, in either order")
+		for _, k := range []*record.Key{key("products", "pen"), key("customers", "ann"), key("lines", "l1"), key("lines", "l2")} {
+			assert.False(t, exists(t, db, k.Collection(), k.ID.(string)), "%v is deleted", k)
+		}
+	}
+}
+
+func TestWithReferences_Transaction(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	seed(t, db)
+
+	err := db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		assert.Same(t, tx, dal.GetTransaction(ctx))
+		if err := tx.Insert(ctx, record.NewRecordWithData(key("customers", "bob"), map[string]any{})); err != nil {
+			return err
+		}
+		if err := tx.Insert(ctx, record.NewRecordWithData(key("orders", "o2"), map[string]any{"customer": "bob"})); err != nil {
+			return err
+		}
+		if err := tx.Delete(ctx, key("orders", "o1")); err != nil {
+			return err
+		}
+		return tx.Insert(ctx, record.NewRecordWithData(key("lines", "l3"), map[string]any{"order": "o1", "product": "pen"}))
+	})
+	assert.ErrorIs(t, err, ErrDanglingReference, "an order deleted earlier in the transaction is missing")
+	assert.False(t, exists(t, db, "customers", "bob"), "the transaction is rolled back")
+	assert.True(t, exists(t, db, "lines", "l1"))
+}
+
+func TestWithReferences_ListReferrers(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	ref := dal.NewRootCollectionRef("customers", "")
+	referrers, err := dbschema.ListReferrers(ctx, db, &ref)
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.Referrer{
+		{Collection: dal.NewRootCollectionRef("orders", ""), Fields: []dal.FieldName{"customer"}},
+		{Collection: dal.NewRootCollectionRef("reviews", ""), Fields: []dal.FieldName{"author"}},
+	}, referrers)
+
+	assert.Equal(t, []Reference{
+		{Collection: "orders", Field: "customer", Target: "customers", OnDelete: SetNull},
+		{Collection: "reviews", Field: "author", Target: "customers", OnDelete: SetNull},
+	}, FromReferrers("customers", referrers, SetNull))
+
+	_, err = dbschema.ListCollections(ctx, db, nil)
+	assert.NoError(t, err, "delegated to the wrapped database")
+}
+
+// plainDB is a dal.DB without optional capabilities of its own.
+type plainDB struct{ dal.DB }
+
+func TestWithReferences_ForwardsCapabilities(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	_, ok := db.(ddl.SchemaModifier)
+	require.True(t, ok)
+	require.True(t, ddl.SupportsTransactionalDDL(db))
+	tags := dal.NewRootCollectionRef("tags", "")
+	plan, err := dal.ExplainQuery(ctx, db, dal.From(tags).NewQuery().SelectKeysOnly(reflect.String))
+	require.NoError(t, err)
+	assert.Equal(t, dal.PlanScan, plan.Root.Operation)
+
+	m := migrate.New(db)
+	require.NoError(t, m.Register(
+		migrate.Migration{Version: 1, Name: "tags", Up: []migrate.Step{
+			migrate.CreateCollection(dbschema.CollectionDef{Name: "tags", Fields: []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}}),
+		}},
+		migrate.Migration{Version: 2, Name: "orphan", Up: []migrate.Step{
+			migrate.CreateCollection(dbschema.CollectionDef{Name: "notes"}),
+			migrate.Data("orphan order", func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+				return tx.Set(ctx, record.NewRecordWithData(key("orders", "o9"), map[string]any{"customer": "nobody"}))
+			}),
+		}},
+	))
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrDanglingReference, "data steps run in guarded transactions")
+	def, err := dbschema.DescribeCollection(ctx, db, &tags)
+	require.NoError(t, err)
+	assert.Equal(t, []dbschema.FieldDef{{Name: "label", Type: dbschema.String}}, def.Fields)
+	notes := dal.NewRootCollectionRef("notes", "")
+	_, err = dbschema.DescribeCollection(ctx, db, &notes)
+	assert.Error(t, err, "the failed migration is rolled back with its DDL")
+
+	plain, err := WithReferences(plainDB{dalgo2memory.NewDB()}, nil)
+	require.NoError(t, err)
+	assert.False(t, ddl.SupportsTransactionalDDL(plain))
+	err = ddl.CreateCollection(ctx, plain, dbschema.CollectionDef{Name: "tags"})
+	assert.ErrorIs(t, err, dal.ErrNotSupported)
+}
+
+func TestReferences(t *testing.T) {
+	refs := References(dbschema.CollectionDef{
+		Name: "orders",
+		Fields: []dbschema.FieldDef{
+			{Name: "id", Type: dbschema.String},
+			{Name: "customer", Type: dbschema.Reference, RefCollection: "customers"},
+			{Name: "untyped", Type: dbschema.Reference},
+		},
+	})
+	assert.Equal(t, []Reference{{Collection: "orders", Field: "customer", Target: "customers"}}, refs)
+	assert.Equal(t, "orders.customer -> customers (restrict)", refs[0].String())
+	assert.Equal(t, "set-null", SetNull.String())
+	assert.Equal(t, "DeleteAction(7)", DeleteAction(7).String())
+}
+
+func TestWithReferences_Errors(t *testing.T) {
+	_, err := WithReferences(nil, nil)
+	assert.Error(t, err)
+	_, err = WithReferences(dalgo2memory.NewDB(), []Reference{{Collection: "orders", Field: "customer"}})
+	assert.Error(t, err)
+	_, err = WithReferences(dalgo2memory.NewDB(), []Reference{{Collection: "orders", Field: "customer", Target: "customers", OnDelete: 7}})
+	assert.Error(t, err)
+	_, err = WithReferences(dalgo2memory.NewDB(), []Reference{shopRefs[0], shopRefs[0]})
+	assert.Error(t, err)
+	assert.Panics(t, func() { MustWithReferences(nil, nil) })
+}
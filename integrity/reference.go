@@ -0,0 +1,84 @@
+package integrity
+
+import (
+	"fmt"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+)
+
+// DeleteAction is what happens to the records that reference a deleted
+// record.
+type DeleteAction int
+
+const (
+	// Restrict rejects the delete of a referenced record.
+	Restrict DeleteAction = iota
+	// Cascade deletes the referencing records, applying their own
+	// references in turn.
+	Cascade
+	// SetNull sets the reference field of the referencing records to null.
+	SetNull
+)
+
+// String returns "restrict", "cascade" or "set-null".
+func (a DeleteAction) String() string {
+	switch a {
+	case Restrict:
+		return "restrict"
+	case Cascade:
+		return "cascade"
+	case SetNull:
+		return "set-null"
+	default:
+		return fmt.Sprintf("DeleteAction(%d)", int(a))
+	}
+}
+
+// Reference declares that Field of the records of Collection holds the id of
+// a record of Target.
+type Reference struct {
+	// Collection is the referencing root collection.
+	Collection string
+	// Field is the top-level field holding the id of the referenced record.
+	Field dal.FieldName
+	// Target is the referenced root collection.
+	Target string
+	// OnDelete applies to the records of Collection that reference a
+	// deleted record of Target.
+	OnDelete DeleteAction
+}
+
+// String returns e.g. "orders.customer -> customers (cascade)".
+func (r Reference) String() string {
+	return fmt.Sprintf("%s.%s -> %s (%v)", r.Collection, r.Field, r.Target, r.OnDelete)
+}
+
+// References returns a Restrict reference for every top-level field of type
+// dbschema.Reference of the given collections. Change the OnDelete of the
+// returned references to cascade or clear them.
+func References(collections ...dbschema.CollectionDef) []Reference {
+	var refs []Reference
+	for _, c := range collections {
+		for _, f := range c.Fields {
+			if f.Type == dbschema.Reference && f.RefCollection != "" {
+				refs = append(refs, Reference{Collection: c.Name, Field: f.Name, Target: f.RefCollection})
+			}
+		}
+	}
+	return refs
+}
+
+// FromReferrers returns a reference with the given delete action for every
+// field of the referrers of target, as reported by
+// dbschema.ListReferrers, e.g. to mirror the foreign keys of a SQL database
+// over a document store.
+func FromReferrers(target string, referrers []dbschema.Referrer, onDelete DeleteAction) []Reference {
+	var refs []Reference
+	for _, referrer := range referrers {
+		for _, field := range referrer.Fields {
+			refs = append(refs, Reference{Collection: referrer.Collection.Name(), Field: field, Target: target, OnDelete: onDelete})
+		}
+	}
+	return refs
+}
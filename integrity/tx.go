@@ -0,0 +1,338 @@
+package integrity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+)
+
+// integrityTx guards the writes of a read-write transaction. It remembers
+// the records written and deleted through it, as a transaction may not see
+// its own writes (Firestore does not).
+type integrityTx struct {
+	dal.ReadwriteTransaction
+	refs    []Reference
+	written map[string]bool
+	deleted map[string]bool
+}
+
+func (tx *integrityTx) Set(ctx context.Context, r record.Record) error {
+	if err := tx.checkRecords(ctx, []record.Record{r}); err != nil {
+		return err
+	}
+	if err := tx.ReadwriteTransaction.Set(ctx, r); err != nil {
+		return err
+	}
+	tx.markWritten(r.Key())
+	return nil
+}
+
+func (tx *integrityTx) SetMulti(ctx context.Context, records []record.Record) error {
+	if err := tx.checkRecords(ctx, records); err != nil {
+		return err
+	}
+	if err := tx.ReadwriteTransaction.SetMulti(ctx, records); err != nil {
+		return err
+	}
+	for _, r := range records {
+		tx.markWritten(r.Key())
+	}
+	return nil
+}
+
+func (tx *integrityTx) Insert(ctx context.Context, r record.Record, opts ...dal.InsertOption) error {
+	if err := tx.checkRecords(ctx, []record.Record{r}); err != nil {
+		return err
+	}
+	if err := tx.ReadwriteTransaction.Insert(ctx, r, opts...); err != nil {
+		return err
+	}
+	tx.markWritten(r.Key())
+	return nil
+}
+
+func (tx *integrityTx) InsertMulti(ctx context.Context, records []record.Record, opts ...dal.InsertOption) error {
+	if err := tx.checkRecords(ctx, records); err != nil {
+		return err
+	}
+	if err := tx.ReadwriteTransaction.InsertMulti(ctx, records, opts...); err != nil {
+		return err
+	}
+	for _, r := range records {
+		tx.markWritten(r.Key())
+	}
+	return nil
+}
+
+func (tx *integrityTx) Update(ctx context.Context, key *record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+	if err := tx.checkUpdates(ctx, key, updates); err != nil {
+		return err
+	}
+	return tx.ReadwriteTransaction.Update(ctx, key, updates, preconditions...)
+}
+
+func (tx *integrityTx) UpdateRecord(ctx context.Context, r record.Record, updates []update.Update, preconditions ...dal.Precondition) error {
+	if err := tx.checkUpdates(ctx, r.Key(), updates); err != nil {
+		return err
+	}
+	return tx.ReadwriteTransaction.UpdateRecord(ctx, r, updates, preconditions...)
+}
+
+func (tx *integrityTx) UpdateMulti(ctx context.Context, keys []*record.Key, updates []update.Update, preconditions ...dal.Precondition) error {
+	for _, key := range keys {
+		if err := tx.checkUpdates(ctx, key, updates); err != nil {
+			return err
+		}
+	}
+	return tx.ReadwriteTransaction.UpdateMulti(ctx, keys, updates, preconditions...)
+}
+
+func (tx *integrityTx) Delete(ctx context.Context, key *record.Key) error {
+	return tx.DeleteMulti(ctx, []*record.Key{key})
+}
+
+// DeleteMulti plans the whole delete, reading every referencing record,
+// before it clears the SetNull references and deletes the records, the
+// cascaded ones first. A Restrict reference is checked only once every
+// cascade is planned, so a referrer that a cascade deletes does not restrict,
+// whatever the order of keys.
+func (tx *integrityTx) DeleteMulti(ctx context.Context, keys []*record.Key) error {
+	p := deletePlan{planned: make(map[string]bool, len(keys))}
+	for _, key := range keys {
+		p.planned[key.String()] = true
+	}
+	for _, key := range keys {
+		if err := tx.plan(ctx, &p, key); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.restricts {
+		if !p.planned[r.Referrer.String()] {
+			return r
+		}
+	}
+	for _, n := range p.nulls {
+		if p.planned[n.key.String()] {
+			continue
+		}
+		if err := tx.ReadwriteTransaction.Update(ctx, n.key, []update.Update{update.ByFieldName(string(n.field), nil)}); err != nil {
+			return fmt.Errorf("integrity: failed to clear %s of %v: %w", n.field, n.key, err)
+		}
+	}
+	var err error
+	if len(p.deletes) == 1 {
+		err = tx.ReadwriteTransaction.Delete(ctx, p.deletes[0])
+	} else {
+		err = tx.ReadwriteTransaction.DeleteMulti(ctx, p.deletes)
+	}
+	if err != nil {
+		return err
+	}
+	for _, key := range p.deletes {
+		s := key.String()
+		tx.deleted[s] = true
+		delete(tx.written, s)
+	}
+	return nil
+}
+
+// deletePlan collects the writes of a delete.
+type deletePlan struct {
+	// planned holds the keys of the records to delete.
+	planned map[string]bool
+	// deletes lists the records to delete, referencing records first.
+	deletes []*record.Key
+	// nulls lists the references to clear.
+	nulls []nullRef
+	// restricts lists the Restrict references to the records to delete,
+	// which fail the delete unless their referrer is deleted too.
+	restricts []*ReferencedError
+}
+
+type nullRef struct {
+	key   *record.Key
+	field dal.FieldName
+}
+
+// plan adds the delete of key, and what its references call for, to p.
+func (tx *integrityTx) plan(ctx context.Context, p *deletePlan, key *record.Key) error {
+	for _, ref := range tx.refs {
+		if ref.Target != key.Collection() {
+			continue
+		}
+		referrers, err := tx.referrers(ctx, ref, key)
+		if err != nil {
+			return err
+		}
+		for _, referrer := range referrers {
+			switch ref.OnDelete {
+			case Restrict:
+				p.restricts = append(p.restricts, &ReferencedError{Reference: ref, Key: key, Referrer: referrer})
+			case Cascade:
+				if !p.planned[referrer.String()] {
+					p.planned[referrer.String()] = true
+					if err = tx.plan(ctx, p, referrer); err != nil {
+						return err
+					}
+				}
+			case SetNull:
+				p.nulls = append(p.nulls, nullRef{key: referrer, field: ref.Field})
+			}
+		}
+	}
+	p.deletes = append(p.deletes, key)
+	return nil
+}
+
+// referrers returns the keys of the records that reference key via ref,
+// leaving out those deleted earlier in the transaction.
+func (tx *integrityTx) referrers(ctx context.Context, ref Reference, key *record.Key) ([]*record.Key, error) {
+	q := dal.From(dal.NewRootCollectionRef(ref.Collection, "")).NewQuery().
+		WhereField(string(ref.Field), dal.Equal, key.ID).
+		SelectKeysOnly(reflect.String)
+	var keys []*record.Key
+	for r, err := range dal.ExecuteQueryAndIterateRecords(ctx, q, tx.ReadwriteTransaction) {
+		if err != nil {
+			return nil, fmt.Errorf("integrity: failed to query %s referencing %v: %w", ref.Collection, key, err)
+		}
+		if !tx.deleted[r.Key().String()] {
+			keys = append(keys, r.Key())
+		}
+	}
+	return keys, nil
+}
+
+// checkRecords checks that the references of records point at existing
+// records, counting the records themselves as written.
+func (tx *integrityTx) checkRecords(ctx context.Context, records []record.Record) error {
+	batch := make(map[string]bool, len(records))
+	for _, r := range records {
+		if r.Key().ID != nil {
+			batch[r.Key().String()] = true
+		}
+	}
+	for _, r := range records {
+		refs := tx.refsFrom(r.Key().Collection())
+		if len(refs) == 0 {
+			continue
+		}
+		fields, err := topLevelFields(dbschema.WrittenData(r))
+		if err != nil {
+			return fmt.Errorf("integrity: %v: %w", r.Key(), err)
+		}
+		for _, ref := range refs {
+			if err = tx.checkReference(ctx, ref, r.Key(), fields[string(ref.Field)], batch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkUpdates checks the reference fields that updates set.
+func (tx *integrityTx) checkUpdates(ctx context.Context, key *record.Key, updates []update.Update) error {
+	refs := tx.refsFrom(key.Collection())
+	if len(refs) == 0 {
+		return nil
+	}
+	for _, u := range updates {
+		name := u.FieldName()
+		if path := u.FieldPath(); len(path) == 1 {
+			name = path[0]
+		} else if len(path) > 1 {
+			continue
+		}
+		value := u.Value()
+		if value == update.DeleteField {
+			continue
+		}
+		for _, ref := range refs {
+			if string(ref.Field) != name {
+				continue
+			}
+			v, err := dbschema.NormalizeValue(value)
+			if err != nil {
+				return fmt.Errorf("integrity: %v: %w", key, err)
+			}
+			if err = tx.checkReference(ctx, ref, key, v, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkReference checks that the normalized value of a reference field is
+// null or the id of an existing record.
+func (tx *integrityTx) checkReference(ctx context.Context, ref Reference, key *record.Key, value any, batch map[string]bool) error {
+	var id any
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		id = v
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return fmt.Errorf("integrity: %v: field %s holds %s, not a record id", key, ref.Field, v)
+		}
+		id = i
+	default:
+		return fmt.Errorf("integrity: %v: field %s holds a %T, not a record id", key, ref.Field, v)
+	}
+	parent := record.NewKeyWithID(ref.Target, id)
+	s := parent.String()
+	if batch[s] || tx.written[s] {
+		return nil
+	}
+	if !tx.deleted[s] {
+		exists, err := tx.ReadwriteTransaction.Exists(ctx, parent)
+		if err != nil {
+			return fmt.Errorf("integrity: failed to check %v referenced by %v: %w", parent, key, err)
+		}
+		if exists {
+			return nil
+		}
+	}
+	return &DanglingReferenceError{Reference: ref, Key: key, Parent: parent}
+}
+
+// refsFrom returns the references declared on the fields of collection.
+func (tx *integrityTx) refsFrom(collection string) []Reference {
+	var refs []Reference
+	for _, ref := range tx.refs {
+		if ref.Collection == collection {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (tx *integrityTx) markWritten(key *record.Key) {
+	if key.ID == nil {
+		return
+	}
+	s := key.String()
+	tx.written[s] = true
+	delete(tx.deleted, s)
+}
+
+// topLevelFields returns the fields of record data as encoding/json
+// encodes them.
+func topLevelFields(data any) (map[string]any, error) {
+	v, err := dbschema.NormalizeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("record data is not an object")
+	}
+	return fields, nil
+}
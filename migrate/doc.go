@@ -0,0 +1,24 @@
+// Package migrate runs versioned schema and data migrations against any
+// DALgo database.
+//
+// A [Migration] has a version, a name, and ordered up and down steps. A step
+// is either a DDL operation applied through the [ddl] helpers
+// ([CreateCollection], [DropCollection], [AlterCollection], [FromPlan]) or a
+// Go function migrating data inside a read-write transaction ([Data]).
+//
+// A [Migrator] records the applied versions in a history collection through
+// the normal dal.DB API and holds a lock record while it runs, so that two
+// runners do not apply the same migrations. Up applies the pending
+// migrations in version order, Down reverts applied ones, and both can be
+// run as a dry run that only reports what they would do.
+//
+// On a driver that supports transactional DDL ([ddl.SupportsTransactionalDDL])
+// the steps of a migration and its history write run in one read-write
+// transaction, so a failing migration leaves no trace. Otherwise each step
+// runs on its own, and only a data step is atomic: a migration whose first
+// step is a data step that fails leaves no trace, while any other failure
+// leaves the migration partially applied. Its history record is then marked
+// dirty and the runner refuses to run until [Migrator.Force] resolves it. The
+// returned *MigrationError wraps the cause, including a
+// *ddl.PartialSuccessError from a non-transactional driver.
+package migrate
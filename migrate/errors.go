@@ -0,0 +1,49 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLocked is returned when another runner holds the migration lock.
+var ErrLocked = errors.New("migrate: locked by another runner")
+
+// ErrDirty is returned when the history has a partially applied migration,
+// which Migrator.Force must resolve before migrations run again.
+var ErrDirty = errors.New("migrate: dirty migration")
+
+// ErrIrreversible is returned by Down for a migration without down steps.
+var ErrIrreversible = errors.New("migrate: migration has no down steps")
+
+// MigrationError is returned when a step of a migration fails.
+type MigrationError struct {
+	// Version and Name identify the migration.
+	Version int64
+	Name    string
+	// Direction is "up" or "down".
+	Direction string
+	// Step is the index of the failed step and StepDescription its
+	// description.
+	Step            int
+	StepDescription string
+	// Dirty is true when the failure left the migration partially applied
+	// and its history record was marked dirty.
+	Dirty bool
+	// Cause is the error of the step.
+	Cause error
+}
+
+// Error returns a readable single-line message.
+func (e *MigrationError) Error() string {
+	state := "nothing applied"
+	if e.Dirty {
+		state = "dirty"
+	}
+	return fmt.Sprintf("migrate: %s %d %q: step %d (%s) failed, %s: %v",
+		e.Direction, e.Version, e.Name, e.Step+1, e.StepDescription, state, e.Cause)
+}
+
+// Unwrap returns Cause, so errors.As finds a *ddl.PartialSuccessError.
+func (e *MigrationError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/record"
+)
+
+// historyRecord is the record of an applied, or partially applied, migration.
+type historyRecord struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"appliedAt"`
+	// Dirty marks a migration a failed step left partially applied in
+	// Direction, with Error the failure.
+	Dirty     bool   `json:"dirty,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// lockRecord is the record of the runner holding the migration lock.
+type lockRecord struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+func (m *Migrator) historyKey(version int64) *record.Key {
+	return record.NewKeyWithID(m.history, strconv.FormatInt(version, 10))
+}
+
+func (m *Migrator) lockKey() *record.Key {
+	return record.NewKeyWithID(m.history+"_lock", "lock")
+}
+
+// loadHistory returns the history records by version.
+func (m *Migrator) loadHistory(ctx context.Context) (map[int64]historyRecord, error) {
+	q := dal.From(dal.NewRootCollectionRef(m.history, "")).NewQuery().SelectIntoRecord(func() record.Record {
+		return record.NewRecordWithIncompleteKey(m.history, reflect.String, new(historyRecord))
+	})
+	history := make(map[int64]historyRecord)
+	for r, err := range dal.ExecuteQueryAndIterateRecords(ctx, q, m.db) {
+		if err != nil {
+			return nil, fmt.Errorf("migrate: failed to load history from %q: %w", m.history, err)
+		}
+		h := *r.Data().(*historyRecord)
+		history[h.Version] = h
+	}
+	return history, nil
+}
+
+// recordRun records in tx that migration was applied, or removes it from the
+// history when it was reverted.
+func (m *Migrator) recordRun(ctx context.Context, tx dal.ReadwriteTransaction, direction string, migration Migration) error {
+	if direction == down {
+		return tx.Delete(ctx, m.historyKey(migration.Version))
+	}
+	h := historyRecord{Version: migration.Version, Name: migration.Name, AppliedAt: m.clock()}
+	return tx.Set(ctx, record.NewRecordWithData(m.historyKey(h.Version), &h))
+}
+
+func (m *Migrator) writeHistory(ctx context.Context, h historyRecord) error {
+	return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Set(ctx, record.NewRecordWithData(m.historyKey(h.Version), &h))
+	})
+}
+
+func (m *Migrator) deleteHistory(ctx context.Context, version int64) error {
+	return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return tx.Delete(ctx, m.historyKey(version))
+	})
+}
+
+// lock takes the migration lock, failing with ErrLocked while another owner
+// holds an unexpired one.
+func (m *Migrator) lock(ctx context.Context) error {
+	return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		var current lockRecord
+		if err := tx.Get(ctx, record.NewRecordWithData(m.lockKey(), &current)); err != nil && !record.IsNotFound(err) {
+			return fmt.Errorf("migrate: failed to read lock: %w", err)
+		} else if err == nil && current.Owner != m.owner && m.clock().Before(current.Expires) {
+			return fmt.Errorf("%w: held by %s until %s", ErrLocked, current.Owner, current.Expires.Format(time.RFC3339))
+		}
+		return tx.Set(ctx, record.NewRecordWithData(m.lockKey(), &lockRecord{Owner: m.owner, Expires: m.clock().Add(m.lockTTL)}))
+	})
+}
+
+// keepLock renews the lock every third of its TTL until the returned stop is
+// called, so that a run outlasting the TTL keeps it. When a renewal fails,
+// because another runner took over the expired lock or the database failed,
+// the returned context is canceled and stop returns the failure.
+func (m *Migrator) keepLock(ctx context.Context) (context.Context, func() error) {
+	interval := m.lockTTL / 3
+	if interval <= 0 {
+		return ctx, func() error { return nil }
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	var renewErr error
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.lock(ctx); err != nil {
+					renewErr = fmt.Errorf("migrate: failed to renew lock: %w", err)
+					cancel(renewErr)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() error {
+		close(done)
+		<-stopped
+		cancel(nil)
+		return renewErr
+	}
+}
+
+// unlock releases the migration lock if this runner still holds it.
+func (m *Migrator) unlock(ctx context.Context) error {
+	return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		var current lockRecord
+		if err := tx.Get(ctx, record.NewRecordWithData(m.lockKey(), &current)); err != nil {
+			if record.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("migrate: failed to read lock: %w", err)
+		}
+		if current.Owner != m.owner {
+			return nil
+		}
+		return tx.Delete(ctx, m.lockKey())
+	})
+}
@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+)
+
+// Migration is one versioned change of a database.
+type Migration struct {
+	// Version orders the migrations and identifies the migration in the
+	// history. It must be positive and unique; a timestamp such as
+	// 20260118093000 is a common choice.
+	Version int64
+	// Name is a short human-readable description.
+	Name string
+	// Up are the steps applying the migration, in order.
+	Up []Step
+	// Down are the steps reverting the migration, in order. A migration
+	// without down steps cannot be reverted.
+	Down []Step
+}
+
+// Step is one operation of a migration: a DDL operation or a data migration.
+type Step struct {
+	description string
+	// ddl is true for a DDL step, which is atomic only on a driver that
+	// supports transactional DDL.
+	ddl bool
+	run func(ctx context.Context, db dal.DB) error
+	// data is the function of a data step, which run runs in a transaction
+	// of its own.
+	data func(ctx context.Context, tx dal.ReadwriteTransaction) error
+}
+
+// runIn runs the step in the read-write transaction tx of db, whose context is
+// ctx.
+func (s Step) runIn(ctx context.Context, db dal.DB, tx dal.ReadwriteTransaction) error {
+	if s.data != nil {
+		return s.data(ctx, tx)
+	}
+	return s.run(ctx, db)
+}
+
+// String describes the step.
+func (s Step) String() string {
+	return s.description
+}
+
+// CreateCollection returns a step creating a collection with ddl.CreateCollection.
+func CreateCollection(c dbschema.CollectionDef, opts ...ddl.Option) Step {
+	return Step{
+		description: ddl.Step{Kind: ddl.StepCreateCollection, Collection: c.Name, Create: &c}.String(),
+		ddl:         true,
+		run: func(ctx context.Context, db dal.DB) error {
+			return ddl.CreateCollection(ctx, db, c, opts...)
+		},
+	}
+}
+
+// DropCollection returns a step dropping a collection with ddl.DropCollection.
+func DropCollection(name string, opts ...ddl.Option) Step {
+	return Step{
+		description: ddl.Step{Kind: ddl.StepDropCollection, Collection: name}.String(),
+		ddl:         true,
+		run: func(ctx context.Context, db dal.DB) error {
+			return ddl.DropCollection(ctx, db, name, opts...)
+		},
+	}
+}
+
+// AlterCollection returns a step applying ops to a collection with a single
+// ddl.AlterCollection call.
+func AlterCollection(name string, ops ...ddl.AlterOp) Step {
+	descriptions := make([]string, len(ops))
+	for i, op := range ops {
+		descriptions[i] = ddl.Step{Kind: ddl.StepAlterCollection, Collection: name, Op: op}.String()
+	}
+	return Step{
+		description: strings.Join(descriptions, "; "),
+		ddl:         true,
+		run: func(ctx context.Context, db dal.DB) error {
+			return ddl.AlterCollection(ctx, db, name, ops...)
+		},
+	}
+}
+
+// FromPlan returns the steps applying a plan produced by ddl.Diff, one per
+// create or drop step and one per run of alterations of a collection, as
+// ddl.Plan.Apply applies them.
+func FromPlan(plan *ddl.Plan) []Step {
+	if plan == nil {
+		return nil
+	}
+	var steps []Step
+	for i := 0; i < len(plan.Steps); {
+		s := plan.Steps[i]
+		switch s.Kind {
+		case ddl.StepCreateCollection:
+			steps = append(steps, CreateCollection(*s.Create))
+			i++
+		case ddl.StepDropCollection:
+			steps = append(steps, DropCollection(s.Collection))
+			i++
+		case ddl.StepAlterCollection:
+			var ops []ddl.AlterOp
+			for ; i < len(plan.Steps) && plan.Steps[i].Kind == s.Kind && plan.Steps[i].Collection == s.Collection; i++ {
+				ops = append(ops, plan.Steps[i].Op)
+			}
+			steps = append(steps, AlterCollection(s.Collection, ops...))
+		default:
+			steps = append(steps, Step{description: s.String(), ddl: true, run: func(context.Context, dal.DB) error {
+				return fmt.Errorf("migrate: plan step %q has unknown kind %d", s.Collection, s.Kind)
+			}})
+			i++
+		}
+	}
+	return steps
+}
+
+// Data returns a step running fn in a read-write transaction, for
+// migrations of stored records that DDL cannot express: backfills, splits,
+// renames of values. A failing fn rolls the transaction back.
+func Data(description string, fn func(ctx context.Context, tx dal.ReadwriteTransaction) error) Step {
+	return Step{
+		description: "data: " + description,
+		run: func(ctx context.Context, db dal.DB) error {
+			return db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+				return fn(ctx, tx)
+			})
+		},
+		data: fn,
+	}
+}
@@ -0,0 +1,421 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/ddl"
+)
+
+// DefaultHistoryCollection is the collection a Migrator records applied
+// migrations in unless WithHistoryCollection names another.
+const DefaultHistoryCollection = "dalgo_migrations"
+
+// DefaultLockTTL is how long a runner's lock is honored unless WithLockTTL
+// sets another duration. A runner that dies holding the lock blocks others
+// until it expires.
+const DefaultLockTTL = 15 * time.Minute
+
+// Option configures a Migrator created by New.
+type Option func(*Migrator)
+
+// WithHistoryCollection sets the collection applied migrations are recorded
+// in. The lock record is kept in a collection of the same name suffixed with
+// "_lock". Both must be writable through the database's schema.
+func WithHistoryCollection(name string) Option {
+	return func(m *Migrator) {
+		m.history = name
+	}
+}
+
+// WithClock sets the clock that stamps applied migrations and lock expiry
+// (default time.Now).
+func WithClock(clock func() time.Time) Option {
+	return func(m *Migrator) {
+		m.clock = clock
+	}
+}
+
+// WithOwner sets the name the runner holds the lock under (default the
+// host name and process id with a random suffix, unique to the Migrator).
+// Runners with the same owner share the lock.
+func WithOwner(owner string) Option {
+	return func(m *Migrator) {
+		m.owner = owner
+	}
+}
+
+// WithLockTTL sets how long a runner's lock is honored (default
+// DefaultLockTTL).
+func WithLockTTL(ttl time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTTL = ttl
+	}
+}
+
+// Migrator applies and reverts registered migrations, recording them in a
+// history collection of the database.
+type Migrator struct {
+	db         dal.DB
+	migrations []Migration // sorted by version
+	history    string
+	clock      func() time.Time
+	owner      string
+	lockTTL    time.Duration
+}
+
+// New returns a Migrator for db.
+func New(db dal.DB, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:      db,
+		history: DefaultHistoryCollection,
+		clock:   time.Now,
+		lockTTL: DefaultLockTTL,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.owner == "" {
+		host, _ := os.Hostname()
+		m.owner = fmt.Sprintf("%s/%d/%s", host, os.Getpid(), rand.Text())
+	}
+	return m
+}
+
+// Register adds migrations. It fails, registering none of them, when a
+// version is not positive or already registered, or a name is empty.
+func (m *Migrator) Register(migrations ...Migration) error {
+	registered := slices.Clone(m.migrations)
+	for _, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migrate: migration %q has version %d, which is not positive", migration.Name, migration.Version)
+		}
+		if migration.Name == "" {
+			return fmt.Errorf("migrate: migration %d has no name", migration.Version)
+		}
+		if _, ok := findMigration(registered, migration.Version); ok {
+			return fmt.Errorf("migrate: migration %d is registered twice", migration.Version)
+		}
+		registered = append(registered, migration)
+	}
+	slices.SortFunc(registered, func(a, b Migration) int { return compareVersions(a.Version, b.Version) })
+	m.migrations = registered
+	return nil
+}
+
+func compareVersions(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func findMigration(migrations []Migration, version int64) (Migration, bool) {
+	i := slices.IndexFunc(migrations, func(m Migration) bool { return m.Version == version })
+	if i < 0 {
+		return Migration{}, false
+	}
+	return migrations[i], true
+}
+
+// RunOption configures a single Up or Down run.
+type RunOption func(*runOptions)
+
+type runOptions struct {
+	to     *int64
+	dryRun bool
+}
+
+// To bounds a run: Up applies the pending migrations up to and including
+// version, and Down reverts the applied migrations above version (To(0)
+// reverts all of them).
+func To(version int64) RunOption {
+	return func(o *runOptions) {
+		o.to = &version
+	}
+}
+
+// DryRun makes a run report the migrations and steps it would run without
+// running them, taking the lock, or writing the history.
+func DryRun() RunOption {
+	return func(o *runOptions) {
+		o.dryRun = true
+	}
+}
+
+// Report lists the migrations a run applied or reverted, in order, or
+// would have for a dry run.
+type Report struct {
+	Direction  string
+	DryRun     bool
+	Migrations []MigrationRun
+}
+
+// MigrationRun is one migration of a Report with the descriptions of its
+// steps.
+type MigrationRun struct {
+	Version int64
+	Name    string
+	Steps   []string
+}
+
+// String renders the report, one line per migration followed by its steps.
+func (r *Report) String() string {
+	if r == nil || len(r.Migrations) == 0 {
+		return "no migrations to run"
+	}
+	var b strings.Builder
+	verb := r.Direction
+	if r.DryRun {
+		verb = "would run " + verb
+	}
+	for _, run := range r.Migrations {
+		fmt.Fprintf(&b, "%s %d %s\n", verb, run.Version, run.Name)
+		for i, step := range run.Steps {
+			fmt.Fprintf(&b, "  %d. %s\n", i+1, step)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+const (
+	up   = "up"
+	down = "down"
+)
+
+// Up applies the pending registered migrations in version order, each
+// recorded in the history once all its steps succeed. A registered
+// migration older than an applied one is still applied. Up returns the
+// report of the migrations applied before an error, if any.
+func (m *Migrator) Up(ctx context.Context, opts ...RunOption) (*Report, error) {
+	o := resolveRunOptions(opts)
+	return m.run(ctx, up, o, func(history map[int64]historyRecord) ([]Migration, error) {
+		var pending []Migration
+		for _, migration := range m.migrations {
+			if _, applied := history[migration.Version]; applied || o.to != nil && migration.Version > *o.to {
+				continue
+			}
+			pending = append(pending, migration)
+		}
+		return pending, nil
+	})
+}
+
+// Down reverts applied migrations in descending version order: the latest
+// one, or, with To, every one above the given version. Reverting a
+// migration that is not registered or has no down steps fails before
+// anything is reverted.
+func (m *Migrator) Down(ctx context.Context, opts ...RunOption) (*Report, error) {
+	o := resolveRunOptions(opts)
+	return m.run(ctx, down, o, func(history map[int64]historyRecord) ([]Migration, error) {
+		versions := make([]int64, 0, len(history))
+		for version := range history {
+			if o.to == nil || version > *o.to {
+				versions = append(versions, version)
+			}
+		}
+		slices.SortFunc(versions, func(a, b int64) int { return compareVersions(b, a) })
+		if o.to == nil && len(versions) > 1 {
+			versions = versions[:1]
+		}
+		reverts := make([]Migration, len(versions))
+		for i, version := range versions {
+			migration, ok := findMigration(m.migrations, version)
+			if !ok {
+				return nil, fmt.Errorf("migrate: applied migration %d (%s) is not registered", version, history[version].Name)
+			}
+			if len(migration.Down) == 0 {
+				return nil, fmt.Errorf("%w: %d %q", ErrIrreversible, version, migration.Name)
+			}
+			reverts[i] = migration
+		}
+		return reverts, nil
+	})
+}
+
+func resolveRunOptions(opts []RunOption) runOptions {
+	var o runOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// run selects the migrations of a run from the history and runs them,
+// holding the lock, renewed while they run, unless it is a dry run.
+func (m *Migrator) run(ctx context.Context, direction string, o runOptions, selectMigrations func(map[int64]historyRecord) ([]Migration, error)) (report *Report, err error) {
+	if !o.dryRun {
+		if err = m.lock(ctx); err != nil {
+			return nil, err
+		}
+		unlockCtx := ctx
+		defer func() {
+			if unlockErr := m.unlock(unlockCtx); err == nil {
+				err = unlockErr
+			}
+		}()
+		var stop func() error
+		ctx, stop = m.keepLock(ctx)
+		defer func() {
+			if renewErr := stop(); renewErr != nil {
+				err = errors.Join(err, renewErr)
+			}
+		}()
+	}
+	history, err := m.loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range history {
+		if h.Dirty {
+			return nil, fmt.Errorf("%w: %s %d %q failed: %s", ErrDirty, h.Direction, h.Version, h.Name, h.Error)
+		}
+	}
+	migrations, err := selectMigrations(history)
+	if err != nil {
+		return nil, err
+	}
+	report = &Report{Direction: direction, DryRun: o.dryRun}
+	for _, migration := range migrations {
+		steps := migration.Up
+		if direction == down {
+			steps = migration.Down
+		}
+		run := MigrationRun{Version: migration.Version, Name: migration.Name, Steps: make([]string, len(steps))}
+		for i, step := range steps {
+			run.Steps[i] = step.String()
+		}
+		if !o.dryRun {
+			if err = context.Cause(ctx); err != nil {
+				return report, err
+			}
+			if err = m.runSteps(ctx, direction, migration, steps); err != nil {
+				return report, err
+			}
+		}
+		report.Migrations = append(report.Migrations, run)
+	}
+	return report, nil
+}
+
+// runSteps runs the steps of a migration and records the outcome. On a
+// database with transactional DDL the steps and the history write run in one
+// read-write transaction, so a failing migration leaves nothing applied.
+// Otherwise each step runs on its own, and a failure once a step took effect
+// marks the migration dirty.
+func (m *Migrator) runSteps(ctx context.Context, direction string, migration Migration, steps []Step) error {
+	if ddl.SupportsTransactionalDDL(m.db) {
+		return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+			for i, step := range steps {
+				if err := step.runIn(ctx, m.db, tx); err != nil {
+					return m.stepError(direction, migration, i, step, false, err)
+				}
+			}
+			return m.recordRun(ctx, tx, direction, migration)
+		})
+	}
+	for i, step := range steps {
+		err := step.run(ctx, m.db)
+		if err == nil {
+			continue
+		}
+		migrationErr := m.stepError(direction, migration, i, step, i > 0 || step.ddl, err)
+		if migrationErr.Dirty {
+			dirty := historyRecord{Version: migration.Version, Name: migration.Name, AppliedAt: m.clock(),
+				Dirty: true, Direction: direction, Error: err.Error()}
+			if err := m.writeHistory(ctx, dirty); err != nil {
+				return fmt.Errorf("%w; recording it failed: %v", migrationErr, err)
+			}
+		}
+		return migrationErr
+	}
+	return m.db.RunReadwriteTransaction(ctx, func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+		return m.recordRun(ctx, tx, direction, migration)
+	})
+}
+
+func (m *Migrator) stepError(direction string, migration Migration, i int, step Step, dirty bool, err error) *MigrationError {
+	return &MigrationError{
+		Version:         migration.Version,
+		Name:            migration.Name,
+		Direction:       direction,
+		Step:            i,
+		StepDescription: step.String(),
+		Dirty:           dirty,
+		Cause:           err,
+	}
+}
+
+// Status describes a migration that is registered, applied, or both.
+type Status struct {
+	Version    int64
+	Name       string
+	Registered bool
+	Applied    bool
+	AppliedAt  time.Time
+	// Dirty is true for a partially applied migration, with Error the
+	// failure that left it so.
+	Dirty bool
+	Error string
+}
+
+// Status returns the registered and applied migrations in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	history, err := m.loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []Status
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name, Registered: true}
+		if h, ok := history[migration.Version]; ok {
+			s.Applied, s.AppliedAt, s.Dirty, s.Error = true, h.AppliedAt, h.Dirty, h.Error
+			delete(history, migration.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, h := range history {
+		statuses = append(statuses, Status{Version: h.Version, Name: h.Name, Applied: true, AppliedAt: h.AppliedAt, Dirty: h.Dirty, Error: h.Error})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return compareVersions(a.Version, b.Version) })
+	return statuses, nil
+}
+
+// Force resolves a migration by hand, typically a dirty one after its
+// partial changes were completed or undone: with applied it records the
+// migration as cleanly applied, otherwise it removes it from the history.
+func (m *Migrator) Force(ctx context.Context, version int64, applied bool) (err error) {
+	if err = m.lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := m.unlock(ctx); err == nil {
+			err = unlockErr
+		}
+	}()
+	if !applied {
+		return m.deleteHistory(ctx, version)
+	}
+	h := historyRecord{Version: version, AppliedAt: m.clock()}
+	if migration, ok := findMigration(m.migrations, version); ok {
+		h.Name = migration.Name
+	} else if history, err := m.loadHistory(ctx); err != nil {
+		return err
+	} else if existing, ok := history[version]; ok {
+		h.Name = existing.Name
+	} else {
+		return fmt.Errorf("migrate: migration %d is neither registered nor applied", version)
+	}
+	return m.writeHistory(ctx, h)
+}
@@ -0,0 +1,307 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dal-go/dalgo/adapters/dalgo2memory"
+	"github.com/dal-go/dalgo/dal"
+	"github.com/dal-go/dalgo/dbschema"
+	"github.com/dal-go/dalgo/ddl"
+	"github.com/dal-go/record"
+	"github.com/dal-go/record/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 2,
+			Name:    "add users.active",
+			Up: []Step{
+				AlterCollection("users", ddl.AddField(dbschema.FieldDef{Name: "active", Type: dbschema.Bool, Default: dbschema.DefaultLiteral{Value: true}})),
+				Data("deactivate guests", func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+					return tx.Update(ctx, record.NewKeyWithID("users", "guest"), []update.Update{update.ByFieldName("active", false)})
+				}),
+			},
+			Down: []Step{AlterCollection("users", ddl.DropField("active"))},
+		},
+		{
+			Version: 1,
+			Name:    "create users",
+			Up: []Step{
+				CreateCollection(dbschema.CollectionDef{Name: "users", Fields: []dbschema.FieldDef{{Name: "email", Type: dbschema.String}}}),
+				Data("seed users", func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+					return tx.SetMulti(ctx, []record.Record{
+						record.NewRecordWithData(record.NewKeyWithID("users", "ann"), map[string]any{"email": "ann@example.com"}),
+						record.NewRecordWithData(record.NewKeyWithID("users", "guest"), map[string]any{"email": ""}),
+					})
+				}),
+			},
+			Down: []Step{DropCollection("users")},
+		},
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	m := New(db)
+	require.NoError(t, m.Register(testMigrations()...))
+
+	report, err := m.Up(ctx, DryRun())
+	require.NoError(t, err)
+	assert.Equal(t, `would run up 1 create users
+  1. create collection users (email string not null)
+  2. data: seed users
+would run up 2 add users.active
+  1. alter users: add field active bool not null default true
+  2. data: deactivate guests`, report.String())
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Applied || statuses[1].Applied)
+
+	report, err = m.Up(ctx, To(1))
+	require.NoError(t, err)
+	require.Len(t, report.Migrations, 1)
+	assert.Equal(t, int64(1), report.Migrations[0].Version)
+
+	report, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Migrations, 1)
+	assert.Equal(t, int64(2), report.Migrations[0].Version)
+	var guest map[string]any
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "guest"), &guest)))
+	assert.Equal(t, false, guest["active"])
+
+	report, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "no migrations to run", report.String())
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied && statuses[1].Applied)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+
+	report, err = m.Down(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "down 2 add users.active\n  1. alter users: drop field active", report.String())
+	guest = nil
+	require.NoError(t, db.Get(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "guest"), &guest)))
+	assert.NotContains(t, guest, "active")
+
+	report, err = m.Down(ctx, To(0))
+	require.NoError(t, err)
+	require.Len(t, report.Migrations, 1)
+	exists, err := db.Exists(ctx, record.NewKeyWithID("users", "ann"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied || statuses[1].Applied)
+}
+
+func TestMigrator_Register(t *testing.T) {
+	m := New(dalgo2memory.NewDB())
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "one"}))
+	assert.Error(t, m.Register(Migration{Version: 2, Name: "two"}, Migration{Version: 1, Name: "again"}))
+	assert.Error(t, m.Register(Migration{Version: 0, Name: "zero"}))
+	assert.Error(t, m.Register(Migration{Version: 3}))
+	assert.Len(t, m.migrations, 1)
+}
+
+func TestMigrator_Irreversible(t *testing.T) {
+	ctx := context.Background()
+	m := New(dalgo2memory.NewDB())
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "noop", Up: []Step{Data("nothing", func(context.Context, dal.ReadwriteTransaction) error { return nil })}}))
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+	_, err = m.Down(ctx)
+	assert.ErrorIs(t, err, ErrIrreversible)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	first := New(db, WithOwner("first"), WithClock(clock), WithLockTTL(time.Minute))
+	second := New(db, WithOwner("second"), WithClock(clock))
+	require.NoError(t, first.lock(ctx))
+
+	_, err := second.Up(ctx)
+	assert.ErrorIs(t, err, ErrLocked)
+	_, err = second.Up(ctx, DryRun())
+	assert.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = second.Up(ctx)
+	require.NoError(t, err)
+	exists, err := db.Exists(ctx, second.lockKey())
+	require.NoError(t, err)
+	assert.False(t, exists, "lock is released after a run")
+}
+
+func TestMigrator_DefaultOwner(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	first, second := New(db), New(db)
+	assert.NotEqual(t, first.owner, second.owner, "Migrators of one process do not share the lock")
+	require.NoError(t, first.lock(ctx))
+	_, err := second.Up(ctx)
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+func TestMigrator_LockRenewal(t *testing.T) {
+	ctx := context.Background()
+	memory := dalgo2memory.NewDB()
+	db := nonTransactionalDB{DB: memory, modifier: memory.(ddl.SchemaModifier)}
+	m := New(db, WithOwner("first"), WithLockTTL(60*time.Millisecond))
+	other := New(db, WithOwner("other"))
+	var lockErr error
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "slow", Up: []Step{{
+		description: "outlast the lock TTL",
+		run: func(ctx context.Context, _ dal.DB) error {
+			time.Sleep(200 * time.Millisecond)
+			lockErr = other.lock(ctx)
+			return nil
+		},
+	}}}))
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, lockErr, ErrLocked, "the lock is renewed while steps run")
+}
+
+func TestMigrator_TransactionalRollback(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	require.True(t, ddl.SupportsTransactionalDDL(db))
+	m := New(db)
+	failure := errors.New("boom")
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "create and fail", Up: []Step{
+		CreateCollection(dbschema.CollectionDef{Name: "users", Fields: []dbschema.FieldDef{{Name: "email", Type: dbschema.String}}}),
+		Data("fail", func(context.Context, dal.ReadwriteTransaction) error { return failure }),
+	}}))
+
+	_, err := m.Up(ctx)
+	assert.ErrorIs(t, err, failure)
+	var migrationErr *MigrationError
+	require.ErrorAs(t, err, &migrationErr)
+	assert.False(t, migrationErr.Dirty, "the step and the history write run in one transaction")
+	assert.Equal(t, 1, migrationErr.Step)
+	users := dal.NewRootCollectionRef("users", "")
+	_, err = dbschema.DescribeCollection(ctx, db, &users)
+	assert.Error(t, err, "the created collection is rolled back")
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}
+
+func TestMigrator_AtomicFailure(t *testing.T) {
+	ctx := context.Background()
+	db := dalgo2memory.NewDB()
+	m := New(db)
+	failure := errors.New("boom")
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "fails", Up: []Step{
+		Data("fail", func(ctx context.Context, tx dal.ReadwriteTransaction) error {
+			if err := tx.Set(ctx, record.NewRecordWithData(record.NewKeyWithID("users", "ann"), map[string]any{})); err != nil {
+				return err
+			}
+			return failure
+		}),
+	}}))
+
+	_, err := m.Up(ctx)
+	assert.ErrorIs(t, err, failure)
+	var migrationErr *MigrationError
+	require.ErrorAs(t, err, &migrationErr)
+	assert.False(t, migrationErr.Dirty)
+	assert.Equal(t, `migrate: up 1 "fails": step 1 (data: fail) failed, nothing applied: boom`, err.Error())
+
+	exists, err := db.Exists(ctx, record.NewKeyWithID("users", "ann"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}
+
+// nonTransactionalDB is a database whose DDL is not transactional and whose
+// alterations fail partway.
+type nonTransactionalDB struct {
+	dal.DB
+	modifier ddl.SchemaModifier
+}
+
+func (db nonTransactionalDB) CreateCollection(ctx context.Context, c dbschema.CollectionDef, opts ...ddl.Option) error {
+	return db.modifier.CreateCollection(ctx, c, opts...)
+}
+
+func (db nonTransactionalDB) DropCollection(ctx context.Context, name string, opts ...ddl.Option) error {
+	return db.modifier.DropCollection(ctx, name, opts...)
+}
+
+func (db nonTransactionalDB) AlterCollection(_ context.Context, name string, ops ...ddl.AlterOp) error {
+	return &ddl.PartialSuccessError{Op: "AlterCollection", Collection: name, Applied: ops[:1], FirstFailed: ops[1], Cause: errors.New("disk full")}
+}
+
+func TestMigrator_DirtyAndForce(t *testing.T) {
+	ctx := context.Background()
+	memory := dalgo2memory.NewDB()
+	db := nonTransactionalDB{DB: memory, modifier: memory.(ddl.SchemaModifier)}
+	m := New(db)
+	require.NoError(t, m.Register(Migration{Version: 1, Name: "alter", Up: []Step{
+		AlterCollection("users", ddl.AddField(dbschema.FieldDef{Name: "a", Type: dbschema.Int, Nullable: true}), ddl.DropField("b")),
+	}}, Migration{Version: 2, Name: "next"}))
+
+	_, err := m.Up(ctx)
+	var partial *ddl.PartialSuccessError
+	assert.ErrorAs(t, err, &partial)
+	var migrationErr *MigrationError
+	require.ErrorAs(t, err, &migrationErr)
+	assert.True(t, migrationErr.Dirty)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Dirty)
+	assert.Contains(t, statuses[0].Error, "disk full")
+	assert.False(t, statuses[1].Applied)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrDirty)
+	_, err = m.Down(ctx)
+	assert.ErrorIs(t, err, ErrDirty)
+
+	require.NoError(t, m.Force(ctx, 1, true))
+	report, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Migrations, 1)
+	assert.Equal(t, int64(2), report.Migrations[0].Version)
+
+	require.NoError(t, m.Force(ctx, 2, false))
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied && !statuses[0].Dirty)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestFromPlan(t *testing.T) {
+	plan := ddl.DiffCollections(
+		[]dbschema.CollectionDef{{Name: "users", Fields: []dbschema.FieldDef{{Name: "nick", Type: dbschema.String}}}, {Name: "logs"}},
+		[]dbschema.CollectionDef{
+			{Name: "users", Fields: []dbschema.FieldDef{{Name: "nick", Type: dbschema.String}, {Name: "age", Type: dbschema.Int, Nullable: true}},
+				Indexes: []dbschema.IndexDef{{Name: "byNick", Fields: []dal.FieldName{"nick"}}}},
+			{Name: "orders"},
+		},
+		ddl.DropUnlistedCollections(),
+	)
+	steps := FromPlan(plan)
+	require.Len(t, steps, 3)
+	assert.Equal(t, "create collection orders ()", steps[0].String())
+	assert.Equal(t, "alter users: add field age int null; alter users: add index byNick index(nick)", steps[1].String())
+	assert.Equal(t, "drop collection logs", steps[2].String())
+	assert.Nil(t, FromPlan(nil))
+}
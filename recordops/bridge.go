@@ -2,8 +2,6 @@
 package recordops
 
 import (
-	"errors"
-
 	"github.com/dal-go/dalgo/dal"
 	"github.com/dal-go/record"
 )
@@ -27,21 +25,16 @@ func SliceToSeq[K comparable](records []record.WithID[K]) RecordSeq[K] {
 //
 // The underlying reader is Closed exactly once when iteration ends —
 // whether by exhausting records (dal.ErrNoMoreRecords), by the consumer
-// breaking out of the range loop early, or by any upstream stream error.
+// breaking out of the range loop early, or by any upstream stream error
+// (see dal.Records, which this bridge is built on).
 //
 // dal.Reader.Cursor() is NOT surfaced through this bridge in MVP;
-// callers needing pagination must drive the reader directly. See
-// spec/ideas/dal-records-reader-iter-seq.md.
+// callers needing pagination must drive the reader directly.
 func ReaderToSeq[K comparable](r dal.RecordsReader, idOf func(record.Record) (K, error)) RecordSeq[K] {
 	return func(yield func(record.WithID[K], error) bool) {
-		defer func() { _ = r.Close() }()
 		var zero record.WithID[K]
-		for {
-			rec, err := r.Next()
+		for rec, err := range dal.Records(r) {
 			if err != nil {
-				if errors.Is(err, dal.ErrNoMoreRecords) {
-					return
-				}
 				yield(zero, err)
 				return
 			}